@@ -293,6 +293,18 @@ func AuthInsecureConn(ctx context.Context, address string, project string) conte
 	return authCtx
 }
 
+// UploadTarget identifies the cluster which a connection created by
+// DialClusterFromInctl talks to, e.g. to remember which images it already holds.
+// It returns an empty string if the cluster is unknown, e.g. for connections to
+// a local port-forward, whose address may lead to different clusters over time.
+func UploadTarget(ctx context.Context, address string) string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if clusters := md.Get("x-server-name"); len(clusters) > 0 {
+		return address + "/" + clusters[len(clusters)-1]
+	}
+	return ""
+}
+
 func resolveClusterAddress(address string, project string) (string, error) {
 	if address != "" {
 		return address, nil
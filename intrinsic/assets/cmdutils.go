@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/google/go-containerregistry/pkg/authn"
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/google"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/pkg/errors"
@@ -59,6 +60,8 @@ const (
 	KeyOrgPrivate = "org_private"
 	// KeyOrganization is used as central flag name for passing an organization name to inctl.
 	KeyOrganization = orgutil.KeyOrganization
+	// KeyPlatform is the name of the flag selecting the platform of multi-platform images.
+	KeyPlatform = "platform"
 	// KeyPolicy defines the flag used to specify the policy option when
 	// interacting with the installed asset service.
 	KeyPolicy = "policy"
@@ -76,6 +79,8 @@ const (
 	KeyType = "type"
 	// KeyTimeout is the name of the timeout flag.
 	KeyTimeout = "timeout"
+	// KeyOperationTimeout is the name of the flag for the timeout of long-running operations.
+	KeyOperationTimeout = "operation_timeout"
 	// KeyUseBorgCredentials is the name of the flag to use borg credentials.
 	KeyUseBorgCredentials = "use_borg_credentials"
 	// KeyUseInProcCatalog is the name of the flag for using an in-proc catalog.
@@ -281,6 +286,26 @@ func (cf *CmdFlags) GetFlagPolicy() (iapb.UpdatePolicy, error) {
 	return iapb.UpdatePolicy_UPDATE_POLICY_UNSPECIFIED, fmt.Errorf("%q provided for --%v is invalid; valid values are: %v", policy, KeyPolicy, maps.Keys(policyMap))
 }
 
+// AddFlagPlatform adds a flag for the platform to install out of multi-platform images.
+func (cf *CmdFlags) AddFlagPlatform(assetType string) {
+	cf.OptionalString(KeyPlatform, "", fmt.Sprintf(`The platform of the %s images to install, e.g.
+"linux/amd64". Images for all platforms are installed if not set.`, assetType))
+}
+
+// GetFlagPlatform gets the value of the platform flag added by AddFlagPlatform. It returns nil if
+// the flag is not set.
+func (cf *CmdFlags) GetFlagPlatform() (*containerregistry.Platform, error) {
+	value := cf.GetString(KeyPlatform)
+	if value == "" {
+		return nil, nil
+	}
+	platform, err := containerregistry.ParsePlatform(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid value passed for --%s", KeyPlatform)
+	}
+	return platform, nil
+}
+
 // AddFlagsProjectOrg adds both the project and org flag, including the necessary handling.
 func (cf *CmdFlags) AddFlagsProjectOrg() {
 	// While WrapCmd returns the pointer to make it inline, it's modifying, so we can use it here.
@@ -420,6 +445,22 @@ func (cf *CmdFlags) GetFlagSideloadStartTimeout() (time.Duration, string, error)
 	return timeout, timeoutStr, nil
 }
 
+// AddFlagOperationTimeout adds a flag for the maximum time to wait for a long-running operation.
+func (cf *CmdFlags) AddFlagOperationTimeout(operation string) {
+	cf.OptionalString(KeyOperationTimeout, "0", fmt.Sprintf(`Maximum time to wait for the %s to
+finish. Can be set to any valid duration (\"60s\", \"5m\", ...) or to \"0\" to wait
+indefinitely.`, operation))
+}
+
+// GetFlagOperationTimeout gets the value of the flag added by AddFlagOperationTimeout.
+func (cf *CmdFlags) GetFlagOperationTimeout() (time.Duration, error) {
+	timeout, err := parseNonNegativeDuration(cf.GetString(KeyOperationTimeout))
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid value passed for --%s", KeyOperationTimeout)
+	}
+	return timeout, nil
+}
+
 // AddFlagSkipDirectUpload adds a flag for disabling direct upload to workcells
 func (cf *CmdFlags) AddFlagSkipDirectUpload(assetType string) {
 	usage := fmt.Sprintf("Skips direct upload of %s to workcell. Requires "+
@@ -25,15 +25,47 @@ type Transferer interface {
 	Read(ref name.Reference) (containerregistry.Image, error)
 }
 
+// IndexWriter is implemented by Transferers which can write image indexes,
+// i.e. images built for several platforms.
+type IndexWriter interface {
+	WriteIndex(ref name.Reference, index containerregistry.ImageIndex) error
+}
+
+// DigestResolver is implemented by Transferers which may know the digest of an
+// image without hashing its layers, e.g. because they uploaded it before.
+type DigestResolver interface {
+	ResolveDigest(img containerregistry.Image) (containerregistry.Hash, bool)
+}
+
 type remoteImage struct {
 	Opts []remote.Option
 }
 
 // Write pushes an image to a container registry.
 func (r remoteImage) Write(ref name.Reference, img containerregistry.Image) error {
+	if err := retryServerErrors(func() error {
+		return remote.Write(ref, img, r.Opts...)
+	}); err != nil {
+		return errors.Wrapf(err, "remote.Write to %q", ref)
+	}
+	return nil
+}
+
+// WriteIndex pushes an image index and all of its images to a container
+// registry.
+func (r remoteImage) WriteIndex(ref name.Reference, index containerregistry.ImageIndex) error {
+	if err := retryServerErrors(func() error {
+		return remote.WriteIndex(ref, index, r.Opts...)
+	}); err != nil {
+		return errors.Wrapf(err, "remote.WriteIndex to %q", ref)
+	}
+	return nil
+}
+
+func retryServerErrors(write func() error) error {
 	b := backoff.WithMaxRetries(backoff.NewExponentialBackOff(), remoteWriteTries)
-	if err := backoff.Retry(func() error {
-		err := remote.Write(ref, img, r.Opts...)
+	return backoff.Retry(func() error {
+		err := write()
 		if err, ok := err.(*transport.Error); ok && err.StatusCode >= 500 {
 			// Retry server errors like 504 Gateway Timeout.
 			return err
@@ -42,10 +74,7 @@ func (r remoteImage) Write(ref name.Reference, img containerregistry.Image) erro
 			return backoff.Permanent(err)
 		}
 		return nil
-	}, b); err != nil {
-		return errors.Wrapf(err, "remote.Write to %q", ref)
-	}
-	return nil
+	}, b)
 }
 
 // Read fetches an image from a container registry.
@@ -70,6 +99,12 @@ func (r *readonly) Write(ref name.Reference, img containerregistry.Image) error
 	return nil
 }
 
+// WriteIndex pushes an image index to a container registry.
+func (r *readonly) WriteIndex(ref name.Reference, index containerregistry.ImageIndex) error {
+	log.Infof("Skipping write to %q", ref.Name())
+	return nil
+}
+
 // Read fetches an image from a container registry.
 func (r *readonly) Read(ref name.Reference) (containerregistry.Image, error) {
 	return remote.Image(ref, r.Opts...)
@@ -4,14 +4,19 @@
 package imageutils
 
 import (
+	"archive/tar"
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/pkg/errors"
 	"github.com/rs/xid"
@@ -161,6 +166,9 @@ type ImageOptions struct {
 	Name string
 	// The tag to be given to the image.
 	Tag string
+	// The platform to push from a multi-platform image. All platforms are
+	// pushed if it is not set.
+	Platform *containerregistry.Platform
 }
 
 // BasicAuth provides the necessary fields to perform basic authentication with
@@ -186,6 +194,59 @@ type RegistryOptions struct {
 // PushImage takes an image and pushes it to the specified registry with the
 // given options.
 func PushImage(img containerregistry.Image, opts ImageOptions, reg RegistryOptions) (*ipb.Image, error) {
+	if opts.Platform != nil {
+		if err := checkPlatform(img, *opts.Platform); err != nil {
+			return nil, err
+		}
+	}
+	return push(imageDigest(img, reg), opts, reg, func(ref name.Tag) error {
+		return reg.Transferer.Write(ref, img)
+	})
+}
+
+// imageDigest returns a function computing the digest of img. The Transferer
+// of reg may know the digest already, which avoids hashing all layers.
+func imageDigest(img containerregistry.Image, reg RegistryOptions) func() (containerregistry.Hash, error) {
+	resolver, ok := reg.Transferer.(imagetransfer.DigestResolver)
+	if !ok {
+		return img.Digest
+	}
+	return func() (containerregistry.Hash, error) {
+		if digest, ok := resolver.ResolveDigest(img); ok {
+			return digest, nil
+		}
+		return img.Digest()
+	}
+}
+
+// PushImageIndex takes a multi-platform image and pushes it to the specified
+// registry with the given options. Only the image for opts.Platform is pushed
+// if it is set.
+func PushImageIndex(index containerregistry.ImageIndex, opts ImageOptions, reg RegistryOptions) (*ipb.Image, error) {
+	images, err := platformImages(index)
+	if err != nil {
+		return nil, fmt.Errorf("could not read image index: %v", err)
+	}
+	if opts.Platform != nil || len(images) == 1 {
+		img, err := selectImage(images, opts.Platform)
+		if err != nil {
+			return nil, err
+		}
+		return PushImage(img, opts, reg)
+	}
+
+	writer, ok := reg.Transferer.(imagetransfer.IndexWriter)
+	if !ok {
+		return nil, fmt.Errorf("the registry cannot store multi-platform images, select one of the platforms %v", platforms(images))
+	}
+	return push(index.Digest, opts, reg, func(ref name.Tag) error {
+		return writer.WriteIndex(ref, index)
+	})
+}
+
+// push writes the image or index with the given digest to the registry using
+// write.
+func push(digest func() (containerregistry.Hash, error), opts ImageOptions, reg RegistryOptions, write func(ref name.Tag) error) (*ipb.Image, error) {
 	registry := strings.TrimSuffix(reg.URI, "/")
 	if len(registry) == 0 {
 		return nil, fmt.Errorf("registry is empty")
@@ -199,12 +260,12 @@ func PushImage(img containerregistry.Image, opts ImageOptions, reg RegistryOptio
 		return nil, errors.Wrapf(err, "name.NewReference(%q)", dst)
 	}
 
-	digest, err := img.Digest()
+	hash, err := digest()
 	if err != nil {
 		return nil, fmt.Errorf("could not get the sha256 of the image: %v", err)
 	}
 
-	if err := reg.Transferer.Write(ref, img); err != nil {
+	if err := write(ref); err != nil {
 		return nil, fmt.Errorf("could not write image %q: %v", dst, err)
 	}
 
@@ -213,15 +274,36 @@ func PushImage(img containerregistry.Image, opts ImageOptions, reg RegistryOptio
 	return &ipb.Image{
 		Registry:     registry,
 		Name:         opts.Name,
-		Tag:          "@" + digest.String(),
+		Tag:          "@" + hash.String(),
 		AuthUser:     reg.User,
 		AuthPassword: reg.Pwd,
 	}, nil
 }
 
 // PushArchive takes an image archive provided by opener pushes it to the
-// specified registry.
+// specified registry. The archive is either a docker tarball or a tarball of
+// an OCI image layout, which may hold images for several platforms.
 func PushArchive(opener tarball.Opener, opts ImageOptions, reg RegistryOptions) (*ipb.Image, error) {
+	isOCI, err := isOCIArchive(opener)
+	if err != nil {
+		return nil, fmt.Errorf("could not read image archive: %v", err)
+	}
+	if isOCI {
+		dir, err := os.MkdirTemp("", "oci-layout")
+		if err != nil {
+			return nil, fmt.Errorf("could not create directory for OCI image layout: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		if err := extractArchive(opener, dir); err != nil {
+			return nil, fmt.Errorf("could not extract OCI image layout: %v", err)
+		}
+		index, err := ReadImageIndex(dir)
+		if err != nil {
+			return nil, err
+		}
+		return PushImageIndex(index, opts, reg)
+	}
+
 	// tarball.Image optionally takes a name.Tag as the second parameter.
 	// That's only needed if there are multiple images in the provided tarball,
 	// since it then uses the reference to find it.  This is different than how
@@ -235,6 +317,87 @@ func PushArchive(opener tarball.Opener, opts ImageOptions, reg RegistryOptions)
 	return PushImage(img, opts, reg)
 }
 
+// isOCIArchive tells if the archive provided by opener holds an OCI image
+// layout rather than a docker tarball. Docker tarballs written by recent
+// versions of docker contain both, they are read as docker tarballs if their
+// manifest.json comes first. Reading stops as soon as either is complete, so
+// layers after them are not read.
+func isOCIArchive(opener tarball.Opener) (bool, error) {
+	r, err := opener()
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	hasIndex, hasLayout := false, false
+	tr := tar.NewReader(r)
+	for !hasIndex || !hasLayout {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return hasIndex, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		switch filepath.Clean(header.Name) {
+		case "manifest.json":
+			return false, nil
+		case "index.json":
+			hasIndex = true
+		case "oci-layout":
+			hasLayout = true
+		}
+	}
+	return true, nil
+}
+
+// extractArchive extracts the directories and regular files of the archive
+// provided by opener into dir.
+func extractArchive(opener tarball.Opener, dir string) error {
+	r, err := opener()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(header.Name)
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("archive entry %q is outside of the archive", header.Name)
+		}
+		path := filepath.Join(dir, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // GetImagePath returns the image path.
 func GetImagePath(target string, targetType TargetType) (string, error) {
 	switch targetType {
@@ -320,16 +483,149 @@ func GetArchiveFromBazelLabel(target string) (string, error) {
 	return "", fmt.Errorf("given build target does not appear to be a skill image rule")
 }
 
-// ReadImage reads the image from the given path.
+// ReadImage reads the image from the given path, which is either a docker
+// tarball or an OCI image layout directory. Layouts need to hold a single
+// image, see ReadImageForPlatform for multi-platform layouts.
 func ReadImage(imagePath string) (containerregistry.Image, error) {
+	return ReadImageForPlatform(imagePath, nil)
+}
+
+// ReadImageForPlatform reads the image for platform from the given path, which
+// is either a docker tarball or an OCI image layout directory. If platform is
+// nil, the path needs to hold a single image.
+func ReadImageForPlatform(imagePath string, platform *containerregistry.Platform) (containerregistry.Image, error) {
+	if info, err := os.Stat(imagePath); err == nil && info.IsDir() {
+		index, err := ReadImageIndex(imagePath)
+		if err != nil {
+			return nil, err
+		}
+		images, err := platformImages(index)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading images of OCI image layout %q", imagePath)
+		}
+		return selectImage(images, platform)
+	}
+
 	log.Printf("Reading image tarball %q", imagePath)
 	image, err := tarball.ImageFromPath(imagePath, nil)
 	if err != nil {
 		return nil, errors.Wrapf(err, "creating tarball image from %q", imagePath)
 	}
+	if platform != nil {
+		if err := checkPlatform(image, *platform); err != nil {
+			return nil, err
+		}
+	}
 	return image, nil
 }
 
+// ReadImageIndex reads the image index of the OCI image layout directory at
+// layoutPath. Layouts written by docker buildx wrap the multi-platform index
+// in a top-level index, which is skipped.
+func ReadImageIndex(layoutPath string) (containerregistry.ImageIndex, error) {
+	log.Printf("Reading OCI image layout %q", layoutPath)
+	index, err := layout.ImageIndexFromPath(layoutPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading OCI image layout from %q", layoutPath)
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading index of OCI image layout %q", layoutPath)
+	}
+	if len(manifest.Manifests) == 1 && manifest.Manifests[0].MediaType.IsIndex() {
+		return index.ImageIndex(manifest.Manifests[0].Digest)
+	}
+	return index, nil
+}
+
+// platformImage is an image of an index together with its platform, which is
+// nil if unknown.
+type platformImage struct {
+	platform *containerregistry.Platform
+	image    containerregistry.Image
+}
+
+// platformImages returns all images of index, including those of nested
+// indexes.
+func platformImages(index containerregistry.ImageIndex) ([]platformImage, error) {
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	var images []platformImage
+	for _, desc := range manifest.Manifests {
+		switch {
+		case desc.MediaType.IsImage():
+			img, err := index.Image(desc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			platform := desc.Platform
+			if platform == nil {
+				config, err := img.ConfigFile()
+				if err != nil {
+					return nil, err
+				}
+				platform = config.Platform()
+			}
+			images = append(images, platformImage{platform: platform, image: img})
+		case desc.MediaType.IsIndex():
+			child, err := index.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			childImages, err := platformImages(child)
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, childImages...)
+		}
+		// other artifacts, like attestations, are not images.
+	}
+	return images, nil
+}
+
+// selectImage returns the image for platform. If platform is nil, images must
+// hold a single image.
+func selectImage(images []platformImage, platform *containerregistry.Platform) (containerregistry.Image, error) {
+	if platform == nil {
+		if len(images) != 1 {
+			return nil, fmt.Errorf("expected a single image, got images for platforms %v", platforms(images))
+		}
+		return images[0].image, nil
+	}
+	for _, candidate := range images {
+		if candidate.platform != nil && candidate.platform.Satisfies(*platform) {
+			return candidate.image, nil
+		}
+	}
+	return nil, fmt.Errorf("no image for platform %s, got images for platforms %v", platform, platforms(images))
+}
+
+// checkPlatform verifies that img was built for platform, if it tells.
+func checkPlatform(img containerregistry.Image, platform containerregistry.Platform) error {
+	config, err := img.ConfigFile()
+	if err != nil {
+		return fmt.Errorf("could not read image config: %v", err)
+	}
+	if actual := config.Platform(); actual != nil && !actual.Satisfies(platform) {
+		return fmt.Errorf("image is built for platform %s, not %s", actual, &platform)
+	}
+	return nil
+}
+
+func platforms(images []platformImage) []string {
+	result := make([]string, 0, len(images))
+	for _, img := range images {
+		if img.platform == nil {
+			result = append(result, "unknown")
+		} else {
+			result = append(result, img.platform.String())
+		}
+	}
+	return result
+}
+
 // InstallContainerParams holds parameters for InstallContainer.
 type InstallContainerParams struct {
 	Address    string
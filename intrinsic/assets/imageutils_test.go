@@ -0,0 +1,332 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package imageutils
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+var (
+	amd64 = containerregistry.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 = containerregistry.Platform{OS: "linux", Architecture: "arm64"}
+)
+
+// recordingTransferer records the digests of written images and indexes.
+type recordingTransferer struct {
+	images  []string
+	indexes []string
+}
+
+func (r *recordingTransferer) Write(_ name.Reference, img containerregistry.Image) error {
+	digest, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	r.images = append(r.images, digest.String())
+	return nil
+}
+
+func (r *recordingTransferer) WriteIndex(_ name.Reference, index containerregistry.ImageIndex) error {
+	digest, err := index.Digest()
+	if err != nil {
+		return err
+	}
+	r.indexes = append(r.indexes, digest.String())
+	return nil
+}
+
+func (r *recordingTransferer) Read(name.Reference) (containerregistry.Image, error) {
+	return nil, os.ErrNotExist
+}
+
+// imageOnlyTransferer cannot write indexes.
+type imageOnlyTransferer struct {
+	recorder recordingTransferer
+}
+
+func (r *imageOnlyTransferer) Write(ref name.Reference, img containerregistry.Image) error {
+	return r.recorder.Write(ref, img)
+}
+
+func (r *imageOnlyTransferer) Read(ref name.Reference) (containerregistry.Image, error) {
+	return r.recorder.Read(ref)
+}
+
+func randomImage(t *testing.T) containerregistry.Image {
+	t.Helper()
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("random.Image() failed: %v", err)
+	}
+	return img
+}
+
+func digestOf(t *testing.T, d interface {
+	Digest() (containerregistry.Hash, error)
+}) string {
+	t.Helper()
+	digest, err := d.Digest()
+	if err != nil {
+		t.Fatalf("Digest() failed: %v", err)
+	}
+	return digest.String()
+}
+
+// multiPlatformIndex returns an index with an image for amd64 and arm64, wrapped in a top-level
+// index like docker buildx does.
+func multiPlatformIndex(t *testing.T) (containerregistry.ImageIndex, map[string]containerregistry.Image) {
+	t.Helper()
+	images := map[string]containerregistry.Image{
+		amd64.String(): randomImage(t),
+		arm64.String(): randomImage(t),
+	}
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: images[amd64.String()], Descriptor: containerregistry.Descriptor{Platform: &amd64}},
+		mutate.IndexAddendum{Add: images[arm64.String()], Descriptor: containerregistry.Descriptor{Platform: &arm64}})
+	return mutate.AppendManifests(empty.Index, mutate.IndexAddendum{Add: index}), images
+}
+
+func writeLayout(t *testing.T, index containerregistry.ImageIndex) string {
+	t.Helper()
+	dir := t.TempDir()
+	if _, err := layout.Write(dir, index); err != nil {
+		t.Fatalf("layout.Write() failed: %v", err)
+	}
+	return dir
+}
+
+// tarDirectory returns an opener of a tarball of dir.
+func tarDirectory(t *testing.T, dir string) tarball.Opener {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(content)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("could not write tarball of %q: %v", dir, err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("could not close tarball of %q: %v", dir, err)
+	}
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	}
+}
+
+// failingReader fails every read with err.
+type failingReader struct {
+	err error
+}
+
+func (r failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestIsOCIArchiveStopsEarly(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		files []string
+		want  bool
+	}{
+		{name: "oci layout", files: []string{"oci-layout", "index.json"}, want: true},
+		{name: "docker tarball", files: []string{"manifest.json"}, want: false},
+		{name: "docker tarball with oci layout", files: []string{"index.json", "manifest.json", "oci-layout"}, want: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			tw := tar.NewWriter(buf)
+			for _, f := range tc.files {
+				if err := tw.WriteHeader(&tar.Header{Name: f, Mode: 0644, Size: 2}); err != nil {
+					t.Fatalf("WriteHeader() failed: %v", err)
+				}
+				if _, err := tw.Write([]byte("{}")); err != nil {
+					t.Fatalf("Write() failed: %v", err)
+				}
+			}
+			if err := tw.Flush(); err != nil {
+				t.Fatalf("Flush() failed: %v", err)
+			}
+			// the layers which follow must not be read.
+			errLayers := errors.New("layers were read")
+			opener := func() (io.ReadCloser, error) {
+				return io.NopCloser(io.MultiReader(bytes.NewReader(buf.Bytes()), failingReader{errLayers})), nil
+			}
+
+			got, err := isOCIArchive(opener)
+			if err != nil {
+				t.Fatalf("isOCIArchive() failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("isOCIArchive() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPushArchiveDockerTarball(t *testing.T) {
+	img := randomImage(t)
+	buf := new(bytes.Buffer)
+	if err := tarball.Write(name.MustParseReference("image:latest"), img, buf); err != nil {
+		t.Fatalf("tarball.Write() failed: %v", err)
+	}
+	transferer := &recordingTransferer{}
+
+	got, err := PushArchive(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	}, ImageOptions{Name: "image", Tag: "latest"}, RegistryOptions{URI: "gcr.io/test", Transferer: transferer})
+	if err != nil {
+		t.Fatalf("PushArchive() failed: %v", err)
+	}
+
+	want := digestOf(t, img)
+	if got.GetTag() != "@"+want {
+		t.Errorf("PushArchive() returned tag %q, want @%s", got.GetTag(), want)
+	}
+	if len(transferer.images) != 1 || transferer.images[0] != want {
+		t.Errorf("PushArchive() wrote images %v, want [%s]", transferer.images, want)
+	}
+}
+
+func TestPushArchiveOCILayout(t *testing.T) {
+	index, images := multiPlatformIndex(t)
+	opener := tarDirectory(t, writeLayout(t, index))
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		t.Fatalf("IndexManifest() failed: %v", err)
+	}
+	wantIndex := manifest.Manifests[0].Digest.String()
+
+	t.Run("all platforms", func(t *testing.T) {
+		transferer := &recordingTransferer{}
+		got, err := PushArchive(opener, ImageOptions{Name: "image", Tag: "latest"}, RegistryOptions{URI: "gcr.io/test", Transferer: transferer})
+		if err != nil {
+			t.Fatalf("PushArchive() failed: %v", err)
+		}
+		if got.GetTag() != "@"+wantIndex {
+			t.Errorf("PushArchive() returned tag %q, want @%s", got.GetTag(), wantIndex)
+		}
+		if len(transferer.indexes) != 1 || transferer.indexes[0] != wantIndex {
+			t.Errorf("PushArchive() wrote indexes %v, want [%s]", transferer.indexes, wantIndex)
+		}
+	})
+
+	t.Run("single platform", func(t *testing.T) {
+		transferer := &recordingTransferer{}
+		opts := ImageOptions{Name: "image", Tag: "latest", Platform: &arm64}
+		if _, err := PushArchive(opener, opts, RegistryOptions{URI: "gcr.io/test", Transferer: transferer}); err != nil {
+			t.Fatalf("PushArchive() failed: %v", err)
+		}
+		want := digestOf(t, images[arm64.String()])
+		if len(transferer.images) != 1 || transferer.images[0] != want || len(transferer.indexes) != 0 {
+			t.Errorf("PushArchive() wrote images %v and indexes %v, want only image %s", transferer.images, transferer.indexes, want)
+		}
+	})
+
+	t.Run("missing platform", func(t *testing.T) {
+		opts := ImageOptions{Name: "image", Tag: "latest", Platform: &containerregistry.Platform{OS: "windows", Architecture: "amd64"}}
+		if _, err := PushArchive(opener, opts, RegistryOptions{URI: "gcr.io/test", Transferer: &recordingTransferer{}}); err == nil {
+			t.Errorf("PushArchive() succeeded for missing platform, want error")
+		}
+	})
+
+	t.Run("registry without indexes", func(t *testing.T) {
+		reg := RegistryOptions{URI: "gcr.io/test", Transferer: &imageOnlyTransferer{}}
+		if _, err := PushArchive(opener, ImageOptions{Name: "image", Tag: "latest"}, reg); err == nil {
+			t.Errorf("PushArchive() succeeded without platform for registry which cannot store indexes, want error")
+		}
+	})
+}
+
+func TestReadImageForPlatform(t *testing.T) {
+	index, images := multiPlatformIndex(t)
+	dir := writeLayout(t, index)
+
+	for _, platform := range []containerregistry.Platform{amd64, arm64} {
+		img, err := ReadImageForPlatform(dir, &platform)
+		if err != nil {
+			t.Fatalf("ReadImageForPlatform(%s) failed: %v", platform, err)
+		}
+		if got, want := digestOf(t, img), digestOf(t, images[platform.String()]); got != want {
+			t.Errorf("ReadImageForPlatform(%s) returned image %s, want %s", platform, got, want)
+		}
+	}
+	if _, err := ReadImage(dir); err == nil {
+		t.Errorf("ReadImage() of multi-platform layout succeeded, want error")
+	}
+}
+
+func TestReadImageOCILayout(t *testing.T) {
+	img := randomImage(t)
+	dir := writeLayout(t, mutate.AppendManifests(empty.Index, mutate.IndexAddendum{Add: img}))
+
+	got, err := ReadImage(dir)
+	if err != nil {
+		t.Fatalf("ReadImage() failed: %v", err)
+	}
+	if digestOf(t, got) != digestOf(t, img) {
+		t.Errorf("ReadImage() returned image %s, want %s", digestOf(t, got), digestOf(t, img))
+	}
+}
+
+// resolvingTransferer knows the digest of every image.
+type resolvingTransferer struct {
+	recordingTransferer
+	digest containerregistry.Hash
+}
+
+func (r *resolvingTransferer) ResolveDigest(containerregistry.Image) (containerregistry.Hash, bool) {
+	return r.digest, true
+}
+
+func TestPushImageResolvesDigest(t *testing.T) {
+	digest := containerregistry.Hash{Algorithm: "sha256", Hex: strings.Repeat("a", 64)}
+	transferer := &resolvingTransferer{digest: digest}
+
+	got, err := PushImage(randomImage(t), ImageOptions{Name: "image", Tag: "latest"}, RegistryOptions{URI: "gcr.io/test", Transferer: transferer})
+	if err != nil {
+		t.Fatalf("PushImage() failed: %v", err)
+	}
+
+	if got.GetTag() != "@"+digest.String() {
+		t.Errorf("PushImage() returned tag %q, want @%s", got.GetTag(), digest)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package lro waits for long-running operations started by inctl commands.
+//
+// Wait polls an operation with exponential backoff until it is done, optionally reports changes of
+// its metadata, gives up after a timeout and cancels the operation if the user interrupts the
+// command. Failed operations are returned as an *Error, which carries the operation's status
+// including its extended status details.
+package lro
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	defaultInitialInterval = 50 * time.Millisecond
+	defaultMaxInterval     = 5 * time.Second
+	// cancelTimeout bounds the CancelOperation call made after the user interrupted a command.
+	cancelTimeout = 10 * time.Second
+)
+
+// Client gets the current state of an operation. It is implemented by the generated clients of
+// all services which implement the google.longrunning.Operations methods.
+type Client interface {
+	GetOperation(ctx context.Context, in *lropb.GetOperationRequest, opts ...grpc.CallOption) (*lropb.Operation, error)
+}
+
+// canceler is implemented by clients which can also cancel operations.
+type canceler interface {
+	CancelOperation(ctx context.Context, in *lropb.CancelOperationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type waitOptions struct {
+	timeout         time.Duration
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	progress        io.Writer
+	interrupt       bool
+}
+
+// Option configures Wait.
+type Option func(*waitOptions)
+
+// WithTimeout sets the maximum time to wait for the operation. Zero means no timeout. The
+// operation is not canceled when the timeout expires.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *waitOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithPollInterval sets the initial and the maximum interval between two polls of the operation.
+func WithPollInterval(initial, maxInterval time.Duration) Option {
+	return func(o *waitOptions) {
+		o.initialInterval = initial
+		o.maxInterval = maxInterval
+	}
+}
+
+// WithProgress writes a line to w every time the metadata of the operation changes.
+func WithProgress(w io.Writer) Option {
+	return func(o *waitOptions) {
+		o.progress = w
+	}
+}
+
+// WithoutInterruptHandling disables canceling the operation when the process receives an
+// interrupt signal (Ctrl-C).
+func WithoutInterruptHandling() Option {
+	return func(o *waitOptions) {
+		o.interrupt = false
+	}
+}
+
+// Error is returned by Wait if the operation finished with an error.
+type Error struct {
+	// Name is the name of the operation.
+	Name string
+	// Status is the status the operation finished with.
+	Status *status.Status
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("operation %q failed: %s: %s", e.Name, e.Status.Code(), e.Status.Message())
+}
+
+// GRPCStatus returns the status the operation finished with, so that the error can be inspected
+// with the functions of the grpc status package and its extended status, if any, is rendered by
+// inctl.
+func (e *Error) GRPCStatus() *status.Status {
+	return e.Status
+}
+
+// Wait waits until the given operation is done and returns its final state. If the operation
+// failed, the final state is returned together with an *Error.
+//
+// If ctx is canceled or the process is interrupted, Wait cancels the operation if client supports
+// it and returns an error. If the timeout expires, the operation keeps running.
+func Wait(ctx context.Context, client Client, op *lropb.Operation, opts ...Option) (*lropb.Operation, error) {
+	o := &waitOptions{
+		initialInterval: defaultInitialInterval,
+		maxInterval:     defaultMaxInterval,
+		interrupt:       true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	waitCtx := ctx
+	if o.interrupt {
+		var stop context.CancelFunc
+		waitCtx, stop = signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+	}
+	var deadline <-chan time.Time
+	if o.timeout > 0 {
+		timer := time.NewTimer(o.timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = o.initialInterval
+	b.MaxInterval = o.maxInterval
+	b.MaxElapsedTime = 0
+	b.Reset()
+
+	var lastMetadata *anypb.Any
+	for {
+		if o.progress != nil && op.GetMetadata() != nil && !proto.Equal(op.GetMetadata(), lastMetadata) {
+			fmt.Fprintf(o.progress, "Operation %q: %s\n", op.GetName(), formatMetadata(op.GetMetadata()))
+			lastMetadata = op.GetMetadata()
+		}
+		if op.GetDone() {
+			if s := op.GetError(); s != nil {
+				return op, &Error{Name: op.GetName(), Status: status.FromProto(s)}
+			}
+			return op, nil
+		}
+
+		timer := time.NewTimer(b.NextBackOff())
+		select {
+		case <-waitCtx.Done():
+			timer.Stop()
+			return op, cancelOperation(ctx, client, op.GetName())
+		case <-deadline:
+			timer.Stop()
+			return op, fmt.Errorf("operation %q did not finish within %v, it continues to run in the background", op.GetName(), o.timeout)
+		case <-timer.C:
+		}
+
+		next, err := client.GetOperation(waitCtx, &lropb.GetOperationRequest{Name: op.GetName()})
+		if err != nil {
+			if waitCtx.Err() != nil {
+				return op, cancelOperation(ctx, client, op.GetName())
+			}
+			return op, errors.Wrapf(err, "unable to get the status of operation %q", op.GetName())
+		}
+		op = next
+	}
+}
+
+// cancelOperation requests the cancellation of an operation after waiting for it was interrupted
+// and returns the error to report to the user.
+func cancelOperation(ctx context.Context, client Client, name string) error {
+	c, ok := client.(canceler)
+	if !ok {
+		return fmt.Errorf("stopped waiting for operation %q, it continues to run in the background", name)
+	}
+	// The original context is canceled at this point, but its metadata is still needed.
+	cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelTimeout)
+	defer cancel()
+	if _, err := c.CancelOperation(cancelCtx, &lropb.CancelOperationRequest{Name: name}); err != nil {
+		return errors.Wrapf(err, "stopped waiting for operation %q, but could not cancel it", name)
+	}
+	return fmt.Errorf("canceled operation %q", name)
+}
+
+// formatMetadata renders operation metadata as single-line text format if its type is known.
+func formatMetadata(metadata *anypb.Any) string {
+	msg, err := metadata.UnmarshalNew()
+	if err != nil {
+		return string(metadata.MessageName())
+	}
+	text, err := prototext.MarshalOptions{}.Marshal(msg)
+	if err != nil {
+		return string(metadata.MessageName())
+	}
+	return fmt.Sprintf("%s{%s}", metadata.MessageName(), strings.TrimSpace(string(text)))
+}
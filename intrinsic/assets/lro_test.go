@@ -0,0 +1,157 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package lro
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"intrinsic/util/status/extstatus"
+)
+
+// fakeClient returns the given operations, one per call to GetOperation, and repeats the last one
+// afterwards.
+type fakeClient struct {
+	operations []*lropb.Operation
+	calls      int
+	canceled   []string
+}
+
+func (c *fakeClient) GetOperation(ctx context.Context, req *lropb.GetOperationRequest, opts ...grpc.CallOption) (*lropb.Operation, error) {
+	op := c.operations[min(c.calls, len(c.operations)-1)]
+	c.calls++
+	return op, nil
+}
+
+func (c *fakeClient) CancelOperation(ctx context.Context, req *lropb.CancelOperationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	c.canceled = append(c.canceled, req.GetName())
+	return &emptypb.Empty{}, nil
+}
+
+// getOnlyClient cannot cancel operations.
+type getOnlyClient struct {
+	c *fakeClient
+}
+
+func (g getOnlyClient) GetOperation(ctx context.Context, req *lropb.GetOperationRequest, opts ...grpc.CallOption) (*lropb.Operation, error) {
+	return g.c.GetOperation(ctx, req, opts...)
+}
+
+func progressOperation(t *testing.T, progress int64, done bool) *lropb.Operation {
+	t.Helper()
+	metadata, err := anypb.New(wrapperspb.Int64(progress))
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	return &lropb.Operation{Name: "op1", Metadata: metadata, Done: done}
+}
+
+var fastPolling = WithPollInterval(time.Millisecond, time.Millisecond)
+
+func TestWaitReportsProgress(t *testing.T) {
+	client := &fakeClient{
+		operations: []*lropb.Operation{
+			progressOperation(t, 10, false),
+			progressOperation(t, 10, false),
+			progressOperation(t, 100, true),
+		},
+	}
+	progress := new(bytes.Buffer)
+
+	op, err := Wait(context.Background(), client, &lropb.Operation{Name: "op1"}, fastPolling, WithProgress(progress))
+	if err != nil {
+		t.Fatalf("Wait() failed: %v", err)
+	}
+	if !op.GetDone() {
+		t.Errorf("Wait() returned operation which is not done: %v", op)
+	}
+	if client.calls != 3 {
+		t.Errorf("Wait() called GetOperation %d times, want 3", client.calls)
+	}
+	lines := strings.Split(strings.TrimSpace(progress.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "10") || !strings.Contains(lines[1], "100") {
+		t.Errorf("Wait() reported progress %q, want one line each for 10 and 100", lines)
+	}
+}
+
+func TestWaitReturnsOperationError(t *testing.T) {
+	es := extstatus.New("ai.intrinsic.test", 42,
+		extstatus.WithTitle("Test failed"),
+		extstatus.WithUserMessage("Something went wrong"),
+		extstatus.WithUserInstructions("Try again"),
+		extstatus.WithGrpcCode(codes.FailedPrecondition))
+	client := &fakeClient{
+		operations: []*lropb.Operation{{
+			Name:   "op1",
+			Done:   true,
+			Result: &lropb.Operation_Error{Error: es.GRPCStatus().Proto()},
+		}},
+	}
+
+	_, err := Wait(context.Background(), client, &lropb.Operation{Name: "op1"}, fastPolling)
+	var opErr *Error
+	if !errors.As(err, &opErr) {
+		t.Fatalf("Wait() returned error %v, want *Error", err)
+	}
+	if got := status.Code(err); got != codes.FailedPrecondition {
+		t.Errorf("status.Code(Wait()) = %v, want %v", got, codes.FailedPrecondition)
+	}
+	if want := `operation "op1" failed: FailedPrecondition: Test failed`; err.Error() != want {
+		t.Errorf("Wait() returned error %q, want %q", err.Error(), want)
+	}
+	got, ok := extstatus.FromGRPCError(err)
+	if !ok {
+		t.Fatalf("extstatus.FromGRPCError(%v) failed, want extended status", err)
+	}
+	if diff := cmp.Diff(es.Proto(), got.Proto(), protocmp.Transform()); diff != "" {
+		t.Errorf("Wait() returned unexpected extended status (-want +got):\n%s", diff)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	client := &fakeClient{operations: []*lropb.Operation{{Name: "op1"}}}
+
+	_, err := Wait(context.Background(), client, &lropb.Operation{Name: "op1"}, fastPolling, WithTimeout(20*time.Millisecond))
+	if err == nil || !strings.Contains(err.Error(), "did not finish") {
+		t.Errorf("Wait() returned error %v, want timeout error", err)
+	}
+	if len(client.canceled) != 0 {
+		t.Errorf("Wait() canceled operations %v after timeout, want none", client.canceled)
+	}
+}
+
+func TestWaitCancel(t *testing.T) {
+	client := &fakeClient{operations: []*lropb.Operation{{Name: "op1"}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Wait(ctx, client, &lropb.Operation{Name: "op1"}, fastPolling)
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("Wait() returned error %v, want cancellation error", err)
+	}
+	if len(client.canceled) != 1 || client.canceled[0] != "op1" {
+		t.Errorf("Wait() canceled operations %v, want [op1]", client.canceled)
+	}
+
+	getOnly := getOnlyClient{&fakeClient{operations: []*lropb.Operation{{Name: "op1"}}}}
+	_, err = Wait(ctx, getOnly, &lropb.Operation{Name: "op1"}, fastPolling)
+	if err == nil || !strings.Contains(err.Error(), "continues to run") {
+		t.Errorf("Wait() returned error %v for client without CancelOperation, want error", err)
+	}
+}
@@ -7,28 +7,46 @@ package add
 import (
 	"fmt"
 	"log"
-	"os"
-	"time"
 
-	oppb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/spf13/cobra"
-	"google.golang.org/protobuf/proto"
 	anypb "google.golang.org/protobuf/types/known/anypb"
 	"intrinsic/assets/clientutils"
 	"intrinsic/assets/cmdutils"
 	"intrinsic/assets/idutils"
+	"intrinsic/assets/lro"
 	adgrpcpb "intrinsic/assets/proto/asset_deployment_go_grpc_proto"
 	adpb "intrinsic/assets/proto/asset_deployment_go_grpc_proto"
 	atpb "intrinsic/assets/proto/asset_type_go_proto"
+	idpb "intrinsic/assets/proto/id_go_proto"
 	iagrpcpb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
+	"intrinsic/assets/services/inctl/serviceconfig"
 	"intrinsic/assets/version"
 )
 
 const (
-	keyConfig = "config"
-	keyName   = "name"
+	keyConfig            = "config"
+	keyName              = "name"
+	keyShowDefaultConfig = "show-default-config"
 )
 
+// showDefaultConfig prints the default configuration of the given service version in a format
+// which can be passed to --config.
+func showDefaultConfig(cmd *cobra.Command, flags *cmdutils.CmdFlags, idv *idpb.IdVersion) error {
+	d, err := serviceconfig.CatalogDescriptors(cmd, flags, idv)
+	if err != nil {
+		return err
+	}
+	if d.DefaultConfig == nil {
+		return fmt.Errorf("the service has no default configuration")
+	}
+	text, err := serviceconfig.FormatConfig(d.DefaultConfig, d)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(cmd.OutOrStdout(), text)
+	return nil
+}
+
 // GetCommand returns a command to add a service instance to a solution.
 func GetCommand() *cobra.Command {
 	var flags = cmdutils.NewCmdFlags()
@@ -39,7 +57,10 @@ func GetCommand() *cobra.Command {
 Add a particular service with a given name and configuration
 $ inctl service add ai.intrinsic.basler_camera \
       --cluster=some_cluster_id \
-      --name=my_instance --config=some_file.binpb"
+      --name=my_instance --config=some_file.textproto
+
+Show the default configuration of a service, e.g., as a starting point for --config
+$ inctl service add ai.intrinsic.basler_camera --show-default-config > some_file.textproto
 `,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -55,16 +76,12 @@ $ inctl service add ai.intrinsic.basler_camera \
 				name = idv.GetId().GetName()
 			}
 
-			var cfg *anypb.Any
-			if f := flags.GetString(keyConfig); f != "" {
-				content, err := os.ReadFile(f)
-				if err != nil {
-					return fmt.Errorf("failed to read configuration proto file %s: %v", f, err)
-				}
-				cfg = &anypb.Any{}
-				if err := proto.Unmarshal(content, cfg); err != nil {
-					return fmt.Errorf("could not unmarshal configuration proto: %v", err)
-				}
+			if flags.GetBool(keyShowDefaultConfig) {
+				return showDefaultConfig(cmd, flags, idv)
+			}
+			timeout, err := flags.GetFlagOperationTimeout()
+			if err != nil {
+				return err
 			}
 
 			ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, flags)
@@ -73,7 +90,8 @@ $ inctl service add ai.intrinsic.basler_camera \
 			}
 			defer conn.Close()
 
-			if err := version.Autofill(ctx, iagrpcpb.NewInstalledAssetsClient(conn), idv); err != nil {
+			iaClient := iagrpcpb.NewInstalledAssetsClient(conn)
+			if err := version.Autofill(ctx, iaClient, idv); err != nil {
 				return err
 			}
 			idVersion, err := idutils.IDVersionFromProto(idv)
@@ -81,6 +99,15 @@ $ inctl service add ai.intrinsic.basler_camera \
 				return err
 			}
 
+			var cfg *anypb.Any
+			if f := flags.GetString(keyConfig); f != "" {
+				if cfg, err = serviceconfig.ReadConfig(ctx, cmd, flags, iaClient, idv, f); err != nil {
+					return err
+				}
+			} else {
+				log.Printf("No configuration given, using the default configuration of %q", idVersion)
+			}
+
 			log.Printf("Requesting %q be added as a service instance", name)
 			client := adgrpcpb.NewAssetDeploymentServiceClient(conn)
 			authCtx := clientutils.AuthInsecureConn(ctx, address, flags.GetFlagProject())
@@ -99,18 +126,8 @@ $ inctl service add ai.intrinsic.basler_camera \
 			}
 
 			log.Printf("Awaiting completion of the add operation")
-			for !op.GetDone() {
-				time.Sleep(15 * time.Millisecond)
-				op, err = client.GetOperation(ctx, &oppb.GetOperationRequest{
-					Name: op.GetName(),
-				})
-				if err != nil {
-					return fmt.Errorf("unable to check status of create operation for %q: %v", name, err)
-				}
-			}
-
-			if err := op.GetError(); err != nil {
-				return fmt.Errorf("failed to add %q: %v", name, err)
+			if _, err := lro.Wait(ctx, client, op, lro.WithTimeout(timeout), lro.WithProgress(log.Writer())); err != nil {
+				return fmt.Errorf("failed to add %q: %w", name, err)
 			}
 
 			log.Printf("Finished adding service %q", name)
@@ -121,7 +138,9 @@ $ inctl service add ai.intrinsic.basler_camera \
 	flags.SetCommand(cmd)
 	flags.AddFlagsAddressClusterSolution()
 	flags.AddFlagsProjectOrg()
-	flags.OptionalString(keyConfig, "", "The filename of a binary-serialized Any proto containing this services's configuration.")
+	flags.AddFlagOperationTimeout("add operation")
+	flags.OptionalString(keyConfig, "", "The filename of this service's configuration. Files ending in .textproto, .txtpb, .pbtxt or .textpb are parsed as text format and files ending in .json as JSON of the service's configuration message, whose type is taken from a '# proto-message: <type>' header or an '@type' field, respectively, or from the service's default configuration. Any other file is read as a binary-serialized Any proto. If not set, the service's default configuration is used.")
+	flags.OptionalBool(keyShowDefaultConfig, false, "Print the service's default configuration in text format instead of adding the service.")
 	flags.OptionalString(keyName, "", "The name of this service instance.")
 
 	return cmd
@@ -7,12 +7,11 @@ package delete
 import (
 	"fmt"
 	"log"
-	"time"
 
-	oppb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/spf13/cobra"
 	"intrinsic/assets/clientutils"
 	"intrinsic/assets/cmdutils"
+	"intrinsic/assets/lro"
 	adgrpcpb "intrinsic/assets/proto/asset_deployment_go_grpc_proto"
 	adpb "intrinsic/assets/proto/asset_deployment_go_grpc_proto"
 )
@@ -31,6 +30,10 @@ $ inctl service delete --project=my_project --cluster=some_cluster my_instance
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 			name := args[0]
+			timeout, err := flags.GetFlagOperationTimeout()
+			if err != nil {
+				return err
+			}
 
 			ctx, conn, _, err := clientutils.DialClusterFromInctl(ctx, flags)
 			if err != nil {
@@ -49,18 +52,8 @@ $ inctl service delete --project=my_project --cluster=some_cluster my_instance
 			}
 
 			log.Printf("Awaiting completion of the delete operation")
-			for !op.GetDone() {
-				time.Sleep(15 * time.Millisecond)
-				op, err = client.GetOperation(ctx, &oppb.GetOperationRequest{
-					Name: op.GetName(),
-				})
-				if err != nil {
-					return fmt.Errorf("unable to check status of delete operation for %q: %v", name, err)
-				}
-			}
-
-			if err := op.GetError(); err != nil {
-				return fmt.Errorf("failed to delete %q: %v", name, err)
+			if _, err := lro.Wait(ctx, client, op, lro.WithTimeout(timeout), lro.WithProgress(log.Writer())); err != nil {
+				return fmt.Errorf("failed to delete %q: %w", name, err)
 			}
 
 			log.Printf("Deleted service %q", name)
@@ -71,6 +64,7 @@ $ inctl service delete --project=my_project --cluster=some_cluster my_instance
 	flags.SetCommand(cmd)
 	flags.AddFlagsAddressClusterSolution()
 	flags.AddFlagsProjectOrg()
+	flags.AddFlagOperationTimeout("delete operation")
 
 	return cmd
 }
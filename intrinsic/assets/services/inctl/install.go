@@ -8,15 +8,14 @@ import (
 	"log"
 
 	lrogrpcpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
-	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc/status"
 	"intrinsic/assets/bundleio"
 	"intrinsic/assets/clientutils"
 	"intrinsic/assets/cmdutils"
 	"intrinsic/assets/idutils"
 	"intrinsic/assets/imagetransfer"
+	"intrinsic/assets/lro"
 	iagrpcpb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
 	iapb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
 	"intrinsic/skills/tools/resource/cmd/bundleimages"
@@ -52,6 +51,10 @@ func GetCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			platform, err := flags.GetFlagPlatform()
+			if err != nil {
+				return err
+			}
 
 			ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, flags)
 			if err != nil {
@@ -70,6 +73,8 @@ func GetCommand() *cobra.Command {
 				opts := []directupload.Option{
 					directupload.WithDiscovery(directupload.NewFromConnection(conn)),
 					directupload.WithOutput(cmd.OutOrStdout()),
+					directupload.WithDefaultJournal(),
+					directupload.WithDefaultCache(clientutils.UploadTarget(ctx, address)),
 				}
 				if registry != "" {
 					// User set external registry, so we can use it as failover.
@@ -83,7 +88,7 @@ func GetCommand() *cobra.Command {
 			}
 
 			manifest, err := bundleio.ProcessService(target, bundleio.ProcessServiceOpts{
-				ImageProcessor: bundleimages.CreateImageProcessor(flags.CreateRegistryOptsWithTransferer(ctx, transfer, registry)),
+				ImageProcessor: bundleimages.CreateImageProcessorForPlatform(flags.CreateRegistryOptsWithTransferer(ctx, transfer, registry), platform),
 			})
 			if err != nil {
 				return fmt.Errorf("could not read bundle file %q: %v", target, err)
@@ -113,16 +118,7 @@ func GetCommand() *cobra.Command {
 
 			log.Printf("Awaiting completion of the installation")
 			lroClient := lrogrpcpb.NewOperationsClient(conn)
-			for !op.GetDone() {
-				op, err = lroClient.WaitOperation(ctx, &lropb.WaitOperationRequest{
-					Name: op.GetName(),
-				})
-				if err != nil {
-					return fmt.Errorf("unable to check status of installation: %v", err)
-				}
-			}
-
-			if err := status.ErrorProto(op.GetError()); err != nil {
+			if _, err := lro.Wait(ctx, lroClient, op, lro.WithProgress(log.Writer())); err != nil {
 				return fmt.Errorf("installation failed: %w", err)
 			}
 
@@ -135,6 +131,7 @@ func GetCommand() *cobra.Command {
 	flags.SetCommand(cmd)
 	flags.AddFlagsAddressClusterSolution()
 	flags.AddFlagPolicy("service")
+	flags.AddFlagPlatform("service")
 	flags.AddFlagsProjectOrg()
 	flags.AddFlagRegistry()
 	flags.AddFlagsRegistryAuthUserPassword()
@@ -0,0 +1,91 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package reconfigure defines the command which applies a new configuration to a running service
+// instance without restarting it.
+package reconfigure
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	iagrpcpb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
+	"intrinsic/assets/services/inctl/serviceconfig"
+	drgrpcpb "intrinsic/assets/services/proto/v1/dynamic_reconfiguration_go_grpc_proto"
+	drpb "intrinsic/assets/services/proto/v1/dynamic_reconfiguration_go_grpc_proto"
+	rrgrpcpb "intrinsic/resources/proto/resource_registry_go_grpc_proto"
+)
+
+const (
+	keyConfig = "config"
+
+	// resourceInstanceHeader routes requests to a particular service instance.
+	resourceInstanceHeader = "x-resource-instance-name"
+)
+
+// GetCommand returns a command to dynamically reconfigure a service instance.
+func GetCommand() *cobra.Command {
+	var flags = cmdutils.NewCmdFlags()
+	var cmd = &cobra.Command{
+		Use:   "reconfigure name",
+		Short: "Apply a new configuration to a running service instance",
+		Long: `Apply a new configuration to a running service instance without restarting it.
+
+Only services which implement the DynamicReconfiguration service support this. The new
+configuration only lives as long as the running instance; use "inctl service update --config" to
+restart the instance with a new configuration which persists.`,
+		Example: `
+Apply a new configuration to a service instance
+$ inctl service reconfigure my_instance --config=some_file.textproto \
+      --project=my_project --cluster=some_cluster_id
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			name := args[0]
+
+			ctx, conn, _, err := clientutils.DialClusterFromInctl(ctx, flags)
+			if err != nil {
+				return fmt.Errorf("could not create connection to cluster: %w", err)
+			}
+			defer conn.Close()
+
+			iaClient := iagrpcpb.NewInstalledAssetsClient(conn)
+			_, idv, err := serviceconfig.Instance(ctx, rrgrpcpb.NewResourceRegistryClient(conn), iaClient, name)
+			if err != nil {
+				return err
+			}
+			cfg, err := serviceconfig.ReadConfig(ctx, cmd, flags, iaClient, idv, flags.GetString(keyConfig))
+			if err != nil {
+				return err
+			}
+
+			log.Printf("Applying configuration to %q", name)
+			client := drgrpcpb.NewDynamicReconfigurationClient(conn)
+			instanceCtx := metadata.AppendToOutgoingContext(ctx, resourceInstanceHeader, name)
+			if _, err := client.ApplyConfiguration(instanceCtx, &drpb.ApplyConfigurationRequest{
+				Configuration: cfg,
+			}); err != nil {
+				if status.Code(err) == codes.Unimplemented {
+					return fmt.Errorf("service %q does not support dynamic reconfiguration, use 'inctl service update --%s' instead: %v", name, keyConfig, err)
+				}
+				return fmt.Errorf("failed to reconfigure %q: %v", name, err)
+			}
+
+			log.Printf("Reconfigured service %q", name)
+			return nil
+		},
+	}
+
+	flags.SetCommand(cmd)
+	flags.AddFlagsAddressClusterSolution()
+	flags.AddFlagsProjectOrg()
+	flags.RequiredString(keyConfig, "The filename of the new configuration, in any of the formats accepted by 'inctl service add --config'.")
+
+	return cmd
+}
@@ -11,8 +11,10 @@ import (
 	"intrinsic/assets/services/inctl/list"
 	"intrinsic/assets/services/inctl/listreleased"
 	"intrinsic/assets/services/inctl/listreleasedversions"
+	"intrinsic/assets/services/inctl/reconfigure"
 	"intrinsic/assets/services/inctl/release"
 	"intrinsic/assets/services/inctl/uninstall"
+	"intrinsic/assets/services/inctl/update"
 	"intrinsic/tools/inctl/cmd/root"
 )
 
@@ -30,8 +32,10 @@ func init() {
 	serviceCmd.AddCommand(list.GetCommand())
 	serviceCmd.AddCommand(listreleased.GetCommand())
 	serviceCmd.AddCommand(listreleasedversions.GetCommand())
+	serviceCmd.AddCommand(reconfigure.GetCommand())
 	serviceCmd.AddCommand(release.GetCommand())
 	serviceCmd.AddCommand(uninstall.GetCommand())
+	serviceCmd.AddCommand(update.GetCommand())
 
 	root.RootCmd.AddCommand(serviceCmd)
 }
@@ -0,0 +1,258 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package serviceconfig reads service instance configurations given on the command line and
+// resolves them against the descriptors of the service. It is shared by the inctl service
+// commands which create or modify service instances.
+package serviceconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	acgrpcpb "intrinsic/assets/catalog/proto/v1/asset_catalog_go_grpc_proto"
+	acpb "intrinsic/assets/catalog/proto/v1/asset_catalog_go_grpc_proto"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/assets/idutils"
+	atpb "intrinsic/assets/proto/asset_type_go_proto"
+	idpb "intrinsic/assets/proto/id_go_proto"
+	iagrpcpb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
+	iapb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
+	viewpb "intrinsic/assets/proto/view_go_proto"
+	"intrinsic/assets/version"
+	rrgrpcpb "intrinsic/resources/proto/resource_registry_go_grpc_proto"
+	rrpb "intrinsic/resources/proto/resource_registry_go_grpc_proto"
+	"intrinsic/util/proto/registryutil"
+)
+
+// messageTypeHeader matches the "# proto-message: <type>" header of textproto files.
+var messageTypeHeader = regexp.MustCompile(`(?m)^#\s*proto-message:\s*(\S+)\s*$`)
+
+// Descriptors resolves the configuration message of a service version.
+type Descriptors struct {
+	// Types contains the messages declared in the service's file descriptor set.
+	Types *protoregistry.Types
+	// DefaultConfig is the default configuration from the service's manifest, if known.
+	DefaultConfig *anypb.Any
+}
+
+// NewDescriptors creates Descriptors from a service's file descriptor set.
+func NewDescriptors(set *dpb.FileDescriptorSet, defaultConfig *anypb.Any) (*Descriptors, error) {
+	types, err := registryutil.NewTypesFromFileDescriptorSet(set)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create type registry from the service's file descriptor set")
+	}
+	return &Descriptors{Types: types, DefaultConfig: defaultConfig}, nil
+}
+
+// FromInstalledAsset returns the descriptors of an installed service version. The installed
+// asset's metadata does not contain the default configuration.
+func FromInstalledAsset(ctx context.Context, client iagrpcpb.InstalledAssetsClient, idv *idpb.IdVersion) (*Descriptors, error) {
+	asset, err := client.GetInstalledAsset(ctx, &iapb.GetInstalledAssetRequest{Id: idv.GetId()})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get installed service")
+	}
+	if got := asset.GetMetadata().GetIdVersion().GetVersion(); idv.GetVersion() != "" && got != idv.GetVersion() {
+		return nil, fmt.Errorf("installed version %q does not match requested version %q", got, idv.GetVersion())
+	}
+	set := asset.GetMetadata().GetFileDescriptorSet()
+	if len(set.GetFile()) == 0 {
+		return nil, fmt.Errorf("installed service has no file descriptor set")
+	}
+	return NewDescriptors(set, nil)
+}
+
+// FromCatalog returns the descriptors and the default configuration of a service version from its
+// manifest in the catalog. If the version is not specified, the default version is used.
+func FromCatalog(ctx context.Context, client acgrpcpb.AssetCatalogClient, idv *idpb.IdVersion) (*Descriptors, error) {
+	req := &acpb.GetAssetRequest{
+		AssetType: atpb.AssetType_ASSET_TYPE_SERVICE,
+		View:      viewpb.AssetViewType_ASSET_VIEW_TYPE_ALL_METADATA,
+	}
+	if idv.GetVersion() == "" {
+		req.AssetId = &acpb.GetAssetRequest_Id{Id: idv.GetId()}
+	} else {
+		req.AssetId = &acpb.GetAssetRequest_IdVersion{IdVersion: idv}
+	}
+	asset, err := client.GetAsset(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get service from the catalog")
+	}
+	assets := asset.GetDeploymentData().GetServiceSpecificDeploymentData().GetManifest().GetAssets()
+	set := assets.GetFileDescriptorSet()
+	if len(set.GetFile()) == 0 {
+		set = asset.GetMetadata().GetFileDescriptorSet()
+	}
+	return NewDescriptors(set, assets.GetDefaultConfiguration())
+}
+
+// CatalogDescriptors returns the descriptors and the default configuration of the given service
+// version from the catalog.
+func CatalogDescriptors(cmd *cobra.Command, flags *cmdutils.CmdFlags, idv *idpb.IdVersion) (*Descriptors, error) {
+	conn, err := clientutils.DialCatalogFromInctl(cmd, flags)
+	if err != nil {
+		return nil, fmt.Errorf("could not create connection to catalog: %w", err)
+	}
+	defer conn.Close()
+	return FromCatalog(cmd.Context(), acgrpcpb.NewAssetCatalogClient(conn), idv)
+}
+
+// ReadConfig reads the configuration file at the given path. Text and JSON configurations are
+// resolved against the descriptors of the installed service version, or of the service version in
+// the catalog if it is not installed.
+func ReadConfig(ctx context.Context, cmd *cobra.Command, flags *cmdutils.CmdFlags, client iagrpcpb.InstalledAssetsClient, idv *idpb.IdVersion, path string) (*anypb.Any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file %s: %v", path, err)
+	}
+	var d *Descriptors
+	if !IsBinary(path) {
+		if d, err = FromInstalledAsset(ctx, client, idv); err != nil {
+			log.Printf("Could not get descriptors of the installed service, using the catalog: %v", err)
+			if d, err = CatalogDescriptors(cmd, flags, idv); err != nil {
+				return nil, err
+			}
+		}
+	}
+	cfg, err := ParseConfig(path, content, d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Instance returns the given service instance and the id version of its service. If the
+// registry does not know the version, the installed version of the service is used.
+func Instance(ctx context.Context, rrClient rrgrpcpb.ResourceRegistryClient, iaClient iagrpcpb.InstalledAssetsClient, name string) (*rrpb.ResourceInstance, *idpb.IdVersion, error) {
+	inst, err := rrClient.GetResourceInstance(ctx, &rrpb.GetResourceInstanceRequest{Name: name})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "could not get service instance %q", name)
+	}
+	idv, err := idutils.IDOrIDVersionProtoFrom(inst.GetTypeId())
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "service instance %q has invalid type id %q", name, inst.GetTypeId())
+	}
+	if err := version.Autofill(ctx, iaClient, idv); err != nil {
+		return nil, nil, err
+	}
+	return inst, idv, nil
+}
+
+// IsBinary reports whether the configuration file at the given path is read as a
+// binary-serialized Any proto, which does not require the service's descriptors.
+func IsBinary(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".textproto", ".txtpb", ".pbtxt", ".textpb", ".json":
+		return false
+	}
+	return true
+}
+
+// ParseConfig parses the content of a configuration file. The format is determined by the file
+// extension of path:
+//   - ".textproto", ".txtpb", ".pbtxt", ".textpb": text format of the configuration message. Its
+//     type is given by a "# proto-message: <type>" header.
+//   - ".json": JSON format of the configuration message. Its type is given by an "@type" field.
+//   - anything else: a binary-serialized Any proto.
+//
+// If the type of a text or JSON configuration is not given, the type of the service's default
+// configuration is used. d is only used for text and JSON configurations.
+func ParseConfig(path string, content []byte, d *Descriptors) (*anypb.Any, error) {
+	if IsBinary(path) {
+		cfg := &anypb.Any{}
+		if err := proto.Unmarshal(content, cfg); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal configuration proto")
+		}
+		return cfg, nil
+	}
+	if d == nil {
+		return nil, fmt.Errorf("cannot parse %s without the service's descriptors", path)
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return d.parseJSON(content)
+	}
+	var typeName protoreflect.FullName
+	if m := messageTypeHeader.FindSubmatch(content); m != nil {
+		typeName = protoreflect.FullName(m[1])
+	}
+	msg, err := d.newMessage(typeName, `add a "# proto-message: <type>" header`)
+	if err != nil {
+		return nil, err
+	}
+	if err := (prototext.UnmarshalOptions{Resolver: d.Types}).Unmarshal(content, msg); err != nil {
+		return nil, errors.Wrapf(err, "could not parse %s as %s", path, msg.ProtoReflect().Descriptor().FullName())
+	}
+	return anypb.New(msg)
+}
+
+func (d *Descriptors) parseJSON(content []byte) (*anypb.Any, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, errors.Wrap(err, "configuration is not a JSON object")
+	}
+	if _, ok := fields["@type"]; ok {
+		cfg := &anypb.Any{}
+		if err := (protojson.UnmarshalOptions{Resolver: d.Types}).Unmarshal(content, cfg); err != nil {
+			return nil, errors.Wrap(err, "could not parse JSON configuration")
+		}
+		return cfg, nil
+	}
+	msg, err := d.newMessage("", `add an "@type" field`)
+	if err != nil {
+		return nil, err
+	}
+	if err := (protojson.UnmarshalOptions{Resolver: d.Types}).Unmarshal(content, msg); err != nil {
+		return nil, errors.Wrapf(err, "could not parse JSON configuration as %s", msg.ProtoReflect().Descriptor().FullName())
+	}
+	return anypb.New(msg)
+}
+
+// newMessage creates an empty message of the given type, or of the type of the default
+// configuration if typeName is empty. hint tells the user how to specify the type.
+func (d *Descriptors) newMessage(typeName protoreflect.FullName, hint string) (proto.Message, error) {
+	if typeName == "" {
+		if d.DefaultConfig == nil {
+			return nil, fmt.Errorf("cannot determine the configuration message type, %s", hint)
+		}
+		typeName = d.DefaultConfig.MessageName()
+	}
+	mt, err := d.Types.FindMessageByName(typeName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "configuration message type %s not found in the service's descriptors", typeName)
+	}
+	return mt.New().Interface(), nil
+}
+
+// FormatConfig formats the given configuration as text format with a "# proto-message" header, so
+// that it can be passed to ParseConfig as a ".textproto" file.
+func FormatConfig(cfg *anypb.Any, d *Descriptors) (string, error) {
+	mt, err := d.Types.FindMessageByURL(cfg.GetTypeUrl())
+	if err != nil {
+		return "", errors.Wrapf(err, "configuration message type %s not found in the service's descriptors", cfg.MessageName())
+	}
+	msg := mt.New().Interface()
+	if err := (proto.UnmarshalOptions{Resolver: d.Types}).Unmarshal(cfg.GetValue(), msg); err != nil {
+		return "", errors.Wrapf(err, "could not unmarshal %s", cfg.MessageName())
+	}
+	text, err := (prototext.MarshalOptions{Multiline: true, Resolver: d.Types}).Marshal(msg)
+	if err != nil {
+		return "", errors.Wrap(err, "could not format configuration")
+	}
+	return fmt.Sprintf("# proto-message: %s\n%s", cfg.MessageName(), text), nil
+}
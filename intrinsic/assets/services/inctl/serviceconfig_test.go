@@ -0,0 +1,161 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package serviceconfig
+
+import (
+	"testing"
+
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/testing/protocmp"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+)
+
+// testDescriptors uses google.protobuf.DescriptorProto as the configuration message of a fake
+// service.
+func testDescriptors(t *testing.T, defaultConfig proto.Message) *Descriptors {
+	t.Helper()
+	var defaultAny *anypb.Any
+	if defaultConfig != nil {
+		var err error
+		if defaultAny, err = anypb.New(defaultConfig); err != nil {
+			t.Fatalf("anypb.New() failed: %v", err)
+		}
+	}
+	set := &dpb.FileDescriptorSet{
+		File: []*dpb.FileDescriptorProto{protodesc.ToFileDescriptorProto((&dpb.DescriptorProto{}).ProtoReflect().Descriptor().ParentFile())},
+	}
+	d, err := NewDescriptors(set, defaultAny)
+	if err != nil {
+		t.Fatalf("NewDescriptors() failed: %v", err)
+	}
+	return d
+}
+
+func TestParseConfig(t *testing.T) {
+	want := &dpb.DescriptorProto{Name: proto.String("Config")}
+	binary, err := anypb.New(want)
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	binaryContent, err := proto.Marshal(binary)
+	if err != nil {
+		t.Fatalf("proto.Marshal() failed: %v", err)
+	}
+	withDefault := testDescriptors(t, &dpb.DescriptorProto{Name: proto.String("Default")})
+	withoutDefault := testDescriptors(t, nil)
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		d       *Descriptors
+		wantErr bool
+	}{
+		{
+			name:    "textproto with header",
+			path:    "config.textproto",
+			content: "# proto-message: google.protobuf.DescriptorProto\nname: \"Config\"\n",
+			d:       withoutDefault,
+		},
+		{
+			name:    "textproto with type of default config",
+			path:    "config.txtpb",
+			content: `name: "Config"`,
+			d:       withDefault,
+		},
+		{
+			name:    "textproto without type",
+			path:    "config.pbtxt",
+			content: `name: "Config"`,
+			d:       withoutDefault,
+			wantErr: true,
+		},
+		{
+			name:    "textproto of unknown type",
+			path:    "config.textproto",
+			content: "# proto-message: com.example.Unknown\nname: \"Config\"\n",
+			d:       withDefault,
+			wantErr: true,
+		},
+		{
+			name:    "invalid textproto",
+			path:    "config.textproto",
+			content: `unknown_field: 1`,
+			d:       withDefault,
+			wantErr: true,
+		},
+		{
+			name:    "json with type",
+			path:    "config.json",
+			content: `{"@type": "type.googleapis.com/google.protobuf.DescriptorProto", "name": "Config"}`,
+			d:       withoutDefault,
+		},
+		{
+			name:    "json with type of default config",
+			path:    "config.JSON",
+			content: `{"name": "Config"}`,
+			d:       withDefault,
+		},
+		{
+			name:    "json without type",
+			path:    "config.json",
+			content: `{"name": "Config"}`,
+			d:       withoutDefault,
+			wantErr: true,
+		},
+		{
+			name:    "binary any",
+			path:    "config.binpb",
+			content: string(binaryContent),
+		},
+		{
+			name:    "text without descriptors",
+			path:    "config.textproto",
+			content: `name: "Config"`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := ParseConfig(tc.path, []byte(tc.content), tc.d)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("ParseConfig() returned error %v, want error: %t", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := &dpb.DescriptorProto{}
+			if err := cfg.UnmarshalTo(got); err != nil {
+				t.Fatalf("UnmarshalTo() failed: %v", err)
+			}
+			if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("ParseConfig() returned unexpected configuration (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatConfigRoundTrip(t *testing.T) {
+	want := &dpb.DescriptorProto{Name: proto.String("Default")}
+	d := testDescriptors(t, want)
+
+	text, err := FormatConfig(d.DefaultConfig, d)
+	if err != nil {
+		t.Fatalf("FormatConfig() failed: %v", err)
+	}
+	// Parse without the default configuration, so that the type is taken from the header.
+	cfg, err := ParseConfig("default.textproto", []byte(text), testDescriptors(t, nil))
+	if err != nil {
+		t.Fatalf("ParseConfig() failed for output of FormatConfig():\n%s\nerror: %v", text, err)
+	}
+	got := &dpb.DescriptorProto{}
+	if err := cfg.UnmarshalTo(got); err != nil {
+		t.Fatalf("UnmarshalTo() failed: %v", err)
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("FormatConfig() did not round trip (-want +got):\n%s", diff)
+	}
+}
@@ -6,15 +6,13 @@ package uninstall
 import (
 	"fmt"
 	"log"
-	"time"
 
 	lrogrpcpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
-	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc/status"
 	"intrinsic/assets/clientutils"
 	"intrinsic/assets/cmdutils"
 	"intrinsic/assets/idutils"
+	"intrinsic/assets/lro"
 	idpb "intrinsic/assets/proto/id_go_proto"
 	iagrpcpb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
 	iapb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
@@ -48,6 +46,10 @@ func GetCommand() *cobra.Command {
 			if v := idv.GetVersion(); v != "" {
 				log.Print("Warning: specifying the version of an asset is deprecated, and soon will cause an error")
 			}
+			timeout, err := flags.GetFlagOperationTimeout()
+			if err != nil {
+				return err
+			}
 
 			ctx, conn, _, err := clientutils.DialClusterFromInctl(ctx, flags)
 			if err != nil {
@@ -67,17 +69,7 @@ func GetCommand() *cobra.Command {
 
 			log.Printf("Awaiting completion of the removal")
 			lroClient := lrogrpcpb.NewOperationsClient(conn)
-			for !op.GetDone() {
-				time.Sleep(15 * time.Millisecond)
-				op, err = lroClient.GetOperation(ctx, &lropb.GetOperationRequest{
-					Name: op.GetName(),
-				})
-				if err != nil {
-					return fmt.Errorf("unable to check status of removal: %v", err)
-				}
-			}
-
-			if err := status.ErrorProto(op.GetError()); err != nil {
+			if _, err := lro.Wait(ctx, lroClient, op, lro.WithTimeout(timeout), lro.WithProgress(log.Writer())); err != nil {
 				return fmt.Errorf("removal failed: %w", err)
 			}
 			log.Printf("Finished uninstalling %q", id)
@@ -89,6 +81,7 @@ func GetCommand() *cobra.Command {
 	flags.SetCommand(cmd)
 	flags.AddFlagsAddressClusterSolution()
 	flags.AddFlagsProjectOrg()
+	flags.AddFlagOperationTimeout("removal")
 
 	return cmd
 }
@@ -0,0 +1,130 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package update defines the command which updates the version or configuration of a service
+// instance in the solution.
+package update
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/assets/idutils"
+	"intrinsic/assets/lro"
+	adgrpcpb "intrinsic/assets/proto/asset_deployment_go_grpc_proto"
+	adpb "intrinsic/assets/proto/asset_deployment_go_grpc_proto"
+	idpb "intrinsic/assets/proto/id_go_proto"
+	iagrpcpb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
+	"intrinsic/assets/services/inctl/serviceconfig"
+	rrgrpcpb "intrinsic/resources/proto/resource_registry_go_grpc_proto"
+)
+
+const (
+	keyConfig  = "config"
+	keyVersion = "version"
+)
+
+// GetCommand returns a command to update a service instance in a solution.
+func GetCommand() *cobra.Command {
+	var flags = cmdutils.NewCmdFlags()
+	var cmd = &cobra.Command{
+		Use:   "update name",
+		Short: "Update the version or configuration of a service instance in a solution",
+		Long: `Update the version or configuration of a service instance in a solution.
+
+The service instance is restarted in place with the given version and configuration. If --version
+is not set, the instance keeps its current version. If --config is not set, the instance keeps its
+current configuration. Use "inctl service reconfigure" to change the configuration of a service
+which supports dynamic reconfiguration without restarting it.`,
+		Example: `
+Upgrade a service instance to a particular version of its service
+$ inctl service update my_instance --version=0.0.2 \
+      --project=my_project --cluster=some_cluster_id
+
+Restart a service instance with a new configuration
+$ inctl service update my_instance --config=some_file.textproto \
+      --project=my_project --cluster=some_cluster_id
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			name := args[0]
+			newVersion := flags.GetString(keyVersion)
+			configFile := flags.GetString(keyConfig)
+			if newVersion == "" && configFile == "" {
+				return fmt.Errorf("nothing to update, at least one of --%s and --%s must be set", keyVersion, keyConfig)
+			}
+			timeout, err := flags.GetFlagOperationTimeout()
+			if err != nil {
+				return err
+			}
+
+			ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, flags)
+			if err != nil {
+				return fmt.Errorf("could not create connection to cluster: %w", err)
+			}
+			defer conn.Close()
+
+			iaClient := iagrpcpb.NewInstalledAssetsClient(conn)
+			inst, idv, err := serviceconfig.Instance(ctx, rrgrpcpb.NewResourceRegistryClient(conn), iaClient, name)
+			if err != nil {
+				return err
+			}
+			if newVersion != "" {
+				idv = &idpb.IdVersion{Id: idv.GetId(), Version: newVersion}
+			}
+			idVersion, err := idutils.IDVersionFromProto(idv)
+			if err != nil {
+				return err
+			}
+
+			var cfg *anypb.Any
+			if configFile != "" {
+				if cfg, err = serviceconfig.ReadConfig(ctx, cmd, flags, iaClient, idv, configFile); err != nil {
+					return err
+				}
+			} else {
+				log.Printf("No configuration given, keeping the current configuration of %q", name)
+				cfg = inst.GetConfiguration()
+			}
+
+			log.Printf("Requesting %q be updated to %q", name, idVersion)
+			client := adgrpcpb.NewAssetDeploymentServiceClient(conn)
+			authCtx := clientutils.AuthInsecureConn(ctx, address, flags.GetFlagProject())
+
+			// This needs an authorized context to pull from the catalog if not available.
+			op, err := client.UpdateResource(authCtx, &adpb.UpdateResourceRequest{
+				Resource: &adpb.Resource{
+					Name:      name,
+					IdVersion: idv,
+					Configuration: &adpb.ResourceConfiguration{
+						Configuration: cfg,
+					},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("could not update service %q to id version %q: %v", name, idVersion, err)
+			}
+
+			log.Printf("Awaiting completion of the update operation")
+			if _, err := lro.Wait(ctx, client, op, lro.WithTimeout(timeout), lro.WithProgress(log.Writer())); err != nil {
+				return fmt.Errorf("failed to update %q: %w", name, err)
+			}
+
+			log.Printf("Finished updating service %q", name)
+			return nil
+		},
+	}
+
+	flags.SetCommand(cmd)
+	flags.AddFlagsAddressClusterSolution()
+	flags.AddFlagsProjectOrg()
+	flags.AddFlagOperationTimeout("update operation")
+	flags.OptionalString(keyConfig, "", "The filename of the service instance's new configuration, in any of the formats accepted by 'inctl service add --config'. If not set, the current configuration is kept.")
+	flags.OptionalString(keyVersion, "", "The version of the service to update the instance to. If not set, the current version is kept.")
+
+	return cmd
+}
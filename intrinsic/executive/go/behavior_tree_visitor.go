@@ -4,6 +4,8 @@
 //
 // Features are:
 // - Enables to walk and execute code for each node and condition in the tree.
+// - Enables to walk all payloads of a tree with path information and to rewrite the tree.
+// - Clears output-only fields of a tree.
 package behaviortree
 
 import (
@@ -19,139 +21,18 @@ type Visitor interface {
 	VisitNode(node *btpb.BehaviorTree_Node) error
 }
 
-func walkCondition(cond *btpb.BehaviorTree_Condition, visitor Visitor) error {
-	if cond == nil {
-		return nil
-	}
-	err := visitor.VisitCondition(cond)
-	if err != nil {
-		return err
-	}
-	switch cond.ConditionType.(type) {
-	case *btpb.BehaviorTree_Condition_BehaviorTree:
-		err := Walk(cond.GetBehaviorTree(), visitor)
-		if err != nil {
-			return err
-		}
-
-	case *btpb.BehaviorTree_Condition_AllOf:
-		for _, c := range cond.GetAllOf().GetConditions() {
-			err := walkCondition(c, visitor)
-			if err != nil {
-				return err
-			}
-		}
-
-	case *btpb.BehaviorTree_Condition_AnyOf:
-		for _, c := range cond.GetAnyOf().GetConditions() {
-			err := walkCondition(c, visitor)
-			if err != nil {
-				return err
-			}
-		}
-
-	case *btpb.BehaviorTree_Condition_Not:
-		err = walkCondition(cond.GetNot(), visitor)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func walkNode(node *btpb.BehaviorTree_Node, visitor Visitor) error {
-	if node == nil {
-		return nil
-	}
-
-	err := visitor.VisitNode(node)
-	if err != nil {
-		return err
-	}
-
-	if node.GetDecorators().GetCondition() != nil {
-		err := walkCondition(node.GetDecorators().GetCondition(), visitor)
-		if err != nil {
-			return err
-		}
-	}
-
-	switch node.NodeType.(type) {
-	case *btpb.BehaviorTree_Node_Sequence:
-		for _, c := range node.GetSequence().GetChildren() {
-			err := walkNode(c, visitor)
-			if err != nil {
-				return err
-			}
-		}
-
-	case *btpb.BehaviorTree_Node_Parallel:
-		for _, c := range node.GetParallel().GetChildren() {
-			err := walkNode(c, visitor)
-			if err != nil {
-				return err
-			}
-		}
-
-	case *btpb.BehaviorTree_Node_Selector:
-		for _, c := range node.GetSelector().GetChildren() {
-			err := walkNode(c, visitor)
-			if err != nil {
-				return err
-			}
-		}
-
-	case *btpb.BehaviorTree_Node_Fallback:
-		for _, c := range node.GetFallback().GetChildren() {
-			err := walkNode(c, visitor)
-			if err != nil {
-				return err
-			}
-		}
-
-	case *btpb.BehaviorTree_Node_Branch:
-		err := walkCondition(node.GetBranch().GetIf(), visitor)
-		if err != nil {
-			return err
-		}
-		err = walkNode(node.GetBranch().GetThen(), visitor)
-		if err != nil {
-			return err
-		}
-		err = walkNode(node.GetBranch().GetElse(), visitor)
-		if err != nil {
-			return err
-		}
-
-	case *btpb.BehaviorTree_Node_Loop:
-		err := walkCondition(node.GetLoop().GetWhile(), visitor)
-		if err != nil {
-			return err
-		}
-		err = walkNode(node.GetLoop().GetDo(), visitor)
-		if err != nil {
-			return err
-		}
-
-	case *btpb.BehaviorTree_Node_Retry:
-		err := walkNode(node.GetRetry().GetChild(), visitor)
-		if err != nil {
-			return err
-		}
-
-	case *btpb.BehaviorTree_Node_SubTree:
-		err := Walk(node.GetSubTree().GetTree(), visitor)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 // Walk walks the given Behavior Tree and invokes the given visitor for nodes
 // and conditions of the tree.
+//
+// Use a Walker for path information, post-order callbacks or to rewrite the tree.
 func Walk(tree *btpb.BehaviorTree, visitor Visitor) error {
-	return walkNode(tree.Root, visitor)
+	w := &Walker{
+		PreNode: func(node *btpb.BehaviorTree_Node, _ *Cursor) error {
+			return visitor.VisitNode(node)
+		},
+		PreCondition: func(cond *btpb.BehaviorTree_Condition, _ *Cursor) error {
+			return visitor.VisitCondition(cond)
+		},
+	}
+	return w.Walk(tree)
 }
@@ -0,0 +1,67 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package behaviortree
+
+import (
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	apb "intrinsic/executive/proto/annotations_go_proto"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+)
+
+var (
+	protoNameBehaviorTree     = proto.MessageName(new(btpb.BehaviorTree))
+	protoNameBehaviorTreeNode = proto.MessageName(new(btpb.BehaviorTree_Node))
+)
+
+func clearField(fieldName string, refl protoreflect.Message) {
+	field := refl.Descriptor().Fields().ByTextName(fieldName)
+	if refl.Has(field) {
+		refl.Clear(field)
+	}
+}
+
+// ClearOutputOnlyFields recursively clears all fields annotated as output_only from the given
+// message, which is usually a BehaviorTree or a part of it. Optionally, the tree_id fields of all
+// trees and the id fields of all nodes are cleared as well.
+func ClearOutputOnlyFields(m proto.Message, clearTreeID bool, clearNodeIDs bool) error {
+	refl := m.ProtoReflect()
+
+	n := proto.MessageName(m)
+	if clearTreeID && n == protoNameBehaviorTree {
+		clearField("tree_id", refl)
+	}
+	if clearNodeIDs && n == protoNameBehaviorTreeNode {
+		clearField("id", refl)
+	}
+
+	for i := 0; i < refl.Descriptor().Fields().Len(); i++ {
+		field := refl.Descriptor().Fields().Get(i)
+		if !refl.Has(field) {
+			continue
+		}
+		options := field.Options().(*descriptorpb.FieldOptions)
+		outputOnly := proto.GetExtension(options, apb.E_OutputOnly).(bool)
+
+		if outputOnly {
+			refl.Clear(field)
+		}
+
+		if field.Kind() == protoreflect.MessageKind {
+			if field.IsList() {
+				list := refl.Get(field).List()
+				for j := 0; j < list.Len(); j++ {
+					if err := ClearOutputOnlyFields(list.Get(j).Message().Interface(), clearTreeID, clearNodeIDs); err != nil {
+						return err
+					}
+				}
+			} else if !field.IsMap() {
+				if err := ClearOutputOnlyFields(refl.Get(field).Message().Interface(), clearTreeID, clearNodeIDs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
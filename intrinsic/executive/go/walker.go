@@ -0,0 +1,452 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package behaviortree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+)
+
+// SkipChildren can be returned by the PreNode, PreCondition and PreTree callbacks of a Walker to
+// skip everything below the current element. The element's post-visit callbacks are still invoked.
+var SkipChildren = errors.New("skip children")
+
+// Cursor describes the position of a visited element in the walked tree.
+type Cursor struct {
+	// Tree is the innermost (sub)tree containing the element. For a tree itself, this is the tree.
+	Tree *btpb.BehaviorTree
+	// Parent is the closest node containing the element. It is nil for the root node of the
+	// top-level tree and for elements of the top-level tree itself.
+	Parent *btpb.BehaviorTree_Node
+	path   []string
+	depth  int
+}
+
+// Path returns the field path of the element relative to the top-level tree, for example
+// "root.sequence.children[1].decorators.condition".
+func (c *Cursor) Path() string {
+	return strings.Join(c.path, ".")
+}
+
+// Depth returns the number of nodes enclosing the element.
+func (c *Cursor) Depth() int {
+	return c.depth
+}
+
+func (c *Cursor) child(fields ...string) *Cursor {
+	return &Cursor{
+		Tree:   c.Tree,
+		Parent: c.Parent,
+		path:   append(c.path[:len(c.path):len(c.path)], fields...),
+		depth:  c.depth,
+	}
+}
+
+func (c *Cursor) childOfNode(node *btpb.BehaviorTree_Node, fields ...string) *Cursor {
+	cc := c.child(fields...)
+	cc.Parent = node
+	cc.depth = c.depth + 1
+	return cc
+}
+
+func indexed(field string, i int) string {
+	return fmt.Sprintf("%s[%d]", field, i)
+}
+
+// Walker walks a behavior tree and invokes the configured callbacks. All callbacks are optional.
+//
+// Nodes and conditions are visited in depth-first order. Pre-visit callbacks are invoked before
+// anything below the element is walked, post-visit callbacks afterwards. Returning an error other
+// than SkipChildren from any callback aborts the walk and the error is returned from Walk.
+type Walker struct {
+	// PreTree and PostTree are invoked for the walked tree and all subtrees, e.g., of subtree nodes
+	// and subtree conditions.
+	PreTree  func(tree *btpb.BehaviorTree, c *Cursor) error
+	PostTree func(tree *btpb.BehaviorTree, c *Cursor) error
+	// PreNode and PostNode are invoked for each node.
+	PreNode  func(node *btpb.BehaviorTree_Node, c *Cursor) error
+	PostNode func(node *btpb.BehaviorTree_Node, c *Cursor) error
+	// PreCondition and PostCondition are invoked for each condition, including nested ones.
+	PreCondition  func(cond *btpb.BehaviorTree_Condition, c *Cursor) error
+	PostCondition func(cond *btpb.BehaviorTree_Condition, c *Cursor) error
+	// Payload is invoked for the messages of a node which are neither nodes nor conditions: node
+	// decorators (breakpoint, execution and failure settings), task behavior calls and code
+	// executions, loop for-each generators and the data, fail, debug and process control node
+	// messages. It is invoked after PreNode and before the node's children are walked.
+	Payload func(payload proto.Message, c *Cursor) error
+	// Any is invoked for each Any proto carried by a node: task parameters, data node protos and
+	// world queries, loop for-each protos and user data.
+	Any func(a *anypb.Any, c *Cursor) error
+
+	// ReplaceNode, if set, is invoked after PostNode and the returned node replaces the visited
+	// node in its parent. Returning nil removes the node from its parent.
+	ReplaceNode func(node *btpb.BehaviorTree_Node, c *Cursor) (*btpb.BehaviorTree_Node, error)
+	// ReplaceCondition, if set, is invoked after PostCondition and the returned condition replaces
+	// the visited condition in its parent. Returning nil removes the condition from its parent.
+	ReplaceCondition func(cond *btpb.BehaviorTree_Condition, c *Cursor) (*btpb.BehaviorTree_Condition, error)
+}
+
+// Walk walks the given tree. The tree is modified in place if ReplaceNode or ReplaceCondition is
+// set. Elements are left unchanged if the walk is aborted while visiting them.
+func (w *Walker) Walk(tree *btpb.BehaviorTree) error {
+	return w.walkTree(tree, &Cursor{})
+}
+
+// pre invokes a pre-visit callback and reports whether to descend into the element's children.
+func pre[T any](fn func(T, *Cursor) error, elem T, c *Cursor) (bool, error) {
+	if fn == nil {
+		return true, nil
+	}
+	if err := fn(elem, c); errors.Is(err, SkipChildren) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func post[T any](fn func(T, *Cursor) error, elem T, c *Cursor) error {
+	if fn == nil {
+		return nil
+	}
+	return fn(elem, c)
+}
+
+func (w *Walker) walkTree(tree *btpb.BehaviorTree, c *Cursor) error {
+	if tree == nil {
+		return nil
+	}
+	c = c.child()
+	c.Tree = tree
+
+	descend, err := pre(w.PreTree, tree, c)
+	if err != nil {
+		return err
+	}
+	if descend {
+		if err := w.walkNodeField(&tree.Root, c.child("root")); err != nil {
+			return err
+		}
+	}
+	return post(w.PostTree, tree, c)
+}
+
+// walkNodeField walks the node stored in *field and only writes to the field if the node was
+// replaced, so that walks without replacement callbacks never modify the tree.
+func (w *Walker) walkNodeField(field **btpb.BehaviorTree_Node, c *Cursor) error {
+	node, err := w.walkNode(*field, c)
+	if node != *field {
+		*field = node
+	}
+	return err
+}
+
+func (w *Walker) walkNode(node *btpb.BehaviorTree_Node, c *Cursor) (*btpb.BehaviorTree_Node, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	descend, err := pre(w.PreNode, node, c)
+	if err != nil {
+		return node, err
+	}
+	if descend {
+		if err := w.walkNodeContents(node, c); err != nil {
+			return node, err
+		}
+	}
+	if err := post(w.PostNode, node, c); err != nil {
+		return node, err
+	}
+
+	if w.ReplaceNode == nil {
+		return node, nil
+	}
+	replacement, err := w.ReplaceNode(node, c)
+	if err != nil {
+		return node, err
+	}
+	return replacement, nil
+}
+
+func (w *Walker) walkNodeContents(node *btpb.BehaviorTree_Node, c *Cursor) error {
+	if decorators := node.GetDecorators(); decorators != nil {
+		if err := w.visitPayload(decorators, c.childOfNode(node, "decorators")); err != nil {
+			return err
+		}
+		if err := w.walkConditionField(&decorators.Condition, c.childOfNode(node, "decorators", "condition")); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch node.NodeType.(type) {
+	case *btpb.BehaviorTree_Node_Sequence:
+		err = w.walkChildren(&node.GetSequence().Children, c.childOfNode(node, "sequence"))
+
+	case *btpb.BehaviorTree_Node_Parallel:
+		err = w.walkChildren(&node.GetParallel().Children, c.childOfNode(node, "parallel"))
+
+	case *btpb.BehaviorTree_Node_Selector:
+		err = w.walkChildren(&node.GetSelector().Children, c.childOfNode(node, "selector"))
+
+	case *btpb.BehaviorTree_Node_Fallback:
+		err = w.walkChildren(&node.GetFallback().Children, c.childOfNode(node, "fallback"))
+
+	case *btpb.BehaviorTree_Node_Branch:
+		b := node.GetBranch()
+		if err := w.walkConditionField(&b.If, c.childOfNode(node, "branch", "if")); err != nil {
+			return err
+		}
+		if err := w.walkNodeField(&b.Then, c.childOfNode(node, "branch", "then")); err != nil {
+			return err
+		}
+		err = w.walkNodeField(&b.Else, c.childOfNode(node, "branch", "else"))
+
+	case *btpb.BehaviorTree_Node_Loop:
+		err = w.walkLoop(node, c)
+
+	case *btpb.BehaviorTree_Node_Retry:
+		r := node.GetRetry()
+		if err := w.walkNodeField(&r.Child, c.childOfNode(node, "retry", "child")); err != nil {
+			return err
+		}
+		err = w.walkNodeField(&r.Recovery, c.childOfNode(node, "retry", "recovery"))
+
+	case *btpb.BehaviorTree_Node_SubTree:
+		err = w.walkTree(node.GetSubTree().GetTree(), c.childOfNode(node, "sub_tree", "tree"))
+
+	case *btpb.BehaviorTree_Node_Task:
+		err = w.walkTask(node, c)
+
+	case *btpb.BehaviorTree_Node_Data:
+		err = w.walkData(node, c)
+
+	case *btpb.BehaviorTree_Node_Fail:
+		err = w.visitPayload(node.GetFail(), c.childOfNode(node, "fail"))
+
+	case *btpb.BehaviorTree_Node_Debug:
+		err = w.visitPayload(node.GetDebug(), c.childOfNode(node, "debug"))
+
+	case *btpb.BehaviorTree_Node_ControlProcess:
+		err = w.visitPayload(node.GetControlProcess(), c.childOfNode(node, "control_process"))
+	}
+	if err != nil {
+		return err
+	}
+
+	return w.visitUserData(node.GetUserData(), c.childOfNode(node, "user_data"))
+}
+
+// walkChildren walks the nodes in *children. The slice is only reassigned if at least one node
+// was replaced or removed.
+func (w *Walker) walkChildren(children *[]*btpb.BehaviorTree_Node, c *Cursor) error {
+	result, err := walkList(*children, func(child *btpb.BehaviorTree_Node, i int) (*btpb.BehaviorTree_Node, error) {
+		return w.walkNode(child, c.child(indexed("children", i)))
+	})
+	if result != nil {
+		*children = *result
+	}
+	return err
+}
+
+// walkList walks the given elements and returns the updated list if any element was replaced or
+// removed, or nil if the list is unchanged. Nothing is returned if the walk fails.
+func walkList[T comparable](elems []T, walk func(T, int) (T, error)) (*[]T, error) {
+	var zero T
+	var result *[]T
+	for i, elem := range elems {
+		replacement, err := walk(elem, i)
+		if err != nil {
+			return nil, err
+		}
+		if replacement != elem && result == nil {
+			updated := append([]T(nil), elems[:i]...)
+			result = &updated
+		}
+		if result != nil && replacement != zero {
+			*result = append(*result, replacement)
+		}
+	}
+	return result, nil
+}
+
+func (w *Walker) walkLoop(node *btpb.BehaviorTree_Node, c *Cursor) error {
+	l := node.GetLoop()
+	switch lt := l.LoopType.(type) {
+	case *btpb.BehaviorTree_LoopNode_While:
+		while, err := w.walkCondition(lt.While, c.childOfNode(node, "loop", "while"))
+		if err != nil {
+			return err
+		}
+		if while == nil && lt.While != nil {
+			l.LoopType = nil
+		} else if while != lt.While {
+			lt.While = while
+		}
+
+	case *btpb.BehaviorTree_LoopNode_ForEach_:
+		forEach := l.GetForEach()
+		fc := c.childOfNode(node, "loop", "for_each")
+		if err := w.visitPayload(forEach, fc); err != nil {
+			return err
+		}
+		for i, item := range forEach.GetProtos().GetItems() {
+			if err := w.visitAny(item, fc.child("protos", indexed("items", i))); err != nil {
+				return err
+			}
+		}
+	}
+
+	return w.walkNodeField(&l.Do, c.childOfNode(node, "loop", "do"))
+}
+
+func (w *Walker) walkTask(node *btpb.BehaviorTree_Node, c *Cursor) error {
+	t := node.GetTask()
+	if callBehavior := t.GetCallBehavior(); callBehavior != nil {
+		tc := c.childOfNode(node, "task", "call_behavior")
+		if err := w.visitPayload(callBehavior, tc); err != nil {
+			return err
+		}
+		if err := w.visitAny(callBehavior.GetParameters(), tc.child("parameters")); err != nil {
+			return err
+		}
+	}
+	if executeCode := t.GetExecuteCode(); executeCode != nil {
+		if err := w.visitPayload(executeCode, c.childOfNode(node, "task", "execute_code")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Walker) walkData(node *btpb.BehaviorTree_Node, c *Cursor) error {
+	d := node.GetData()
+	dc := c.childOfNode(node, "data")
+	if err := w.visitPayload(d, dc); err != nil {
+		return err
+	}
+
+	createOrUpdate := d.GetCreateOrUpdate()
+	cc := dc.child("create_or_update")
+	if err := w.visitAny(createOrUpdate.GetProto(), cc.child("proto")); err != nil {
+		return err
+	}
+	if err := w.visitAny(createOrUpdate.GetFromWorld().GetProto(), cc.child("from_world", "proto")); err != nil {
+		return err
+	}
+	for i, item := range createOrUpdate.GetProtos().GetItems() {
+		if err := w.visitAny(item, cc.child("protos", indexed("items", i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Walker) walkCondition(cond *btpb.BehaviorTree_Condition, c *Cursor) (*btpb.BehaviorTree_Condition, error) {
+	if cond == nil {
+		return nil, nil
+	}
+
+	descend, err := pre(w.PreCondition, cond, c)
+	if err != nil {
+		return cond, err
+	}
+	if descend {
+		switch ct := cond.ConditionType.(type) {
+		case *btpb.BehaviorTree_Condition_BehaviorTree:
+			err = w.walkTree(cond.GetBehaviorTree(), c.child("behavior_tree"))
+
+		case *btpb.BehaviorTree_Condition_AllOf:
+			err = w.walkConditions(&cond.GetAllOf().Conditions, c.child("all_of"))
+
+		case *btpb.BehaviorTree_Condition_AnyOf:
+			err = w.walkConditions(&cond.GetAnyOf().Conditions, c.child("any_of"))
+
+		case *btpb.BehaviorTree_Condition_Not:
+			var not *btpb.BehaviorTree_Condition
+			if not, err = w.walkCondition(ct.Not, c.child("not")); err != nil {
+				return cond, err
+			}
+			if not == nil && ct.Not != nil {
+				cond.ConditionType = nil
+			} else if not != ct.Not {
+				ct.Not = not
+			}
+		}
+		if err != nil {
+			return cond, err
+		}
+	}
+	if err := post(w.PostCondition, cond, c); err != nil {
+		return cond, err
+	}
+
+	if w.ReplaceCondition == nil {
+		return cond, nil
+	}
+	replacement, err := w.ReplaceCondition(cond, c)
+	if err != nil {
+		return cond, err
+	}
+	return replacement, nil
+}
+
+// walkConditionField walks the condition stored in *field and only writes to the field if the
+// condition was replaced.
+func (w *Walker) walkConditionField(field **btpb.BehaviorTree_Condition, c *Cursor) error {
+	cond, err := w.walkCondition(*field, c)
+	if cond != *field {
+		*field = cond
+	}
+	return err
+}
+
+// walkConditions walks the conditions in *conditions. The slice is only reassigned if at least one
+// condition was replaced or removed.
+func (w *Walker) walkConditions(conditions *[]*btpb.BehaviorTree_Condition, c *Cursor) error {
+	result, err := walkList(*conditions, func(cond *btpb.BehaviorTree_Condition, i int) (*btpb.BehaviorTree_Condition, error) {
+		return w.walkCondition(cond, c.child(indexed("conditions", i)))
+	})
+	if result != nil {
+		*conditions = *result
+	}
+	return err
+}
+
+func (w *Walker) visitPayload(payload proto.Message, c *Cursor) error {
+	if w.Payload == nil {
+		return nil
+	}
+	return w.Payload(payload, c)
+}
+
+func (w *Walker) visitAny(a *anypb.Any, c *Cursor) error {
+	if w.Any == nil || a == nil {
+		return nil
+	}
+	return w.Any(a, c)
+}
+
+func (w *Walker) visitUserData(userData *btpb.BehaviorTree_UserData, c *Cursor) error {
+	if w.Any == nil || userData == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(userData.GetDataAny()))
+	for k := range userData.GetDataAny() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := w.visitAny(userData.GetDataAny()[k], c.child(fmt.Sprintf("data_any[%q]", k))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
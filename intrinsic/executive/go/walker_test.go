@@ -0,0 +1,333 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package behaviortree_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"intrinsic/executive/go/behaviortree"
+	anylistpb "intrinsic/executive/proto/any_list_go_proto"
+	bcpb "intrinsic/executive/proto/behavior_call_go_proto"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+)
+
+func mustAny(t *testing.T, m proto.Message) *anypb.Any {
+	t.Helper()
+	a, err := anypb.New(m)
+	if err != nil {
+		t.Fatalf("anypb.New(%v) failed: %v", m, err)
+	}
+	return a
+}
+
+func leaf(name string) *btpb.BehaviorTree_Node {
+	return &btpb.BehaviorTree_Node{
+		Name:     proto.String(name),
+		NodeType: &btpb.BehaviorTree_Node_Fail{Fail: &btpb.BehaviorTree_FailNode{}},
+	}
+}
+
+func sequence(name string, children ...*btpb.BehaviorTree_Node) *btpb.BehaviorTree_Node {
+	return &btpb.BehaviorTree_Node{
+		Name: proto.String(name),
+		NodeType: &btpb.BehaviorTree_Node_Sequence{
+			Sequence: &btpb.BehaviorTree_SequenceNode{Children: children},
+		},
+	}
+}
+
+func subtreeCondition(name string) *btpb.BehaviorTree_Condition {
+	return &btpb.BehaviorTree_Condition{
+		ConditionType: &btpb.BehaviorTree_Condition_BehaviorTree{
+			BehaviorTree: &btpb.BehaviorTree{Root: leaf(name)},
+		},
+	}
+}
+
+func TestWalkerPaths(t *testing.T) {
+	task := &btpb.BehaviorTree_Node{
+		Name: proto.String("task"),
+		NodeType: &btpb.BehaviorTree_Node_Task{
+			Task: &btpb.BehaviorTree_TaskNode{
+				TaskType: &btpb.BehaviorTree_TaskNode_CallBehavior{
+					CallBehavior: &bcpb.BehaviorCall{
+						SkillId:    "ai.intrinsic.my_skill",
+						Parameters: mustAny(t, wrapperspb.String("params")),
+					},
+				},
+			},
+		},
+		UserData: &btpb.BehaviorTree_UserData{
+			DataAny: map[string]*anypb.Any{
+				"b": mustAny(t, wrapperspb.Int32(2)),
+				"a": mustAny(t, wrapperspb.Int32(1)),
+			},
+		},
+	}
+	retry := &btpb.BehaviorTree_Node{
+		Name: proto.String("retry"),
+		Decorators: &btpb.BehaviorTree_Node_Decorators{
+			Condition: subtreeCondition("cond"),
+		},
+		NodeType: &btpb.BehaviorTree_Node_Retry{
+			Retry: &btpb.BehaviorTree_RetryNode{
+				Child:    task,
+				Recovery: leaf("recovery"),
+			},
+		},
+	}
+	loop := &btpb.BehaviorTree_Node{
+		Name: proto.String("loop"),
+		NodeType: &btpb.BehaviorTree_Node_Loop{
+			Loop: &btpb.BehaviorTree_LoopNode{
+				LoopType: &btpb.BehaviorTree_LoopNode_ForEach_{
+					ForEach: &btpb.BehaviorTree_LoopNode_ForEach{
+						ForEachGeneratorType: &btpb.BehaviorTree_LoopNode_ForEach_Protos{
+							Protos: &anylistpb.AnyList{
+								Items: []*anypb.Any{mustAny(t, wrapperspb.Bool(true))},
+							},
+						},
+					},
+				},
+				Do: leaf("do"),
+			},
+		},
+	}
+	tree := &btpb.BehaviorTree{Root: sequence("root", retry, loop)}
+
+	var got []string
+	w := &behaviortree.Walker{
+		PreTree: func(tree *btpb.BehaviorTree, c *behaviortree.Cursor) error {
+			got = append(got, fmt.Sprintf("tree %s", c.Path()))
+			return nil
+		},
+		PreNode: func(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) error {
+			got = append(got, fmt.Sprintf("node %s %s depth=%d parent=%s", node.GetName(), c.Path(), c.Depth(), c.Parent.GetName()))
+			return nil
+		},
+		PreCondition: func(cond *btpb.BehaviorTree_Condition, c *behaviortree.Cursor) error {
+			got = append(got, fmt.Sprintf("condition %s", c.Path()))
+			return nil
+		},
+		Payload: func(payload proto.Message, c *behaviortree.Cursor) error {
+			got = append(got, fmt.Sprintf("payload %s %s", payload.ProtoReflect().Descriptor().Name(), c.Path()))
+			return nil
+		},
+		Any: func(a *anypb.Any, c *behaviortree.Cursor) error {
+			got = append(got, fmt.Sprintf("any %s %s", a.MessageName(), c.Path()))
+			return nil
+		},
+	}
+	if err := w.Walk(tree); err != nil {
+		t.Fatalf("Walk() failed: %v", err)
+	}
+
+	want := []string{
+		"tree ",
+		"node root root depth=0 parent=",
+		"node retry root.sequence.children[0] depth=1 parent=root",
+		"payload Decorators root.sequence.children[0].decorators",
+		"condition root.sequence.children[0].decorators.condition",
+		"tree root.sequence.children[0].decorators.condition.behavior_tree",
+		"node cond root.sequence.children[0].decorators.condition.behavior_tree.root depth=2 parent=retry",
+		"payload FailNode root.sequence.children[0].decorators.condition.behavior_tree.root.fail",
+		"node task root.sequence.children[0].retry.child depth=2 parent=retry",
+		"payload BehaviorCall root.sequence.children[0].retry.child.task.call_behavior",
+		"any google.protobuf.StringValue root.sequence.children[0].retry.child.task.call_behavior.parameters",
+		`any google.protobuf.Int32Value root.sequence.children[0].retry.child.user_data.data_any["a"]`,
+		`any google.protobuf.Int32Value root.sequence.children[0].retry.child.user_data.data_any["b"]`,
+		"node recovery root.sequence.children[0].retry.recovery depth=2 parent=retry",
+		"payload FailNode root.sequence.children[0].retry.recovery.fail",
+		"node loop root.sequence.children[1] depth=1 parent=root",
+		"payload ForEach root.sequence.children[1].loop.for_each",
+		"any google.protobuf.BoolValue root.sequence.children[1].loop.for_each.protos.items[0]",
+		"node do root.sequence.children[1].loop.do depth=2 parent=loop",
+		"payload FailNode root.sequence.children[1].loop.do.fail",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Walk() visited unexpected elements (-want +got):\n%s", diff)
+	}
+}
+
+func TestWalkerSkipChildrenAndPostOrder(t *testing.T) {
+	tree := &btpb.BehaviorTree{
+		Root: sequence("A",
+			sequence("B", leaf("C")),
+			sequence("D", leaf("E")),
+		),
+	}
+
+	var got []string
+	w := &behaviortree.Walker{
+		PreNode: func(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) error {
+			got = append(got, "pre "+node.GetName())
+			if node.GetName() == "B" {
+				return behaviortree.SkipChildren
+			}
+			return nil
+		},
+		PostNode: func(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) error {
+			got = append(got, "post "+node.GetName())
+			return nil
+		},
+	}
+	if err := w.Walk(tree); err != nil {
+		t.Fatalf("Walk() failed: %v", err)
+	}
+
+	want := []string{"pre A", "pre B", "post B", "pre D", "pre E", "post E", "post D", "post A"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Walk() visited unexpected nodes (-want +got):\n%s", diff)
+	}
+}
+
+func TestWalkerError(t *testing.T) {
+	tree := &btpb.BehaviorTree{Root: sequence("A", leaf("B"), leaf("C"))}
+	wantErr := errors.New("stop")
+
+	var got []string
+	w := &behaviortree.Walker{
+		PreNode: func(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) error {
+			got = append(got, node.GetName())
+			if node.GetName() == "B" {
+				return wantErr
+			}
+			return nil
+		},
+	}
+	if err := w.Walk(tree); !errors.Is(err, wantErr) {
+		t.Errorf("Walk() returned %v, want %v", err, wantErr)
+	}
+	if diff := cmp.Diff([]string{"A", "B"}, got); diff != "" {
+		t.Errorf("Walk() visited unexpected nodes (-want +got):\n%s", diff)
+	}
+}
+
+func TestWalkerReplace(t *testing.T) {
+	tree := &btpb.BehaviorTree{
+		Root: sequence("A",
+			leaf("remove"),
+			&btpb.BehaviorTree_Node{
+				Name: proto.String("B"),
+				Decorators: &btpb.BehaviorTree_Node_Decorators{
+					Condition: &btpb.BehaviorTree_Condition{
+						ConditionType: &btpb.BehaviorTree_Condition_AllOf{
+							AllOf: &btpb.BehaviorTree_Condition_LogicalCompound{
+								Conditions: []*btpb.BehaviorTree_Condition{
+									subtreeCondition("remove"),
+									subtreeCondition("C"),
+								},
+							},
+						},
+					},
+				},
+				NodeType: &btpb.BehaviorTree_Node_Fail{Fail: &btpb.BehaviorTree_FailNode{}},
+			},
+			leaf("rename"),
+		),
+	}
+
+	w := &behaviortree.Walker{
+		ReplaceNode: func(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) (*btpb.BehaviorTree_Node, error) {
+			switch node.GetName() {
+			case "remove":
+				return nil, nil
+			case "rename":
+				return leaf("renamed"), nil
+			}
+			return node, nil
+		},
+		ReplaceCondition: func(cond *btpb.BehaviorTree_Condition, c *behaviortree.Cursor) (*btpb.BehaviorTree_Condition, error) {
+			// The subtree root has been removed before its condition is visited.
+			if cond.GetBehaviorTree() != nil && cond.GetBehaviorTree().GetRoot() == nil {
+				return nil, nil
+			}
+			return cond, nil
+		},
+	}
+	if err := w.Walk(tree); err != nil {
+		t.Fatalf("Walk() failed: %v", err)
+	}
+
+	want := &btpb.BehaviorTree{
+		Root: sequence("A",
+			&btpb.BehaviorTree_Node{
+				Name: proto.String("B"),
+				Decorators: &btpb.BehaviorTree_Node_Decorators{
+					Condition: &btpb.BehaviorTree_Condition{
+						ConditionType: &btpb.BehaviorTree_Condition_AllOf{
+							AllOf: &btpb.BehaviorTree_Condition_LogicalCompound{
+								Conditions: []*btpb.BehaviorTree_Condition{
+									subtreeCondition("C"),
+								},
+							},
+						},
+					},
+				},
+				NodeType: &btpb.BehaviorTree_Node_Fail{Fail: &btpb.BehaviorTree_FailNode{}},
+			},
+			leaf("renamed"),
+		),
+	}
+	if diff := cmp.Diff(want, tree, protocmp.Transform()); diff != "" {
+		t.Errorf("Walk() returned unexpected tree (-want +got):\n%s", diff)
+	}
+}
+
+func TestWalkerWithoutReplaceLeavesTreeUntouched(t *testing.T) {
+	while := &btpb.BehaviorTree_LoopNode_While{
+		While: &btpb.BehaviorTree_Condition{
+			ConditionType: &btpb.BehaviorTree_Condition_Not{Not: subtreeCondition("while")},
+		},
+	}
+	not := while.While.ConditionType
+	children := []*btpb.BehaviorTree_Node{
+		leaf("A"),
+		{
+			Name: proto.String("loop"),
+			NodeType: &btpb.BehaviorTree_Node_Loop{
+				Loop: &btpb.BehaviorTree_LoopNode{LoopType: while, Do: leaf("do")},
+			},
+		},
+		{
+			Name: proto.String("retry"),
+			NodeType: &btpb.BehaviorTree_Node_Retry{
+				Retry: &btpb.BehaviorTree_RetryNode{Child: leaf("child"), Recovery: leaf("recovery")},
+			},
+		},
+	}
+	tree := &btpb.BehaviorTree{Root: sequence("root", children...)}
+	root := tree.GetRoot()
+	want := proto.Clone(tree)
+
+	w := &behaviortree.Walker{
+		PreNode:       func(*btpb.BehaviorTree_Node, *behaviortree.Cursor) error { return nil },
+		PreCondition:  func(*btpb.BehaviorTree_Condition, *behaviortree.Cursor) error { return nil },
+		PostCondition: func(*btpb.BehaviorTree_Condition, *behaviortree.Cursor) error { return nil },
+	}
+	if err := w.Walk(tree); err != nil {
+		t.Fatalf("Walk() failed: %v", err)
+	}
+
+	if !proto.Equal(want, tree) {
+		t.Errorf("Walk() modified the tree to %v, want %v", tree, want)
+	}
+	if tree.GetRoot() != root {
+		t.Errorf("Walk() replaced the root node")
+	}
+	got := tree.GetRoot().GetSequence().GetChildren()
+	if &got[0] != &children[0] {
+		t.Errorf("Walk() replaced the children of the root node")
+	}
+	loop := got[1].GetLoop()
+	if loop.LoopType != while || while.While.ConditionType != not {
+		t.Errorf("Walk() replaced the oneof wrappers of the loop condition")
+	}
+}
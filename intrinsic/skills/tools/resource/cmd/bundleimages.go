@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
 	"intrinsic/assets/bundleio"
 	"intrinsic/assets/idutils"
 	"intrinsic/assets/imageutils"
@@ -27,6 +28,13 @@ const (
 // pushes images to the registry using a default tag.  The image is named with
 // the id of the resource with the basename image filename appended.
 func CreateImageProcessor(reg imageutils.RegistryOptions) bundleio.ImageProcessor {
+	return CreateImageProcessorForPlatform(reg, nil)
+}
+
+// CreateImageProcessorForPlatform is like CreateImageProcessor, but only
+// pushes the image for platform out of multi-platform images.  All platforms
+// are pushed if platform is nil.
+func CreateImageProcessorForPlatform(reg imageutils.RegistryOptions, platform *containerregistry.Platform) bundleio.ImageProcessor {
 	return func(idProto *idpb.Id, filename string, r io.Reader) (*ipb.Image, error) {
 		id, err := idutils.IDFromProto(idProto)
 		if err != nil {
@@ -39,6 +47,7 @@ func CreateImageProcessor(reg imageutils.RegistryOptions) bundleio.ImageProcesso
 		if err != nil {
 			return nil, fmt.Errorf("unable to get tag for image: %v", err)
 		}
+		opts.Platform = platform
 
 		// Some images can be quite large (>1GB) and cause out-of-memory issues when
 		// read into a byte buffer. We use the readeropener utility to use an
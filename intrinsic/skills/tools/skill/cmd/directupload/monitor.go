@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 
 	"intrinsic/storage/artifacts/client/client"
 )
@@ -21,6 +22,7 @@ const (
 // uploadMonitor is simple tool for output progress of the object upload into the provided writer
 // in text format.
 type uploadMonitor struct {
+	mu     sync.Mutex
 	writer io.Writer
 	refMap map[string]any
 }
@@ -33,6 +35,9 @@ func newMonitor(w io.Writer) client.ProgressMonitor {
 }
 
 func (u *uploadMonitor) UpdateProgress(ref string, update client.ProgressUpdate) {
+	// updates arrive from parallel upload tasks.
+	u.mu.Lock()
+	defer u.mu.Unlock()
 	shortRef := asShortName(ref)
 	status := update.Status
 	if status == client.StatusUndetermined || status == client.StatusContinue {
@@ -62,6 +67,16 @@ func (u *uploadMonitor) UpdateProgress(ref string, update client.ProgressUpdate)
 	}
 }
 
+func (u *uploadMonitor) UpdateUploadMode(update client.UploadModeUpdate) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if update.Err == nil {
+		fmt.Fprintf(u.writer, "upload mode: %s\n", update.Mode)
+	} else {
+		fmt.Fprintf(u.writer, "switching to %s upload: %s\n", update.Mode, update.Err)
+	}
+}
+
 func asShortName(name string) string {
 	if strings.HasPrefix(name, "sha") {
 		// shaXYZ:IDENTIFIER
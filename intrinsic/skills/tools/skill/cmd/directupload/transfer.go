@@ -57,6 +57,40 @@ func WithDiscovery(discovery TargetDiscovery) Option {
 	}
 }
 
+// WithDefaultJournal allows resuming uploads of layers which were interrupted,
+// also across processes, by keeping track of pending writes in the journal in
+// the users config directory. Resuming is limited to the running process if
+// the journal cannot be opened.
+func WithDefaultJournal() Option {
+	return func(transfer *directTransfer) {
+		journal, err := client.OpenDefaultJournal()
+		if err != nil {
+			log.Warningf("cannot open upload journal: %s", err)
+			return
+		}
+		transfer.journal = journal
+	}
+}
+
+// WithDefaultCache allows skipping uploads of images which target already
+// holds by remembering uploaded images in the cache in the users config
+// directory. target identifies the upload destination, e.g. a cluster of a
+// project. Every upload checks for missing content if the cache cannot be
+// opened or target is empty.
+func WithDefaultCache(target string) Option {
+	return func(transfer *directTransfer) {
+		if target == "" {
+			return
+		}
+		cache, err := client.OpenDefaultCache()
+		if err != nil {
+			log.Warningf("cannot open image cache: %s", err)
+			return
+		}
+		transfer.cache, transfer.cacheTarget = cache, target
+	}
+}
+
 // WithFailOver allows to set fail-over transferer in case direct upload
 // is not possible.
 func WithFailOver(failOver imagetransfer.Transferer) Option {
@@ -94,20 +128,47 @@ type directTransfer struct {
 	client     artifactgrpcpb.ArtifactServiceApiClient
 	ctx        context.Context
 	discovery  TargetDiscovery
+	journal    *client.Journal
+	// cache remembers images which cacheTarget holds already.
+	cache       *client.Cache
+	cacheTarget string
 }
 
 func (dt *directTransfer) Write(ref name.Reference, img crv1.Image) error {
+	return dt.write(ref, func(uploader client.Uploader) error {
+		return uploader.UploadImage(dt.ctx, ref.String(), img)
+	}, func() error {
+		return dt.failOver.Write(ref, img)
+	})
+}
+
+// WriteIndex uploads all images of index followed by the index itself.
+func (dt *directTransfer) WriteIndex(ref name.Reference, index crv1.ImageIndex) error {
+	return dt.write(ref, func(uploader client.Uploader) error {
+		return uploader.UploadIndex(dt.ctx, ref.String(), index)
+	}, func() error {
+		writer, ok := dt.failOver.(imagetransfer.IndexWriter)
+		if !ok {
+			return fmt.Errorf("fail over cannot write image index %q", ref)
+		}
+		return writer.WriteIndex(ref, index)
+	})
+}
+
+// write runs upload with retries and falls back to failOver if it does not
+// succeed and a fail over transferer is configured.
+func (dt *directTransfer) write(ref name.Reference, upload func(client.Uploader) error, failOver func() error) error {
 
 	if dt.uploader == nil {
 		apiClient, err := dt.getClient()
 		if err != nil {
 			return fmt.Errorf("cannot connect: %w", err)
 		}
-		dt.uploader, err = client.NewUploader(apiClient, client.WithSequentialUpload(),
-			// To mitigate b/330747118; this is not full fix, but should help.
-			// This setting is forcing to run only 1 upload task at a time.
-			// We are taking significant performance penalty.
-			client.WithUploadParallelism(1))
+		// Uploads through the cloud relay can run into its proxy limits
+		// (b/330747118), the adaptive uploader falls back to sequential and
+		// then to single uploads when that happens.
+		dt.uploader, err = client.NewUploader(apiClient, client.WithAdaptiveUpload(),
+			client.WithJournal(dt.journal), client.WithCache(dt.cache, dt.cacheTarget))
 		if err != nil {
 			return fmt.Errorf("cannot create uploader: %w", err)
 		}
@@ -121,7 +182,7 @@ func (dt *directTransfer) Write(ref name.Reference, img crv1.Image) error {
 			return backoff.Permanent(dt.ctx.Err())
 		}
 		attempt := numAttempts.Inc()
-		err := dt.uploader.UploadImage(dt.ctx, ref.String(), img)
+		err := upload(dt.uploader)
 		if err != nil {
 			log.Errorf("attempt %d/%d: failed to upload image (%s): %s", attempt, maxAttempts, ref, err)
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
@@ -133,7 +194,7 @@ func (dt *directTransfer) Write(ref name.Reference, img crv1.Image) error {
 	}, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), uint64(dt.maxRetries)))
 	if err != nil {
 		if dt.failOver != nil {
-			if foErr := dt.failOver.Write(ref, img); foErr != nil {
+			if foErr := failOver(); foErr != nil {
 				return fmt.Errorf("image write failed (direct: %s): %w", err, foErr)
 			}
 			log.Warningf("fail over succeeded with prior direct upload failure: %s", err)
@@ -144,6 +205,12 @@ func (dt *directTransfer) Write(ref name.Reference, img crv1.Image) error {
 	return nil
 }
 
+// ResolveDigest returns the digest of img if it was uploaded before, which
+// saves hashing its layers.
+func (dt *directTransfer) ResolveDigest(img crv1.Image) (crv1.Hash, bool) {
+	return dt.cache.Digest(img)
+}
+
 func (dt *directTransfer) Read(ref name.Reference) (crv1.Image, error) {
 	// Note (@rkomara): Direct upload is "write-only" operation. There is no
 	// meaningful way to scan for presence of images in remote workcell repository.
@@ -0,0 +1,206 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package directupload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	crv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"intrinsic/assets/imagetransfer"
+	"intrinsic/storage/artifacts/client/artifacttest"
+)
+
+const testImageName = "gcr.io/test-project/test-image:latest"
+
+func randomImage(t *testing.T) crv1.Image {
+	t.Helper()
+	img, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatalf("random.Image() failed: %v", err)
+	}
+	return img
+}
+
+type recordingTransferer struct {
+	written []string
+}
+
+func (r *recordingTransferer) Write(ref name.Reference, _ crv1.Image) error {
+	r.written = append(r.written, ref.String())
+	return nil
+}
+
+func (r *recordingTransferer) Read(_ name.Reference) (crv1.Image, error) {
+	return nil, ErrUnsupported
+}
+
+func TestWrite(t *testing.T) {
+	server := artifacttest.NewServer()
+	output := new(bytes.Buffer)
+	transfer := NewTransferer(context.Background(),
+		WithDiscovery(NewFromConnection(server.Start(t))), WithOutput(output))
+	img := randomImage(t)
+
+	if err := transfer.Write(name.MustParseReference(testImageName), img); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	manifest, err := img.RawManifest()
+	if err != nil {
+		t.Fatalf("RawManifest() failed: %v", err)
+	}
+	if got, ok := server.Content(testImageName); !ok || !bytes.Equal(got, manifest) {
+		t.Errorf("Write() did not store the manifest of %s, server has %v", testImageName, server.Refs())
+	}
+	if output.Len() == 0 {
+		t.Errorf("Write() did not report progress")
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	server := artifacttest.NewServer()
+	transfer := NewTransferer(context.Background(), WithClient(server.Client(t)))
+	index, err := random.Index(1024, 1, 2)
+	if err != nil {
+		t.Fatalf("random.Index() failed: %v", err)
+	}
+
+	writer, ok := transfer.(imagetransfer.IndexWriter)
+	if !ok {
+		t.Fatalf("NewTransferer() returned %T, which cannot write indexes", transfer)
+	}
+	if err := writer.WriteIndex(name.MustParseReference(testImageName), index); err != nil {
+		t.Fatalf("WriteIndex() failed: %v", err)
+	}
+
+	manifest, err := index.RawManifest()
+	if err != nil {
+		t.Fatalf("RawManifest() failed: %v", err)
+	}
+	if got, ok := server.Content(testImageName); !ok || !bytes.Equal(got, manifest) {
+		t.Errorf("WriteIndex() did not store the index of %s, server has %v", testImageName, server.Refs())
+	}
+}
+
+func TestWriteWithCache(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	server := artifacttest.NewServer()
+	img := randomImage(t)
+	for _, ref := range []name.Reference{
+		name.MustParseReference("gcr.io/test-project/test-image:v1"),
+		name.MustParseReference("gcr.io/test-project/test-image:v2"),
+	} {
+		transfer := NewTransferer(context.Background(), WithClient(server.Client(t)), WithDefaultCache("test-cluster"))
+		if err := transfer.Write(ref, img); err != nil {
+			t.Fatalf("Write() of %s failed: %v", ref, err)
+		}
+	}
+
+	if got := server.Calls(artifacttest.MethodCheckImage); got != 1 {
+		t.Errorf("Write() of cached image called CheckImage %d times, want 1", got)
+	}
+	resolver, ok := NewTransferer(context.Background(), WithClient(server.Client(t)), WithDefaultCache("test-cluster")).(imagetransfer.DigestResolver)
+	if !ok {
+		t.Fatalf("NewTransferer() returned transferer which cannot resolve digests")
+	}
+	want, err := img.Digest()
+	if err != nil {
+		t.Fatalf("Digest() failed: %v", err)
+	}
+	if got, ok := resolver.ResolveDigest(img); !ok || got != want {
+		t.Errorf("ResolveDigest() = %s, %t, want %s", got, ok, want)
+	}
+}
+
+func TestWriteWithoutCacheTarget(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	server := artifacttest.NewServer()
+	img := randomImage(t)
+	for i := 0; i < 2; i++ {
+		transfer := NewTransferer(context.Background(), WithClient(server.Client(t)), WithDefaultCache(""))
+		if err := transfer.Write(name.MustParseReference(testImageName), img); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+
+	if got := server.Calls(artifacttest.MethodCheckImage); got != 2 {
+		t.Errorf("Write() without cache target called CheckImage %d times, want 2", got)
+	}
+}
+
+func TestWriteRetries(t *testing.T) {
+	server := artifacttest.NewServer()
+	server.FailNext(artifacttest.MethodCheckImage, "", 1, status.Error(codes.Internal, "internal"))
+	transfer := NewTransferer(context.Background(), WithClient(server.Client(t)))
+
+	if err := transfer.Write(name.MustParseReference(testImageName), randomImage(t)); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	if got := server.Calls(artifacttest.MethodCheckImage); got != 2 {
+		t.Errorf("Write() called CheckImage %d times, want 2", got)
+	}
+}
+
+func TestWriteDowngradesUploadMode(t *testing.T) {
+	server := artifacttest.NewServer()
+	server.FailNext(artifacttest.MethodUploadContent, "", 1, status.Error(codes.Internal, "stream terminated by RST_STREAM with error code: PROTOCOL_ERROR"))
+	output := new(bytes.Buffer)
+	transfer := NewTransferer(context.Background(),
+		WithClient(server.Client(t)), WithMaxRetries(0), WithOutput(output))
+
+	if err := transfer.Write(name.MustParseReference(testImageName), randomImage(t)); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	if !strings.Contains(output.String(), "switching to sequential") {
+		t.Errorf("Write() did not report switching upload mode, output:\n%s", output)
+	}
+	if got := server.Calls(artifacttest.MethodWriteContent); got == 0 {
+		t.Errorf("Write() did not fall back to sequential writes")
+	}
+}
+
+func TestWriteFailOver(t *testing.T) {
+	server := artifacttest.NewServer()
+	server.FailNext(artifacttest.MethodUploadContent, "", 1, status.Error(codes.PermissionDenied, "denied"))
+	failOver := &recordingTransferer{}
+	transfer := NewTransferer(context.Background(),
+		WithClient(server.Client(t)), WithMaxRetries(0), WithFailOver(failOver))
+
+	if err := transfer.Write(name.MustParseReference(testImageName), randomImage(t)); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	if len(failOver.written) != 1 || failOver.written[0] != testImageName {
+		t.Errorf("Write() wrote %v to fail over, want [%s]", failOver.written, testImageName)
+	}
+}
+
+func TestWriteWithoutFailOver(t *testing.T) {
+	server := artifacttest.NewServer()
+	server.FailNext(artifacttest.MethodCheckImage, "", 1, status.Error(codes.PermissionDenied, "denied"))
+	transfer := NewTransferer(context.Background(), WithClient(server.Client(t)), WithMaxRetries(0))
+
+	err := transfer.Write(name.MustParseReference(testImageName), randomImage(t))
+	if err == nil || !strings.Contains(err.Error(), "image write failed") {
+		t.Errorf("Write() returned error %v, want image write failure", err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	server := artifacttest.NewServer()
+	transfer := NewTransferer(context.Background(), WithClient(server.Client(t)))
+
+	if _, err := transfer.Read(name.MustParseReference(testImageName)); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Read() returned error %v, want %v", err, ErrUnsupported)
+	}
+}
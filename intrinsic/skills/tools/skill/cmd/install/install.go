@@ -8,14 +8,13 @@ import (
 	"log"
 
 	lrogrpcpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
-	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc/status"
 	"intrinsic/assets/bundleio"
 	"intrinsic/assets/clientutils"
 	"intrinsic/assets/cmdutils"
 	"intrinsic/assets/idutils"
 	"intrinsic/assets/imagetransfer"
+	"intrinsic/assets/lro"
 	iagrpcpb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
 	iapb "intrinsic/assets/proto/installed_assets_go_grpc_proto"
 	"intrinsic/skills/tools/resource/cmd/bundleimages"
@@ -48,11 +47,19 @@ $ inctl skill install abc/skill.bundle.tar --solution=my-solution
 			if err != nil {
 				return err
 			}
+			platform, err := flags.GetFlagPlatform()
+			if err != nil {
+				return err
+			}
 
 			timeout, timeoutStr, err := flags.GetFlagSideloadStartTimeout()
 			if err != nil {
 				return err
 			}
+			operationTimeout, err := flags.GetFlagOperationTimeout()
+			if err != nil {
+				return err
+			}
 
 			ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, flags)
 			if err != nil {
@@ -73,6 +80,8 @@ $ inctl skill install abc/skill.bundle.tar --solution=my-solution
 				opts := []directupload.Option{
 					directupload.WithDiscovery(directupload.NewFromConnection(conn)),
 					directupload.WithOutput(command.OutOrStdout()),
+					directupload.WithDefaultJournal(),
+					directupload.WithDefaultCache(clientutils.UploadTarget(ctx, address)),
 				}
 				if registry != "" {
 					// User set external registry, so we can use it as failover.
@@ -85,7 +94,7 @@ $ inctl skill install abc/skill.bundle.tar --solution=my-solution
 				transfer = directupload.NewTransferer(ctx, opts...)
 			}
 			manifest, err := bundleio.ProcessSkill(target, bundleio.ProcessSkillOpts{
-				ImageProcessor: bundleimages.CreateImageProcessor(flags.CreateRegistryOptsWithTransferer(ctx, transfer, registry)),
+				ImageProcessor: bundleimages.CreateImageProcessorForPlatform(flags.CreateRegistryOptsWithTransferer(ctx, transfer, registry), platform),
 			})
 			if err != nil {
 				return fmt.Errorf("could not read bundle file %q: %v", target, err)
@@ -115,16 +124,8 @@ $ inctl skill install abc/skill.bundle.tar --solution=my-solution
 
 			log.Printf("Awaiting completion of the installation")
 			lroClient := lrogrpcpb.NewOperationsClient(conn)
-			for !op.GetDone() {
-				op, err = lroClient.WaitOperation(ctx, &lropb.WaitOperationRequest{
-					Name: op.GetName(),
-				})
-				if err != nil {
-					return fmt.Errorf("unable to check status of installation: %v", err)
-				}
-			}
-
-			if err := status.ErrorProto(op.GetError()); err != nil {
+			op, err = lro.Wait(ctx, lroClient, op, lro.WithTimeout(operationTimeout), lro.WithProgress(log.Writer()))
+			if err != nil {
 				return fmt.Errorf("installation failed: %w", err)
 			}
 
@@ -157,10 +158,12 @@ $ inctl skill install abc/skill.bundle.tar --solution=my-solution
 	flags.SetCommand(cmd)
 	flags.AddFlagsAddressClusterSolution()
 	flags.AddFlagPolicy("skill")
+	flags.AddFlagPlatform("skill")
 	flags.AddFlagsProjectOrg()
 	flags.AddFlagRegistry()
 	flags.AddFlagsRegistryAuthUserPassword()
 	flags.AddFlagSideloadStartTimeout("skill")
+	flags.AddFlagOperationTimeout("installation")
 	flags.AddFlagSkipDirectUpload("skill")
 
 	return cmd
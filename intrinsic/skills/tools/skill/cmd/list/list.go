@@ -95,7 +95,7 @@ $ inctl skill list --project my-project --cluster my-cluster
 		}
 		defer conn.Close()
 
-		prtr, err := printer.NewPrinter(root.FlagOutput)
+		prtr, err := printer.NewPrinter(root.FlagOutput, printer.WithSortBy(root.FlagSortBy))
 		if err != nil {
 			return err
 		}
@@ -6,10 +6,12 @@ package listutil
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
 	spb "intrinsic/skills/proto/skills_go_proto"
+	"intrinsic/tools/inctl/util/printer"
 )
 
 // SkillDescription has custom proto->json conversion to handle fields like the update timestamp.
@@ -50,6 +52,28 @@ func (sd SkillDescriptions) MarshalJSON() ([]byte, error) {
 	}{Skills: sd.Skills})
 }
 
+// Table converts a SkillDescriptions to a table sorted by id version.
+func (sd SkillDescriptions) Table() *printer.Table {
+	skills := slices.Clone(sd.Skills)
+	slices.SortFunc(skills, func(a, b SkillDescription) int {
+		return strings.Compare(a.IDVersion, b.IDVersion)
+	})
+
+	t := &printer.Table{
+		Columns: []printer.Column{
+			{Name: "ID Version"},
+			{Name: "Name"},
+			{Name: "Package Name", Hidden: true},
+			{Name: "ID", Hidden: true},
+			{Name: "Description", Hidden: true},
+		},
+	}
+	for _, skill := range skills {
+		t.Rows = append(t.Rows, []string{skill.IDVersion, skill.Name, skill.PackageName, skill.ID, skill.Description})
+	}
+	return t
+}
+
 // String converts a SkillDescription to a string
 func (sd SkillDescriptions) String() string {
 	lines := []string{}
@@ -0,0 +1,489 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package pythonserializer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file contains a parser for the subset of Python emitted by PythonSerializer. It produces a
+// small AST which is then interpreted into a behavior tree proto, see pythonreader.go.
+
+type pyTokenKind int
+
+const (
+	pyTokenEOF pyTokenKind = iota
+	pyTokenNewline
+	pyTokenIdent
+	pyTokenNumber
+	pyTokenString
+	pyTokenBytes
+	pyTokenPunct
+)
+
+type pyToken struct {
+	kind pyTokenKind
+	text string
+	line int
+}
+
+func (t pyToken) String() string {
+	switch t.kind {
+	case pyTokenEOF:
+		return "end of input"
+	case pyTokenNewline:
+		return "end of line"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// pyExpr is one of *pyName, *pyCall, *pyList, *pyDict, *pyString, *pyBytes or *pyNumber.
+type pyExpr interface{}
+
+// pyName is a possibly dotted name, e.g., "bt.Sequence".
+type pyName struct {
+	parts []string
+}
+
+func (n *pyName) String() string {
+	return strings.Join(n.parts, ".")
+}
+
+type pyKwarg struct {
+	name  string
+	value pyExpr
+}
+
+type pyCall struct {
+	fn     *pyName
+	args   []pyExpr
+	kwargs []pyKwarg
+}
+
+type pyList struct {
+	items []pyExpr
+}
+
+// pyDict is a dict display. keys and values have the same length.
+type pyDict struct {
+	keys   []pyExpr
+	values []pyExpr
+}
+
+type pyString struct {
+	value string
+}
+
+// pyBytes is a bytes literal, e.g., b"\x00".
+type pyBytes struct {
+	value []byte
+}
+
+type pyNumber struct {
+	text string
+}
+
+// pyStatement is either an assignment (target is set) or an expression statement.
+type pyStatement struct {
+	target *pyName
+	value  pyExpr
+	line   int
+}
+
+func tokenizePython(code string) ([]pyToken, error) {
+	var tokens []pyToken
+	line := 1
+	depth := 0
+	runes := []rune(code)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			if depth == 0 && len(tokens) > 0 && tokens[len(tokens)-1].kind != pyTokenNewline {
+				tokens = append(tokens, pyToken{kind: pyTokenNewline, line: line})
+			}
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '"' || (r == 'b' && i+1 < len(runes) && runes[i+1] == '"'):
+			kind := pyTokenString
+			if r == 'b' {
+				kind = pyTokenBytes
+				i++
+			}
+			start := i
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+				if i < len(runes) && runes[i] == '\n' {
+					return nil, fmt.Errorf("line %d: unterminated string literal", line)
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated string literal", line)
+			}
+			i++
+			tokens = append(tokens, pyToken{kind: kind, text: string(runes[start:i]), line: line})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, pyToken{kind: pyTokenIdent, text: string(runes[start:i]), line: line})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) {
+				c := runes[i]
+				if unicode.IsDigit(c) || unicode.IsLetter(c) || c == '.' || c == '_' {
+					i++
+				} else if (c == '+' || c == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E') {
+					i++
+				} else {
+					break
+				}
+			}
+			tokens = append(tokens, pyToken{kind: pyTokenNumber, text: string(runes[start:i]), line: line})
+		case strings.ContainsRune("()[]{},:=.-", r):
+			switch r {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				depth--
+			}
+			tokens = append(tokens, pyToken{kind: pyTokenPunct, text: string(r), line: line})
+			i++
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, r)
+		}
+	}
+	tokens = append(tokens, pyToken{kind: pyTokenEOF, line: line})
+	return tokens, nil
+}
+
+// unquotePython interprets a double-quoted Python string or bytes literal (without the "b" prefix
+// of bytes literals). Unlike strconv.Unquote it follows Python's escape rules: \x and octal escapes
+// denote code points in strings and bytes in bytes literals, and unknown escapes are kept verbatim.
+func unquotePython(text string, isBytes bool) (string, error) {
+	if len(text) < 2 || text[0] != '"' || text[len(text)-1] != '"' {
+		return "", fmt.Errorf("missing quotes")
+	}
+	body := text[1 : len(text)-1]
+	var b strings.Builder
+	for i := 0; i < len(body); {
+		c := body[i]
+		if c != '\\' {
+			if isBytes && c >= utf8.RuneSelf {
+				return "", fmt.Errorf("bytes can only contain ASCII literal characters")
+			}
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		if i+1 >= len(body) {
+			return "", fmt.Errorf("trailing backslash")
+		}
+		e := body[i+1]
+		i += 2
+		switch e {
+		case '\\', '\'', '"':
+			b.WriteByte(e)
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := i - 1
+			for end < len(body) && end < i+2 && body[end] >= '0' && body[end] <= '7' {
+				end++
+			}
+			v, _ := strconv.ParseUint(body[i-1:end], 8, 32)
+			if isBytes && v > 0xff {
+				return "", fmt.Errorf("octal escape \\%s out of range", body[i-1:end])
+			}
+			writeCodePoint(&b, rune(v), isBytes)
+			i = end
+		case 'x', 'u', 'U':
+			n := 2
+			switch e {
+			case 'u':
+				n = 4
+			case 'U':
+				n = 8
+			}
+			if isBytes && e != 'x' {
+				// Bytes literals do not support unicode escapes, Python keeps them verbatim.
+				b.WriteByte('\\')
+				b.WriteByte(e)
+				continue
+			}
+			if i+n > len(body) {
+				return "", fmt.Errorf("truncated \\%c escape", e)
+			}
+			v, err := strconv.ParseUint(body[i:i+n], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid \\%c escape %q", e, body[i:i+n])
+			}
+			if !isBytes && (v > unicode.MaxRune || (v >= 0xd800 && v < 0xe000)) {
+				return "", fmt.Errorf("escape \\%c%s is not a valid code point", e, body[i:i+n])
+			}
+			writeCodePoint(&b, rune(v), isBytes)
+			i += n
+		case 'N':
+			if isBytes {
+				b.WriteString(`\N`)
+				continue
+			}
+			return "", fmt.Errorf("named unicode escapes are not supported")
+		default:
+			// Python keeps unknown escape sequences verbatim.
+			b.WriteByte('\\')
+			b.WriteByte(e)
+		}
+	}
+	return b.String(), nil
+}
+
+func writeCodePoint(b *strings.Builder, r rune, isBytes bool) {
+	if isBytes {
+		b.WriteByte(byte(r))
+	} else {
+		b.WriteRune(r)
+	}
+}
+
+type pyParser struct {
+	tokens []pyToken
+	pos    int
+}
+
+func (p *pyParser) peek() pyToken {
+	return p.tokens[p.pos]
+}
+
+func (p *pyParser) peekAt(offset int) pyToken {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *pyParser) next() pyToken {
+	t := p.tokens[p.pos]
+	if t.kind != pyTokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *pyParser) isPunct(text string) bool {
+	t := p.peek()
+	return t.kind == pyTokenPunct && t.text == text
+}
+
+func (p *pyParser) expectPunct(text string) error {
+	t := p.next()
+	if t.kind != pyTokenPunct || t.text != text {
+		return fmt.Errorf("line %d: expected %q, got %v", t.line, text, t)
+	}
+	return nil
+}
+
+// parsePython parses the given code into a list of statements.
+func parsePython(code string) ([]*pyStatement, error) {
+	tokens, err := tokenizePython(code)
+	if err != nil {
+		return nil, err
+	}
+	p := &pyParser{tokens: tokens}
+
+	var statements []*pyStatement
+	for p.peek().kind != pyTokenEOF {
+		if p.peek().kind == pyTokenNewline {
+			p.next()
+			continue
+		}
+		s, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, s)
+	}
+	return statements, nil
+}
+
+func (p *pyParser) parseStatement() (*pyStatement, error) {
+	line := p.peek().line
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	s := &pyStatement{value: expr, line: line}
+	if p.isPunct("=") {
+		p.next()
+		target, ok := expr.(*pyName)
+		if !ok {
+			return nil, fmt.Errorf("line %d: can only assign to names", line)
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		s.target = target
+		s.value = value
+	}
+	if t := p.next(); t.kind != pyTokenNewline && t.kind != pyTokenEOF {
+		return nil, fmt.Errorf("line %d: expected end of statement, got %v", t.line, t)
+	}
+	return s, nil
+}
+
+func (p *pyParser) parseExpr() (pyExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case pyTokenString:
+		value, err := unquotePython(t.text, false)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid string literal %s: %v", t.line, t.text, err)
+		}
+		return &pyString{value: value}, nil
+	case pyTokenBytes:
+		value, err := unquotePython(t.text, true)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid bytes literal b%s: %v", t.line, t.text, err)
+		}
+		return &pyBytes{value: []byte(value)}, nil
+	case pyTokenNumber:
+		return &pyNumber{text: t.text}, nil
+	case pyTokenPunct:
+		switch t.text {
+		case "-":
+			n := p.next()
+			if n.kind != pyTokenNumber {
+				return nil, fmt.Errorf("line %d: expected number after '-', got %v", n.line, n)
+			}
+			return &pyNumber{text: "-" + n.text}, nil
+		case "[":
+			items, err := p.parseList()
+			if err != nil {
+				return nil, err
+			}
+			return &pyList{items: items}, nil
+		case "{":
+			return p.parseDict()
+		}
+	case pyTokenIdent:
+		name := &pyName{parts: []string{t.text}}
+		for p.isPunct(".") {
+			p.next()
+			part := p.next()
+			if part.kind != pyTokenIdent {
+				return nil, fmt.Errorf("line %d: expected identifier after '.', got %v", part.line, part)
+			}
+			name.parts = append(name.parts, part.text)
+		}
+		if !p.isPunct("(") {
+			return name, nil
+		}
+		p.next()
+		return p.parseCallArgs(name)
+	}
+	return nil, fmt.Errorf("line %d: unexpected %v", t.line, t)
+}
+
+func (p *pyParser) parseList() ([]pyExpr, error) {
+	var items []pyExpr
+	for !p.isPunct("]") {
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectPunct("]"); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (p *pyParser) parseDict() (*pyDict, error) {
+	d := &pyDict{}
+	for !p.isPunct("}") {
+		key, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		d.keys = append(d.keys, key)
+		d.values = append(d.values, value)
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectPunct("}"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (p *pyParser) parseCallArgs(fn *pyName) (*pyCall, error) {
+	call := &pyCall{fn: fn}
+	for !p.isPunct(")") {
+		if p.peek().kind == pyTokenIdent && p.peekAt(1).kind == pyTokenPunct && p.peekAt(1).text == "=" {
+			name := p.next().text
+			p.next()
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.kwargs = append(call.kwargs, pyKwarg{name: name, value: value})
+		} else {
+			if len(call.kwargs) > 0 {
+				return nil, fmt.Errorf("line %d: positional argument follows keyword argument", p.peek().line)
+			}
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, value)
+		}
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
@@ -0,0 +1,1248 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package pythonserializer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	"intrinsic/executive/go/behaviortree"
+	anylistpb "intrinsic/executive/proto/any_list_go_proto"
+	awapb "intrinsic/executive/proto/any_with_assignments_go_proto"
+	bcpb "intrinsic/executive/proto/behavior_call_go_proto"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	wqpb "intrinsic/executive/proto/world_query_go_proto"
+	skillspb "intrinsic/skills/proto/skills_go_proto"
+	espb "intrinsic/util/status/extended_status_go_proto"
+)
+
+const (
+	anyFullName      = protoreflect.FullName("google.protobuf.Any")
+	durationFullName = protoreflect.FullName("google.protobuf.Duration")
+	// rootTreeIdentifier is the identifier the serializer assigns the top-level tree to.
+	rootTreeIdentifier = "tree"
+)
+
+// pythonReader interprets Python code as emitted by PythonSerializer and rebuilds the behavior
+// tree it declares. It only understands the statements and constructors the serializer emits.
+type pythonReader struct {
+	skills        map[string]*skillspb.Skill
+	pt            *protoregistry.Types
+	messageSkills map[protoreflect.FullName]string
+	skillPrefix   string
+	trees         map[string]*btpb.BehaviorTree
+	nodes         map[string]*btpb.BehaviorTree_Node
+}
+
+// ParseBehaviorTree parses Python code generated by PythonSerializer back into a behavior tree
+// proto. The given skills must be the ones the code was generated with.
+func ParseBehaviorTree(code []byte, sk []*skillspb.Skill) (*btpb.BehaviorTree, error) {
+	pt, messageSkills, err := newSkillTypes(sk)
+	if err != nil {
+		return nil, err
+	}
+	r := &pythonReader{
+		skills:        make(map[string]*skillspb.Skill),
+		pt:            pt,
+		messageSkills: messageSkills,
+		skillPrefix:   "skills",
+		trees:         make(map[string]*btpb.BehaviorTree),
+		nodes:         make(map[string]*btpb.BehaviorTree_Node),
+	}
+	for _, skill := range sk {
+		r.skills[skill.GetSkillName()] = skill
+	}
+
+	statements, err := parsePython(string(code))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse Python code")
+	}
+	for _, s := range statements {
+		if err := r.evalStatement(s); err != nil {
+			return nil, errors.Wrapf(err, "line %d", s.line)
+		}
+	}
+
+	bt, ok := r.trees[rootTreeIdentifier]
+	if !ok {
+		return nil, fmt.Errorf("no behavior tree assigned to %q", rootTreeIdentifier)
+	}
+	return bt, nil
+}
+
+// VerifyRoundTrip serializes the given behavior tree to Python, parses the generated code back into
+// a behavior tree and compares it to the original. Output-only fields and tree IDs are ignored, the
+// same way "inctl process get" clears them. On mismatch, the returned error contains a structural
+// diff.
+func VerifyRoundTrip(bt *btpb.BehaviorTree, sk []*skillspb.Skill) error {
+	serializer, err := NewPythonSerializer(sk)
+	if err != nil {
+		return errors.Wrap(err, "could not create python serializer")
+	}
+	code, err := serializer.Serialize(bt)
+	if err != nil {
+		return err
+	}
+	got, err := ParseBehaviorTree(code, sk)
+	if err != nil {
+		return errors.Wrapf(err, "could not read generated code:\n%s", code)
+	}
+
+	want := proto.Clone(bt).(*btpb.BehaviorTree)
+	if err := behaviortree.ClearOutputOnlyFields(want, true, false); err != nil {
+		return errors.Wrap(err, "could not clear output-only fields")
+	}
+	for _, m := range []proto.Message{want, got} {
+		if err := canonicalizeAnys(m.ProtoReflect(), serializer.pt); err != nil {
+			return err
+		}
+	}
+
+	if diff := cmp.Diff(want, got, protocmp.Transform(), cmpopts.EquateNaNs()); diff != "" {
+		return fmt.Errorf("behavior tree changed in round trip through Python (-want +got):\n%s\n\ngenerated code:\n%s", diff, code)
+	}
+	return nil
+}
+
+// canonicalizeAnys re-encodes all Any payloads in the given message deterministically, so that
+// Any protos carrying equal messages compare equal byte-wise.
+func canonicalizeAnys(m protoreflect.Message, resolver *protoregistry.Types) error {
+	if m.Descriptor().FullName() == anyFullName {
+		fields := m.Descriptor().Fields()
+		typeURL := m.Get(fields.ByName("type_url")).String()
+		mt, err := resolver.FindMessageByURL(typeURL)
+		if err != nil {
+			// Leave Any protos of unknown types as they are.
+			return nil
+		}
+		msg := mt.New()
+		if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(m.Get(fields.ByName("value")).Bytes(), msg.Interface()); err != nil {
+			return errors.Wrapf(err, "could not unmarshal %s", typeURL)
+		}
+		if err := canonicalizeAnys(msg, resolver); err != nil {
+			return err
+		}
+		value, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg.Interface())
+		if err != nil {
+			return errors.Wrapf(err, "could not marshal %s", typeURL)
+		}
+		m.Set(fields.ByName("value"), protoreflect.ValueOfBytes(value))
+		return nil
+	}
+
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+			return true
+		}
+		switch {
+		case fd.IsList():
+			for i := 0; i < v.List().Len() && err == nil; i++ {
+				err = canonicalizeAnys(v.List().Get(i).Message(), resolver)
+			}
+		case fd.IsMap():
+			if fd.MapValue().Kind() == protoreflect.MessageKind {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					err = canonicalizeAnys(mv.Message(), resolver)
+					return err == nil
+				})
+			}
+		default:
+			err = canonicalizeAnys(v.Message(), resolver)
+		}
+		return err == nil
+	})
+	return err
+}
+
+func (r *pythonReader) evalStatement(s *pyStatement) error {
+	if s.target != nil {
+		switch {
+		case len(s.target.parts) == 1:
+			call, ok := s.value.(*pyCall)
+			if !ok {
+				return fmt.Errorf("expected constructor call assigned to %s", s.target)
+			}
+			name := s.target.parts[0]
+			if call.fn.String() == "bt.BehaviorTree" {
+				bt, err := r.evalTree(call)
+				if err != nil {
+					return err
+				}
+				r.trees[name] = bt
+				return nil
+			}
+			node, err := r.evalNodeConstructor(call)
+			if err != nil {
+				return err
+			}
+			r.nodes[name] = node
+			return nil
+		case len(s.target.parts) == 2 && s.target.parts[1] == "node_id":
+			node, err := r.evalNode(&pyName{parts: s.target.parts[:1]})
+			if err != nil {
+				return err
+			}
+			id, err := evalUint(s.value, 32)
+			if err != nil {
+				return err
+			}
+			node.Id = proto.Uint32(uint32(id))
+			return nil
+		}
+		return fmt.Errorf("unsupported assignment to %s", s.target)
+	}
+
+	call, ok := s.value.(*pyCall)
+	if !ok || len(call.fn.parts) < 2 {
+		return fmt.Errorf("unsupported expression statement")
+	}
+	node, err := r.evalNode(&pyName{parts: call.fn.parts[:1]})
+	if err != nil {
+		return err
+	}
+	method := (&pyName{parts: call.fn.parts[1:]}).String()
+	switch method {
+	case "set_decorators":
+		return r.evalDecorators(node, call)
+	case "on_failure.emit_extended_status":
+		return r.evalEmitExtendedStatus(node, call)
+	case "world_query.assignments.append":
+		fromWorld := node.GetData().GetCreateOrUpdate().GetFromWorld()
+		if fromWorld == nil {
+			return fmt.Errorf("%s requires a data node with a world query", method)
+		}
+		if len(call.args) != 1 {
+			return fmt.Errorf("%s expects a single assignment", method)
+		}
+		a := &awapb.AnyWithAssignments_Assignment{}
+		if err := r.evalProtoConstructor(call.args[0], "any_with_assignments_pb2.AnyWithAssignments.Assignment", a.ProtoReflect()); err != nil {
+			return err
+		}
+		fromWorld.Assign = append(fromWorld.Assign, a)
+		return nil
+	case "on_failure.emit_extended_status_to":
+		if len(call.args) != 1 || len(call.kwargs) != 0 {
+			return fmt.Errorf("%s expects a single blackboard key", method)
+		}
+		key, err := evalString(call.args[0])
+		if err != nil {
+			return err
+		}
+		failureSettings(node).EmitExtendedStatus.ToBlackboardKey = key
+		return nil
+	}
+	return fmt.Errorf("unsupported method %s", method)
+}
+
+// kwargs returns the keyword arguments of the given call and fails on positional or unknown
+// arguments.
+func kwargs(call *pyCall, allowed ...string) (map[string]pyExpr, error) {
+	if len(call.args) > 0 {
+		return nil, fmt.Errorf("%s: unexpected positional arguments", call.fn)
+	}
+	allowedSet := make(map[string]bool)
+	for _, a := range allowed {
+		allowedSet[a] = true
+	}
+	args := make(map[string]pyExpr)
+	for _, kw := range call.kwargs {
+		if !allowedSet[kw.name] {
+			return nil, fmt.Errorf("%s: unexpected keyword argument %q", call.fn, kw.name)
+		}
+		if _, ok := args[kw.name]; ok {
+			return nil, fmt.Errorf("%s: duplicate keyword argument %q", call.fn, kw.name)
+		}
+		args[kw.name] = kw.value
+	}
+	return args, nil
+}
+
+func evalString(e pyExpr) (string, error) {
+	s, ok := e.(*pyString)
+	if !ok {
+		return "", fmt.Errorf("expected string literal, got %T", e)
+	}
+	return s.value, nil
+}
+
+func evalInt(e pyExpr, bitSize int) (int64, error) {
+	n, ok := e.(*pyNumber)
+	if !ok {
+		return 0, fmt.Errorf("expected integer literal, got %T", e)
+	}
+	return strconv.ParseInt(n.text, 10, bitSize)
+}
+
+func evalUint(e pyExpr, bitSize int) (uint64, error) {
+	n, ok := e.(*pyNumber)
+	if !ok {
+		return 0, fmt.Errorf("expected integer literal, got %T", e)
+	}
+	return strconv.ParseUint(n.text, 10, bitSize)
+}
+
+func evalFloat(e pyExpr, bitSize int) (float64, error) {
+	// Special values are written as float("nan"), float("inf") and float("-inf").
+	if call, ok := e.(*pyCall); ok && call.fn.String() == "float" && len(call.args) == 1 && len(call.kwargs) == 0 {
+		s, err := evalString(call.args[0])
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseFloat(s, bitSize)
+	}
+	n, ok := e.(*pyNumber)
+	if !ok {
+		return 0, fmt.Errorf("expected float literal, got %T", e)
+	}
+	return strconv.ParseFloat(n.text, bitSize)
+}
+
+func evalBytes(e pyExpr) ([]byte, error) {
+	b, ok := e.(*pyBytes)
+	if !ok {
+		return nil, fmt.Errorf("expected bytes literal, got %T", e)
+	}
+	return b.value, nil
+}
+
+func evalBool(e pyExpr) (bool, error) {
+	if n, ok := e.(*pyName); ok && len(n.parts) == 1 {
+		switch n.parts[0] {
+		case "True":
+			return true, nil
+		case "False":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("expected True or False, got %T", e)
+}
+
+// evalEnum returns the number of the enum value referenced as <prefix>.<VALUE_NAME>.
+func evalEnum(e pyExpr, prefix string, values map[string]int32) (int32, error) {
+	n, ok := e.(*pyName)
+	if !ok || len(n.parts) < 2 {
+		return 0, fmt.Errorf("expected %s value, got %T", prefix, e)
+	}
+	valuePrefix := &pyName{parts: n.parts[:len(n.parts)-1]}
+	value, ok := values[n.parts[len(n.parts)-1]]
+	if valuePrefix.String() != prefix || !ok {
+		return 0, fmt.Errorf("expected %s value, got %s", prefix, n)
+	}
+	return value, nil
+}
+
+func (r *pythonReader) evalNode(e pyExpr) (*btpb.BehaviorTree_Node, error) {
+	n, ok := e.(*pyName)
+	if !ok || len(n.parts) != 1 {
+		return nil, fmt.Errorf("expected node identifier, got %T", e)
+	}
+	node, ok := r.nodes[n.parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("undefined node %q", n.parts[0])
+	}
+	return node, nil
+}
+
+func (r *pythonReader) evalTreeRef(e pyExpr) (*btpb.BehaviorTree, error) {
+	n, ok := e.(*pyName)
+	if !ok || len(n.parts) != 1 {
+		return nil, fmt.Errorf("expected behavior tree identifier, got %T", e)
+	}
+	bt, ok := r.trees[n.parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("undefined behavior tree %q", n.parts[0])
+	}
+	return bt, nil
+}
+
+func (r *pythonReader) evalNodes(e pyExpr) ([]*btpb.BehaviorTree_Node, error) {
+	l, ok := e.(*pyList)
+	if !ok {
+		return nil, fmt.Errorf("expected list of nodes, got %T", e)
+	}
+	var nodes []*btpb.BehaviorTree_Node
+	for _, item := range l.items {
+		node, err := r.evalNode(item)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func (r *pythonReader) evalTree(call *pyCall) (*btpb.BehaviorTree, error) {
+	args, err := kwargs(call, "name", "root")
+	if err != nil {
+		return nil, err
+	}
+	bt := &btpb.BehaviorTree{}
+	if e, ok := args["name"]; ok {
+		if bt.Name, err = evalString(e); err != nil {
+			return nil, err
+		}
+	}
+	if e, ok := args["root"]; ok {
+		if bt.Root, err = r.evalNode(e); err != nil {
+			return nil, err
+		}
+	}
+	return bt, nil
+}
+
+func (r *pythonReader) evalNodeConstructor(call *pyCall) (*btpb.BehaviorTree_Node, error) {
+	var allowed []string
+	switch call.fn.String() {
+	case "bt.Sequence", "bt.Selector", "bt.Fallback":
+		allowed = []string{"children"}
+	case "bt.Parallel":
+		allowed = []string{"children", "failure_behavior"}
+	case "bt.Task":
+		allowed = []string{"action"}
+	case "bt.Branch":
+		allowed = []string{"if_condition", "then_child", "else_child"}
+	case "bt.Loop":
+		allowed = []string{"max_times", "do_child", "while_condition", "loop_counter_key", "for_each_value_key", "for_each_protos", "for_each_generator_cel_expression"}
+	case "bt.Retry":
+		allowed = []string{"max_tries", "child", "recovery", "retry_counter_key"}
+	case "bt.SubTree":
+		allowed = []string{"behavior_tree"}
+	case "bt.Data":
+		allowed = []string{"blackboard_key", "operation", "cel_expression", "proto", "protos", "world_query"}
+	case "bt.Fail":
+	case "bt.Debug":
+		allowed = []string{"fail_on_resume"}
+	default:
+		return nil, fmt.Errorf("unsupported constructor %s", call.fn)
+	}
+	args, err := kwargs(call, append(allowed, "name")...)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &btpb.BehaviorTree_Node{}
+	if e, ok := args["name"]; ok {
+		name, err := evalString(e)
+		if err != nil {
+			return nil, err
+		}
+		node.Name = proto.String(name)
+	}
+
+	var children []*btpb.BehaviorTree_Node
+	if e, ok := args["children"]; ok {
+		if children, err = r.evalNodes(e); err != nil {
+			return nil, err
+		}
+	}
+
+	switch call.fn.String() {
+	case "bt.Sequence":
+		node.NodeType = &btpb.BehaviorTree_Node_Sequence{Sequence: &btpb.BehaviorTree_SequenceNode{Children: children}}
+	case "bt.Selector":
+		node.NodeType = &btpb.BehaviorTree_Node_Selector{Selector: &btpb.BehaviorTree_SelectorNode{Children: children}}
+	case "bt.Fallback":
+		node.NodeType = &btpb.BehaviorTree_Node_Fallback{Fallback: &btpb.BehaviorTree_FallbackNode{Children: children}}
+	case "bt.Parallel":
+		parallel := &btpb.BehaviorTree_ParallelNode{Children: children}
+		if e, ok := args["failure_behavior"]; ok {
+			v, err := evalEnum(e, "bt.Parallel.FailureBehavior", btpb.BehaviorTree_ParallelNode_FailureBehavior_value)
+			if err != nil {
+				return nil, err
+			}
+			parallel.FailureBehavior = btpb.BehaviorTree_ParallelNode_FailureBehavior(v)
+		}
+		node.NodeType = &btpb.BehaviorTree_Node_Parallel{Parallel: parallel}
+	case "bt.Task":
+		action, ok := args["action"]
+		if !ok {
+			return nil, fmt.Errorf("bt.Task requires an action")
+		}
+		bc, err := r.evalAction(action)
+		if err != nil {
+			return nil, err
+		}
+		node.NodeType = &btpb.BehaviorTree_Node_Task{Task: &btpb.BehaviorTree_TaskNode{
+			TaskType: &btpb.BehaviorTree_TaskNode_CallBehavior{CallBehavior: bc},
+		}}
+	case "bt.Branch":
+		branch := &btpb.BehaviorTree_BranchNode{}
+		if e, ok := args["if_condition"]; ok {
+			if branch.If, err = r.evalCondition(e); err != nil {
+				return nil, err
+			}
+		}
+		if e, ok := args["then_child"]; ok {
+			if branch.Then, err = r.evalNode(e); err != nil {
+				return nil, err
+			}
+		}
+		if e, ok := args["else_child"]; ok {
+			if branch.Else, err = r.evalNode(e); err != nil {
+				return nil, err
+			}
+		}
+		node.NodeType = &btpb.BehaviorTree_Node_Branch{Branch: branch}
+	case "bt.Loop":
+		loop, err := r.evalLoop(args)
+		if err != nil {
+			return nil, err
+		}
+		node.NodeType = &btpb.BehaviorTree_Node_Loop{Loop: loop}
+	case "bt.Retry":
+		retry := &btpb.BehaviorTree_RetryNode{}
+		if e, ok := args["max_tries"]; ok {
+			v, err := evalUint(e, 32)
+			if err != nil {
+				return nil, err
+			}
+			retry.MaxTries = uint32(v)
+		}
+		if e, ok := args["child"]; ok {
+			if retry.Child, err = r.evalNode(e); err != nil {
+				return nil, err
+			}
+		}
+		if e, ok := args["recovery"]; ok {
+			if retry.Recovery, err = r.evalNode(e); err != nil {
+				return nil, err
+			}
+		}
+		if e, ok := args["retry_counter_key"]; ok {
+			if retry.RetryCounterBlackboardKey, err = evalString(e); err != nil {
+				return nil, err
+			}
+		}
+		node.NodeType = &btpb.BehaviorTree_Node_Retry{Retry: retry}
+	case "bt.SubTree":
+		subtree := &btpb.BehaviorTree_SubtreeNode{}
+		if e, ok := args["behavior_tree"]; ok {
+			if subtree.Tree, err = r.evalTreeRef(e); err != nil {
+				return nil, err
+			}
+		}
+		node.NodeType = &btpb.BehaviorTree_Node_SubTree{SubTree: subtree}
+	case "bt.Data":
+		data, err := r.evalData(args)
+		if err != nil {
+			return nil, err
+		}
+		node.NodeType = &btpb.BehaviorTree_Node_Data{Data: data}
+	case "bt.Fail":
+		node.NodeType = &btpb.BehaviorTree_Node_Fail{Fail: &btpb.BehaviorTree_FailNode{}}
+	case "bt.Debug":
+		debug := &btpb.BehaviorTree_DebugNode{}
+		if e, ok := args["fail_on_resume"]; ok {
+			failOnResume, err := evalBool(e)
+			if err != nil {
+				return nil, err
+			}
+			debug.Suspend = &btpb.BehaviorTree_DebugNode_Suspend{FailOnResume: failOnResume}
+		}
+		node.NodeType = &btpb.BehaviorTree_Node_Debug{Debug: debug}
+	}
+	return node, nil
+}
+
+func (r *pythonReader) evalLoop(args map[string]pyExpr) (*btpb.BehaviorTree_LoopNode, error) {
+	var err error
+	loop := &btpb.BehaviorTree_LoopNode{}
+	if e, ok := args["max_times"]; ok {
+		v, err := evalUint(e, 32)
+		if err != nil {
+			return nil, err
+		}
+		loop.MaxTimes = proto.Uint32(uint32(v))
+	}
+	if e, ok := args["do_child"]; ok {
+		if loop.Do, err = r.evalNode(e); err != nil {
+			return nil, err
+		}
+	}
+	if e, ok := args["while_condition"]; ok {
+		condition, err := r.evalCondition(e)
+		if err != nil {
+			return nil, err
+		}
+		loop.LoopType = &btpb.BehaviorTree_LoopNode_While{While: condition}
+	}
+	if e, ok := args["loop_counter_key"]; ok {
+		if loop.LoopCounterBlackboardKey, err = evalString(e); err != nil {
+			return nil, err
+		}
+	}
+
+	forEach := &btpb.BehaviorTree_LoopNode_ForEach{}
+	isForEach := false
+	if e, ok := args["for_each_value_key"]; ok {
+		if forEach.ValueBlackboardKey, err = evalString(e); err != nil {
+			return nil, err
+		}
+		isForEach = true
+	}
+	if e, ok := args["for_each_protos"]; ok {
+		items, err := r.evalAnyList(e)
+		if err != nil {
+			return nil, err
+		}
+		forEach.ForEachGeneratorType = &btpb.BehaviorTree_LoopNode_ForEach_Protos{Protos: &anylistpb.AnyList{Items: items}}
+		isForEach = true
+	}
+	if e, ok := args["for_each_generator_cel_expression"]; ok {
+		expr, err := evalString(e)
+		if err != nil {
+			return nil, err
+		}
+		forEach.ForEachGeneratorType = &btpb.BehaviorTree_LoopNode_ForEach_GeneratorCelExpression{GeneratorCelExpression: expr}
+		isForEach = true
+	}
+	if isForEach {
+		if loop.GetWhile() != nil {
+			return nil, fmt.Errorf("bt.Loop cannot have both a while condition and a for each generator")
+		}
+		loop.LoopType = &btpb.BehaviorTree_LoopNode_ForEach_{ForEach: forEach}
+	}
+	return loop, nil
+}
+
+func (r *pythonReader) evalData(args map[string]pyExpr) (*btpb.BehaviorTree_DataNode, error) {
+	key := ""
+	if e, ok := args["blackboard_key"]; ok {
+		var err error
+		if key, err = evalString(e); err != nil {
+			return nil, err
+		}
+	}
+
+	if e, ok := args["operation"]; ok {
+		if _, err := evalEnum(e, "bt.Data.OperationType", map[string]int32{"REMOVE": 0}); err != nil {
+			return nil, err
+		}
+		return &btpb.BehaviorTree_DataNode{
+			OperationType: &btpb.BehaviorTree_DataNode_Remove_{Remove: &btpb.BehaviorTree_DataNode_Remove{BlackboardKey: key}},
+		}, nil
+	}
+
+	createOrUpdate := &btpb.BehaviorTree_DataNode_CreateOrUpdate{BlackboardKey: key}
+	if e, ok := args["cel_expression"]; ok {
+		expr, err := evalString(e)
+		if err != nil {
+			return nil, err
+		}
+		createOrUpdate.InputType = &btpb.BehaviorTree_DataNode_CreateOrUpdate_CelExpression{CelExpression: expr}
+	}
+	if e, ok := args["proto"]; ok {
+		a, err := r.evalAny(e)
+		if err != nil {
+			return nil, err
+		}
+		createOrUpdate.InputType = &btpb.BehaviorTree_DataNode_CreateOrUpdate_Proto{Proto: a}
+	}
+	if e, ok := args["protos"]; ok {
+		items, err := r.evalAnyList(e)
+		if err != nil {
+			return nil, err
+		}
+		createOrUpdate.InputType = &btpb.BehaviorTree_DataNode_CreateOrUpdate_Protos{Protos: &anylistpb.AnyList{Items: items}}
+	}
+	if e, ok := args["world_query"]; ok {
+		a, err := r.evalWorldQuery(e)
+		if err != nil {
+			return nil, err
+		}
+		createOrUpdate.InputType = &btpb.BehaviorTree_DataNode_CreateOrUpdate_FromWorld{FromWorld: &awapb.AnyWithAssignments{Proto: a}}
+	}
+	return &btpb.BehaviorTree_DataNode{
+		OperationType: &btpb.BehaviorTree_DataNode_CreateOrUpdate_{CreateOrUpdate: createOrUpdate},
+	}, nil
+}
+
+func (r *pythonReader) evalCondition(e pyExpr) (*btpb.BehaviorTree_Condition, error) {
+	call, ok := e.(*pyCall)
+	if !ok {
+		return nil, fmt.Errorf("expected condition constructor, got %T", e)
+	}
+	if len(call.kwargs) > 0 {
+		return nil, fmt.Errorf("%s: unexpected keyword arguments", call.fn)
+	}
+	fn := call.fn.String()
+	wantArgs := 1
+	if fn == "bt.ExtendedStatusMatch" {
+		wantArgs = 2
+	}
+	if len(call.args) != wantArgs {
+		return nil, fmt.Errorf("%s: expected %d arguments, got %d", fn, wantArgs, len(call.args))
+	}
+
+	switch fn {
+	case "bt.Blackboard":
+		expr, err := evalString(call.args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &btpb.BehaviorTree_Condition{ConditionType: &btpb.BehaviorTree_Condition_Blackboard{
+			Blackboard: &btpb.BehaviorTree_Condition_BlackboardExpression{
+				ExpressionType: &btpb.BehaviorTree_Condition_BlackboardExpression_CelExpression{CelExpression: expr},
+			},
+		}}, nil
+	case "bt.AllOf", "bt.AnyOf":
+		l, ok := call.args[0].(*pyList)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected list of conditions", fn)
+		}
+		compound := &btpb.BehaviorTree_Condition_LogicalCompound{}
+		for _, item := range l.items {
+			c, err := r.evalCondition(item)
+			if err != nil {
+				return nil, err
+			}
+			compound.Conditions = append(compound.Conditions, c)
+		}
+		if fn == "bt.AllOf" {
+			return &btpb.BehaviorTree_Condition{ConditionType: &btpb.BehaviorTree_Condition_AllOf{AllOf: compound}}, nil
+		}
+		return &btpb.BehaviorTree_Condition{ConditionType: &btpb.BehaviorTree_Condition_AnyOf{AnyOf: compound}}, nil
+	case "bt.Not":
+		c, err := r.evalCondition(call.args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &btpb.BehaviorTree_Condition{ConditionType: &btpb.BehaviorTree_Condition_Not{Not: c}}, nil
+	case "bt.SubTreeCondition":
+		bt, err := r.evalTreeRef(call.args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &btpb.BehaviorTree_Condition{ConditionType: &btpb.BehaviorTree_Condition_BehaviorTree{BehaviorTree: bt}}, nil
+	case "bt.ExtendedStatusMatch":
+		key, err := evalString(call.args[0])
+		if err != nil {
+			return nil, err
+		}
+		matcher, ok := call.args[1].(*pyCall)
+		if !ok || matcher.fn.String() != "bt.ExtendedStatusMatch.MatchStatusCode" || len(matcher.args) != 2 {
+			return nil, fmt.Errorf("%s: expected bt.ExtendedStatusMatch.MatchStatusCode(component, code)", fn)
+		}
+		statusCode, err := evalStatusCode(matcher.args[0], matcher.args[1])
+		if err != nil {
+			return nil, err
+		}
+		return &btpb.BehaviorTree_Condition{ConditionType: &btpb.BehaviorTree_Condition_StatusMatch{
+			StatusMatch: &btpb.BehaviorTree_Condition_ExtendedStatusMatch{
+				BlackboardKey: key,
+				MatchType:     &btpb.BehaviorTree_Condition_ExtendedStatusMatch_StatusCode{StatusCode: statusCode},
+			},
+		}}, nil
+	}
+	return nil, fmt.Errorf("unsupported condition %s", fn)
+}
+
+func evalStatusCode(component pyExpr, code pyExpr) (*espb.StatusCode, error) {
+	c, err := evalString(component)
+	if err != nil {
+		return nil, err
+	}
+	n, err := evalUint(code, 32)
+	if err != nil {
+		return nil, err
+	}
+	return &espb.StatusCode{Component: c, Code: uint32(n)}, nil
+}
+
+func decorators(node *btpb.BehaviorTree_Node) *btpb.BehaviorTree_Node_Decorators {
+	if node.Decorators == nil {
+		node.Decorators = &btpb.BehaviorTree_Node_Decorators{}
+	}
+	return node.Decorators
+}
+
+func failureSettings(node *btpb.BehaviorTree_Node) *btpb.BehaviorTree_Node_Decorators_FailureSettings {
+	d := decorators(node)
+	if d.OnFailure == nil {
+		d.OnFailure = &btpb.BehaviorTree_Node_Decorators_FailureSettings{}
+	}
+	if d.OnFailure.EmitExtendedStatus == nil {
+		d.OnFailure.EmitExtendedStatus = &btpb.BehaviorTree_Node_Decorators_FailureSettings_ExtendedStatusSettings{}
+	}
+	return d.OnFailure
+}
+
+func (r *pythonReader) evalDecorators(node *btpb.BehaviorTree_Node, call *pyCall) error {
+	if len(call.args) != 1 || len(call.kwargs) != 0 {
+		return fmt.Errorf("set_decorators expects a single bt.Decorators argument")
+	}
+	decoratorsCall, ok := call.args[0].(*pyCall)
+	if !ok || decoratorsCall.fn.String() != "bt.Decorators" {
+		return fmt.Errorf("set_decorators expects a single bt.Decorators argument")
+	}
+	args, err := kwargs(decoratorsCall, "condition", "breakpoint_type", "execution_mode", "disabled_result_state")
+	if err != nil {
+		return err
+	}
+
+	d := decorators(node)
+	if e, ok := args["condition"]; ok {
+		if d.Condition, err = r.evalCondition(e); err != nil {
+			return err
+		}
+	}
+	if e, ok := args["breakpoint_type"]; ok {
+		v, err := evalEnum(e, "bt.BreakpointType", btpb.BehaviorTree_Breakpoint_Type_value)
+		if err != nil {
+			return err
+		}
+		d.Breakpoint = btpb.BehaviorTree_Breakpoint_Type(v).Enum()
+	}
+	if e, ok := args["execution_mode"]; ok {
+		v, err := evalEnum(e, "bt.NodeExecutionMode", btpb.BehaviorTree_Node_ExecutionSettings_Mode_value)
+		if err != nil {
+			return err
+		}
+		d.ExecutionSettings = &btpb.BehaviorTree_Node_ExecutionSettings{Mode: btpb.BehaviorTree_Node_ExecutionSettings_Mode(v)}
+		if e, ok := args["disabled_result_state"]; ok {
+			v, err := evalEnum(e, "bt.DisabledResultState", btpb.BehaviorTree_Node_ExecutionSettings_DisabledResultState_value)
+			if err != nil {
+				return err
+			}
+			d.ExecutionSettings.DisabledResultState = btpb.BehaviorTree_Node_ExecutionSettings_DisabledResultState(v).Enum()
+		}
+	}
+	return nil
+}
+
+func (r *pythonReader) evalEmitExtendedStatus(node *btpb.BehaviorTree_Node, call *pyCall) error {
+	if len(call.args) != 2 {
+		return fmt.Errorf("emit_extended_status expects component and code")
+	}
+	statusCode, err := evalStatusCode(call.args[0], call.args[1])
+	if err != nil {
+		return err
+	}
+	args, err := kwargs(&pyCall{fn: call.fn, kwargs: call.kwargs}, "title", "user_message", "debug_message", "to_blackboard_key")
+	if err != nil {
+		return err
+	}
+
+	es := &espb.ExtendedStatus{StatusCode: statusCode}
+	if e, ok := args["title"]; ok {
+		if es.Title, err = evalString(e); err != nil {
+			return err
+		}
+	}
+	if e, ok := args["user_message"]; ok {
+		msg, err := evalString(e)
+		if err != nil {
+			return err
+		}
+		es.UserReport = &espb.ExtendedStatus_UserReport{Message: msg}
+	}
+	if e, ok := args["debug_message"]; ok {
+		msg, err := evalString(e)
+		if err != nil {
+			return err
+		}
+		es.DebugReport = &espb.ExtendedStatus_DebugReport{Message: msg}
+	}
+
+	settings := failureSettings(node).EmitExtendedStatus
+	settings.ExtendedStatus = es
+	if e, ok := args["to_blackboard_key"]; ok {
+		if settings.ToBlackboardKey, err = evalString(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *pythonReader) evalAction(e pyExpr) (*bcpb.BehaviorCall, error) {
+	call, ok := e.(*pyCall)
+	if !ok || len(call.fn.parts) != 2 || call.fn.parts[0] != r.skillPrefix {
+		return nil, fmt.Errorf("expected skill call, got %T", e)
+	}
+	if len(call.args) > 0 {
+		return nil, fmt.Errorf("%s: unexpected positional arguments", call.fn)
+	}
+	skill, ok := r.skills[call.fn.parts[1]]
+	if !ok {
+		return nil, fmt.Errorf("unknown skill %q", call.fn.parts[1])
+	}
+	paramMessageName := protoreflect.FullName(skill.GetParameterDescription().GetParameterMessageFullName())
+	paramMessageType, err := r.pt.FindMessageByName(paramMessageName)
+	if err != nil {
+		return nil, fmt.Errorf("could not find message by name, skill_id=%s, message name=%s", skill.GetId(), paramMessageName)
+	}
+
+	bc := &bcpb.BehaviorCall{SkillId: skill.GetId()}
+	params := paramMessageType.New()
+	for _, kw := range call.kwargs {
+		if kw.name == "return_value_key" {
+			if bc.ReturnValueName, err = evalString(kw.value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if n, ok := kw.value.(*pyName); ok && len(n.parts) == 2 && n.parts[0] == "resources" {
+			if bc.Resources == nil {
+				bc.Resources = make(map[string]*bcpb.BehaviorCall_ResourceSpecification)
+			}
+			bc.Resources[kw.name] = &bcpb.BehaviorCall_ResourceSpecification{
+				ResourceSpecificationType: &bcpb.BehaviorCall_ResourceSpecification_Handle{Handle: n.parts[1]},
+			}
+			continue
+		}
+		if err := r.evalField(params, kw.name, kw.value); err != nil {
+			return nil, errors.Wrapf(err, "parameter %s of skill %s", kw.name, skill.GetId())
+		}
+	}
+	if bc.Parameters, err = anypb.New(params.Interface()); err != nil {
+		return nil, errors.Wrap(err, "could not pack parameters")
+	}
+	return bc, nil
+}
+
+// skillMember splits a name of the form skills.<skill name>.<full proto name> into the skill name
+// and the full proto name.
+func (r *pythonReader) skillMember(n *pyName) (string, protoreflect.FullName, error) {
+	if len(n.parts) < 3 || n.parts[0] != r.skillPrefix {
+		return "", "", fmt.Errorf("expected %s.<skill>.<proto name>, got %s", r.skillPrefix, n)
+	}
+	if _, ok := r.skills[n.parts[1]]; !ok {
+		return "", "", fmt.Errorf("unknown skill %q", n.parts[1])
+	}
+	return n.parts[1], protoreflect.FullName(strings.Join(n.parts[2:], ".")), nil
+}
+
+// evalMessage fills the given message from a call of its message wrapper.
+func (r *pythonReader) evalMessage(e pyExpr, msg protoreflect.Message) error {
+	call, ok := e.(*pyCall)
+	if !ok {
+		return fmt.Errorf("expected message constructor for %s, got %T", msg.Descriptor().FullName(), e)
+	}
+	if _, fullName, err := r.skillMember(call.fn); err != nil {
+		return err
+	} else if fullName != msg.Descriptor().FullName() {
+		return fmt.Errorf("expected message constructor for %s, got %s", msg.Descriptor().FullName(), call.fn)
+	}
+	if len(call.args) > 0 {
+		return fmt.Errorf("%s: unexpected positional arguments", call.fn)
+	}
+	for _, kw := range call.kwargs {
+		if err := r.evalField(msg, kw.name, kw.value); err != nil {
+			return errors.Wrapf(err, "field %s of %s", kw.name, msg.Descriptor().FullName())
+		}
+	}
+	return nil
+}
+
+func (r *pythonReader) evalAny(e pyExpr) (*anypb.Any, error) {
+	call, ok := e.(*pyCall)
+	if !ok {
+		return nil, fmt.Errorf("expected message constructor, got %T", e)
+	}
+	_, fullName, err := r.skillMember(call.fn)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := r.messageSkills[fullName]; !ok {
+		return nil, fmt.Errorf("unknown message %s", call.fn)
+	}
+	mt, err := r.pt.FindMessageByName(fullName)
+	if err != nil {
+		return nil, fmt.Errorf("could not find message by name %s", fullName)
+	}
+	msg := mt.New()
+	if err := r.evalMessage(call, msg); err != nil {
+		return nil, err
+	}
+	return anypb.New(msg.Interface())
+}
+
+func (r *pythonReader) evalAnyList(e pyExpr) ([]*anypb.Any, error) {
+	l, ok := e.(*pyList)
+	if !ok {
+		return nil, fmt.Errorf("expected list of messages, got %T", e)
+	}
+	var items []*anypb.Any
+	for _, item := range l.items {
+		a, err := r.evalAny(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, a)
+	}
+	return items, nil
+}
+
+func (r *pythonReader) evalField(msg protoreflect.Message, name string, e pyExpr) error {
+	fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil {
+		return fmt.Errorf("unknown field")
+	}
+	if fd.IsMap() {
+		d, ok := e.(*pyDict)
+		if !ok {
+			return fmt.Errorf("expected dict, got %T", e)
+		}
+		m := msg.Mutable(fd).Map()
+		for i, keyExpr := range d.keys {
+			key, err := r.evalValue(fd.MapKey(), keyExpr, nil)
+			if err != nil {
+				return errors.Wrap(err, "map key")
+			}
+			v, err := r.evalValue(fd.MapValue(), d.values[i], m.NewValue)
+			if err != nil {
+				return errors.Wrapf(err, "map value for key %v", key)
+			}
+			m.Set(key.MapKey(), v)
+		}
+		return nil
+	}
+	if fd.IsList() {
+		l, ok := e.(*pyList)
+		if !ok {
+			return fmt.Errorf("expected list, got %T", e)
+		}
+		list := msg.Mutable(fd).List()
+		for _, item := range l.items {
+			v, err := r.evalValue(fd, item, list.NewElement)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+	v, err := r.evalValue(fd, e, func() protoreflect.Value { return msg.NewField(fd) })
+	if err != nil {
+		return err
+	}
+	msg.Set(fd, v)
+	return nil
+}
+
+// evalValue evaluates a single (non-repeated) value of the given field. newValue creates a new
+// empty value for message fields.
+func (r *pythonReader) evalValue(fd protoreflect.FieldDescriptor, e pyExpr, newValue func() protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := evalBool(e)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := evalInt(e, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := evalInt(e, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := evalUint(e, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := evalUint(e, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := evalFloat(e, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := evalFloat(e, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.StringKind:
+		s, err := evalString(e)
+		return protoreflect.ValueOfString(s), err
+	case protoreflect.BytesKind:
+		b, err := evalBytes(e)
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.EnumKind:
+		n, err := r.evalEnumValue(e, fd.Enum())
+		return protoreflect.ValueOfEnum(n), err
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		v := newValue()
+		msg := v.Message()
+		switch msg.Descriptor().FullName() {
+		case anyFullName:
+			a, err := r.evalAny(e)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			fields := msg.Descriptor().Fields()
+			msg.Set(fields.ByName("type_url"), protoreflect.ValueOfString(a.GetTypeUrl()))
+			msg.Set(fields.ByName("value"), protoreflect.ValueOfBytes(a.GetValue()))
+			return v, nil
+		case durationFullName:
+			if call, ok := e.(*pyCall); ok && call.fn.String() == "datetime.timedelta" {
+				return v, evalDuration(call, msg)
+			}
+		}
+		if err := r.evalMessage(e, msg); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return v, nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unimplemented field kind %v", fd.Kind())
+}
+
+// evalWorldQuery evaluates a bt.WorldQuery as serialized by PythonSerializer.serializeWorldQuery.
+func (r *pythonReader) evalWorldQuery(e pyExpr) (*anypb.Any, error) {
+	call, ok := e.(*pyCall)
+	if !ok || call.fn.String() != "bt.WorldQuery" || len(call.args) != 1 || len(call.kwargs) != 0 {
+		return nil, fmt.Errorf("expected bt.WorldQuery with a single WorldQuery proto")
+	}
+	wq := &wqpb.WorldQuery{}
+	if err := r.evalProtoConstructor(call.args[0], "world_query_pb2.WorldQuery", wq.ProtoReflect()); err != nil {
+		return nil, err
+	}
+	return anypb.New(wq)
+}
+
+// evalProtoConstructor fills the given message from a call of its Python proto constructor with
+// keyword arguments, see PythonSerializer.serializeProtoKwargs.
+func (r *pythonReader) evalProtoConstructor(e pyExpr, constructor string, msg protoreflect.Message) error {
+	call, ok := e.(*pyCall)
+	if !ok || call.fn.String() != constructor {
+		return fmt.Errorf("expected %s, got %T", constructor, e)
+	}
+	if len(call.args) > 0 {
+		return fmt.Errorf("%s: unexpected positional arguments", call.fn)
+	}
+	for _, kw := range call.kwargs {
+		if err := r.evalProtoField(msg, kw.name, kw.value); err != nil {
+			return errors.Wrapf(err, "field %s of %s", kw.name, msg.Descriptor().FullName())
+		}
+	}
+	return nil
+}
+
+func (r *pythonReader) evalProtoField(msg protoreflect.Message, name string, e pyExpr) error {
+	fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil {
+		return fmt.Errorf("unknown field")
+	}
+	if fd.IsMap() {
+		return fmt.Errorf("map fields are not supported")
+	}
+	if fd.IsList() {
+		l, ok := e.(*pyList)
+		if !ok {
+			return fmt.Errorf("expected list, got %T", e)
+		}
+		list := msg.Mutable(fd).List()
+		for _, item := range l.items {
+			v, err := r.evalProtoValue(fd, item, list.NewElement)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+	v, err := r.evalProtoValue(fd, e, func() protoreflect.Value { return msg.NewField(fd) })
+	if err != nil {
+		return err
+	}
+	msg.Set(fd, v)
+	return nil
+}
+
+// evalProtoValue evaluates a single value of a plain proto message field. Messages are given as
+// dicts with field names as keys and enum values by name.
+func (r *pythonReader) evalProtoValue(fd protoreflect.FieldDescriptor, e pyExpr, newValue func() protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if _, ok := e.(*pyNumber); ok {
+			n, err := evalInt(e, 32)
+			return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+		}
+		name, err := evalString(e)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		v := fd.Enum().Values().ByName(protoreflect.Name(name))
+		if v == nil {
+			return protoreflect.Value{}, fmt.Errorf("unknown value %q of enum %s", name, fd.Enum().FullName())
+		}
+		return protoreflect.ValueOfEnum(v.Number()), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		d, ok := e.(*pyDict)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected dict, got %T", e)
+		}
+		v := newValue()
+		for i, keyExpr := range d.keys {
+			name, err := evalString(keyExpr)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			if err := r.evalProtoField(v.Message(), name, d.values[i]); err != nil {
+				return protoreflect.Value{}, errors.Wrapf(err, "field %s of %s", name, fd.Message().FullName())
+			}
+		}
+		return v, nil
+	}
+	return r.evalValue(fd, e, newValue)
+}
+
+// evalEnumValue evaluates an enum value referenced by name as serialized by
+// PythonSerializer.serializeEnum, or given as a plain number.
+func (r *pythonReader) evalEnumValue(e pyExpr, ed protoreflect.EnumDescriptor) (protoreflect.EnumNumber, error) {
+	if _, ok := e.(*pyNumber); ok {
+		n, err := evalInt(e, 32)
+		return protoreflect.EnumNumber(n), err
+	}
+	n, ok := e.(*pyName)
+	if !ok || len(n.parts) < 4 {
+		return 0, fmt.Errorf("expected value of enum %s, got %T", ed.FullName(), e)
+	}
+	skillName, _, err := r.skillMember(&pyName{parts: n.parts[:len(n.parts)-1]})
+	if err != nil {
+		return 0, err
+	}
+	values := make(map[string]int32)
+	for i := 0; i < ed.Values().Len(); i++ {
+		v := ed.Values().Get(i)
+		values[string(v.Name())] = int32(v.Number())
+	}
+	number, err := evalEnum(e, fmt.Sprintf("%s.%s.%s", r.skillPrefix, skillName, ed.FullName()), values)
+	return protoreflect.EnumNumber(number), err
+}
+
+// evalDuration fills the given google.protobuf.Duration from a call of datetime.timedelta.
+func evalDuration(call *pyCall, msg protoreflect.Message) error {
+	args, err := kwargs(call, "seconds", "microseconds")
+	if err != nil {
+		return err
+	}
+	var seconds, micros int64
+	if e, ok := args["seconds"]; ok {
+		if seconds, err = evalInt(e, 64); err != nil {
+			return err
+		}
+	}
+	if e, ok := args["microseconds"]; ok {
+		if micros, err = evalInt(e, 64); err != nil {
+			return err
+		}
+	}
+	seconds += micros / 1e6
+	nanos := (micros % 1e6) * 1000
+	// Seconds and nanos of a Duration must have the same sign.
+	if seconds > 0 && nanos < 0 {
+		seconds--
+		nanos += 1e9
+	} else if seconds < 0 && nanos > 0 {
+		seconds++
+		nanos -= 1e9
+	}
+	fields := msg.Descriptor().Fields()
+	msg.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(seconds))
+	msg.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(nanos)))
+	return nil
+}
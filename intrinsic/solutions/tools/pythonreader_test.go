@@ -0,0 +1,163 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package pythonserializer
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	mypb "intrinsic/solutions/tools/proto/my_msg_go_proto"
+)
+
+const (
+	treeWithUserData = `
+		root {
+			fail {}
+			user_data {
+				data_bytes { key: "key" value: "value" }
+			}
+		}
+	`
+)
+
+func TestVerifyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		tree string
+	}{
+		{name: "tree with data", tree: treeWithData},
+		{name: "tree with all node types", tree: treeWithAllNodeTypes},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bt := mustParseTree(t, tc.tree)
+			if err := VerifyRoundTrip(bt, mySkills()); err != nil {
+				t.Errorf("VerifyRoundTrip() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRoundTripReportsDiff(t *testing.T) {
+	bt := mustParseTree(t, treeWithUserData)
+
+	err := VerifyRoundTrip(bt, mySkills())
+	if err == nil {
+		t.Fatalf("VerifyRoundTrip() succeeded for a tree with user data, want error")
+	}
+	if !strings.Contains(err.Error(), "user_data") {
+		t.Errorf("VerifyRoundTrip() returned error %q, want diff mentioning user_data", err)
+	}
+}
+
+func TestParseBehaviorTreeProtoNames(t *testing.T) {
+	// Message wrappers and enums are referenced by their full proto names below the skill, the way
+	// the solution building library exposes them.
+	code := `my_skill = bt.Task(action=skills.my_skill(
+  enum_value=skills.my_skill.intrinsic_proto.solutions.tools.MyMsg.Enum.VALUE_B,
+  msg_value=skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
+    string_value="nested")))
+tree = bt.BehaviorTree(root=my_skill)
+`
+	bt, err := ParseBehaviorTree([]byte(code), mySkills())
+	if err != nil {
+		t.Fatalf("ParseBehaviorTree() failed: %v", err)
+	}
+	got := &mypb.MyMsg{}
+	if err := bt.GetRoot().GetTask().GetCallBehavior().GetParameters().UnmarshalTo(got); err != nil {
+		t.Fatalf("UnmarshalTo() failed: %v", err)
+	}
+	want := &mypb.MyMsg{
+		EnumValue: mypb.MyMsg_VALUE_B,
+		MsgValue:  &mypb.MyMsg{StringValue: "nested"},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("ParseBehaviorTree() returned parameters %v, want %v", got, want)
+	}
+
+	serializer, err := NewPythonSerializer(mySkills())
+	if err != nil {
+		t.Fatalf("NewPythonSerializer() failed: %v", err)
+	}
+	serialized, err := serializer.Serialize(bt)
+	if err != nil {
+		t.Fatalf("Serialize() failed: %v", err)
+	}
+	if string(serialized) != code {
+		t.Errorf("Serialize() = %q, want %q", serialized, code)
+	}
+}
+
+func TestParseBehaviorTreeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{name: "no tree", code: "fail = bt.Fail()\n"},
+		{name: "unknown constructor", code: "x = bt.Unknown()\ntree = bt.BehaviorTree(root=x)\n"},
+		{name: "undefined node", code: "tree = bt.BehaviorTree(root=x)\n"},
+		{name: "unknown skill", code: "x = bt.Task(action=skills.unknown_skill())\ntree = bt.BehaviorTree(root=x)\n"},
+		{name: "unknown keyword", code: "x = bt.Fail(foo=1)\ntree = bt.BehaviorTree(root=x)\n"},
+		{name: "syntax error", code: "x = bt.Fail(\n"},
+		{
+			name: "message shortcut",
+			code: "x = bt.Task(action=skills.my_skill(msg_value=skills.my_skill.MyMsg()))\ntree = bt.BehaviorTree(root=x)\n",
+		},
+		{
+			name: "enum shortcut",
+			code: "x = bt.Task(action=skills.my_skill(enum_value=skills.my_skill.MyMsg.Enum.VALUE_A))\ntree = bt.BehaviorTree(root=x)\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseBehaviorTree([]byte(tc.code), mySkills()); err == nil {
+				t.Errorf("ParseBehaviorTree(%q) succeeded, want error", tc.code)
+			}
+		})
+	}
+}
+
+func TestUnquotePython(t *testing.T) {
+	tests := []struct {
+		literal string
+		isBytes bool
+		want    string
+	}{
+		{literal: `"a\"b\\c"`, want: `a"b\c`},
+		{literal: `"\'"`, want: "'"},
+		{literal: `"\xe9"`, want: "é"},
+		{literal: `"é\U0001f600"`, want: "é😀"},
+		{literal: `"\101\0"`, want: "A\x00"},
+		{literal: `"\q"`, want: `\q`},
+		{literal: `"\xe9"`, isBytes: true, want: "\xe9"},
+		{literal: `"\377"`, isBytes: true, want: "\xff"},
+	}
+	for _, tc := range tests {
+		got, err := unquotePython(tc.literal, tc.isBytes)
+		if err != nil {
+			t.Errorf("unquotePython(%s, %t) failed: %v", tc.literal, tc.isBytes, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("unquotePython(%s, %t) = %q, want %q", tc.literal, tc.isBytes, got, tc.want)
+		}
+	}
+}
+
+func TestUnquotePythonErrors(t *testing.T) {
+	tests := []struct {
+		literal string
+		isBytes bool
+	}{
+		{literal: `"\x9"`},
+		{literal: `"\ud800"`},
+		{literal: `"\N{DASH}"`},
+		{literal: `"é"`, isBytes: true},
+	}
+	for _, tc := range tests {
+		if got, err := unquotePython(tc.literal, tc.isBytes); err == nil {
+			t.Errorf("unquotePython(%s, %t) = %q, want error", tc.literal, tc.isBytes, got)
+		}
+	}
+}
@@ -5,7 +5,9 @@ package pythonserializer
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
+	"math"
 	"slices"
 	"strconv"
 	"strings"
@@ -14,8 +16,11 @@ import (
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+	awapb "intrinsic/executive/proto/any_with_assignments_go_proto"
 	bcpb "intrinsic/executive/proto/behavior_call_go_proto"
 	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	wqpb "intrinsic/executive/proto/world_query_go_proto"
 	skillspb "intrinsic/skills/proto/skills_go_proto"
 	"intrinsic/util/proto/registryutil"
 )
@@ -25,6 +30,7 @@ import (
 type PythonSerializer struct {
 	skills         map[string]*skillspb.Skill
 	pt             *protoregistry.Types
+	messageSkills  map[protoreflect.FullName]string
 	identifiers    []string
 	skillPrefix    string
 	resourcePrefix string
@@ -40,48 +46,93 @@ func NewPythonSerializer(sk []*skillspb.Skill) (*PythonSerializer, error) {
 		skills[skill.GetId()] = skill
 	}
 
+	pt, messageSkills, err := newSkillTypes(sk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PythonSerializer{
+		skills:         skills,
+		pt:             pt,
+		messageSkills:  messageSkills,
+		skillPrefix:    "skills",
+		resourcePrefix: "resources",
+		identifiers:    []string{"bt", "datetime", "deployments", "solutions", "executive", "resources", "world", "skills"},
+		indent:         "  ",
+		buffer:         bytes.NewBuffer([]byte{}),
+	}, nil
+}
+
+// newSkillTypes creates a type registry from the parameter descriptors of the given skills. It
+// also returns a map from message names to the name of the first skill declaring them, which is
+// used to address messages that are not skill parameters (e.g., protos in data nodes) via the
+// skill's message wrappers.
+func newSkillTypes(sk []*skillspb.Skill) (*protoregistry.Types, map[protoreflect.FullName]string, error) {
 	r := new(protoregistry.Files)
-	for _, skill := range skills {
+	messageSkills := make(map[protoreflect.FullName]string)
+	for _, skill := range sk {
 		for _, parameterDescriptorFile := range skill.GetParameterDescription().GetParameterDescriptorFileset().GetFile() {
 			fd, err := protodesc.NewFile(parameterDescriptorFile, r)
 			if err != nil {
-				return nil, errors.Wrapf(err, "failed to add file to registry")
+				return nil, nil, errors.Wrapf(err, "failed to add file to registry")
 			}
 			r.RegisterFile(fd)
+			addMessageSkills(messageSkills, fd.Messages(), skill.GetSkillName())
 		}
 	}
 
 	pt := new(protoregistry.Types)
 	if err := registryutil.PopulateTypesFromFiles(pt, r); err != nil {
-		return nil, errors.Wrapf(err, "failed to populate types from files")
+		return nil, nil, errors.Wrapf(err, "failed to populate types from files")
 	}
+	return pt, messageSkills, nil
+}
 
-	return &PythonSerializer{
-		skills:         skills,
-		pt:             pt,
-		skillPrefix:    "skills",
-		resourcePrefix: "resources",
-		identifiers:    []string{"deployments", "solutions", "executive", "world", "skills"},
-		indent:         "  ",
-		buffer:         bytes.NewBuffer([]byte{}),
-	}, nil
+func addMessageSkills(messageSkills map[protoreflect.FullName]string, messages protoreflect.MessageDescriptors, skillName string) {
+	for i := 0; i < messages.Len(); i++ {
+		msg := messages.Get(i)
+		if _, ok := messageSkills[msg.FullName()]; !ok {
+			messageSkills[msg.FullName()] = skillName
+		}
+		addMessageSkills(messageSkills, msg.Messages(), skillName)
+	}
 }
 
 func (t *PythonSerializer) indentString(indent int) string {
 	return strings.Repeat(t.indent, indent)
 }
 
+// pythonKeywords cannot be used as identifiers in the generated code.
+var pythonKeywords = []string{
+	"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class", "continue",
+	"def", "del", "elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in",
+	"is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield",
+}
+
+// pythonIdentifier turns the given name into a valid Python identifier by lowercasing it and
+// replacing all characters other than letters, digits and underscores.
+func pythonIdentifier(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(name))
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
 func (t *PythonSerializer) generateUniqueIdentifier(baseName string) string {
-	baseName = strings.ToLower(baseName)              // Convert to lowercase
-	baseName = strings.ReplaceAll(baseName, " ", "_") // Replace spaces with underscores
-	baseName = strings.ReplaceAll(baseName, "-", "_") // Replace hyphens with underscores
+	baseName = pythonIdentifier(baseName)
 
 	for i := 1; ; i++ { // Use a loop for incrementing
 		candidate := baseName
 		if i > 1 {
 			candidate += "_" + strconv.Itoa(i) // Append counter if needed
 		}
-		if !slices.Contains(t.identifiers, candidate) {
+		if !slices.Contains(t.identifiers, candidate) && !slices.Contains(pythonKeywords, candidate) {
 			t.identifiers = append(t.identifiers, candidate)
 			return candidate // Return if unique
 		}
@@ -90,46 +141,117 @@ func (t *PythonSerializer) generateUniqueIdentifier(baseName string) string {
 
 // Serialize serializes the given BT to Python code.
 func (t *PythonSerializer) Serialize(bt *btpb.BehaviorTree) ([]byte, error) {
-	_, err := t.serializeBT(bt)
-	if err != nil {
+	// The top-level tree is always assigned to "tree" as the script templates refer to it by name.
+	identifier := t.generateUniqueIdentifier("tree")
+	if err := t.serializeBT(bt, identifier); err != nil {
 		return nil, errors.Wrapf(err, "could not serialize BT")
 	}
 
 	return t.buffer.Bytes(), nil
 }
 
-func (t *PythonSerializer) serializeBT(bt *btpb.BehaviorTree) (string, error) {
-	childIdentifier, err := t.serializeNode(bt.GetRoot())
-	if err != nil {
-		return "", err
+func treeBaseName(bt *btpb.BehaviorTree) string {
+	if bt.GetName() != "" {
+		return bt.GetName()
 	}
+	return "tree"
+}
 
-	identifier := t.generateUniqueIdentifier("tree")
-
-	var optionalNameParam string
+func (t *PythonSerializer) serializeBT(bt *btpb.BehaviorTree, identifier string) error {
+	var params []string
 	if bt.GetName() != "" {
-		optionalNameParam = fmt.Sprintf("name=%q, ", bt.GetName())
+		params = append(params, fmt.Sprintf("name=%q", bt.GetName()))
+	}
+	if bt.GetRoot() != nil {
+		childIdentifier, err := t.serializeNode(bt.GetRoot())
+		if err != nil {
+			return err
+		}
+		params = append(params, fmt.Sprintf("root=%s", childIdentifier))
 	}
 
-	fmt.Fprintf(t.buffer, "%s = bt.BehaviorTree(%sroot=%s)\n", identifier, optionalNameParam, childIdentifier)
+	fmt.Fprintf(t.buffer, "%s = bt.BehaviorTree(%s)\n", identifier, strings.Join(params, ", "))
 
+	return nil
+}
+
+// serializeSubtree emits the given tree under a new identifier and returns that identifier.
+func (t *PythonSerializer) serializeSubtree(bt *btpb.BehaviorTree) (string, error) {
+	identifier := t.generateUniqueIdentifier(treeBaseName(bt))
+	if err := t.serializeBT(bt, identifier); err != nil {
+		return "", err
+	}
 	return identifier, nil
 }
 
 func (t *PythonSerializer) serializeNode(node *btpb.BehaviorTree_Node) (string, error) {
+	var identifier string
+	var err error
 	switch node.GetNodeType().(type) {
 	case *btpb.BehaviorTree_Node_Sequence:
-		return t.serializeSequence(node)
+		identifier, err = t.serializeNodeWithChildren(node, "Sequence", "sequence", node.GetSequence().GetChildren(), nil)
+	case *btpb.BehaviorTree_Node_Parallel:
+		var params []string
+		if fb := node.GetParallel().GetFailureBehavior(); fb != btpb.BehaviorTree_ParallelNode_DEFAULT {
+			params = append(params, fmt.Sprintf("failure_behavior=bt.Parallel.FailureBehavior.%s", fb))
+		}
+		identifier, err = t.serializeNodeWithChildren(node, "Parallel", "parallel", node.GetParallel().GetChildren(), params)
+	case *btpb.BehaviorTree_Node_Selector:
+		identifier, err = t.serializeNodeWithChildren(node, "Selector", "selector", node.GetSelector().GetChildren(), nil)
+	case *btpb.BehaviorTree_Node_Fallback:
+		identifier, err = t.serializeNodeWithChildren(node, "Fallback", "fallback", node.GetFallback().GetChildren(), nil)
 	case *btpb.BehaviorTree_Node_Task:
-		return t.serializeTask(node)
+		identifier, err = t.serializeTask(node)
+	case *btpb.BehaviorTree_Node_Branch:
+		identifier, err = t.serializeBranch(node)
+	case *btpb.BehaviorTree_Node_Loop:
+		identifier, err = t.serializeLoop(node)
+	case *btpb.BehaviorTree_Node_Retry:
+		identifier, err = t.serializeRetry(node)
+	case *btpb.BehaviorTree_Node_SubTree:
+		identifier, err = t.serializeSubTreeNode(node)
+	case *btpb.BehaviorTree_Node_Data:
+		identifier, err = t.serializeData(node)
+	case *btpb.BehaviorTree_Node_Fail:
+		identifier = t.writeNode(node, "fail", "Fail", nil)
+	case *btpb.BehaviorTree_Node_Debug:
+		var params []string
+		if node.GetDebug().GetSuspend().GetFailOnResume() {
+			params = append(params, "fail_on_resume=True")
+		}
+		identifier = t.writeNode(node, "debug", "Debug", params)
+	case *btpb.BehaviorTree_Node_ControlProcess:
+		return "", fmt.Errorf("process control nodes are not supported by the Python behavior tree API")
 	default:
+		return "", fmt.Errorf("unimplemented node type: %T", node.GetNodeType())
 	}
-	return "", fmt.Errorf("unimplemented node type: %T", node.GetNodeType())
+	if err != nil {
+		return "", err
+	}
+
+	if err := t.serializeNodeSettings(identifier, node); err != nil {
+		return "", errors.Wrapf(err, "could not serialize settings of node %q", identifier)
+	}
+
+	return identifier, nil
+}
+
+// writeNode emits an assignment of a node constructor call to a new unique identifier and returns
+// the identifier. The node's name, if any, is used as the identifier and passed as the first
+// parameter.
+func (t *PythonSerializer) writeNode(node *btpb.BehaviorTree_Node, baseName string, constructor string, params []string) string {
+	if node.GetName() != "" {
+		baseName = node.GetName()
+		params = append([]string{fmt.Sprintf("name=%q", node.GetName())}, params...)
+	}
+	identifier := t.generateUniqueIdentifier(baseName)
+	fmt.Fprintf(t.buffer, "%s = bt.%s(%s)\n", identifier, constructor, strings.Join(params, ", "))
+	return identifier
 }
 
-func (t *PythonSerializer) serializeSequence(node *btpb.BehaviorTree_Node) (string, error) {
+func (t *PythonSerializer) serializeNodeWithChildren(node *btpb.BehaviorTree_Node, constructor string, baseName string, children []*btpb.BehaviorTree_Node, params []string) (string, error) {
 	var childIdentifiers []string
-	for _, child := range node.GetSequence().GetChildren() {
+	for _, child := range children {
 		identifier, err := t.serializeNode(child)
 		if err != nil {
 			return "", err
@@ -137,52 +259,402 @@ func (t *PythonSerializer) serializeSequence(node *btpb.BehaviorTree_Node) (stri
 		childIdentifiers = append(childIdentifiers, identifier)
 	}
 
-	var optionalNameParam string
-	if node.GetName() != "" {
-		optionalNameParam = fmt.Sprintf("name=%q, ", node.GetName())
+	params = append([]string{fmt.Sprintf("children=[%s]", strings.Join(childIdentifiers, ", "))}, params...)
+	return t.writeNode(node, baseName, constructor, params), nil
+}
+
+// serializeOptionalNode serializes the given node if it is set and appends it as keyword parameter
+// to params.
+func (t *PythonSerializer) serializeOptionalNode(params []string, keyword string, node *btpb.BehaviorTree_Node) ([]string, error) {
+	if node == nil {
+		return params, nil
+	}
+	identifier, err := t.serializeNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return append(params, fmt.Sprintf("%s=%s", keyword, identifier)), nil
+}
+
+func (t *PythonSerializer) serializeTask(node *btpb.BehaviorTree_Node) (string, error) {
+	if node.GetTask().GetCallBehavior() == nil {
+		return "", fmt.Errorf("only tasks calling a behavior are supported by the Python behavior tree API")
 	}
 
-	name := node.GetName()
-	if name == "" {
-		name = "sequence"
+	action, err := t.serializeAction(node.GetTask().GetCallBehavior())
+	if err != nil {
+		return "", err
 	}
-	identifier := t.generateUniqueIdentifier(name)
-	fmt.Fprintf(t.buffer, "%s = bt.Sequence(%schildren=[%s])\n", identifier, optionalNameParam, strings.Join(childIdentifiers, ", "))
 
-	if node.GetDecorators() != nil {
-		return "", fmt.Errorf("decorators are not yet implemented")
+	baseName := strings.ReplaceAll(node.GetTask().GetCallBehavior().GetSkillId(), ".", "_")
+	return t.writeNode(node, baseName, "Task", []string{fmt.Sprintf("action=%s", action)}), nil
+}
+
+func (t *PythonSerializer) serializeBranch(node *btpb.BehaviorTree_Node) (string, error) {
+	branch := node.GetBranch()
+
+	var params []string
+	if branch.GetIf() != nil {
+		condition, err := t.serializeCondition(branch.GetIf())
+		if err != nil {
+			return "", err
+		}
+		params = append(params, fmt.Sprintf("if_condition=%s", condition))
+	}
+	params, err := t.serializeOptionalNode(params, "then_child", branch.GetThen())
+	if err != nil {
+		return "", err
+	}
+	params, err = t.serializeOptionalNode(params, "else_child", branch.GetElse())
+	if err != nil {
+		return "", err
 	}
 
-	return identifier, nil
+	return t.writeNode(node, "branch", "Branch", params), nil
 }
 
-func (t *PythonSerializer) serializeTask(node *btpb.BehaviorTree_Node) (string, error) {
-	var name string
-	if node.GetName() != "" {
-		name = node.GetName()
+func (t *PythonSerializer) serializeLoop(node *btpb.BehaviorTree_Node) (string, error) {
+	loop := node.GetLoop()
+
+	var params []string
+	if loop.GetMaxTimes() != 0 {
+		params = append(params, fmt.Sprintf("max_times=%d", loop.GetMaxTimes()))
+	}
+	params, err := t.serializeOptionalNode(params, "do_child", loop.GetDo())
+	if err != nil {
+		return "", err
+	}
+	if loop.GetWhile() != nil {
+		condition, err := t.serializeCondition(loop.GetWhile())
+		if err != nil {
+			return "", err
+		}
+		params = append(params, fmt.Sprintf("while_condition=%s", condition))
+	}
+	if loop.GetLoopCounterBlackboardKey() != "" {
+		params = append(params, fmt.Sprintf("loop_counter_key=%q", loop.GetLoopCounterBlackboardKey()))
+	}
+
+	if forEach := loop.GetForEach(); forEach != nil {
+		if forEach.GetValueBlackboardKey() != "" {
+			params = append(params, fmt.Sprintf("for_each_value_key=%q", forEach.GetValueBlackboardKey()))
+		}
+		if forEach.GetProtos() != nil {
+			protos, err := t.serializeAnyList(forEach.GetProtos().GetItems(), 1)
+			if err != nil {
+				return "", errors.Wrap(err, "could not serialize for each protos")
+			}
+			params = append(params, fmt.Sprintf("for_each_protos=%s", protos))
+		}
+		if forEach.GetGeneratorCelExpression() != "" {
+			params = append(params, fmt.Sprintf("for_each_generator_cel_expression=%q", forEach.GetGeneratorCelExpression()))
+		}
+	}
+
+	return t.writeNode(node, "loop", "Loop", params), nil
+}
+
+func (t *PythonSerializer) serializeRetry(node *btpb.BehaviorTree_Node) (string, error) {
+	retry := node.GetRetry()
+
+	params := []string{fmt.Sprintf("max_tries=%d", retry.GetMaxTries())}
+	params, err := t.serializeOptionalNode(params, "child", retry.GetChild())
+	if err != nil {
+		return "", err
+	}
+	params, err = t.serializeOptionalNode(params, "recovery", retry.GetRecovery())
+	if err != nil {
+		return "", err
+	}
+	if retry.GetRetryCounterBlackboardKey() != "" {
+		params = append(params, fmt.Sprintf("retry_counter_key=%q", retry.GetRetryCounterBlackboardKey()))
+	}
+
+	return t.writeNode(node, "retry", "Retry", params), nil
+}
+
+func (t *PythonSerializer) serializeSubTreeNode(node *btpb.BehaviorTree_Node) (string, error) {
+	var params []string
+	if tree := node.GetSubTree().GetTree(); tree != nil {
+		treeIdentifier, err := t.serializeSubtree(tree)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, fmt.Sprintf("behavior_tree=%s", treeIdentifier))
+	}
+
+	return t.writeNode(node, "sub_tree", "SubTree", params), nil
+}
+
+func (t *PythonSerializer) serializeData(node *btpb.BehaviorTree_Node) (string, error) {
+	data := node.GetData()
+
+	var params []string
+	var assignments []*awapb.AnyWithAssignments_Assignment
+	if createOrUpdate := data.GetCreateOrUpdate(); createOrUpdate != nil {
+		params = append(params, fmt.Sprintf("blackboard_key=%q", createOrUpdate.GetBlackboardKey()))
+		switch {
+		case createOrUpdate.GetFromWorld() != nil:
+			worldQuery, err := t.serializeWorldQuery(createOrUpdate.GetFromWorld().GetProto())
+			if err != nil {
+				return "", errors.Wrap(err, "could not serialize data node world query")
+			}
+			params = append(params, fmt.Sprintf("world_query=%s", worldQuery))
+			assignments = createOrUpdate.GetFromWorld().GetAssign()
+		case createOrUpdate.GetProto() != nil:
+			proto, err := t.serializeAny(createOrUpdate.GetProto(), 1)
+			if err != nil {
+				return "", errors.Wrap(err, "could not serialize data node proto")
+			}
+			params = append(params, fmt.Sprintf("proto=%s", proto))
+		case createOrUpdate.GetProtos() != nil:
+			protos, err := t.serializeAnyList(createOrUpdate.GetProtos().GetItems(), 1)
+			if err != nil {
+				return "", errors.Wrap(err, "could not serialize data node protos")
+			}
+			params = append(params, fmt.Sprintf("protos=%s", protos))
+		default:
+			params = append(params, fmt.Sprintf("cel_expression=%q", createOrUpdate.GetCelExpression()))
+		}
+	} else if remove := data.GetRemove(); remove != nil {
+		params = append(params,
+			fmt.Sprintf("blackboard_key=%q", remove.GetBlackboardKey()),
+			"operation=bt.Data.OperationType.REMOVE")
 	} else {
-		name = node.GetTask().GetCallBehavior().GetSkillId()
+		return "", fmt.Errorf("data node has no operation set")
 	}
-	identifierPrefix := strings.ReplaceAll(name, ".", "_")
 
-	taskIdentifier := t.generateUniqueIdentifier(identifierPrefix)
+	identifier := t.writeNode(node, "data", "Data", params)
+	for _, a := range assignments {
+		fmt.Fprintf(t.buffer, "%s.world_query.assignments.append(any_with_assignments_pb2.AnyWithAssignments.Assignment(path=%s, cel_expression=%s))\n",
+			identifier, quoteString(a.GetPath()), quoteString(a.GetCelExpression()))
+	}
+	return identifier, nil
+}
 
-	action, err := t.serializeAction(node.GetTask().GetCallBehavior())
+// serializeWorldQuery serializes the WorldQuery packed in the given Any as bt.WorldQuery. The
+// WorldQuery proto is constructed from keyword arguments, see serializeProtoKwargs.
+func (t *PythonSerializer) serializeWorldQuery(a *anypb.Any) (string, error) {
+	wq := &wqpb.WorldQuery{}
+	if err := a.UnmarshalTo(wq); err != nil {
+		return "", errors.Wrapf(err, "could not unmarshal world query of type %s", a.GetTypeUrl())
+	}
+	kwargs, err := t.serializeProtoKwargs(wq.ProtoReflect())
 	if err != nil {
 		return "", err
 	}
+	return fmt.Sprintf("bt.WorldQuery(world_query_pb2.WorldQuery(%s))", kwargs), nil
+}
 
-	var optionalNameParam string
-	if node.GetName() != "" {
-		optionalNameParam = fmt.Sprintf("name=%q, ", node.GetName())
+// serializeProtoKwargs serializes the fields of a plain proto message (not a skill message wrapper)
+// as keyword arguments of its Python constructor. Message fields are given as dicts and enum
+// fields by the name of their value, both of which Python proto constructors accept.
+func (t *PythonSerializer) serializeProtoKwargs(msg protoreflect.Message) (string, error) {
+	fields, err := t.serializeProtoFields(msg, "%s=%s")
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(fields, ", "), nil
+}
+
+func (t *PythonSerializer) serializeProtoFields(msg protoreflect.Message, format string) ([]string, error) {
+	var fields []string
+	fds := msg.Descriptor().Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fd := fds.Get(i)
+		if !msg.Has(fd) {
+			continue
+		}
+		if fd.IsMap() {
+			return nil, fmt.Errorf("map field %s is not supported", fd.FullName())
+		}
+		var value string
+		if fd.IsList() {
+			var items []string
+			for j := 0; j < msg.Get(fd).List().Len(); j++ {
+				item, err := t.serializeProtoValue(fd, msg.Get(fd).List().Get(j))
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			value = fmt.Sprintf("[%s]", strings.Join(items, ", "))
+		} else {
+			var err error
+			if value, err = t.serializeProtoValue(fd, msg.Get(fd)); err != nil {
+				return nil, err
+			}
+		}
+		fields = append(fields, fmt.Sprintf(format, fd.Name(), value))
+	}
+	return fields, nil
+}
+
+func (t *PythonSerializer) serializeProtoValue(fd protoreflect.FieldDescriptor, value protoreflect.Value) (string, error) {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if v := fd.Enum().Values().ByNumber(value.Enum()); v != nil {
+			return quoteString(string(v.Name())), nil
+		}
+		return strconv.FormatInt(int64(value.Enum()), 10), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		fields, err := t.serializeProtoFields(value.Message(), "\"%s\": %s")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("{%s}", strings.Join(fields, ", ")), nil
+	}
+	return t.serializeValue("", fd, value, 0)
+}
+
+// serializeNodeSettings emits the statements that configure decorators, failure settings and the
+// node ID on an already constructed node.
+//
+// Extended status on failure is limited to the fields the Python API exposes (status code, title,
+// user and debug message).
+func (t *PythonSerializer) serializeNodeSettings(identifier string, node *btpb.BehaviorTree_Node) error {
+	if node.GetId() != 0 {
+		fmt.Fprintf(t.buffer, "%s.node_id = %d\n", identifier, node.GetId())
+	}
+
+	decorators := node.GetDecorators()
+	if decorators == nil {
+		return nil
+	}
+	if decorators.GetExtensionPoint() != btpb.BehaviorTree_Node_Decorators_EXTENSION_POINT_TYPE_UNSPECIFIED {
+		return fmt.Errorf("extension point decorators are not supported by the Python behavior tree API")
+	}
+
+	var params []string
+	if decorators.GetCondition() != nil {
+		condition, err := t.serializeCondition(decorators.GetCondition())
+		if err != nil {
+			return err
+		}
+		params = append(params, fmt.Sprintf("condition=%s", condition))
+	}
+	if bp := decorators.GetBreakpoint(); bp != btpb.BehaviorTree_Breakpoint_TYPE_UNSPECIFIED {
+		params = append(params, fmt.Sprintf("breakpoint_type=bt.BreakpointType.%s", bp))
+	}
+	if mode := decorators.GetExecutionSettings().GetMode(); mode != btpb.BehaviorTree_Node_ExecutionSettings_UNSPECIFIED {
+		params = append(params, fmt.Sprintf("execution_mode=bt.NodeExecutionMode.%s", mode))
+		if state := decorators.GetExecutionSettings().GetDisabledResultState(); state != btpb.BehaviorTree_Node_ExecutionSettings_DISABLED_RESULT_STATE_UNSPECIFIED {
+			params = append(params, fmt.Sprintf("disabled_result_state=bt.DisabledResultState.%s", state))
+		}
+	}
+	if len(params) > 0 {
+		fmt.Fprintf(t.buffer, "%s.set_decorators(bt.Decorators(%s))\n", identifier, strings.Join(params, ", "))
+	}
+
+	emit := decorators.GetOnFailure().GetEmitExtendedStatus()
+	if es := emit.GetExtendedStatus(); es != nil {
+		params := []string{
+			fmt.Sprintf("%q", es.GetStatusCode().GetComponent()),
+			strconv.FormatUint(uint64(es.GetStatusCode().GetCode()), 10),
+		}
+		if es.GetTitle() != "" {
+			params = append(params, fmt.Sprintf("title=%q", es.GetTitle()))
+		}
+		if es.GetUserReport().GetMessage() != "" {
+			params = append(params, fmt.Sprintf("user_message=%q", es.GetUserReport().GetMessage()))
+		}
+		if es.GetDebugReport().GetMessage() != "" {
+			params = append(params, fmt.Sprintf("debug_message=%q", es.GetDebugReport().GetMessage()))
+		}
+		if emit.GetToBlackboardKey() != "" {
+			params = append(params, fmt.Sprintf("to_blackboard_key=%q", emit.GetToBlackboardKey()))
+		}
+		fmt.Fprintf(t.buffer, "%s.on_failure.emit_extended_status(%s)\n", identifier, strings.Join(params, ", "))
+	} else if emit.GetToBlackboardKey() != "" {
+		fmt.Fprintf(t.buffer, "%s.on_failure.emit_extended_status_to(%q)\n", identifier, emit.GetToBlackboardKey())
+	}
+
+	return nil
+}
+
+func (t *PythonSerializer) serializeCondition(cond *btpb.BehaviorTree_Condition) (string, error) {
+	switch cond.GetConditionType().(type) {
+	case *btpb.BehaviorTree_Condition_Blackboard:
+		return fmt.Sprintf("bt.Blackboard(%q)", cond.GetBlackboard().GetCelExpression()), nil
+	case *btpb.BehaviorTree_Condition_AllOf:
+		conditions, err := t.serializeConditions(cond.GetAllOf().GetConditions())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("bt.AllOf([%s])", conditions), nil
+	case *btpb.BehaviorTree_Condition_AnyOf:
+		conditions, err := t.serializeConditions(cond.GetAnyOf().GetConditions())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("bt.AnyOf([%s])", conditions), nil
+	case *btpb.BehaviorTree_Condition_Not:
+		condition, err := t.serializeCondition(cond.GetNot())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("bt.Not(%s)", condition), nil
+	case *btpb.BehaviorTree_Condition_BehaviorTree:
+		treeIdentifier, err := t.serializeSubtree(cond.GetBehaviorTree())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("bt.SubTreeCondition(%s)", treeIdentifier), nil
+	case *btpb.BehaviorTree_Condition_StatusMatch:
+		match := cond.GetStatusMatch()
+		if match.GetStatusCode() == nil {
+			return "", fmt.Errorf("unimplemented status match type: %T", match.GetMatchType())
+		}
+		return fmt.Sprintf("bt.ExtendedStatusMatch(%q, bt.ExtendedStatusMatch.MatchStatusCode(%q, %d))",
+			match.GetBlackboardKey(), match.GetStatusCode().GetComponent(), match.GetStatusCode().GetCode()), nil
+	case *btpb.BehaviorTree_Condition_DomainFormula:
+		return "", fmt.Errorf("domain formula conditions are not supported by the Python behavior tree API")
+	default:
 	}
-	fmt.Fprintf(t.buffer, "%s = bt.Task(%saction=%s)\n", taskIdentifier, optionalNameParam, action)
+	return "", fmt.Errorf("unimplemented condition type: %T", cond.GetConditionType())
+}
 
-	if node.GetDecorators() != nil {
-		return "", fmt.Errorf("decorators are not yet implemented")
+func (t *PythonSerializer) serializeConditions(conditions []*btpb.BehaviorTree_Condition) (string, error) {
+	var reprs []string
+	for _, c := range conditions {
+		s, err := t.serializeCondition(c)
+		if err != nil {
+			return "", err
+		}
+		reprs = append(reprs, s)
 	}
+	return strings.Join(reprs, ", "), nil
+}
 
-	return taskIdentifier, nil
+// serializeAny serializes the message packed in the given Any as a message of the skill that
+// declares its type.
+func (t *PythonSerializer) serializeAny(a *anypb.Any, indent int) (string, error) {
+	msgType, err := t.pt.FindMessageByURL(a.GetTypeUrl())
+	if err != nil {
+		return "", fmt.Errorf("could not find message by type URL %q", a.GetTypeUrl())
+	}
+	skillName, ok := t.messageSkills[msgType.Descriptor().FullName()]
+	if !ok {
+		return "", fmt.Errorf("message %s is not declared by any skill", msgType.Descriptor().FullName())
+	}
+	msg := msgType.New().Interface()
+	if err := a.UnmarshalTo(msg); err != nil {
+		return "", errors.Wrapf(err, "could not unmarshal %s", msgType.Descriptor().FullName())
+	}
+	return t.serializeMessage(skillName, msg.ProtoReflect(), indent)
+}
+
+func (t *PythonSerializer) serializeAnyList(items []*anypb.Any, indent int) (string, error) {
+	var listRepr []string
+	for _, item := range items {
+		s, err := t.serializeAny(item, indent+1)
+		if err != nil {
+			return "", err
+		}
+		listRepr = append(listRepr, s)
+	}
+	indentString := t.indentString(indent)
+	return fmt.Sprintf("[\n%s%s]", indentString, strings.Join(listRepr, fmt.Sprintf(",\n%s", indentString))), nil
 }
 
 func (t *PythonSerializer) serializeAction(action *bcpb.BehaviorCall) (string, error) {
@@ -208,7 +680,7 @@ func (t *PythonSerializer) serializeAction(action *bcpb.BehaviorCall) (string, e
 		if !refl.Has(field) {
 			continue
 		}
-		pythonRepr, err := t.serializeField(skillInfo.GetSkillName(), refl.Get(field), indent+1)
+		pythonRepr, err := t.serializeField(skillInfo.GetSkillName(), field, refl.Get(field), indent+1)
 		if err != nil {
 			return "", err
 		}
@@ -228,62 +700,194 @@ func (t *PythonSerializer) serializeAction(action *bcpb.BehaviorCall) (string, e
 	return fmt.Sprintf("%s.%s(\n%s%s)", t.skillPrefix, skillInfo.GetSkillName(), indentString, strings.Join(params, fmt.Sprintf(",\n%s", indentString))), nil
 }
 
-func (t *PythonSerializer) serializeField(skillName string, value protoreflect.Value, indent int) (string, error) {
-	switch value.Interface().(type) {
-	case bool:
-		if value.Bool() {
-			return "True", nil
+// serializeField serializes the value of the given field. Repeated fields are serialized as
+// Python lists and map fields as Python dicts.
+func (t *PythonSerializer) serializeField(skillName string, fd protoreflect.FieldDescriptor, value protoreflect.Value, indent int) (string, error) {
+	indentString := t.indentString(indent)
+	switch {
+	case fd.IsMap():
+		m := value.Map()
+		var keys []protoreflect.MapKey
+		m.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+			keys = append(keys, k)
+			return true
+		})
+		slices.SortFunc(keys, compareMapKeys)
+		var entries []string
+		for _, k := range keys {
+			key, err := t.serializeValue(skillName, fd.MapKey(), k.Value(), indent+1)
+			if err != nil {
+				return "", errors.Wrapf(err, "could not serialize map key %v", k.Interface())
+			}
+			v, err := t.serializeValue(skillName, fd.MapValue(), m.Get(k), indent+1)
+			if err != nil {
+				return "", errors.Wrapf(err, "could not serialize map value for key %v", k.Interface())
+			}
+			entries = append(entries, fmt.Sprintf("%s: %s", key, v))
 		}
-		return "False", nil
-	case int32:
-		return strconv.FormatInt(value.Int(), 10), nil
-	case int64:
-		return strconv.FormatInt(value.Int(), 10), nil
-	case uint32:
-		return strconv.FormatUint(value.Uint(), 10), nil
-	case uint64:
-		return strconv.FormatUint(value.Uint(), 10), nil
-	case float32:
-		var s string
-		if value.Float() == float64(int(value.Float())) {
-			// Force a decimal point to indicate to Python that this is a float.
-			s = ".0"
-		}
-		return strconv.FormatFloat(value.Float(), 'g', -1, 32) + s, nil
-	case float64:
-		var s string
-		if value.Float() == float64(int(value.Float())) {
-			// Force a decimal point to indicate to Python that this is a float.
-			s = ".0"
-		}
-		return strconv.FormatFloat(value.Float(), 'g', -1, 64) + s, nil
-	case string:
-		s := value.String()
-		s = strings.ReplaceAll(s, "\\", "\\\\")
-		s = strings.ReplaceAll(s, "\"", "\\\"")
-		return fmt.Sprintf("\"%s\"", s), nil
-	case protoreflect.EnumNumber:
-		enum := value.Enum()
-		return strconv.FormatInt(int64(enum), 10), nil
-	case protoreflect.Message:
-		return t.serializeMessage(skillName, value.Message(), indent)
-	case protoreflect.List:
+		return fmt.Sprintf("{\n%s%s}", indentString, strings.Join(entries, fmt.Sprintf(",\n%s", indentString))), nil
+	case fd.IsList():
 		var listRepr []string
 		for i := 0; i < value.List().Len(); i++ {
 			v := value.List().Get(i)
-			s, err := t.serializeField(skillName, v, indent+1)
+			s, err := t.serializeValue(skillName, fd, v, indent+1)
 			if err != nil {
 				return "", errors.Wrapf(err, "could not serialize list field %v", v)
 			}
 			listRepr = append(listRepr, s)
 		}
-		indentString := t.indentString(indent)
 		return fmt.Sprintf("[\n%s%s]", indentString, strings.Join(listRepr, fmt.Sprintf(",\n%s", indentString))), nil
-	case protoreflect.Map:
-		return "", fmt.Errorf("unimplemented: cannot serialize map field")
-	default:
-		return "", fmt.Errorf("unimplemented field type %T", value.Interface())
 	}
+	return t.serializeValue(skillName, fd, value, indent)
+}
+
+// serializeValue serializes a single (non-repeated) value of the given field.
+func (t *PythonSerializer) serializeValue(skillName string, fd protoreflect.FieldDescriptor, value protoreflect.Value, indent int) (string, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if value.Bool() {
+			return "True", nil
+		}
+		return "False", nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case protoreflect.FloatKind:
+		return formatFloat(value.Float(), 32), nil
+	case protoreflect.DoubleKind:
+		return formatFloat(value.Float(), 64), nil
+	case protoreflect.StringKind:
+		return quoteString(value.String()), nil
+	case protoreflect.BytesKind:
+		return quoteBytes(value.Bytes()), nil
+	case protoreflect.EnumKind:
+		return t.serializeEnum(skillName, fd.Enum(), value.Enum()), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		msg := value.Message()
+		switch msg.Descriptor().FullName() {
+		case anyFullName:
+			fields := msg.Descriptor().Fields()
+			return t.serializeAny(&anypb.Any{
+				TypeUrl: msg.Get(fields.ByName("type_url")).String(),
+				Value:   msg.Get(fields.ByName("value")).Bytes(),
+			}, indent)
+		case durationFullName:
+			if s, ok := serializeDuration(msg); ok {
+				return s, nil
+			}
+		}
+		return t.serializeMessage(skillName, msg, indent)
+	}
+	return "", fmt.Errorf("unimplemented field kind %v", fd.Kind())
+}
+
+// serializeEnum serializes an enum value by name, e.g.,
+// skills.my_skill.intrinsic_proto.test.MyMsg.Enum.VALUE_A.
+// Numbers which are not declared by the enum (possible for open enums) are serialized as
+// integers.
+func (t *PythonSerializer) serializeEnum(skillName string, ed protoreflect.EnumDescriptor, n protoreflect.EnumNumber) string {
+	v := ed.Values().ByNumber(n)
+	if v == nil {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return fmt.Sprintf("%s.%s.%s.%s", t.skillPrefix, skillName, ed.FullName(), v.Name())
+}
+
+// serializeDuration serializes a google.protobuf.Duration as datetime.timedelta, which the
+// solution building library converts automatically. Returns false if the duration cannot be
+// represented exactly, i.e., if it has sub-microsecond precision.
+func serializeDuration(msg protoreflect.Message) (string, bool) {
+	fields := msg.Descriptor().Fields()
+	seconds := msg.Get(fields.ByName("seconds")).Int()
+	nanos := msg.Get(fields.ByName("nanos")).Int()
+	if nanos%1000 != 0 {
+		return "", false
+	}
+	if nanos == 0 {
+		return fmt.Sprintf("datetime.timedelta(seconds=%d)", seconds), true
+	}
+	return fmt.Sprintf("datetime.timedelta(seconds=%d, microseconds=%d)", seconds, nanos/1000), true
+}
+
+func compareMapKeys(a, b protoreflect.MapKey) int {
+	switch a.Interface().(type) {
+	case bool:
+		if a.Bool() == b.Bool() {
+			return 0
+		}
+		if !a.Bool() {
+			return -1
+		}
+		return 1
+	case int32, int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case uint32, uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return `float("nan")`
+	case math.IsInf(f, 1):
+		return `float("inf")`
+	case math.IsInf(f, -1):
+		return `float("-inf")`
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(s, ".e") {
+		// Force a decimal point to indicate to Python that this is a float.
+		s += ".0"
+	}
+	return s
+}
+
+// quoteString returns a Python string literal for the given string.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '"':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// quoteBytes returns a Python bytes literal for the given bytes.
+func quoteBytes(value []byte) string {
+	var b strings.Builder
+	b.WriteString(`b"`)
+	for _, c := range value {
+		switch {
+		case c == '\\' || c == '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
 }
 
 func (t *PythonSerializer) serializeMessage(skillName string, msg protoreflect.Message, indent int) (string, error) {
@@ -294,17 +898,17 @@ func (t *PythonSerializer) serializeMessage(skillName string, msg protoreflect.M
 		if !refl.Has(field) {
 			continue
 		}
-		pythonRepr, err := t.serializeField(skillName, refl.Get(field), indent+1)
+		pythonRepr, err := t.serializeField(skillName, field, refl.Get(field), indent+1)
 		if err != nil {
 			return "", err
 		}
 		params = append(params, fmt.Sprintf("%s=%s", field.Name(), pythonRepr))
 	}
 
+	// Message wrappers are only available under their full proto name, the skill_utils shortcuts
+	// of the form skills.my_skill.MyMsg raise an AttributeError.
 	fullMsgName := msg.Interface().ProtoReflect().Descriptor().FullName()
-	parts := strings.Split(string(fullMsgName), ".")
-	shortMsgName := parts[len(parts)-1]
 	indentString := t.indentString(indent)
-	s := fmt.Sprintf("%s.%s.%s(\n%s%s)", t.skillPrefix, skillName, shortMsgName, indentString, strings.Join(params, fmt.Sprintf(",\n%s", indentString)))
+	s := fmt.Sprintf("%s.%s.%s(\n%s%s)", t.skillPrefix, skillName, fullMsgName, indentString, strings.Join(params, fmt.Sprintf(",\n%s", indentString)))
 	return s, nil
 }
@@ -8,7 +8,11 @@ import (
 	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+	bcpb "intrinsic/executive/proto/behavior_call_go_proto"
 	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
 	skillspb "intrinsic/skills/proto/skills_go_proto"
 	mypb "intrinsic/solutions/tools/proto/my_msg_go_proto"
@@ -74,6 +78,113 @@ const (
 			}
 		}
 	`
+	treeWithAllNodeTypes = `
+		name: "all_nodes"
+		root {
+			name: "root"
+			parallel {
+				failure_behavior: ABORT_REMAINING_CHILDREN
+				children {
+					selector {
+						children {
+							fail {}
+							decorators {
+								on_failure {
+									emit_extended_status {
+										to_blackboard_key: "err"
+										extended_status {
+											status_code { component: "ai.intrinsic.test" code: 42 }
+											title: "failed"
+										}
+									}
+								}
+							}
+						}
+						children {
+							debug { suspend { fail_on_resume: true } }
+						}
+					}
+				}
+				children {
+					fallback {
+						children {
+							branch {
+								if { blackboard { cel_expression: "x == 1" } }
+								then { data { remove { blackboard_key: "x" } } }
+								else { data { create_or_update { blackboard_key: "x" cel_expression: "1" } } }
+							}
+						}
+					}
+				}
+				children {
+					retry {
+						max_tries: 3
+						retry_counter_blackboard_key: "retry_counter"
+						child {
+							loop {
+								while {
+									all_of {
+										conditions { not { blackboard { cel_expression: "done" } } }
+										conditions {
+											status_match {
+												blackboard_key: "err"
+												status_code { component: "ai.intrinsic.test" code: 42 }
+											}
+										}
+									}
+								}
+								max_times: 5
+								loop_counter_blackboard_key: "loop_counter"
+								do {
+									task {
+										call_behavior {
+											skill_id: "my_skill"
+											parameters {
+												[type.googleapis.com/intrinsic_proto.solutions.tools.MyMsg] {
+													int32_value: 1
+												}
+											}
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+				children {
+					id: 7
+					decorators {
+						breakpoint: BEFORE
+						execution_settings { mode: DISABLED disabled_result_state: FAILED }
+					}
+					sub_tree {
+						tree {
+							name: "inner"
+							root {
+								data {
+									create_or_update {
+										blackboard_key: "msgs"
+										protos {
+											items {
+												[type.googleapis.com/intrinsic_proto.solutions.tools.MyMsg] {
+													string_value: "a"
+												}
+											}
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	`
+	treeWithProcessControl = `
+		root {
+			control_process { start {} }
+		}
+	`
 )
 
 func mustParseTree(t *testing.T, content string) *btpb.BehaviorTree {
@@ -86,28 +197,46 @@ func mustParseTree(t *testing.T, content string) *btpb.BehaviorTree {
 	return bt
 }
 
-func TestSerializeToPythonCode(t *testing.T) {
-	bt := mustParseTree(t, treeWithData)
+// fileDescriptorSet returns the given file and its transitive dependencies, dependencies first.
+func fileDescriptorSet(fd protoreflect.FileDescriptor) *dpb.FileDescriptorSet {
+	set := &dpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		for i := 0; i < fd.Imports().Len(); i++ {
+			add(fd.Imports().Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(fd)
+	return set
+}
 
+func mySkills() []*skillspb.Skill {
 	msg := mypb.MyMsg{}
 	refl := msg.ProtoReflect()
 	fd := refl.Descriptor().ParentFile()
-	fdp := protodesc.ToFileDescriptorProto(fd)
 
-	sk := []*skillspb.Skill{
+	return []*skillspb.Skill{
 		&skillspb.Skill{
 			Id:        "my_skill",
 			SkillName: "my_skill",
 			ParameterDescription: &skillspb.ParameterDescription{
-				ParameterMessageFullName: "intrinsic_proto.solutions.tools.MyMsg",
-				ParameterDescriptorFileset: &dpb.FileDescriptorSet{
-					File: []*dpb.FileDescriptorProto{fdp},
-				},
+				ParameterMessageFullName:   "intrinsic_proto.solutions.tools.MyMsg",
+				ParameterDescriptorFileset: fileDescriptorSet(fd),
 			},
 		},
 	}
+}
 
-	serializer, err := NewPythonSerializer(sk)
+func TestSerializeToPythonCode(t *testing.T) {
+	bt := mustParseTree(t, treeWithData)
+
+	serializer, err := NewPythonSerializer(mySkills())
 	if err != nil {
 		t.Fatalf("failed to create serializer: %v", err)
 	}
@@ -125,11 +254,11 @@ func TestSerializeToPythonCode(t *testing.T) {
   float_value=5.5,
   double_value=6.6,
   string_value="my_msg",
-  enum_value=1,
-  msg_value=skills.my_skill.MyMsg(
+  enum_value=skills.my_skill.intrinsic_proto.solutions.tools.MyMsg.Enum.VALUE_A,
+  msg_value=skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
     string_value="nested_msg"),
   repeated_msg_value=[
-    skills.my_skill.MyMsg(
+    skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
       string_value="repeated_msg")]))
 my_skill_2 = bt.Task(action=skills.my_skill(
   float_value=5.0,
@@ -143,3 +272,402 @@ tree = bt.BehaviorTree(name="tree_name", root=sequence)
 		t.Errorf("getProcess() returned diff (-want +got):\n%s\n\ngot:\n%s\n\nwant:\n%s", diff, got, expected)
 	}
 }
+
+func TestSerializeAllNodeTypes(t *testing.T) {
+	bt := mustParseTree(t, treeWithAllNodeTypes)
+
+	serializer, err := NewPythonSerializer(mySkills())
+	if err != nil {
+		t.Fatalf("failed to create serializer: %v", err)
+	}
+	got, err := serializer.Serialize(bt)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+
+	expected := `fail = bt.Fail()
+fail.on_failure.emit_extended_status("ai.intrinsic.test", 42, title="failed", to_blackboard_key="err")
+debug = bt.Debug(fail_on_resume=True)
+selector = bt.Selector(children=[fail, debug])
+data = bt.Data(blackboard_key="x", operation=bt.Data.OperationType.REMOVE)
+data_2 = bt.Data(blackboard_key="x", cel_expression="1")
+branch = bt.Branch(if_condition=bt.Blackboard("x == 1"), then_child=data, else_child=data_2)
+fallback = bt.Fallback(children=[branch])
+my_skill = bt.Task(action=skills.my_skill(
+  int32_value=1))
+loop = bt.Loop(max_times=5, do_child=my_skill, while_condition=bt.AllOf([bt.Not(bt.Blackboard("done")), bt.ExtendedStatusMatch("err", bt.ExtendedStatusMatch.MatchStatusCode("ai.intrinsic.test", 42))]), loop_counter_key="loop_counter")
+retry = bt.Retry(max_tries=3, child=loop, retry_counter_key="retry_counter")
+data_3 = bt.Data(blackboard_key="msgs", protos=[
+  skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
+    string_value="a")])
+inner = bt.BehaviorTree(name="inner", root=data_3)
+sub_tree = bt.SubTree(behavior_tree=inner)
+sub_tree.node_id = 7
+sub_tree.set_decorators(bt.Decorators(breakpoint_type=bt.BreakpointType.BEFORE, execution_mode=bt.NodeExecutionMode.DISABLED, disabled_result_state=bt.DisabledResultState.FAILED))
+root = bt.Parallel(name="root", children=[selector, fallback, retry, sub_tree], failure_behavior=bt.Parallel.FailureBehavior.ABORT_REMAINING_CHILDREN)
+tree = bt.BehaviorTree(name="all_nodes", root=root)
+`
+	if diff := cmp.Diff(expected, string(got)); diff != "" {
+		t.Errorf("Serialize() returned diff (-want +got):\n%s\n\ngot:\n%s\n\nwant:\n%s", diff, got, expected)
+	}
+}
+
+func TestSerializeNodeNames(t *testing.T) {
+	bt := mustParseTree(t, `
+		root {
+			sequence {
+				children { name: "Pick & Place" fail {} }
+				children { name: "1st try" fail {} }
+				children { name: "pass" fail {} }
+				children { name: "skills" fail {} }
+				children { name: "gripper.open" fail {} }
+				children { name: "gripper-open" fail {} }
+			}
+		}
+	`)
+
+	serializer, err := NewPythonSerializer(mySkills())
+	if err != nil {
+		t.Fatalf("failed to create serializer: %v", err)
+	}
+	got, err := serializer.Serialize(bt)
+	if err != nil {
+		t.Fatalf("Serialize() failed: %v", err)
+	}
+
+	expected := `pick___place = bt.Fail(name="Pick & Place")
+_1st_try = bt.Fail(name="1st try")
+pass_2 = bt.Fail(name="pass")
+skills_2 = bt.Fail(name="skills")
+gripper_open = bt.Fail(name="gripper.open")
+gripper_open_2 = bt.Fail(name="gripper-open")
+sequence = bt.Sequence(children=[pick___place, _1st_try, pass_2, skills_2, gripper_open, gripper_open_2])
+tree = bt.BehaviorTree(root=sequence)
+`
+	if diff := cmp.Diff(expected, string(got)); diff != "" {
+		t.Errorf("Serialize() returned diff (-want +got):\n%s\n\ngot:\n%s", diff, got)
+	}
+}
+
+func TestSerializeWorldQuery(t *testing.T) {
+	bt := mustParseTree(t, `
+		root {
+			data {
+				create_or_update {
+					blackboard_key: "objects"
+					from_world {
+						proto {
+							[type.googleapis.com/intrinsic_proto.executive.WorldQuery] {
+								select { children_of { by_name { object_name: "table" } } }
+								filter { name_regex: "box_.*" }
+								order { by: NAME direction: DESCENDING }
+							}
+						}
+						assign { path: "select.children_of" cel_expression: "parent" }
+					}
+				}
+			}
+		}
+	`)
+
+	serializer, err := NewPythonSerializer(mySkills())
+	if err != nil {
+		t.Fatalf("failed to create serializer: %v", err)
+	}
+	got, err := serializer.Serialize(bt)
+	if err != nil {
+		t.Fatalf("Serialize() failed: %v", err)
+	}
+
+	expected := `data = bt.Data(blackboard_key="objects", world_query=bt.WorldQuery(world_query_pb2.WorldQuery(select={"children_of": {"by_name": {"object_name": "table"}}}, filter={"name_regex": "box_.*"}, order={"by": "NAME", "direction": "DESCENDING"})))
+data.world_query.assignments.append(any_with_assignments_pb2.AnyWithAssignments.Assignment(path="select.children_of", cel_expression="parent"))
+tree = bt.BehaviorTree(root=data)
+`
+	if diff := cmp.Diff(expected, string(got)); diff != "" {
+		t.Errorf("Serialize() returned diff (-want +got):\n%s\n\ngot:\n%s", diff, got)
+	}
+
+	if err := VerifyRoundTrip(bt, mySkills()); err != nil {
+		t.Errorf("VerifyRoundTrip() failed: %v", err)
+	}
+}
+
+func TestSerializeUnsupportedNodeType(t *testing.T) {
+	bt := mustParseTree(t, treeWithProcessControl)
+
+	serializer, err := NewPythonSerializer(mySkills())
+	if err != nil {
+		t.Fatalf("failed to create serializer: %v", err)
+	}
+	if _, err := serializer.Serialize(bt); err == nil {
+		t.Errorf("Serialize() succeeded for a process control node, want error")
+	}
+}
+
+// taskTree returns a tree with a single task calling my_skill with the given parameters.
+func taskTree(t *testing.T, params proto.Message) *btpb.BehaviorTree {
+	t.Helper()
+	packed, err := anypb.New(params)
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	return &btpb.BehaviorTree{
+		Root: &btpb.BehaviorTree_Node{
+			NodeType: &btpb.BehaviorTree_Node_Task{Task: &btpb.BehaviorTree_TaskNode{
+				TaskType: &btpb.BehaviorTree_TaskNode_CallBehavior{CallBehavior: &bcpb.BehaviorCall{
+					SkillId:    "my_skill",
+					Parameters: packed,
+				}},
+			}},
+		},
+	}
+}
+
+func TestSerializeFieldKinds(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		want   string
+	}{
+		{
+			name: "integers",
+			params: `
+				bool_value: true
+				int32_value: -7
+				int64_value: -8
+				uint32_value: 9
+				uint64_value: 18446744073709551615
+				sint32_value: -1
+				sint64_value: -2
+				fixed32_value: 3
+				fixed64_value: 4
+				sfixed32_value: -5
+				sfixed64_value: -6
+			`,
+			want: `bool_value=True,
+  int32_value=-7,
+  int64_value=-8,
+  uint32_value=9,
+  uint64_value=18446744073709551615,
+  sint32_value=-1,
+  sint64_value=-2,
+  fixed32_value=3,
+  fixed64_value=4,
+  sfixed32_value=-5,
+  sfixed64_value=-6`,
+		},
+		{
+			name: "floats",
+			params: `
+				float_value: inf
+				double_value: nan
+				repeated_double_value: [-inf, 1e21, 0.5, 2]
+			`,
+			want: `float_value=float("inf"),
+  double_value=float("nan"),
+  repeated_double_value=[
+    float("-inf"),
+    1e+21,
+    0.5,
+    2.0]`,
+		},
+		{
+			name: "strings and bytes",
+			params: `
+				string_value: "a\"b\\c\nd\x01ü"
+				bytes_value: "\x00\xffab\"\\"
+			`,
+			want: `string_value="a\"b\\c\nd\u0001ü",
+  bytes_value=b"\x00\xffab\"\\"`,
+		},
+		{
+			name: "enums",
+			params: `
+				enum_value: VALUE_B
+				repeated_enum_value: [VALUE_A, UNKNOWN]
+			`,
+			want: `enum_value=skills.my_skill.intrinsic_proto.solutions.tools.MyMsg.Enum.VALUE_B,
+  repeated_enum_value=[
+    skills.my_skill.intrinsic_proto.solutions.tools.MyMsg.Enum.VALUE_A,
+    skills.my_skill.intrinsic_proto.solutions.tools.MyMsg.Enum.UNKNOWN]`,
+		},
+		{
+			name:   "undeclared enum number",
+			params: `enum_value: 7`,
+			want:   `enum_value=7`,
+		},
+		{
+			name: "repeated scalars",
+			params: `
+				repeated_int32_value: [1, -2]
+				repeated_string_value: ["a", "b"]
+			`,
+			want: `repeated_int32_value=[
+    1,
+    -2],
+  repeated_string_value=[
+    "a",
+    "b"]`,
+		},
+		{
+			name: "repeated messages",
+			params: `
+				repeated_msg_value { string_value: "a" }
+				repeated_msg_value { msg_value { int32_value: 1 } }
+			`,
+			want: `repeated_msg_value=[
+    skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
+      string_value="a"),
+    skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
+      msg_value=skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
+        int32_value=1))]`,
+		},
+		{
+			name: "maps",
+			params: `
+				string_int32_map { key: "b" value: 2 }
+				string_int32_map { key: "a" value: 1 }
+				int64_msg_map { key: 10 value { string_value: "ten" } }
+				int64_msg_map { key: -1 value {} }
+				bool_enum_map { key: true value: VALUE_A }
+				bool_enum_map { key: false value: VALUE_B }
+			`,
+			want: `string_int32_map={
+    "a": 1,
+    "b": 2},
+  int64_msg_map={
+    -1: skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
+      ),
+    10: skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
+      string_value="ten")},
+  bool_enum_map={
+    False: skills.my_skill.intrinsic_proto.solutions.tools.MyMsg.Enum.VALUE_B,
+    True: skills.my_skill.intrinsic_proto.solutions.tools.MyMsg.Enum.VALUE_A}`,
+		},
+		{
+			name: "oneof and optional with default values",
+			params: `
+				optional_int32_value: 0
+				oneof_string_value: ""
+			`,
+			want: `optional_int32_value=0,
+  oneof_string_value=""`,
+		},
+		{
+			name:   "oneof message",
+			params: `oneof_msg_value { string_value: "x" }`,
+			want: `oneof_msg_value=skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
+    string_value="x")`,
+		},
+		{
+			name: "well-known types",
+			params: `
+				duration_value { seconds: 1 nanos: 500000000 }
+				timestamp_value { seconds: 1700000000 nanos: 5 }
+				double_wrapper_value { value: 1.5 }
+				string_wrapper_value {}
+			`,
+			want: `duration_value=datetime.timedelta(seconds=1, microseconds=500000),
+  timestamp_value=skills.my_skill.google.protobuf.Timestamp(
+    seconds=1700000000,
+    nanos=5),
+  double_wrapper_value=skills.my_skill.google.protobuf.DoubleValue(
+    value=1.5),
+  string_wrapper_value=skills.my_skill.google.protobuf.StringValue(
+    )`,
+		},
+		{
+			name:   "negative duration",
+			params: `duration_value { seconds: -2 nanos: -250000000 }`,
+			want:   `duration_value=datetime.timedelta(seconds=-2, microseconds=-250000)`,
+		},
+		{
+			name:   "duration with sub-microsecond precision",
+			params: `duration_value { seconds: -1 nanos: -5 }`,
+			want: `duration_value=skills.my_skill.google.protobuf.Duration(
+    seconds=-1,
+    nanos=-5)`,
+		},
+		{
+			name: "nested any",
+			params: `
+				any_value {
+					[type.googleapis.com/intrinsic_proto.solutions.tools.MyMsg] {
+						string_value: "packed"
+						enum_value: VALUE_A
+					}
+				}
+				repeated_any_value {
+					[type.googleapis.com/intrinsic_proto.solutions.tools.MyMsg] { int32_value: 1 }
+				}
+				repeated_any_value {
+					[type.googleapis.com/google.protobuf.DoubleValue] { value: 2 }
+				}
+			`,
+			want: `any_value=skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
+    string_value="packed",
+    enum_value=skills.my_skill.intrinsic_proto.solutions.tools.MyMsg.Enum.VALUE_A),
+  repeated_any_value=[
+    skills.my_skill.intrinsic_proto.solutions.tools.MyMsg(
+      int32_value=1),
+    skills.my_skill.google.protobuf.DoubleValue(
+      value=2.0)]`,
+		},
+	}
+
+	// Every kind must be covered by at least one of the test cases. Groups do not exist in proto3.
+	covered := map[protoreflect.Kind]bool{protoreflect.GroupKind: true}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			params := &mypb.MyMsg{}
+			if err := prototext.Unmarshal([]byte(tc.params), params); err != nil {
+				t.Fatalf("prototext.Unmarshal() failed: %v", err)
+			}
+			params.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+				covered[fd.Kind()] = true
+				if fd.IsMap() {
+					covered[fd.MapKey().Kind()] = true
+					covered[fd.MapValue().Kind()] = true
+				}
+				return true
+			})
+			bt := taskTree(t, params)
+
+			serializer, err := NewPythonSerializer(mySkills())
+			if err != nil {
+				t.Fatalf("failed to create serializer: %v", err)
+			}
+			got, err := serializer.Serialize(bt)
+			if err != nil {
+				t.Fatalf("Serialize() failed: %v", err)
+			}
+			want := "my_skill = bt.Task(action=skills.my_skill(\n  " + tc.want + "))\ntree = bt.BehaviorTree(root=my_skill)\n"
+			if diff := cmp.Diff(want, string(got)); diff != "" {
+				t.Errorf("Serialize() returned diff (-want +got):\n%s\n\ngot:\n%s", diff, got)
+			}
+
+			if err := VerifyRoundTrip(bt, mySkills()); err != nil {
+				t.Errorf("VerifyRoundTrip() failed: %v", err)
+			}
+		})
+	}
+	for k := protoreflect.DoubleKind; k <= protoreflect.Sint64Kind; k++ {
+		if !covered[k] {
+			t.Errorf("no test case covers fields of kind %v", k)
+		}
+	}
+}
+
+func TestSerializeUnknownAnyType(t *testing.T) {
+	bt := taskTree(t, &mypb.MyMsg{
+		AnyValue: &anypb.Any{TypeUrl: "type.googleapis.com/com.example.Unknown"},
+	})
+
+	serializer, err := NewPythonSerializer(mySkills())
+	if err != nil {
+		t.Fatalf("failed to create serializer: %v", err)
+	}
+	if _, err := serializer.Serialize(bt); err == nil {
+		t.Errorf("Serialize() succeeded for an Any of unknown type, want error")
+	}
+}
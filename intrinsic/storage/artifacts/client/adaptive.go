@@ -0,0 +1,150 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/golang/glog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UploadMode describes how an uploader transfers blobs to the service.
+type UploadMode struct {
+	// Streaming is true if every blob is sent over a single stream and false
+	// if it is sent as a sequence of unary calls.
+	Streaming bool
+	// Parallelism is the maximum number of blobs uploaded at the same time.
+	Parallelism int
+}
+
+func (m UploadMode) String() string {
+	if m.Streaming {
+		return fmt.Sprintf("streaming (parallelism %d)", m.Parallelism)
+	}
+	return fmt.Sprintf("sequential (parallelism %d)", m.Parallelism)
+}
+
+func (m UploadMode) strategy() uploadStrategy {
+	if m.Streaming {
+		return streamingUpload
+	}
+	return nonStreamingUpload
+}
+
+// UploadModeUpdate reports the upload mode chosen by an adaptive uploader.
+type UploadModeUpdate struct {
+	Mode UploadMode
+	// Downgrades counts how many times the uploader switched to a safer mode.
+	Downgrades int
+	// Err is the failure which caused the last downgrade, nil for the initial
+	// mode.
+	Err error
+}
+
+// UploadModeMonitor can be implemented by a ProgressMonitor to receive
+// updates about the mode chosen by adaptive uploads.
+type UploadModeMonitor interface {
+	// UpdateUploadMode is called with the initial mode and after every downgrade.
+	UpdateUploadMode(update UploadModeUpdate)
+}
+
+// modeController picks the upload mode of an uploader. Adaptive uploaders
+// start with the fastest mode and fall back to safer modes once uploads over
+// their connection run into limits of reverse proxies, like the cloud-relay
+// nginx ingress. Downgrades stick for the lifetime of the uploader.
+type modeController struct {
+	adaptive bool
+
+	mu         sync.Mutex
+	modes      []UploadMode
+	current    int
+	downgrades int
+	reported   bool
+}
+
+func newModeController(options uploaderOptions) *modeController {
+	parallelism := options.maxNumberOfUploads
+	if !options.adaptive {
+		return &modeController{modes: []UploadMode{{
+			Streaming:   options.strategy == streamingUpload,
+			Parallelism: parallelism,
+		}}}
+	}
+	modes := []UploadMode{
+		{Streaming: true, Parallelism: parallelism},
+		{Streaming: false, Parallelism: parallelism},
+	}
+	if parallelism > 1 {
+		modes = append(modes, UploadMode{Streaming: false, Parallelism: 1})
+	}
+	return &modeController{adaptive: true, modes: modes}
+}
+
+// mode returns the mode to use for the next upload.
+func (c *modeController) mode(ctx context.Context) UploadMode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	mode := c.modes[c.current]
+	if c.adaptive && !c.reported {
+		c.reported = true
+		reportUploadMode(ctx, UploadModeUpdate{Mode: mode})
+	}
+	return mode
+}
+
+// downgrade switches to the next safer mode after an upload in mode failed
+// with err. It returns false if the upload should not be retried, because err
+// is not caused by proxy limits or there is no safer mode left.
+func (c *modeController) downgrade(ctx context.Context, mode UploadMode, err error) bool {
+	if !c.adaptive || !isProxyLimitError(ctx, err) {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.modes[c.current] != mode {
+		// a concurrent upload downgraded already.
+		return true
+	}
+	if c.current+1 >= len(c.modes) {
+		return false
+	}
+	c.current++
+	c.downgrades++
+	log.WarningContextf(ctx, "switching to %s upload after failure: %s", c.modes[c.current], err)
+	reportUploadMode(ctx, UploadModeUpdate{
+		Mode:       c.modes[c.current],
+		Downgrades: c.downgrades,
+		Err:        err,
+	})
+	return true
+}
+
+func reportUploadMode(ctx context.Context, update UploadModeUpdate) {
+	if monitor, ok := getProgressMonitor(ctx).(UploadModeMonitor); ok {
+		monitor.UpdateUploadMode(update)
+	}
+}
+
+// isProxyLimitError tells if err looks like a reverse proxy between us and
+// the service cut the upload short, e.g. because of its body size, timeout or
+// concurrent stream limits.
+func isProxyLimitError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	case codes.Internal, codes.Unknown:
+		message := status.Convert(err).Message()
+		return strings.Contains(message, "RST_STREAM") ||
+			strings.Contains(message, "unexpected HTTP status code")
+	default:
+		return false
+	}
+}
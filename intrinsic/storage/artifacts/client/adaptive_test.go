@@ -0,0 +1,141 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"intrinsic/storage/artifacts/client/artifacttest"
+)
+
+var errStreamReset = status.Error(codes.Internal, "stream terminated by RST_STREAM with error code: PROTOCOL_ERROR")
+
+type modeRecorder struct {
+	mu    sync.Mutex
+	modes []UploadMode
+}
+
+func (r *modeRecorder) UpdateProgress(string, ProgressUpdate) {}
+
+func (r *modeRecorder) UpdateUploadMode(update UploadModeUpdate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modes = append(r.modes, update.Mode)
+}
+
+func TestAdaptiveUpload(t *testing.T) {
+	streaming := UploadMode{Streaming: true, Parallelism: 4}
+	sequential := UploadMode{Streaming: false, Parallelism: 4}
+	single := UploadMode{Streaming: false, Parallelism: 1}
+	tests := []struct {
+		name      string
+		fail      func(*artifacttest.Server)
+		wantModes []UploadMode
+	}{
+		{
+			name:      "no failures",
+			fail:      func(*artifacttest.Server) {},
+			wantModes: []UploadMode{streaming},
+		},
+		{
+			name: "stream reset",
+			fail: func(s *artifacttest.Server) {
+				s.FailNext(artifacttest.MethodUploadContent, "", 1, errStreamReset)
+			},
+			wantModes: []UploadMode{streaming, sequential},
+		},
+		{
+			name: "request too large",
+			fail: func(s *artifacttest.Server) {
+				s.FailNext(artifacttest.MethodUploadContent, "", 1, status.Error(codes.Unknown, "unexpected HTTP status code received from server: 413 (Request Entity Too Large)"))
+			},
+			wantModes: []UploadMode{streaming, sequential},
+		},
+		{
+			name: "unavailable",
+			fail: func(s *artifacttest.Server) {
+				s.FailNext(artifacttest.MethodUploadContent, "", 1, status.Error(codes.Unavailable, "upstream connect error"))
+			},
+			wantModes: []UploadMode{streaming, sequential},
+		},
+		{
+			name: "limits in every mode",
+			fail: func(s *artifacttest.Server) {
+				s.FailNext(artifacttest.MethodUploadContent, "", 1, errStreamReset)
+				s.FailNext(artifacttest.MethodWriteContent, "", 1, status.Error(codes.ResourceExhausted, "too many streams"))
+			},
+			wantModes: []UploadMode{streaming, sequential, single},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := artifacttest.NewServer()
+			tc.fail(server)
+			uploader := newTestUploader(t, server.Client(t), WithAdaptiveUpload(), WithMaxResumes(0))
+			img := randomImage(t)
+			recorder := &modeRecorder{}
+			ctx := SetProgressMonitor(context.Background(), recorder)
+
+			if err := uploader.UploadImage(ctx, testImageName, img); err != nil {
+				t.Fatalf("UploadImage() failed: %v", err)
+			}
+
+			checkImageStored(t, server, img, k8sNames(t, img)...)
+			if diff := cmp.Diff(tc.wantModes, recorder.modes); diff != "" {
+				t.Errorf("UploadImage() reported unexpected modes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAdaptiveUploadKeepsDowngrade(t *testing.T) {
+	server := artifacttest.NewServer()
+	server.FailNext(artifacttest.MethodUploadContent, "", 1, errStreamReset)
+	uploader := newTestUploader(t, server.Client(t), WithAdaptiveUpload())
+
+	if err := uploader.UploadImage(context.Background(), testImageName, randomImage(t)); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+	streams := server.Calls(artifacttest.MethodUploadContent)
+	if err := uploader.UploadImage(context.Background(), "gcr.io/test-project/other:latest", randomImage(t)); err != nil {
+		t.Fatalf("UploadImage() of second image failed: %v", err)
+	}
+
+	if got := server.Calls(artifacttest.MethodUploadContent); got != streams {
+		t.Errorf("UploadImage() after downgrade opened %d streams, want none", got-streams)
+	}
+}
+
+func TestAdaptiveUploadDoesNotDowngradeOtherErrors(t *testing.T) {
+	server := artifacttest.NewServer()
+	server.FailNext(artifacttest.MethodUploadContent, "", 1, status.Error(codes.PermissionDenied, "denied"))
+	uploader := newTestUploader(t, server.Client(t), WithAdaptiveUpload())
+	recorder := &modeRecorder{}
+	ctx := SetProgressMonitor(context.Background(), recorder)
+
+	if err := uploader.UploadImage(ctx, testImageName, randomImage(t)); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("UploadImage() returned error %v, want PermissionDenied", err)
+	}
+
+	if got := server.Calls(artifacttest.MethodWriteContent); got != 0 {
+		t.Errorf("UploadImage() made %d sequential writes, want none", got)
+	}
+	if len(recorder.modes) != 1 {
+		t.Errorf("UploadImage() reported modes %v, want only the initial mode", recorder.modes)
+	}
+}
+
+func TestFixedUploadModeDoesNotDowngrade(t *testing.T) {
+	server := artifacttest.NewServer()
+	server.FailNext(artifacttest.MethodUploadContent, "", 1, errStreamReset)
+	uploader := newTestUploader(t, server.Client(t), WithStreamingUpload(), WithMaxResumes(0))
+
+	if err := uploader.UploadImage(context.Background(), testImageName, randomImage(t)); status.Code(err) != codes.Internal {
+		t.Fatalf("UploadImage() returned error %v, want Internal", err)
+	}
+}
@@ -0,0 +1,520 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package artifacttest provides an in-memory ArtifactServiceApi for tests of code which uploads
+// images to the artifact service.
+package artifacttest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/local"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	artifactgrpcpb "intrinsic/storage/artifacts/proto/artifact_go_grpc_proto"
+	artifactpb "intrinsic/storage/artifacts/proto/artifact_go_grpc_proto"
+	"intrinsic/testing/grpctest"
+)
+
+// Names of the methods of the ArtifactServiceApi, used to inject failures and count calls.
+const (
+	MethodCheckImage         = "CheckImage"
+	MethodWriteContent       = "WriteContent"
+	MethodWriteContentStream = "WriteContentStream"
+	MethodUploadContent      = "UploadContent"
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithMaxUpdateSize sets the maximum chunk size the server announces in CheckImage and accepts in
+// updates. Zero, the default, lets clients choose the chunk size.
+func WithMaxUpdateSize(size int32) Option {
+	return func(s *Server) {
+		s.maxUpdateSize = size
+	}
+}
+
+// WithLatency delays every call to the server.
+func WithLatency(latency time.Duration) Option {
+	return func(s *Server) {
+		s.latency = latency
+	}
+}
+
+// WithoutStat makes the server reject updates with the STAT action as unimplemented, like services
+// which cannot report the progress of pending writes.
+func WithoutStat() Option {
+	return func(s *Server) {
+		s.withoutStat = true
+	}
+}
+
+type pendingWrite struct {
+	expectedDigest string
+	lastChunkID    int64
+	data           []byte
+}
+
+type failure struct {
+	method string
+	ref    string
+	times  int
+	err    error
+}
+
+type interruption struct {
+	ref    string
+	offset int64
+	err    error
+	// whether the update which reaches offset is applied before failing.
+	applied bool
+}
+
+// Server is an in-memory implementation of the ArtifactServiceApi. Content is stored by its
+// reference, which is the digest of blobs and the digest or name of manifests. It is safe for
+// concurrent use.
+type Server struct {
+	artifactgrpcpb.UnimplementedArtifactServiceApiServer
+
+	maxUpdateSize int32
+	latency       time.Duration
+	withoutStat   bool
+
+	mu            sync.Mutex
+	content       map[string][]byte
+	pending       map[string]*pendingWrite
+	attempts      map[string]int
+	received      map[string]int64
+	calls         map[string]int
+	failures      []*failure
+	interruptions []*interruption
+}
+
+// NewServer creates an empty Server.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		content:  map[string][]byte{},
+		pending:  map[string]*pendingWrite{},
+		attempts: map[string]int{},
+		received: map[string]int64{},
+		calls:    map[string]int{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start serves s until the test ends and returns a connection to it.
+func (s *Server) Start(t testing.TB) *grpc.ClientConn {
+	t.Helper()
+	srv := grpc.NewServer()
+	artifactgrpcpb.RegisterArtifactServiceApiServer(srv, s)
+	address := grpctest.StartServerT(t, srv)
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(local.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create client for %s: %v", address, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// Client serves s like Start and returns a client connected to it.
+func (s *Server) Client(t testing.TB) artifactgrpcpb.ArtifactServiceApiClient {
+	t.Helper()
+	return artifactgrpcpb.NewArtifactServiceApiClient(s.Start(t))
+}
+
+// Put stores content under ref as if it was uploaded before. Writing the same content to ref fails
+// with AlreadyExists afterwards.
+func (s *Server) Put(ref string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content[ref] = slices.Clone(content)
+}
+
+// Content returns the committed content stored under ref.
+func (s *Server) Content(ref string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.content[ref]
+	return slices.Clone(content), ok
+}
+
+// Refs returns the sorted references of all committed content.
+func (s *Server) Refs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refs := make([]string, 0, len(s.content))
+	for ref := range s.content {
+		refs = append(refs, ref)
+	}
+	slices.Sort(refs)
+	return refs
+}
+
+// Calls returns the number of calls of method.
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// WriteAttempts returns the number of writes of ref which were started, including writes which
+// failed because ref already exists.
+func (s *Server) WriteAttempts(ref string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[ref]
+}
+
+// Received returns the number of content bytes received for writes of ref, including bytes which
+// were sent again or belong to writes which failed.
+func (s *Server) Received(ref string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received[ref]
+}
+
+// FailNext makes the next times calls of method for ref fail with err before they are handled.
+// An empty ref matches all references. Streaming calls fail after their first request.
+func (s *Server) FailNext(method string, ref string, times int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure{method: method, ref: ref, times: times, err: err})
+}
+
+// InterruptAfter makes the next write of ref fail with err once the server received at least offset
+// bytes of it. The received bytes stay pending, like when a connection drops mid-write, so the write
+// can be resumed.
+func (s *Server) InterruptAfter(ref string, offset int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interruptions = append(s.interruptions, &interruption{ref: ref, offset: offset, err: err, applied: true})
+}
+
+// RejectAfter makes the next update of ref fail with err without applying it once the server
+// received at least offset bytes of it. The received bytes stay pending, like when an update gets
+// lost on its way to the server.
+func (s *Server) RejectAfter(ref string, offset int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interruptions = append(s.interruptions, &interruption{ref: ref, offset: offset, err: err})
+}
+
+// enter delays a call by the configured latency, counts it and returns an injected failure.
+func (s *Server) enter(ctx context.Context, method string, ref string) error {
+	if s.latency > 0 {
+		timer := time.NewTimer(s.latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+	for i, f := range s.failures {
+		if f.method == method && (f.ref == "" || f.ref == ref) {
+			if f.times--; f.times <= 0 {
+				s.failures = slices.Delete(s.failures, i, i+1)
+			}
+			return f.err
+		}
+	}
+	return nil
+}
+
+// CheckImage reports which of the blobs referenced by the image manifest are stored.
+func (s *Server) CheckImage(ctx context.Context, req *artifactpb.ImageRequest) (*artifactpb.ArtifactResponse, error) {
+	if err := s.enter(ctx, MethodCheckImage, req.GetName()); err != nil {
+		return nil, err
+	}
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "image name is required")
+	}
+	refs, err := referencedDigests(req.GetManifest().GetData())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid manifest of %s: %v", req.GetName(), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &artifactpb.ArtifactResponse{
+		Ref:           req.GetName(),
+		MaxUpdateSize: s.maxUpdateSize,
+	}
+	if content, ok := s.content[req.GetName()]; ok {
+		resp.Digest = proto.String(digestOf(content))
+	}
+	if digest := req.GetManifest().GetDigest(); digest != "" {
+		refs = append(refs, digest)
+	}
+	for _, ref := range refs {
+		if _, ok := s.content[ref]; ok {
+			resp.PresentRefs = append(resp.PresentRefs, ref)
+		} else {
+			resp.MissingRefs = append(resp.MissingRefs, ref)
+		}
+	}
+	resp.Available = len(resp.MissingRefs) == 0
+	return resp, nil
+}
+
+// WriteContent applies a single update of a write.
+func (s *Server) WriteContent(ctx context.Context, req *artifactpb.UpdateRequest) (*artifactpb.UpdateResponse, error) {
+	if err := s.enter(ctx, MethodWriteContent, req.GetRef()); err != nil {
+		return nil, err
+	}
+	return s.update(req)
+}
+
+// WriteContentStream applies a stream of updates of one write and responds to every update. Writes
+// stay pending when the stream ends before they are committed.
+func (s *Server) WriteContentStream(stream artifactgrpcpb.ArtifactServiceApi_WriteContentStreamServer) error {
+	var ref string
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// the pending write stays around to be resumed.
+			return err
+		}
+		if ref == "" {
+			if err := s.enter(stream.Context(), MethodWriteContentStream, req.GetRef()); err != nil {
+				return err
+			}
+			ref = req.GetRef()
+		} else if req.GetRef() != ref {
+			s.drop(ref)
+			return status.Errorf(codes.InvalidArgument, "stream of %s received an update of %s", ref, req.GetRef())
+		}
+		resp, err := s.update(req)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+		if isTerminal(req.GetAction()) {
+			return nil
+		}
+	}
+}
+
+// UploadContent applies a stream of updates of one write and responds once it was committed or
+// aborted. Writes stay pending when the stream ends before they are committed.
+func (s *Server) UploadContent(stream artifactgrpcpb.ArtifactServiceApi_UploadContentServer) error {
+	var ref string
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return status.Errorf(codes.Aborted, "upload of %s ended without commit", ref)
+		}
+		if err != nil {
+			// the pending write stays around to be resumed.
+			return err
+		}
+		if ref == "" {
+			if err := s.enter(stream.Context(), MethodUploadContent, req.GetRef()); err != nil {
+				return err
+			}
+			ref = req.GetRef()
+		} else if req.GetRef() != ref {
+			s.drop(ref)
+			return status.Errorf(codes.InvalidArgument, "upload of %s received an update of %s", ref, req.GetRef())
+		}
+		resp, err := s.update(req)
+		if err != nil {
+			return err
+		}
+		if isTerminal(req.GetAction()) {
+			return stream.SendAndClose(resp)
+		}
+	}
+}
+
+// update applies one update of a write. Any error discards the pending write of the reference,
+// except for injected interruptions and unsupported STAT updates.
+func (s *Server) update(req *artifactpb.UpdateRequest) (*artifactpb.UpdateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.withoutStat && req.GetAction() == artifactpb.UpdateAction_UPDATE_ACTION_STAT {
+		return nil, status.Error(codes.Unimplemented, "stat is not supported")
+	}
+	if err := s.interruptLocked(req.GetRef(), false); err != nil {
+		return nil, err
+	}
+	resp, err := s.updateLocked(req)
+	if err != nil {
+		delete(s.pending, req.GetRef())
+		return nil, err
+	}
+	if err := s.interruptLocked(req.GetRef(), true); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// interruptLocked returns the error of the first interruption of ref which is due, either after
+// updates were applied or before.
+func (s *Server) interruptLocked(ref string, applied bool) error {
+	w, ok := s.pending[ref]
+	if !ok {
+		return nil
+	}
+	for i, in := range s.interruptions {
+		if in.ref == ref && in.applied == applied && int64(len(w.data)) >= in.offset {
+			s.interruptions = slices.Delete(s.interruptions, i, i+1)
+			return in.err
+		}
+	}
+	return nil
+}
+
+func (s *Server) updateLocked(req *artifactpb.UpdateRequest) (*artifactpb.UpdateResponse, error) {
+	ref := req.GetRef()
+	action := req.GetAction()
+	if ref == "" {
+		return nil, status.Error(codes.InvalidArgument, "ref is required")
+	}
+	if int(req.GetLength()) != len(req.GetData()) {
+		return nil, status.Errorf(codes.InvalidArgument, "length %d of %s does not match data size %d", req.GetLength(), ref, len(req.GetData()))
+	}
+	if s.maxUpdateSize > 0 && len(req.GetData()) > int(s.maxUpdateSize) {
+		return nil, status.Errorf(codes.InvalidArgument, "chunk of %d bytes of %s exceeds max update size %d", len(req.GetData()), ref, s.maxUpdateSize)
+	}
+
+	w, ok := s.pending[ref]
+	if action == artifactpb.UpdateAction_UPDATE_ACTION_STAT {
+		return s.statLocked(req)
+	}
+	if !ok {
+		switch action {
+		case artifactpb.UpdateAction_UPDATE_ACTION_UPDATE, artifactpb.UpdateAction_UPDATE_ACTION_COMMIT:
+		default:
+			return nil, status.Errorf(codes.FailedPrecondition, "no pending write of %s to %s", ref, action)
+		}
+		s.attempts[ref]++
+		// Names may be overwritten with different content, like tags of a registry.
+		if content, ok := s.content[ref]; ok && (req.GetExpectedDigest() == "" || digestOf(content) == req.GetExpectedDigest()) {
+			return nil, status.Errorf(codes.AlreadyExists, "%s already exists", ref)
+		}
+		w = &pendingWrite{expectedDigest: req.GetExpectedDigest(), lastChunkID: -1}
+		s.pending[ref] = w
+	}
+	if req.GetChunkId() <= w.lastChunkID {
+		return nil, status.Errorf(codes.InvalidArgument, "chunk id %d of %s is not greater than %d", req.GetChunkId(), ref, w.lastChunkID)
+	}
+	w.lastChunkID = req.GetChunkId()
+
+	switch action {
+	case artifactpb.UpdateAction_UPDATE_ACTION_UPDATE:
+		w.data = append(w.data, req.GetData()...)
+		s.received[ref] += int64(len(req.GetData()))
+	case artifactpb.UpdateAction_UPDATE_ACTION_COMMIT:
+		w.data = append(w.data, req.GetData()...)
+		s.received[ref] += int64(len(req.GetData()))
+		if digest := digestOf(w.data); w.expectedDigest != "" && digest != w.expectedDigest {
+			return nil, status.Errorf(codes.InvalidArgument, "digest %s of %s does not match expected digest %s", digest, ref, w.expectedDigest)
+		}
+		delete(s.pending, ref)
+		s.content[ref] = w.data
+	case artifactpb.UpdateAction_UPDATE_ACTION_ABORT:
+		delete(s.pending, ref)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid action %s for %s", action, ref)
+	}
+	return response(req, int64(len(w.data))), nil
+}
+
+// statLocked reports the progress of a pending write with its last chunk id, or the size of
+// committed content with a commit action.
+func (s *Server) statLocked(req *artifactpb.UpdateRequest) (*artifactpb.UpdateResponse, error) {
+	ref := req.GetRef()
+	if w, ok := s.pending[ref]; ok {
+		return &artifactpb.UpdateResponse{
+			Ref:     ref,
+			ChunkId: w.lastChunkID,
+			Total:   proto.Int64(int64(len(w.data))),
+			Action:  artifactpb.UpdateAction_UPDATE_ACTION_STAT.Enum(),
+		}, nil
+	}
+	if content, ok := s.content[ref]; ok {
+		return &artifactpb.UpdateResponse{
+			Ref:     ref,
+			ChunkId: req.GetChunkId(),
+			Total:   proto.Int64(int64(len(content))),
+			Action:  artifactpb.UpdateAction_UPDATE_ACTION_COMMIT.Enum(),
+		}, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "%s not found", ref)
+}
+
+func (s *Server) drop(ref string) {
+	if ref == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, ref)
+}
+
+func response(req *artifactpb.UpdateRequest, total int64) *artifactpb.UpdateResponse {
+	return &artifactpb.UpdateResponse{
+		Ref:     req.GetRef(),
+		ChunkId: req.GetChunkId(),
+		Total:   proto.Int64(total),
+		Action:  req.GetAction().Enum(),
+	}
+}
+
+func isTerminal(action artifactpb.UpdateAction) bool {
+	return action == artifactpb.UpdateAction_UPDATE_ACTION_COMMIT ||
+		action == artifactpb.UpdateAction_UPDATE_ACTION_ABORT
+}
+
+func digestOf(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
+
+// referencedDigests returns the digests of the config and layers of an image manifest and of the
+// manifests of an index.
+func referencedDigests(manifest []byte) ([]string, error) {
+	if len(manifest) == 0 {
+		return nil, nil
+	}
+	type descriptor struct {
+		Digest string `json:"digest"`
+	}
+	var m struct {
+		Config    *descriptor  `json:"config"`
+		Layers    []descriptor `json:"layers"`
+		Manifests []descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, err
+	}
+	var digests []string
+	if m.Config != nil {
+		digests = append(digests, m.Config.Digest)
+	}
+	for _, d := range append(m.Layers, m.Manifests...) {
+		digests = append(digests, d.Digest)
+	}
+	return digests, nil
+}
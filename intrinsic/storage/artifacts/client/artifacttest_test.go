@@ -0,0 +1,159 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package artifacttest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	artifactpb "intrinsic/storage/artifacts/proto/artifact_go_grpc_proto"
+)
+
+func update(ref string, chunkID int64, action artifactpb.UpdateAction, data string) *artifactpb.UpdateRequest {
+	return &artifactpb.UpdateRequest{
+		Ref:     ref,
+		ChunkId: chunkID,
+		Action:  action,
+		Length:  int32(len(data)),
+		Data:    []byte(data),
+	}
+}
+
+func TestWriteContent(t *testing.T) {
+	ctx := context.Background()
+	content := "hello world"
+	ref := digestOf([]byte(content))
+	tests := []struct {
+		name     string
+		requests []*artifactpb.UpdateRequest
+		wantCode codes.Code
+		want     string
+	}{
+		{
+			name: "commit",
+			requests: []*artifactpb.UpdateRequest{
+				update(ref, 1, artifactpb.UpdateAction_UPDATE_ACTION_UPDATE, "hello "),
+				update(ref, 5, artifactpb.UpdateAction_UPDATE_ACTION_COMMIT, "world"),
+			},
+			want: content,
+		},
+		{
+			name: "digest mismatch",
+			requests: []*artifactpb.UpdateRequest{
+				update(ref, 1, artifactpb.UpdateAction_UPDATE_ACTION_COMMIT, "goodbye"),
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "chunk id not increasing",
+			requests: []*artifactpb.UpdateRequest{
+				update(ref, 5, artifactpb.UpdateAction_UPDATE_ACTION_UPDATE, "hello "),
+				update(ref, 5, artifactpb.UpdateAction_UPDATE_ACTION_COMMIT, "world"),
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "abort",
+			requests: []*artifactpb.UpdateRequest{
+				update(ref, 1, artifactpb.UpdateAction_UPDATE_ACTION_UPDATE, "hello "),
+				update(ref, 2, artifactpb.UpdateAction_UPDATE_ACTION_ABORT, ""),
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer()
+			tc.requests[0].ExpectedDigest = proto.String(ref)
+			var err error
+			for _, req := range tc.requests {
+				if _, err = s.WriteContent(ctx, req); err != nil {
+					break
+				}
+			}
+			if status.Code(err) != tc.wantCode {
+				t.Fatalf("WriteContent() returned error %v, want code %v", err, tc.wantCode)
+			}
+			got, ok := s.Content(ref)
+			if ok != (tc.want != "") || string(got) != tc.want {
+				t.Errorf("Content(%q) = %q, %t, want %q", ref, got, ok, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteContentAlreadyExists(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer()
+	s.Put("my-image", []byte("old manifest"))
+
+	req := update("my-image", 1, artifactpb.UpdateAction_UPDATE_ACTION_COMMIT, "old manifest")
+	req.ExpectedDigest = proto.String(digestOf([]byte("old manifest")))
+	if _, err := s.WriteContent(ctx, req); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("WriteContent() of same content returned error %v, want AlreadyExists", err)
+	}
+
+	// Names can be overwritten with different content.
+	req = update("my-image", 1, artifactpb.UpdateAction_UPDATE_ACTION_COMMIT, "new manifest")
+	req.ExpectedDigest = proto.String(digestOf([]byte("new manifest")))
+	if _, err := s.WriteContent(ctx, req); err != nil {
+		t.Errorf("WriteContent() of new content failed: %v", err)
+	}
+	if got := s.WriteAttempts("my-image"); got != 2 {
+		t.Errorf("WriteAttempts() = %d, want 2", got)
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+	s := NewServer(WithMaxUpdateSize(1024))
+	s.Put("sha256:config", []byte("config"))
+	manifest := []byte(`{
+		"config": {"digest": "sha256:config"},
+		"layers": [{"digest": "sha256:layer1"}, {"digest": "sha256:layer2"}]
+	}`)
+	s.Put("sha256:layer2", []byte("layer2"))
+
+	got, err := s.CheckImage(context.Background(), &artifactpb.ImageRequest{
+		Name:     "my-image",
+		Manifest: &artifactpb.ImageManifest{Data: manifest, Digest: "sha256:manifest"},
+	})
+	if err != nil {
+		t.Fatalf("CheckImage() failed: %v", err)
+	}
+
+	want := &artifactpb.ArtifactResponse{
+		Ref:           "my-image",
+		MaxUpdateSize: 1024,
+		PresentRefs:   []string{"sha256:config", "sha256:layer2"},
+		MissingRefs:   []string{"sha256:layer1", "sha256:manifest"},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("CheckImage() returned unexpected response (-want +got):\n%s", diff)
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer()
+	s.FailNext(MethodWriteContent, "sha256:other", 1, status.Error(codes.Internal, "injected"))
+	s.FailNext(MethodWriteContent, "", 2, status.Error(codes.Unavailable, "injected"))
+
+	var codesGot []codes.Code
+	for i := 0; i < 3; i++ {
+		req := update("sha256:a", int64(i+1), artifactpb.UpdateAction_UPDATE_ACTION_UPDATE, "a")
+		_, err := s.WriteContent(ctx, req)
+		codesGot = append(codesGot, status.Code(err))
+	}
+
+	want := []codes.Code{codes.Unavailable, codes.Unavailable, codes.OK}
+	if diff := cmp.Diff(want, codesGot); diff != "" {
+		t.Errorf("WriteContent() returned unexpected codes (-want +got):\n%s", diff)
+	}
+	if got := s.Calls(MethodWriteContent); got != 3 {
+		t.Errorf("Calls(%q) = %d, want 3", MethodWriteContent, got)
+	}
+}
@@ -0,0 +1,376 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+	crv1 "github.com/google/go-containerregistry/pkg/v1"
+	crtypes "github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	cacheFilename = "cache.json"
+	// confirmations older than this are not trusted anymore, the target may
+	// have garbage collected the content in the meantime.
+	cacheMaxAge = 7 * 24 * time.Hour
+)
+
+// CachedImage is an image the cache knows the manifest of.
+type CachedImage struct {
+	// Config is the digest of the image config. It identifies the content of
+	// the image without hashing its layers.
+	Config string `json:"config"`
+	// Digest is the digest of the manifest.
+	Digest    string `json:"digest"`
+	MediaType string `json:"mediaType"`
+	Manifest  []byte `json:"manifest"`
+	// Blobs holds the digests of the config and the layers of the image.
+	Blobs []string `json:"blobs"`
+	// Used tells when the image was last uploaded or looked up.
+	Used time.Time `json:"used"`
+}
+
+// CacheTarget summarizes what the cache knows about the content of a target,
+// e.g. a cluster.
+type CacheTarget struct {
+	Name string
+	// Digests counts the blobs and manifests confirmed to be present.
+	Digests int
+	// Confirmed tells when content was last confirmed to be present.
+	Confirmed time.Time
+}
+
+type cacheContent struct {
+	// Images are keyed by their config digest.
+	Images map[string]*CachedImage `json:"images"`
+	// Targets map digests to the time they were last confirmed present.
+	Targets map[string]map[string]time.Time `json:"targets"`
+}
+
+// Cache remembers manifests of uploaded images and which digests a target
+// already confirmed to be present. Uploads of an unchanged image to a target
+// which holds all of its content only write the manifest under the new name,
+// they neither ask the service which parts are missing nor hash the layers.
+//
+// A nil cache is valid and remembers nothing.
+type Cache struct {
+	path string
+
+	mu      sync.Mutex
+	content cacheContent
+}
+
+// OpenCache loads the cache stored at path. A missing file yields an empty
+// cache, which is created on first write.
+func OpenCache(path string) (*Cache, error) {
+	c := &Cache{path: path}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("cannot read image cache: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, &c.content); err != nil {
+			return nil, fmt.Errorf("invalid image cache %s: %w", path, err)
+		}
+	}
+	if c.content.Images == nil {
+		c.content.Images = map[string]*CachedImage{}
+	}
+	if c.content.Targets == nil {
+		c.content.Targets = map[string]map[string]time.Time{}
+	}
+	return c, nil
+}
+
+// OpenDefaultCache loads the cache from the users config directory.
+func OpenDefaultCache() (*Cache, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("cannot find image cache: %w", err)
+	}
+	return OpenCache(filepath.Join(configDir, journalDirectory, cacheFilename))
+}
+
+// Path returns the file the cache is stored in.
+func (c *Cache) Path() string {
+	return c.path
+}
+
+// Images returns the cached images, most recently used first.
+func (c *Cache) Images() []CachedImage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	images := make([]CachedImage, 0, len(c.content.Images))
+	for _, image := range c.content.Images {
+		images = append(images, *image)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Used.After(images[j].Used)
+	})
+	return images
+}
+
+// Targets returns what the cache knows about every target, sorted by name.
+func (c *Cache) Targets() []CacheTarget {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	targets := make([]CacheTarget, 0, len(c.content.Targets))
+	for name, digests := range c.content.Targets {
+		target := CacheTarget{Name: name, Digests: len(digests)}
+		for _, confirmed := range digests {
+			if confirmed.After(target.Confirmed) {
+				target.Confirmed = confirmed
+			}
+		}
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Name < targets[j].Name
+	})
+	return targets
+}
+
+// Prune forgets confirmations and images older than maxAge, or everything if
+// maxAge is 0. If target is set, only its confirmations are forgotten and
+// images are kept. It returns the number of forgotten entries.
+func (c *Cache) Prune(target string, maxAge time.Duration) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expired := func(t time.Time) bool {
+		return maxAge == 0 || time.Since(t) > maxAge
+	}
+	pruned := 0
+	for name, digests := range c.content.Targets {
+		if target != "" && name != target {
+			continue
+		}
+		for digest, confirmed := range digests {
+			if expired(confirmed) {
+				delete(digests, digest)
+				pruned++
+			}
+		}
+		if len(digests) == 0 {
+			delete(c.content.Targets, name)
+		}
+	}
+	if target == "" {
+		for config, image := range c.content.Images {
+			if expired(image.Used) {
+				delete(c.content.Images, config)
+				pruned++
+			}
+		}
+	}
+	if pruned == 0 {
+		return 0, nil
+	}
+	return pruned, c.writeLocked()
+}
+
+// Digest returns the manifest digest of image if the cache knows its
+// manifest, without hashing its layers.
+func (c *Cache) Digest(image crv1.Image) (crv1.Hash, bool) {
+	if c == nil {
+		return crv1.Hash{}, false
+	}
+	config, err := image.ConfigName()
+	if err != nil {
+		return crv1.Hash{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.content.Images[config.String()]
+	if !ok {
+		return crv1.Hash{}, false
+	}
+	digest, err := crv1.NewHash(cached.Digest)
+	return digest, err == nil
+}
+
+// lookup returns the cached manifest of image if target confirmed all of its
+// content to be present. It marks the image as used but does not save the
+// cache, which is left to the end of the upload.
+func (c *Cache) lookup(target string, image crv1.Image) (*CachedImage, bool) {
+	if c == nil {
+		return nil, false
+	}
+	config, err := image.ConfigName()
+	if err != nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.content.Images[config.String()]
+	if !ok {
+		return nil, false
+	}
+	digests := c.content.Targets[target]
+	for _, digest := range append([]string{cached.Digest}, cached.Blobs...) {
+		confirmed, ok := digests[digest]
+		if !ok || time.Since(confirmed) > cacheMaxAge {
+			return nil, false
+		}
+	}
+	cached.Used = time.Now()
+	result := *cached
+	return &result, true
+}
+
+// confirm remembers the manifest of image and that target holds all of its
+// content.
+func (c *Cache) confirm(target string, image crv1.Image) {
+	if c == nil {
+		return
+	}
+	cached, err := toCachedImage(image)
+	if err != nil {
+		log.Warningf("cannot cache image: %s", err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.content.Images[cached.Config] = cached
+	digests, ok := c.content.Targets[target]
+	if !ok {
+		digests = map[string]time.Time{}
+		c.content.Targets[target] = digests
+	}
+	for _, digest := range append([]string{cached.Digest}, cached.Blobs...) {
+		digests[digest] = cached.Used
+	}
+	c.saveLocked()
+}
+
+// save writes the cache, e.g. to remember the use of an image returned by
+// lookup.
+func (c *Cache) save() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saveLocked()
+}
+
+// forget drops the confirmations of image for target, e.g. because the
+// target turned out not to hold its content anymore.
+func (c *Cache) forget(target string, image *CachedImage) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	digests := c.content.Targets[target]
+	for _, digest := range append([]string{image.Digest}, image.Blobs...) {
+		delete(digests, digest)
+	}
+	c.saveLocked()
+}
+
+func toCachedImage(image crv1.Image) (*CachedImage, error) {
+	config, err := image.ConfigName()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := image.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := image.RawManifest()
+	if err != nil {
+		return nil, err
+	}
+	digest, err := image.Digest()
+	if err != nil {
+		return nil, err
+	}
+	mediaType, err := image.MediaType()
+	if err != nil {
+		return nil, err
+	}
+	blobs := []string{manifest.Config.Digest.String()}
+	for _, layer := range manifest.Layers {
+		blobs = append(blobs, layer.Digest.String())
+	}
+	return &CachedImage{
+		Config:    config.String(),
+		Digest:    digest.String(),
+		MediaType: string(mediaType),
+		Manifest:  raw,
+		Blobs:     blobs,
+		Used:      time.Now(),
+	}, nil
+}
+
+// saveLocked writes the cache to its path. Failures only cost round-trips of
+// later uploads, so they are logged but not returned.
+func (c *Cache) saveLocked() {
+	if err := c.writeLocked(); err != nil {
+		log.Warningf("cannot save image cache %s: %s", c.path, err)
+	}
+}
+
+func (c *Cache) writeLocked() error {
+	if c.path == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(c.content, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first to never leave behind a truncated cache.
+	// Every write gets its own file, so concurrent processes do not mix their
+	// content.
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly after the rename.
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// cachedManifest allows to write the manifest of a cached image.
+type cachedManifest struct {
+	image *CachedImage
+}
+
+func (m cachedManifest) Digest() (crv1.Hash, error) {
+	return crv1.NewHash(m.image.Digest)
+}
+
+func (m cachedManifest) MediaType() (crtypes.MediaType, error) {
+	return crtypes.MediaType(m.image.MediaType), nil
+}
+
+func (m cachedManifest) Size() (int64, error) {
+	return int64(len(m.image.Manifest)), nil
+}
+
+func (m cachedManifest) RawManifest() ([]byte, error) {
+	return m.image.Manifest, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	crv1 "github.com/google/go-containerregistry/pkg/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"intrinsic/storage/artifacts/client/artifacttest"
+)
+
+const (
+	testCacheTarget = "test-cluster"
+	otherImageName  = "gcr.io/test-project/test-image:other"
+)
+
+// unhashedImage fails the test if anything but its config is looked at.
+type unhashedImage struct {
+	crv1.Image
+	t *testing.T
+}
+
+func (i unhashedImage) Digest() (crv1.Hash, error) {
+	i.t.Errorf("Digest() of cached image called")
+	return i.Image.Digest()
+}
+
+func (i unhashedImage) Manifest() (*crv1.Manifest, error) {
+	i.t.Errorf("Manifest() of cached image called")
+	return i.Image.Manifest()
+}
+
+func (i unhashedImage) Layers() ([]crv1.Layer, error) {
+	i.t.Errorf("Layers() of cached image called")
+	return i.Image.Layers()
+}
+
+func openTestCache(t *testing.T) (*Cache, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cache, err := OpenCache(path)
+	if err != nil {
+		t.Fatalf("OpenCache() failed: %v", err)
+	}
+	return cache, path
+}
+
+func reopenCache(t *testing.T, path string) *Cache {
+	t.Helper()
+	cache, err := OpenCache(path)
+	if err != nil {
+		t.Fatalf("OpenCache() failed: %v", err)
+	}
+	return cache
+}
+
+func TestUploadImageUsesCache(t *testing.T) {
+	server := artifacttest.NewServer()
+	cache, path := openTestCache(t)
+	img := randomImage(t)
+	uploader := newTestUploader(t, server.Client(t), WithCache(cache, testCacheTarget))
+	if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+
+	// a later process uploads the unchanged image under another name.
+	uploader = newTestUploader(t, server.Client(t), WithCache(reopenCache(t, path), testCacheTarget))
+	if err := uploader.UploadImage(context.Background(), otherImageName, unhashedImage{Image: img, t: t}); err != nil {
+		t.Fatalf("UploadImage() of cached image failed: %v", err)
+	}
+
+	rawManifest, err := img.RawManifest()
+	if err != nil {
+		t.Fatalf("RawManifest() failed: %v", err)
+	}
+	checkStored(t, server, otherImageName, rawManifest)
+	if got := server.Calls(artifacttest.MethodCheckImage); got != 1 {
+		t.Errorf("UploadImage() called CheckImage %d times, want 1", got)
+	}
+	for _, ref := range imageRefs(t, img) {
+		if got := server.WriteAttempts(ref); got != 1 {
+			t.Errorf("UploadImage() started %d writes of %s, want 1", got, ref)
+		}
+	}
+}
+
+func TestUploadImageSavesUseOfCachedImage(t *testing.T) {
+	server := artifacttest.NewServer()
+	cache, path := openTestCache(t)
+	img := randomImage(t)
+	uploader := newTestUploader(t, server.Client(t), WithCache(cache, testCacheTarget))
+	if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+	uploaded := cache.Images()[0].Used
+
+	if err := uploader.UploadImage(context.Background(), otherImageName, img); err != nil {
+		t.Fatalf("UploadImage() of cached image failed: %v", err)
+	}
+
+	if images := reopenCache(t, path).Images(); len(images) != 1 || !images[0].Used.After(uploaded) {
+		t.Errorf("Images() = %v after upload of cached image, want it used after %v", images, uploaded)
+	}
+	// temporary files are renamed to the cache or removed.
+	if files, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*")); err != nil || len(files) != 1 {
+		t.Errorf("cache directory holds %v, want only %s", files, path)
+	}
+}
+
+func TestUploadImageDoesNotShareCacheBetweenTargets(t *testing.T) {
+	server := artifacttest.NewServer()
+	cache, _ := openTestCache(t)
+	img := randomImage(t)
+	if err := newTestUploader(t, server.Client(t), WithCache(cache, testCacheTarget)).UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+
+	other := artifacttest.NewServer()
+	if err := newTestUploader(t, other.Client(t), WithCache(cache, "other-cluster")).UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() to other target failed: %v", err)
+	}
+
+	checkImageStored(t, other, img, k8sNames(t, img)...)
+}
+
+func TestUploadImageIgnoresExpiredConfirmations(t *testing.T) {
+	server := artifacttest.NewServer()
+	cache, _ := openTestCache(t)
+	img := randomImage(t)
+	uploader := newTestUploader(t, server.Client(t), WithCache(cache, testCacheTarget))
+	if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+	for digest := range cache.content.Targets[testCacheTarget] {
+		cache.content.Targets[testCacheTarget][digest] = time.Now().Add(-cacheMaxAge - time.Hour)
+	}
+
+	if err := uploader.UploadImage(context.Background(), otherImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+
+	if got := server.Calls(artifacttest.MethodCheckImage); got != 2 {
+		t.Errorf("UploadImage() called CheckImage %d times, want 2", got)
+	}
+}
+
+func TestUploadImageFallsBackOnCacheFailure(t *testing.T) {
+	server := artifacttest.NewServer()
+	cache, _ := openTestCache(t)
+	img := randomImage(t)
+	uploader := newTestUploader(t, server.Client(t), WithCache(cache, testCacheTarget), WithMaxResumes(0))
+	if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+	server.FailNext(artifacttest.MethodWriteContent, otherImageName, 1, status.Error(codes.FailedPrecondition, "missing blobs"))
+
+	if err := uploader.UploadImage(context.Background(), otherImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+
+	checkImageStored(t, server, img, otherImageName)
+	if got := server.Calls(artifacttest.MethodCheckImage); got != 2 {
+		t.Errorf("UploadImage() called CheckImage %d times, want 2", got)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	cache, path := openTestCache(t)
+	img := randomImage(t)
+	cache.confirm(testCacheTarget, img)
+	cache.confirm("other-cluster", img)
+
+	if pruned, err := cache.Prune(testCacheTarget, time.Hour); err != nil || pruned != 0 {
+		t.Errorf("Prune(%q, 1h) = %d, %v, want nothing pruned", testCacheTarget, pruned, err)
+	}
+	if pruned, err := cache.Prune(testCacheTarget, 0); err != nil || pruned == 0 {
+		t.Errorf("Prune(%q, 0) = %d, %v, want confirmations pruned", testCacheTarget, pruned, err)
+	}
+
+	cache = reopenCache(t, path)
+	if targets := cache.Targets(); len(targets) != 1 || targets[0].Name != "other-cluster" {
+		t.Errorf("Targets() = %v, want only other-cluster", targets)
+	}
+	if images := cache.Images(); len(images) != 1 {
+		t.Errorf("Images() = %v, want the pruned target to keep the image", images)
+	}
+	if _, ok := cache.Digest(img); !ok {
+		t.Errorf("Digest() does not know the cached image")
+	}
+
+	if _, err := cache.Prune("", 0); err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	cache = reopenCache(t, path)
+	if len(cache.Targets()) != 0 || len(cache.Images()) != 0 {
+		t.Errorf("Prune() left targets %v and images %v, want none", cache.Targets(), cache.Images())
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+)
+
+const (
+	journalDirectory = "intrinsic/uploads"
+	journalFilename  = "journal.json"
+	// entries older than this are dropped, the service is not expected to keep
+	// pending writes around for longer.
+	journalMaxAge = 7 * 24 * time.Hour
+)
+
+// journalEntry records a pending write of a blob.
+type journalEntry struct {
+	Digest  string    `json:"digest"`
+	Size    int64     `json:"size"`
+	Offset  int64     `json:"offset"`
+	ChunkID int64     `json:"chunkId"`
+	Updated time.Time `json:"updated"`
+}
+
+// Journal keeps track of pending writes of blobs, so that interrupted uploads
+// can be resumed instead of restarted. A journal with a path persists pending
+// writes, which allows a later process to resume uploads of an earlier one.
+//
+// The progress recorded in the journal is only a hint, the service is always
+// asked for the progress of a pending write before it is resumed.
+type Journal struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]journalEntry
+}
+
+// OpenJournal loads the journal stored at path. A missing file yields an empty
+// journal, which is created on first write.
+func OpenJournal(path string) (*Journal, error) {
+	j := newMemoryJournal()
+	j.path = path
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read upload journal: %w", err)
+	}
+	if err := json.Unmarshal(content, &j.entries); err != nil {
+		return nil, fmt.Errorf("invalid upload journal %s: %w", path, err)
+	}
+	if j.entries == nil {
+		j.entries = map[string]journalEntry{}
+	}
+	for ref, entry := range j.entries {
+		if time.Since(entry.Updated) > journalMaxAge {
+			delete(j.entries, ref)
+		}
+	}
+	return j, nil
+}
+
+// OpenDefaultJournal loads the journal from the users config directory.
+func OpenDefaultJournal() (*Journal, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("cannot find upload journal: %w", err)
+	}
+	return OpenJournal(filepath.Join(configDir, journalDirectory, journalFilename))
+}
+
+func newMemoryJournal() *Journal {
+	return &Journal{entries: map[string]journalEntry{}}
+}
+
+// lookup returns the pending write of ref if it is a write of content with
+// the given digest.
+func (j *Journal) lookup(ref string, digest string) (journalEntry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry, ok := j.entries[ref]
+	if !ok || entry.Digest != digest {
+		return journalEntry{}, false
+	}
+	return entry, true
+}
+
+// record stores the progress of the pending write of ref.
+func (j *Journal) record(ref string, entry journalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry.Updated = time.Now()
+	j.entries[ref] = entry
+	j.saveLocked()
+}
+
+// remove forgets the write of ref once it is either finished or failed for good.
+func (j *Journal) remove(ref string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.entries[ref]; !ok {
+		return
+	}
+	delete(j.entries, ref)
+	j.saveLocked()
+}
+
+// saveLocked writes the journal to its path. Failures only cost the ability
+// to resume in a later process, so they are logged but not returned.
+func (j *Journal) saveLocked() {
+	if j.path == "" {
+		return
+	}
+	if err := j.writeLocked(); err != nil {
+		log.Warningf("cannot save upload journal %s: %s", j.path, err)
+	}
+}
+
+func (j *Journal) writeLocked() error {
+	content, err := json.MarshalIndent(j.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first to never leave behind a truncated journal.
+	// Every write gets its own file, so concurrent processes do not mix their
+	// content.
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly after the rename.
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), j.path)
+}
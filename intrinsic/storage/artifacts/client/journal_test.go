@@ -0,0 +1,74 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uploads", "journal.json")
+	journal, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal() of missing file failed: %v", err)
+	}
+	journal.record("sha256:a", journalEntry{Digest: "sha256:a", Size: 10, Offset: 5, ChunkID: 42})
+	journal.record("image:latest", journalEntry{Digest: "sha256:b", Size: 20})
+
+	reopened, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal() failed: %v", err)
+	}
+	entry, ok := reopened.lookup("sha256:a", "sha256:a")
+	if !ok || entry.Offset != 5 || entry.ChunkID != 42 {
+		t.Errorf("lookup(sha256:a) = %+v, %t, want offset 5 and chunk 42", entry, ok)
+	}
+	if _, ok := reopened.lookup("image:latest", "sha256:c"); ok {
+		t.Errorf("lookup() returned a write of different content")
+	}
+
+	reopened.remove("sha256:a")
+	reopened, err = OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal() failed: %v", err)
+	}
+	if _, ok := reopened.lookup("sha256:a", "sha256:a"); ok {
+		t.Errorf("lookup() returned removed write")
+	}
+	if _, ok := reopened.lookup("image:latest", "sha256:b"); !ok {
+		t.Errorf("lookup() did not return write of image:latest")
+	}
+	// temporary files are renamed to the journal or removed.
+	if files, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*")); err != nil || len(files) != 1 {
+		t.Errorf("journal directory holds %v, want only %s", files, path)
+	}
+}
+
+func TestJournalDropsExpiredEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	content, err := json.Marshal(map[string]journalEntry{
+		"sha256:old": {Digest: "sha256:old", Updated: time.Now().Add(-journalMaxAge - time.Hour)},
+		"sha256:new": {Digest: "sha256:new", Updated: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("os.WriteFile() failed: %v", err)
+	}
+
+	journal, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal() failed: %v", err)
+	}
+	if _, ok := journal.lookup("sha256:old", "sha256:old"); ok {
+		t.Errorf("lookup() returned expired write")
+	}
+	if _, ok := journal.lookup("sha256:new", "sha256:new"); !ok {
+		t.Errorf("lookup() did not return recent write")
+	}
+}
@@ -19,8 +19,11 @@ import (
 var (
 	// internal error to indicate that item already exists, and we should stop update
 	errAlreadyExists = errors.New("already exists")
-	// this can be set in tests to speed them up
-	backOffStrategy = backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 3)
+	// this can be set in tests to speed them up. Back offs are stateful, so
+	// every chunk gets its own.
+	newChunkBackOff = func() backoff.BackOff {
+		return backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 3)
+	}
 )
 
 type nonStreamingTask struct {
@@ -32,22 +35,26 @@ func newNonStreamingTask(base taskData) (uploadTask, error) {
 }
 
 func (t *nonStreamingTask) runWithCtx(ctx context.Context) error {
-	log.InfoContextf(ctx, "[%s] starting upload", asShortName(t.name))
-	updateMonitor(t.monitor, asShortName(t.name), ProgressUpdate{
-		Status:  StatusUndetermined,
-		Current: 0,
-		Total:   t.descriptor.Size,
-		Message: "waiting for upload",
-	})
+	return t.runResumable(ctx, t.upload)
+}
 
+func (t *nonStreamingTask) upload(ctx context.Context, reader io.Reader, from *resumePoint) error {
+	log.InfoContextf(ctx, "[%s] starting upload", asShortName(t.name))
 	contentBuffer := make([]byte, 100) // initial exploratory chunk, see b/327799134
 	idTracker := atomic.NewInt64(0)
 	firstChunk := true
 	var totalSize int64 = 0
+	if from != nil {
+		// we are continuing pending write, there is nothing to explore
+		contentBuffer = make([]byte, t.updateSize)
+		idTracker.Store(from.chunkID)
+		firstChunk = false
+		totalSize = from.offset
+	}
 	var ctxErr error = nil
 	for ctxErr = ctx.Err(); ctxErr == nil; ctxErr = ctx.Err() {
 		action := artifactpb.UpdateAction_UPDATE_ACTION_UPDATE
-		length, err := t.reader.Read(contentBuffer)
+		length, err := reader.Read(contentBuffer)
 		if err != nil {
 			if err != io.EOF {
 				abortRun(t.name, firstChunk, idTracker, t.sender(ctx))
@@ -113,32 +120,29 @@ func (t *nonStreamingTask) runWithCtx(ctx context.Context) error {
 }
 
 func (t *nonStreamingTask) writeContent(ctx context.Context, updateRequest *artifactpb.UpdateRequest, firstChunk bool) (response *artifactpb.UpdateResponse, err error) {
-	// adding retry for unavailable (503) response. see:b/330747118
+	// transient errors, like unavailable (503) responses (see:b/330747118), are
+	// handled by resuming the pending write. Services which cannot report the
+	// progress of a pending write get the chunk again instead.
 	err = backoff.Retry(func() error {
 		var localErr error
 		response, localErr = t.client.WriteContent(ctx, updateRequest)
-		if localErr != nil {
-			if errStatus, ok := status.FromError(localErr); ok {
-				// this is valid only for first request
-				if codes.AlreadyExists == errStatus.Code() && firstChunk {
-					log.InfoContextf(ctx, "[%s] already exists", asShortName(t.name))
-					return backoff.Permanent(errAlreadyExists) // our work is done.
-				}
-				if codes.Unavailable == errStatus.Code() {
-					// This is transient error, let's retry
-					log.WarningContextf(ctx, "[%s] transient error: %s", asShortName(t.name), errStatus)
-					return localErr
-				}
-			}
+		switch code := status.Code(localErr); {
+		case code == codes.OK:
+			return nil
+		case code == codes.AlreadyExists && firstChunk:
+			// this is valid only for first request
+			log.InfoContextf(ctx, "[%s] already exists", asShortName(t.name))
+			return backoff.Permanent(errAlreadyExists) // our work is done.
+		case code == codes.Unavailable && !t.canResume(ctx):
+			log.WarningContextf(ctx, "[%s] transient error: %s", asShortName(t.name), localErr)
+			return localErr
 		}
-		// any other error is considered permanent
 		return backoff.Permanent(localErr)
-	}, backOffStrategy)
-
+	}, backoff.WithContext(newChunkBackOff(), ctx))
 	return response, err
 }
 
-func (t *nonStreamingTask) sender(ctx context.Context) func(request *artifactpb.UpdateRequest) error {
+func (t *taskData) sender(ctx context.Context) func(request *artifactpb.UpdateRequest) error {
 	return func(request *artifactpb.UpdateRequest) error {
 		_, err := t.client.WriteContent(ctx, request)
 		return err
@@ -0,0 +1,202 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	log "github.com/golang/glog"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	artifactpb "intrinsic/storage/artifacts/proto/artifact_go_grpc_proto"
+)
+
+// this can be set in tests to speed them up. Back offs are stateful, so every
+// task gets its own.
+var newBackOff = func() backoff.BackOff {
+	return backoff.NewExponentialBackOff()
+}
+
+// resumePoint describes how much of a pending write the service acknowledged.
+type resumePoint struct {
+	offset  int64
+	chunkID int64
+}
+
+// uploadFunc uploads content read from reader. If from is not nil, reader
+// starts at from.offset and the upload continues the pending write.
+type uploadFunc func(ctx context.Context, reader io.Reader, from *resumePoint) error
+
+// runResumable uploads the content of the task using upload. After transient
+// errors, e.g. a dropped connection or a 503 of the proxy (see b/330747118),
+// the pending write is resumed from the progress acknowledged by the service
+// instead of being restarted.
+func (t *taskData) runResumable(ctx context.Context, upload uploadFunc) error {
+	updateMonitor(t.monitor, asShortName(t.name), ProgressUpdate{
+		Status:  StatusUndetermined,
+		Current: 0,
+		Total:   t.descriptor.Size,
+		Message: "waiting for upload",
+	})
+
+	// a pending write in the journal may have been started by an earlier process.
+	_, resume := t.journal.lookup(t.name, t.descriptor.Digest.String())
+	strategy := backoff.WithMaxRetries(newBackOff(), uint64(t.maxResumes))
+	for {
+		err := t.attempt(ctx, upload, resume)
+		if err == nil {
+			t.journal.remove(t.name)
+			return nil
+		}
+		if !isTransient(ctx, err) {
+			if !isProxyLimitError(ctx, err) {
+				t.journal.remove(t.name)
+			}
+			// otherwise keep the journal entry, an upload in a safer mode
+			// may resume the write.
+			return err
+		}
+		next := strategy.NextBackOff()
+		if next == backoff.Stop {
+			// keep the journal entry, a later process may resume the write.
+			return err
+		}
+		log.WarningContextf(ctx, "[%s] resuming upload after transient error: %s", asShortName(t.name), err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("[%s] premature termination: %w", asShortName(t.name), ctx.Err())
+		case <-time.After(next):
+		}
+		resume = true
+	}
+}
+
+// attempt uploads the content of the task once. If resume is set, the service
+// is asked for the progress of a pending write first.
+func (t *taskData) attempt(ctx context.Context, upload uploadFunc, resume bool) error {
+	var from *resumePoint
+	if resume {
+		var done bool
+		var err error
+		from, done, err = t.stat(ctx)
+		if err != nil {
+			return fmt.Errorf("[%s] cannot query pending write: %w", asShortName(t.name), err)
+		}
+		if done {
+			updateMonitor(t.monitor, asShortName(t.name), ProgressUpdate{
+				Status:  StatusSuccess,
+				Current: t.descriptor.Size,
+				Total:   t.descriptor.Size,
+				Message: "already committed",
+			})
+			return nil
+		}
+	}
+
+	entry := journalEntry{Digest: t.descriptor.Digest.String(), Size: t.descriptor.Size}
+	if from != nil {
+		log.InfoContextf(ctx, "[%s] resuming upload at %d/%d", asShortName(t.name), from.offset, t.descriptor.Size)
+		updateMonitor(t.monitor, asShortName(t.name), ProgressUpdate{
+			Status:  StatusContinue,
+			Current: from.offset,
+			Total:   t.descriptor.Size,
+			Message: "resuming upload",
+		})
+		entry.Offset, entry.ChunkID = from.offset, from.chunkID
+	}
+	t.journal.record(t.name, entry)
+
+	reader, err := t.openAt(entry.Offset)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return upload(ctx, reader, from)
+}
+
+// stat asks the service for the progress of the pending write of the task. It
+// returns no resume point if there is nothing to resume and done if the
+// content was committed already.
+func (t *taskData) stat(ctx context.Context) (_ *resumePoint, done bool, _ error) {
+	response, err := t.client.WriteContent(ctx, &artifactpb.UpdateRequest{
+		Ref:    t.name,
+		Action: artifactpb.UpdateAction_UPDATE_ACTION_STAT,
+	})
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.NotFound, codes.Unimplemented:
+		return nil, false, nil
+	default:
+		return nil, false, err
+	}
+
+	total := valueOrDefault(response.Total, 0)
+	action := valueOrDefault(response.Action, artifactpb.UpdateAction_UPDATE_ACTION_UNDEFINED)
+	switch {
+	case action == artifactpb.UpdateAction_UPDATE_ACTION_COMMIT:
+		return nil, total == t.descriptor.Size, nil
+	case total > t.descriptor.Size:
+		// the pending write cannot be ours, let's get rid of it and start over.
+		abortRun(t.name, false, atomic.NewInt64(response.ChunkId), t.sender(ctx))
+		return nil, false, nil
+	case total == 0:
+		return nil, false, nil
+	default:
+		return &resumePoint{offset: total, chunkID: response.ChunkId}, false, nil
+	}
+}
+
+// canResume tells if the service can report the progress of pending writes,
+// which resuming them requires. A service which does not implement STAT
+// updates is only asked once.
+func (t *taskData) canResume(ctx context.Context) bool {
+	if t.statUnsupported {
+		return false
+	}
+	_, err := t.client.WriteContent(ctx, &artifactpb.UpdateRequest{
+		Ref:    t.name,
+		Action: artifactpb.UpdateAction_UPDATE_ACTION_STAT,
+	})
+	t.statUnsupported = status.Code(err) == codes.Unimplemented
+	return !t.statUnsupported
+}
+
+// openAt opens the content of the task and skips the first offset bytes,
+// which the service already holds.
+func (t *taskData) openAt(offset int64) (io.ReadCloser, error) {
+	reader, err := t.open()
+	if err != nil {
+		return nil, fmt.Errorf("[%s] cannot open content: %w", asShortName(t.name), err)
+	}
+	if offset == 0 {
+		return reader, nil
+	}
+	if seeker, ok := reader.(io.Seeker); ok {
+		_, err = seeker.Seek(offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(io.Discard, reader, offset)
+	}
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("[%s] cannot skip to %d: %w", asShortName(t.name), offset, err)
+	}
+	return reader, nil
+}
+
+// isTransient tells if an upload which failed with err is worth resuming.
+func isTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	crv1 "github.com/google/go-containerregistry/pkg/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"intrinsic/storage/artifacts/client/artifacttest"
+)
+
+// firstLayer returns the digest and compressed size of the first layer of img.
+func firstLayer(t *testing.T, img crv1.Image) (string, int64) {
+	t.Helper()
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatalf("Layers() failed: %v", err)
+	}
+	digest, err := layers[0].Digest()
+	if err != nil {
+		t.Fatalf("Digest() failed: %v", err)
+	}
+	size, err := layers[0].Size()
+	if err != nil {
+		t.Fatalf("Size() failed: %v", err)
+	}
+	return digest.String(), size
+}
+
+func TestUploadImageResumesInterruptedWrite(t *testing.T) {
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			server := artifacttest.NewServer(artifacttest.WithMaxUpdateSize(256))
+			uploader := newTestUploader(t, server.Client(t), s.option)
+			img := randomImage(t)
+			layer, size := firstLayer(t, img)
+			server.InterruptAfter(layer, size/2, status.Error(codes.Unavailable, "connection reset"))
+
+			if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+				t.Fatalf("UploadImage() failed: %v", err)
+			}
+
+			checkImageStored(t, server, img, k8sNames(t, img)...)
+			if got := server.WriteAttempts(layer); got != 1 {
+				t.Errorf("UploadImage() started %d writes of %s, want 1", got, layer)
+			}
+			if got := server.Received(layer); got != size {
+				t.Errorf("UploadImage() sent %d bytes of %s, want %d", got, layer, size)
+			}
+		})
+	}
+}
+
+func TestUploadImageResumesFromJournal(t *testing.T) {
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			server := artifacttest.NewServer(artifacttest.WithMaxUpdateSize(256))
+			client := server.Client(t)
+			img := randomImage(t)
+			layer, size := firstLayer(t, img)
+			server.InterruptAfter(layer, size/2, status.Error(codes.Unavailable, "connection reset"))
+			path := filepath.Join(t.TempDir(), "journal.json")
+			journal, err := OpenJournal(path)
+			if err != nil {
+				t.Fatalf("OpenJournal() failed: %v", err)
+			}
+			uploader := newTestUploader(t, client, s.option, WithJournal(journal), WithMaxResumes(0))
+
+			if err := uploader.UploadImage(context.Background(), testImageName, img); status.Code(err) != codes.Unavailable {
+				t.Fatalf("UploadImage() returned error %v, want Unavailable", err)
+			}
+
+			// a new process picks up the pending write from the journal.
+			journal, err = OpenJournal(path)
+			if err != nil {
+				t.Fatalf("OpenJournal() failed: %v", err)
+			}
+			if _, ok := journal.lookup(layer, layer); !ok {
+				t.Fatalf("journal has no pending write of %s", layer)
+			}
+			uploader = newTestUploader(t, client, s.option, WithJournal(journal))
+			if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+				t.Fatalf("UploadImage() of resumed upload failed: %v", err)
+			}
+
+			checkImageStored(t, server, img, k8sNames(t, img)...)
+			if got := server.WriteAttempts(layer); got != 1 {
+				t.Errorf("UploadImage() started %d writes of %s, want 1", got, layer)
+			}
+			if got := server.Received(layer); got != size {
+				t.Errorf("UploadImage() sent %d bytes of %s, want %d", got, layer, size)
+			}
+			if len(journal.entries) != 0 {
+				t.Errorf("journal has pending writes %v after upload, want none", journal.entries)
+			}
+		})
+	}
+}
+
+func TestUploadImageRetriesChunksWithoutStat(t *testing.T) {
+	server := artifacttest.NewServer(artifacttest.WithMaxUpdateSize(256), artifacttest.WithoutStat())
+	uploader := newTestUploader(t, server.Client(t), WithSequentialUpload())
+	img := randomImage(t)
+	layer, size := firstLayer(t, img)
+	server.RejectAfter(layer, size/2, status.Error(codes.Unavailable, "unavailable"))
+
+	if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+
+	checkImageStored(t, server, img, k8sNames(t, img)...)
+	if got := server.WriteAttempts(layer); got != 1 {
+		t.Errorf("UploadImage() started %d writes of %s, want 1", got, layer)
+	}
+	if got := server.Received(layer); got != size {
+		t.Errorf("UploadImage() sent %d bytes of %s, want %d", got, layer, size)
+	}
+}
+
+func TestUploadImageDoesNotResumePermanentErrors(t *testing.T) {
+	server := artifacttest.NewServer(artifacttest.WithMaxUpdateSize(256))
+	journal, err := OpenJournal(filepath.Join(t.TempDir(), "journal.json"))
+	if err != nil {
+		t.Fatalf("OpenJournal() failed: %v", err)
+	}
+	uploader := newTestUploader(t, server.Client(t), WithJournal(journal))
+	img := randomImage(t)
+	layer, size := firstLayer(t, img)
+	server.InterruptAfter(layer, size/2, status.Error(codes.PermissionDenied, "denied"))
+
+	if err := uploader.UploadImage(context.Background(), testImageName, img); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("UploadImage() returned error %v, want PermissionDenied", err)
+	}
+
+	if got := server.Calls(artifacttest.MethodWriteContent); got == 0 {
+		t.Fatalf("UploadImage() did not write content")
+	}
+	if _, ok := journal.lookup(layer, layer); ok {
+		t.Errorf("journal keeps failed write of %s", layer)
+	}
+}
+
+func TestUploadImageRestartsWithoutPendingWrite(t *testing.T) {
+	server := artifacttest.NewServer(artifacttest.WithMaxUpdateSize(256))
+	img := randomImage(t)
+	layer, size := firstLayer(t, img)
+	journal := newMemoryJournal()
+	// the journal knows about a write the service does not hold anymore.
+	journal.record(layer, journalEntry{Digest: layer, Size: size, Offset: size / 2})
+	uploader := newTestUploader(t, server.Client(t), WithJournal(journal))
+
+	if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+
+	checkImageStored(t, server, img, k8sNames(t, img)...)
+	if got := server.Received(layer); got != size {
+		t.Errorf("UploadImage() sent %d bytes of %s, want %d", got, layer, size)
+	}
+}
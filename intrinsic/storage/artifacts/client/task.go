@@ -47,19 +47,27 @@ type uploadTask interface {
 }
 
 type taskData struct {
-	reader     io.ReadCloser
+	open       itemReader
 	client     artifactgrpcpb.ArtifactServiceApiClient
 	descriptor *crv1.Descriptor
 	monitor    ProgressMonitor
 	updateSize int32
 	name       string
+	journal    *Journal
+	maxResumes int
+	// set once the service rejected a STAT update as unimplemented, so
+	// pending writes cannot be resumed.
+	statUnsupported bool
 }
 
-func newTask(ctx context.Context, strategy uploadStrategy, client artifactgrpcpb.ArtifactServiceApiClient, maxUpdateSize int32, item namedObject, getItemReader itemReader) (uploadTask, error) {
+func newTask(ctx context.Context, options uploaderOptions, client artifactgrpcpb.ArtifactServiceApiClient, maxUpdateSize int32, item namedObject, getItemReader itemReader) (uploadTask, error) {
 	baseData := taskData{
+		open:       getItemReader,
 		updateSize: maxUpdateSize,
 		monitor:    getProgressMonitor(ctx),
 		client:     client,
+		journal:    options.journal,
+		maxResumes: options.maxResumes,
 	}
 
 	var err error
@@ -80,17 +88,12 @@ func newTask(ctx context.Context, strategy uploadStrategy, client artifactgrpcpb
 		return nil, err
 	}
 
-	baseData.reader, err = getItemReader()
-	if err != nil {
-		return nil, err
-	}
-
 	baseData.name, err = item.Name()
 	if err != nil {
 		return nil, err
 	}
 
-	switch strategy {
+	switch options.strategy {
 	case streamingUpload:
 		return newStreamingTask(baseData)
 	default:
@@ -122,27 +125,30 @@ func (t *streamingTask) run() error {
 }
 
 func (t *streamingTask) runWithCtx(ctx context.Context) error {
-	log.InfoContextf(ctx, "starting upload: %s", asShortName(t.name))
-	updateMonitor(t.monitor, asShortName(t.name), ProgressUpdate{
-		Status:  StatusUndetermined,
-		Current: 0,
-		Total:   t.descriptor.Size,
-		Message: "waiting for upload",
-	})
+	return t.runResumable(ctx, t.upload)
+}
 
+func (t *streamingTask) upload(ctx context.Context, reader io.Reader, from *resumePoint) error {
+	log.InfoContextf(ctx, "starting upload: %s", asShortName(t.name))
 	stream, err := t.client.UploadContent(ctx)
 	if err != nil {
-		return fmt.Errorf("cannot contact upstream: %s", err)
+		return fmt.Errorf("cannot contact upstream: %w", err)
 	}
 	contentBuffer := make([]byte, t.updateSize)
 	idTracker := atomic.NewInt64(0)
 	firstChunk := true
 
 	var totalSize int64 = 0
+	if from != nil {
+		// we are continuing pending write, so this is not a first chunk
+		idTracker.Store(from.chunkID)
+		firstChunk = false
+		totalSize = from.offset
+	}
 	var ctxErr error = nil
 	for ctxErr = ctx.Err(); ctxErr == nil; ctxErr = ctx.Err() {
 		action := artifactpb.UpdateAction_UPDATE_ACTION_UPDATE
-		length, err := t.reader.Read(contentBuffer)
+		length, err := reader.Read(contentBuffer)
 		if err != nil {
 			if err != io.EOF {
 				abortRun(t.name, firstChunk, idTracker, stream.Send)
@@ -176,9 +182,13 @@ func (t *streamingTask) runWithCtx(ctx context.Context) error {
 		err = stream.Send(updateRequest)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
-				// fetch error details and break the loop.
-				_, ctxErr = stream.CloseAndRecv()
-				break
+				// server closed the stream, fetch error details. The resource may
+				// already exist, which we consider success.
+				_, err = stream.CloseAndRecv()
+				if err = t.checkForAlreadyExists(ctx, err); err != nil {
+					return fmt.Errorf("[%s] upload failed: %w", asShortName(t.name), err)
+				}
+				return nil
 			}
 			if firstChunk {
 				// on first chunk we need to check if resource we are writing already
@@ -0,0 +1,49 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"intrinsic/storage/artifacts/client/artifacttest"
+)
+
+func TestStreamingUploadAlreadyExistsWhileSending(t *testing.T) {
+	// layers exceeding the flow control window make the server close the
+	// stream while chunks are still being sent, which the client sees as
+	// io.EOF from Send.
+	server := artifacttest.NewServer(artifacttest.WithMaxUpdateSize(4096))
+	img, err := random.Image(256*1024, 2)
+	if err != nil {
+		t.Fatalf("random.Image() failed: %v", err)
+	}
+	for _, ref := range imageRefs(t, img)[1:] {
+		server.FailNext(artifacttest.MethodUploadContent, ref, 1, status.Errorf(codes.AlreadyExists, "%s already exists", ref))
+	}
+	uploader := newTestUploader(t, server.Client(t), WithStreamingUpload())
+
+	if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+}
+
+func TestSequentialUploadRetriesInParallel(t *testing.T) {
+	// every write uses up all of its retries, which only succeeds if the
+	// tasks do not share their back off. Run with -race to catch sharing.
+	server := artifacttest.NewServer()
+	img := randomImage(t)
+	for _, ref := range imageRefs(t, img) {
+		server.FailNext(artifacttest.MethodWriteContent, ref, 3, status.Error(codes.Unavailable, "try again"))
+	}
+	uploader := newTestUploader(t, server.Client(t), WithSequentialUpload(), WithUploadParallelism(4))
+
+	if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+
+	checkImageStored(t, server, img, k8sNames(t, img)...)
+}
@@ -13,6 +13,7 @@ import (
 	log "github.com/golang/glog"
 	"github.com/google/go-containerregistry/pkg/name"
 	crv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
 	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/pborman/uuid"
@@ -33,8 +34,13 @@ type uploaderOptions struct {
 	maxNumberOfUploads int
 	maxCheckWaitTime   time.Duration
 	strategy           uploadStrategy
+	adaptive           bool
 	alignForK8s        bool
 	clientIDHeader     string
+	maxResumes         int
+	journal            *Journal
+	cache              *Cache
+	cacheTarget        string
 }
 
 var defaultOptions = uploaderOptions{
@@ -42,6 +48,7 @@ var defaultOptions = uploaderOptions{
 	maxCheckWaitTime:   5 * time.Minute,
 	strategy:           nonStreamingUpload,
 	alignForK8s:        true,
+	maxResumes:         5,
 }
 
 // WithUploadParallelism sets the maximum number of parallel uploads.
@@ -67,6 +74,7 @@ func WithMaxCheckWaitTime(value time.Duration) UploaderOption {
 func WithStreamingUpload() UploaderOption {
 	return func(options uploaderOptions) uploaderOptions {
 		options.strategy = streamingUpload
+		options.adaptive = false
 		return options
 	}
 }
@@ -77,6 +85,19 @@ func WithStreamingUpload() UploaderOption {
 func WithSequentialUpload() UploaderOption {
 	return func(options uploaderOptions) uploaderOptions {
 		options.strategy = nonStreamingUpload
+		options.adaptive = false
+		return options
+	}
+}
+
+// WithAdaptiveUpload option instructs uploader to start with streaming tasks
+// running in parallel and to fall back to non-streaming tasks and then to a
+// single task at a time once uploads fail because of reverse-proxy limits.
+// Chosen modes are reported to progress monitors implementing
+// UploadModeMonitor.
+func WithAdaptiveUpload() UploaderOption {
+	return func(options uploaderOptions) uploaderOptions {
+		options.adaptive = true
 		return options
 	}
 }
@@ -92,6 +113,37 @@ func WithAlignForK8sDeployments(align bool) UploaderOption {
 	}
 }
 
+// WithMaxResumes sets how many times the upload of a single blob is resumed
+// after transient errors before the upload fails. Zero disables resuming.
+func WithMaxResumes(value int) UploaderOption {
+	return func(options uploaderOptions) uploaderOptions {
+		options.maxResumes = value
+		return options
+	}
+}
+
+// WithJournal sets the journal which keeps track of pending writes. Using a
+// journal which is stored on disk allows resuming uploads interrupted in an
+// earlier process. By default, pending writes are tracked in memory only.
+func WithJournal(journal *Journal) UploaderOption {
+	return func(options uploaderOptions) uploaderOptions {
+		options.journal = journal
+		return options
+	}
+}
+
+// WithCache option makes uploader remember which content target already
+// holds in cache. Unchanged images are then uploaded by only writing their
+// manifest. The target names the service the uploader talks to, e.g. its
+// cluster, as content is only shared between uploads to the same target.
+func WithCache(cache *Cache, target string) UploaderOption {
+	return func(options uploaderOptions) uploaderOptions {
+		options.cache = cache
+		options.cacheTarget = target
+		return options
+	}
+}
+
 // ContentReader allows access to the underlying reader. Every time this
 // function is called, new fresh io.ReaderCloser is expected by caller.
 type ContentReader tarball.Opener
@@ -112,6 +164,12 @@ type Uploader interface {
 	//
 	// Returns error on any failure
 	UploadImage(ctx context.Context, imageName string, image crv1.Image) error
+
+	// UploadIndex uploads every image referenced by provided index followed by
+	// the index itself, so the image can be pulled on any of its platforms.
+	//
+	// Returns error on any failure
+	UploadIndex(ctx context.Context, imageName string, index crv1.ImageIndex) error
 }
 
 // NewUploader creates a new Uploader.
@@ -122,13 +180,21 @@ func NewUploader(client artifactgrpcpb.ArtifactServiceApiClient, opts ...Uploade
 	}
 	// client ID for ingress affinity calculations, collisions are ok.
 	options.clientIDHeader = uuid.New()
+	if options.journal == nil {
+		options.journal = newMemoryJournal()
+	}
 
-	return &defaultHelper{uploaderOptions: options, client: client}, nil
+	return &defaultHelper{
+		uploaderOptions: options,
+		client:          client,
+		modes:           newModeController(options),
+	}, nil
 }
 
 type defaultHelper struct {
 	uploaderOptions
 	client artifactgrpcpb.ArtifactServiceApiClient
+	modes  *modeController
 }
 
 func (h *defaultHelper) UploadImageFromArchive(ctx context.Context, imageName string, reader ContentReader) error {
@@ -143,6 +209,16 @@ func (h *defaultHelper) UploadImageFromArchive(ctx context.Context, imageName st
 }
 
 func (h *defaultHelper) UploadImage(ctx context.Context, imageName string, image crv1.Image) error {
+	if cached, ok := h.cache.lookup(h.cacheTarget, image); ok {
+		err := h.uploadCachedManifest(ctx, imageName, cached)
+		if err == nil {
+			h.cache.save()
+			return nil
+		}
+		log.WarningContextf(ctx, "cannot reuse cached image %s, uploading it: %s", cached.Digest, err)
+		h.cache.forget(h.cacheTarget, cached)
+	}
+
 	imageManifest, err := toImageManifest(image)
 	if err != nil {
 		return fmt.Errorf("error reading image: %w", err)
@@ -159,12 +235,146 @@ func (h *defaultHelper) UploadImage(ctx context.Context, imageName string, image
 
 	ctx = metadata.AppendToOutgoingContext(ctx, extraHeaderIntrinsicClientID, h.clientIDHeader)
 	ctx = attachImageName(ctx, imageName)
-	return h.uploadImageParts(ctx, image, response)
+	if err := h.uploadImageParts(ctx, image, response); err != nil {
+		return err
+	}
+	h.cache.confirm(h.cacheTarget, image)
+	return nil
+}
+
+// uploadCachedManifest writes the manifest of an image, whose content the
+// target confirmed to hold before, under imageName.
+func (h *defaultHelper) uploadCachedManifest(ctx context.Context, imageName string, cached *CachedImage) error {
+	log.InfoContextf(ctx, "image %s is cached as %s, uploading manifest only", imageName, cached.Digest)
+	ctx = metadata.AppendToOutgoingContext(ctx, extraHeaderIntrinsicClientID, h.clientIDHeader)
+	ctx = attachImageName(ctx, imageName)
+	response := &artifactpb.ArtifactResponse{Ref: imageName}
+	return h.withModes(ctx, func(mode UploadMode) error {
+		return h.uploadManifest(ctx, h.optionsFor(mode), response, cachedManifest{image: cached})
+	})
+}
+
+func (h *defaultHelper) UploadIndex(ctx context.Context, imageName string, index crv1.ImageIndex) error {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return fmt.Errorf("error reading index: %w", err)
+	}
+
+	// children go first, the index can only be stored once all of them exist.
+	for _, desc := range indexManifest.Manifests {
+		childName := getDigestReference(imageName, desc.Digest.String())
+		switch {
+		case desc.MediaType.IsImage():
+			image, err := index.Image(desc.Digest)
+			if err != nil {
+				return fmt.Errorf("cannot read image %s: %w", desc.Digest, err)
+			}
+			if err := h.UploadImage(ctx, childName, image); err != nil {
+				return fmt.Errorf("error uploading image %s: %w", desc.Digest, err)
+			}
+		case desc.MediaType.IsIndex():
+			child, err := index.ImageIndex(desc.Digest)
+			if err != nil {
+				return fmt.Errorf("cannot read index %s: %w", desc.Digest, err)
+			}
+			if err := h.UploadIndex(ctx, childName, child); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported manifest %s of type %s", desc.Digest, desc.MediaType)
+		}
+	}
+
+	manifest, err := toIndexManifest(index)
+	if err != nil {
+		return fmt.Errorf("error reading index: %w", err)
+	}
+	request := &artifactpb.ImageRequest{
+		Name:     imageName,
+		Manifest: manifest,
+	}
+	response, err := h.client.CheckImage(ctx, request)
+	if err != nil {
+		return fmt.Errorf("check image failed: %w", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, extraHeaderIntrinsicClientID, h.clientIDHeader)
+	ctx = attachImageName(ctx, imageName)
+	return h.withModes(ctx, func(mode UploadMode) error {
+		return h.uploadIndexPartsWithMode(ctx, mode, index, indexManifest, response)
+	})
+}
+
+func (h *defaultHelper) uploadIndexPartsWithMode(ctx context.Context, mode UploadMode, index crv1.ImageIndex, indexManifest *crv1.IndexManifest, response *artifactpb.ArtifactResponse) error {
+	options := h.optionsFor(mode)
+	taskGroup, _ := errgroup.WithContext(ctx)
+	taskGroup.SetLimit(mode.Parallelism)
+
+	log.InfoContextf(ctx, "uploading index %s to upstream...", response.Ref)
+	missingRefs := asRefMap(response.MissingRefs...)
+	// children are stored under their repository digest reference already,
+	// the service may expect them under their plain digest as well.
+	for _, desc := range indexManifest.Manifests {
+		if _, missing := missingRefs[desc.Digest.String()]; !missing {
+			continue
+		}
+		var raw func() ([]byte, error)
+		if desc.MediaType.IsIndex() {
+			child, err := index.ImageIndex(desc.Digest)
+			if err != nil {
+				return fmt.Errorf("cannot read index %s: %w", desc.Digest, err)
+			}
+			raw = child.RawManifest
+		} else {
+			child, err := index.Image(desc.Digest)
+			if err != nil {
+				return fmt.Errorf("cannot read image %s: %w", desc.Digest, err)
+			}
+			raw = child.RawManifest
+		}
+		task, err := newTask(ctx, options, h.client, response.MaxUpdateSize, asDigestNamed(descWrap{value: desc}), bytesReader(raw))
+		if err != nil {
+			return err
+		}
+		taskGroup.Go(runWithContext(ctx, task))
+	}
+	if err := taskGroup.Wait(); err != nil {
+		return fmt.Errorf("error uploading index: %w", err)
+	}
+
+	return h.uploadManifest(ctx, options, response, index)
+}
+
+// withModes runs upload in the mode picked by the uploader and repeats it in
+// safer modes for as long as adaptive uploads allow.
+func (h *defaultHelper) withModes(ctx context.Context, upload func(mode UploadMode) error) error {
+	for {
+		// blobs which were uploaded before a downgrade are skipped as they
+		// already exist, partial ones are resumed.
+		mode := h.modes.mode(ctx)
+		err := upload(mode)
+		if err == nil || !h.modes.downgrade(ctx, mode, err) {
+			return err
+		}
+	}
+}
+
+func (h *defaultHelper) optionsFor(mode UploadMode) uploaderOptions {
+	options := h.uploaderOptions
+	options.strategy = mode.strategy()
+	return options
 }
 
 func (h *defaultHelper) uploadImageParts(ctx context.Context, image crv1.Image, response *artifactpb.ArtifactResponse) error {
+	return h.withModes(ctx, func(mode UploadMode) error {
+		return h.uploadImagePartsWithMode(ctx, mode, image, response)
+	})
+}
+
+func (h *defaultHelper) uploadImagePartsWithMode(ctx context.Context, mode UploadMode, image crv1.Image, response *artifactpb.ArtifactResponse) error {
+	options := h.optionsFor(mode)
 	taskGroup, _ := errgroup.WithContext(ctx)
-	taskGroup.SetLimit(h.maxNumberOfUploads)
+	taskGroup.SetLimit(mode.Parallelism)
 
 	log.InfoContextf(ctx, "uploading image %s to upstream...", response.Ref)
 	missingRefs := asRefMap(response.MissingRefs...)
@@ -208,7 +418,7 @@ func (h *defaultHelper) uploadImageParts(ctx context.Context, image crv1.Image,
 				mediaType, _ := layer.MediaType()
 				log.InfoContextf(ctx, "uploading layer %s (%s) ", digest, mediaType)
 				delete(missingRefs, digest.String())
-				task, err := newTask(ctx, h.strategy, h.client, maxUpdateSize, asDigestNamed(layer), reader)
+				task, err := newTask(ctx, options, h.client, maxUpdateSize, asDigestNamed(layer), reader)
 				if err != nil {
 					return err
 				}
@@ -225,7 +435,7 @@ func (h *defaultHelper) uploadImageParts(ctx context.Context, image crv1.Image,
 		if _, missing := missingRefs[configRef]; missing || fullUpload {
 			log.InfoContextf(ctx, "uploading config for %s: ", response.Ref)
 			delete(missingRefs, configRef)
-			task, err := newTask(ctx, h.strategy, h.client, maxUpdateSize, asDigestNamed(descWrap{value: manifest.Config}), bytesReader(image.RawConfigFile))
+			task, err := newTask(ctx, options, h.client, maxUpdateSize, asDigestNamed(descWrap{value: manifest.Config}), bytesReader(image.RawConfigFile))
 			if err != nil {
 				return err
 			}
@@ -237,19 +447,38 @@ func (h *defaultHelper) uploadImageParts(ctx context.Context, image crv1.Image,
 		return fmt.Errorf("error uploading image: %w", err)
 	}
 
+	return h.uploadManifest(ctx, options, response, image)
+}
+
+// manifestObject is an image or an index, whose manifest ties together
+// previously uploaded blobs.
+type manifestObject interface {
+	partial.Describable
+	RawManifest() ([]byte, error)
+}
+
+// uploadManifest stores the manifest of object under the name of response.
+func (h *defaultHelper) uploadManifest(ctx context.Context, options uploaderOptions, response *artifactpb.ArtifactResponse, object manifestObject) error {
+	digest, err := object.Digest()
+	if err != nil {
+		return fmt.Errorf("system error: %w", err)
+	}
+
 	// this needs to be last step to tie together all previously uploaded blobs under image name
 	log.InfoContextf(ctx, "uploading manifest for %s", response.Ref)
-	manifestNames := []namedObject{asSimplyNamed(response.Ref, image)}
+	manifestNames := []namedObject{asSimplyNamed(response.Ref, object)}
 	if h.alignForK8s {
 		// In order to allow k8s to identify locally sourced image correctly
 		// we need to store manifest under few alternative names...
-		manifestNames = append(manifestNames,
-			asDigestNamed(image),
-			asSimplyNamed(getDigestReference(response.Ref, imgDigest.String()), image))
+		manifestNames = append(manifestNames, asDigestNamed(object))
+		if digestRef := getDigestReference(response.Ref, digest.String()); digestRef != response.Ref {
+			// children of an index are named by their digest reference already.
+			manifestNames = append(manifestNames, asSimplyNamed(digestRef, object))
+		}
 	}
 
 	for _, named := range manifestNames {
-		task, err := newTask(ctx, h.strategy, h.client, maxUpdateSize, named, bytesReader(image.RawManifest))
+		task, err := newTask(ctx, options, h.client, response.MaxUpdateSize, named, bytesReader(object.RawManifest))
 		if err != nil {
 			return err
 		}
@@ -305,39 +534,60 @@ func toImageManifest(image crv1.Image) (*artifactpb.ImageManifest, error) {
 		return nil, err
 	}
 
-	hash, err := image.Digest()
+	config, err := image.ConfigFile()
 	if err != nil {
 		return nil, err
 	}
 
-	size, err := image.Size()
+	ociDescriptor, err := toManifestDescriptor(image, manifest.Annotations)
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := image.RawManifest()
+	if platform := config.Platform(); platform != nil {
+		platformStr := platform.String()
+		ociDescriptor.Platform = &platformStr
+	}
+
+	return ociDescriptor, nil
+
+}
+
+func toIndexManifest(index crv1.ImageIndex) (*artifactpb.ImageManifest, error) {
+	manifest, err := index.IndexManifest()
 	if err != nil {
 		return nil, err
 	}
 
-	config, err := image.ConfigFile()
+	return toManifestDescriptor(index, manifest.Annotations)
+}
+
+func toManifestDescriptor(object manifestObject, annotations map[string]string) (*artifactpb.ImageManifest, error) {
+	mediaType, err := object.MediaType()
 	if err != nil {
 		return nil, err
 	}
 
-	ociDescriptor := &artifactpb.ImageManifest{
-		MediaType:   string(manifest.MediaType),
-		Digest:      hash.String(),
-		Size:        size,
-		Data:        data,
-		Annotations: manifest.Annotations,
+	hash, err := object.Digest()
+	if err != nil {
+		return nil, err
 	}
 
-	if platform := config.Platform(); platform != nil {
-		platformStr := platform.String()
-		ociDescriptor.Platform = &platformStr
+	size, err := object.Size()
+	if err != nil {
+		return nil, err
 	}
 
-	return ociDescriptor, nil
+	data, err := object.RawManifest()
+	if err != nil {
+		return nil, err
+	}
 
+	return &artifactpb.ImageManifest{
+		MediaType:   string(mediaType),
+		Digest:      hash.String(),
+		Size:        size,
+		Data:        data,
+		Annotations: annotations,
+	}, nil
 }
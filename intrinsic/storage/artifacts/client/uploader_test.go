@@ -0,0 +1,526 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/google/go-containerregistry/pkg/name"
+	crv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"intrinsic/storage/artifacts/client/artifacttest"
+	artifactgrpcpb "intrinsic/storage/artifacts/proto/artifact_go_grpc_proto"
+)
+
+const testImageName = "gcr.io/test-project/test-image:latest"
+
+var strategies = []struct {
+	name   string
+	option UploaderOption
+	method string
+}{
+	{name: "sequential", option: WithSequentialUpload(), method: artifacttest.MethodWriteContent},
+	{name: "streaming", option: WithStreamingUpload(), method: artifacttest.MethodUploadContent},
+}
+
+func init() {
+	newBackOff = func() backoff.BackOff {
+		return &backoff.ZeroBackOff{}
+	}
+	newChunkBackOff = func() backoff.BackOff {
+		return &backoff.ZeroBackOff{}
+	}
+}
+
+func randomImage(t *testing.T) crv1.Image {
+	t.Helper()
+	img, err := random.Image(1024, 3)
+	if err != nil {
+		t.Fatalf("random.Image() failed: %v", err)
+	}
+	return img
+}
+
+func newTestUploader(t *testing.T, client artifactgrpcpb.ArtifactServiceApiClient, opts ...UploaderOption) Uploader {
+	t.Helper()
+	uploader, err := NewUploader(client, opts...)
+	if err != nil {
+		t.Fatalf("NewUploader() failed: %v", err)
+	}
+	return uploader
+}
+
+// imageRefs returns the digests of the layers and the config of img.
+func imageRefs(t *testing.T, img crv1.Image) []string {
+	t.Helper()
+	manifest, err := img.Manifest()
+	if err != nil {
+		t.Fatalf("Manifest() failed: %v", err)
+	}
+	refs := []string{manifest.Config.Digest.String()}
+	for _, layer := range manifest.Layers {
+		refs = append(refs, layer.Digest.String())
+	}
+	return refs
+}
+
+func checkStored(t *testing.T, server *artifacttest.Server, ref string, want []byte) {
+	t.Helper()
+	got, ok := server.Content(ref)
+	if !ok {
+		t.Errorf("%s was not uploaded, server has %v", ref, server.Refs())
+		return
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("content of %s does not match (%d bytes, want %d bytes)", ref, len(got), len(want))
+	}
+}
+
+// checkImageStored verifies that all parts of img are stored under the given manifest names.
+func checkImageStored(t *testing.T, server *artifacttest.Server, img crv1.Image, manifestNames ...string) {
+	t.Helper()
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatalf("Layers() failed: %v", err)
+	}
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			t.Fatalf("Digest() failed: %v", err)
+		}
+		rc, err := layer.Compressed()
+		if err != nil {
+			t.Fatalf("Compressed() failed: %v", err)
+		}
+		content := new(bytes.Buffer)
+		content.ReadFrom(rc)
+		rc.Close()
+		checkStored(t, server, digest.String(), content.Bytes())
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		t.Fatalf("Manifest() failed: %v", err)
+	}
+	config, err := img.RawConfigFile()
+	if err != nil {
+		t.Fatalf("RawConfigFile() failed: %v", err)
+	}
+	checkStored(t, server, manifest.Config.Digest.String(), config)
+	rawManifest, err := img.RawManifest()
+	if err != nil {
+		t.Fatalf("RawManifest() failed: %v", err)
+	}
+	for _, name := range manifestNames {
+		checkStored(t, server, name, rawManifest)
+	}
+}
+
+func k8sNames(t *testing.T, img crv1.Image) []string {
+	t.Helper()
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("Digest() failed: %v", err)
+	}
+	ref, err := name.ParseReference(testImageName)
+	if err != nil {
+		t.Fatalf("name.ParseReference(%q) failed: %v", testImageName, err)
+	}
+	return []string{testImageName, digest.String(), ref.Context().Digest(digest.String()).Name()}
+}
+
+func TestUploadImage(t *testing.T) {
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			// A small update size makes every blob span several chunks.
+			server := artifacttest.NewServer(artifacttest.WithMaxUpdateSize(256))
+			uploader := newTestUploader(t, server.Client(t), s.option)
+			img := randomImage(t)
+
+			if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+				t.Fatalf("UploadImage() failed: %v", err)
+			}
+
+			checkImageStored(t, server, img, k8sNames(t, img)...)
+			if got := server.Calls(artifacttest.MethodCheckImage); got != 1 {
+				t.Errorf("UploadImage() called CheckImage %d times, want 1", got)
+			}
+			if got := server.Calls(s.method); got == 0 {
+				t.Errorf("UploadImage() did not call %s", s.method)
+			}
+		})
+	}
+}
+
+func TestUploadImageWithoutK8sAlignment(t *testing.T) {
+	server := artifacttest.NewServer()
+	uploader := newTestUploader(t, server.Client(t), WithAlignForK8sDeployments(false))
+	img := randomImage(t)
+
+	if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+
+	checkImageStored(t, server, img, testImageName)
+	digest, _ := img.Digest()
+	if _, ok := server.Content(digest.String()); ok {
+		t.Errorf("UploadImage() stored manifest under %s without K8s alignment", digest)
+	}
+}
+
+func TestUploadImageFromArchive(t *testing.T) {
+	server := artifacttest.NewServer()
+	uploader := newTestUploader(t, server.Client(t))
+	img := randomImage(t)
+	archive := path.Join(t.TempDir(), "image.tar")
+	if err := tarball.WriteToFile(archive, name.MustParseReference(testImageName), img); err != nil {
+		t.Fatalf("tarball.WriteToFile() failed: %v", err)
+	}
+
+	if err := uploader.UploadImageFromArchive(context.Background(), testImageName, ContentFromPath(archive)); err != nil {
+		t.Fatalf("UploadImageFromArchive() failed: %v", err)
+	}
+
+	checkImageStored(t, server, img, k8sNames(t, img)...)
+}
+
+func TestUploadImageSkipsPresentRefs(t *testing.T) {
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			server := artifacttest.NewServer()
+			uploader := newTestUploader(t, server.Client(t), s.option)
+			img := randomImage(t)
+			layers, err := img.Layers()
+			if err != nil {
+				t.Fatalf("Layers() failed: %v", err)
+			}
+			present, err := layers[0].Digest()
+			if err != nil {
+				t.Fatalf("Digest() failed: %v", err)
+			}
+			// The fake does not verify content stored with Put.
+			server.Put(present.String(), []byte("present"))
+
+			if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+				t.Fatalf("UploadImage() failed: %v", err)
+			}
+
+			if got := server.WriteAttempts(present.String()); got != 0 {
+				t.Errorf("UploadImage() wrote present layer %s %d times, want 0", present, got)
+			}
+			for _, ref := range imageRefs(t, img) {
+				if ref == present.String() {
+					continue
+				}
+				if got := server.WriteAttempts(ref); got != 1 {
+					t.Errorf("UploadImage() wrote missing ref %s %d times, want 1", ref, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadImageTwice(t *testing.T) {
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			server := artifacttest.NewServer()
+			uploader := newTestUploader(t, server.Client(t), s.option)
+			img := randomImage(t)
+
+			for i := 0; i < 2; i++ {
+				if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+					t.Fatalf("UploadImage() #%d failed: %v", i, err)
+				}
+			}
+
+			for _, ref := range imageRefs(t, img) {
+				if got := server.WriteAttempts(ref); got != 1 {
+					t.Errorf("UploadImage() wrote %s %d times, want 1", ref, got)
+				}
+			}
+			checkImageStored(t, server, img, k8sNames(t, img)...)
+		})
+	}
+}
+
+func TestUploadImageAlreadyExists(t *testing.T) {
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			server := artifacttest.NewServer()
+			uploader := newTestUploader(t, server.Client(t), s.option)
+			img := randomImage(t)
+			manifest, err := img.Manifest()
+			if err != nil {
+				t.Fatalf("Manifest() failed: %v", err)
+			}
+			config, err := img.RawConfigFile()
+			if err != nil {
+				t.Fatalf("RawConfigFile() failed: %v", err)
+			}
+			// The config is uploaded on a full upload even if it is reported as present.
+			configRef := manifest.Config.Digest.String()
+			server.Put(configRef, config)
+
+			if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+				t.Fatalf("UploadImage() failed: %v", err)
+			}
+
+			if got := server.WriteAttempts(configRef); got != 1 {
+				t.Errorf("UploadImage() wrote config %d times, want 1", got)
+			}
+			checkImageStored(t, server, img, k8sNames(t, img)...)
+		})
+	}
+}
+
+func TestUploadImageRetriesUnavailable(t *testing.T) {
+	server := artifacttest.NewServer()
+	uploader := newTestUploader(t, server.Client(t), WithSequentialUpload())
+	img := randomImage(t)
+	server.FailNext(artifacttest.MethodWriteContent, "", 2, status.Error(codes.Unavailable, "try again"))
+
+	if err := uploader.UploadImage(context.Background(), testImageName, img); err != nil {
+		t.Fatalf("UploadImage() failed: %v", err)
+	}
+
+	checkImageStored(t, server, img, k8sNames(t, img)...)
+}
+
+func TestUploadImageFailures(t *testing.T) {
+	img := randomImage(t)
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatalf("Layers() failed: %v", err)
+	}
+	layer, err := layers[1].Digest()
+	if err != nil {
+		t.Fatalf("Digest() failed: %v", err)
+	}
+	tests := []struct {
+		name    string
+		option  UploaderOption
+		method  string
+		ref     string
+		wantErr string
+	}{
+		{
+			name:    "check image",
+			option:  WithSequentialUpload(),
+			method:  artifacttest.MethodCheckImage,
+			wantErr: "check image failed",
+		},
+		{
+			name:    "sequential layer",
+			option:  WithSequentialUpload(),
+			method:  artifacttest.MethodWriteContent,
+			ref:     layer.String(),
+			wantErr: "error uploading image",
+		},
+		{
+			name:    "streaming layer",
+			option:  WithStreamingUpload(),
+			method:  artifacttest.MethodUploadContent,
+			ref:     layer.String(),
+			wantErr: "error uploading image",
+		},
+		{
+			name:    "manifest",
+			option:  WithStreamingUpload(),
+			method:  artifacttest.MethodUploadContent,
+			ref:     testImageName,
+			wantErr: "error uploading manifest",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := artifacttest.NewServer()
+			uploader := newTestUploader(t, server.Client(t), tc.option)
+			server.FailNext(tc.method, tc.ref, 1, status.Error(codes.PermissionDenied, "denied"))
+
+			err := uploader.UploadImage(context.Background(), testImageName, img)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("UploadImage() returned error %v, want error containing %q", err, tc.wantErr)
+			}
+			if status.Code(err) != codes.PermissionDenied {
+				t.Errorf("UploadImage() returned error %v, want PermissionDenied", err)
+			}
+			if _, ok := server.Content(testImageName); ok {
+				t.Errorf("UploadImage() stored manifest of %s despite failure", testImageName)
+			}
+		})
+	}
+}
+
+func TestUploadImageHonorsContext(t *testing.T) {
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			server := artifacttest.NewServer(artifacttest.WithLatency(time.Minute))
+			uploader := newTestUploader(t, server.Client(t), s.option)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			if err := uploader.UploadImage(ctx, testImageName, randomImage(t)); err == nil {
+				t.Errorf("UploadImage() succeeded, want deadline error")
+			}
+		})
+	}
+}
+
+type recordingMonitor struct {
+	mu   sync.Mutex
+	last map[string]ProgressUpdate
+}
+
+func (m *recordingMonitor) UpdateProgress(ref string, update ProgressUpdate) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last[ref] = update
+}
+
+func TestUploadImageReportsProgress(t *testing.T) {
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			server := artifacttest.NewServer(artifacttest.WithMaxUpdateSize(256))
+			uploader := newTestUploader(t, server.Client(t), s.option)
+			img := randomImage(t)
+			monitor := &recordingMonitor{last: map[string]ProgressUpdate{}}
+			ctx := SetProgressMonitor(context.Background(), monitor)
+
+			if err := uploader.UploadImage(ctx, testImageName, img); err != nil {
+				t.Fatalf("UploadImage() failed: %v", err)
+			}
+
+			for _, ref := range append(imageRefs(t, img), testImageName) {
+				update, ok := monitor.last[asShortName(ref)]
+				if !ok {
+					t.Errorf("no progress reported for %s", ref)
+					continue
+				}
+				if update.Status != StatusSuccess || update.Current != update.Total {
+					t.Errorf("last progress of %s is %v, want success of all bytes", ref, update)
+				}
+			}
+		})
+	}
+}
+
+func randomIndex(t *testing.T) crv1.ImageIndex {
+	t.Helper()
+	index, err := random.Index(1024, 2, 2)
+	if err != nil {
+		t.Fatalf("random.Index() failed: %v", err)
+	}
+	return index
+}
+
+// indexImages returns the images of index by their digest reference in the repository of testImageName.
+func indexImages(t *testing.T, index crv1.ImageIndex) map[string]crv1.Image {
+	t.Helper()
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		t.Fatalf("IndexManifest() failed: %v", err)
+	}
+	images := make(map[string]crv1.Image, len(manifest.Manifests))
+	for _, desc := range manifest.Manifests {
+		img, err := index.Image(desc.Digest)
+		if err != nil {
+			t.Fatalf("Image(%s) failed: %v", desc.Digest, err)
+		}
+		images[getDigestReference(testImageName, desc.Digest.String())] = img
+	}
+	return images
+}
+
+func TestUploadIndex(t *testing.T) {
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			server := artifacttest.NewServer(artifacttest.WithMaxUpdateSize(256))
+			uploader := newTestUploader(t, server.Client(t), s.option)
+			index := randomIndex(t)
+
+			if err := uploader.UploadIndex(context.Background(), testImageName, index); err != nil {
+				t.Fatalf("UploadIndex() failed: %v", err)
+			}
+
+			images := indexImages(t, index)
+			for ref, img := range images {
+				digest, err := img.Digest()
+				if err != nil {
+					t.Fatalf("Digest() failed: %v", err)
+				}
+				checkImageStored(t, server, img, ref, digest.String())
+			}
+			rawIndex, err := index.RawManifest()
+			if err != nil {
+				t.Fatalf("RawManifest() failed: %v", err)
+			}
+			digest, err := index.Digest()
+			if err != nil {
+				t.Fatalf("Digest() failed: %v", err)
+			}
+			for _, name := range []string{testImageName, digest.String(), getDigestReference(testImageName, digest.String())} {
+				checkStored(t, server, name, rawIndex)
+			}
+			if got, want := server.Calls(artifacttest.MethodCheckImage), len(images)+1; got != want {
+				t.Errorf("UploadIndex() called CheckImage %d times, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestUploadIndexWithoutK8sAlignment(t *testing.T) {
+	server := artifacttest.NewServer()
+	uploader := newTestUploader(t, server.Client(t), WithAlignForK8sDeployments(false))
+	index := randomIndex(t)
+
+	if err := uploader.UploadIndex(context.Background(), testImageName, index); err != nil {
+		t.Fatalf("UploadIndex() failed: %v", err)
+	}
+
+	// the index references its images by digest, so they are stored under it anyway.
+	for ref, img := range indexImages(t, index) {
+		digest, err := img.Digest()
+		if err != nil {
+			t.Fatalf("Digest() failed: %v", err)
+		}
+		checkImageStored(t, server, img, ref, digest.String())
+	}
+	rawIndex, err := index.RawManifest()
+	if err != nil {
+		t.Fatalf("RawManifest() failed: %v", err)
+	}
+	checkStored(t, server, testImageName, rawIndex)
+	digest, _ := index.Digest()
+	if _, ok := server.Content(digest.String()); ok {
+		t.Errorf("UploadIndex() stored index under %s without K8s alignment", digest)
+	}
+}
+
+func TestUploadIndexTwice(t *testing.T) {
+	server := artifacttest.NewServer()
+	uploader := newTestUploader(t, server.Client(t))
+	index := randomIndex(t)
+
+	for i := 0; i < 2; i++ {
+		if err := uploader.UploadIndex(context.Background(), testImageName, index); err != nil {
+			t.Fatalf("UploadIndex() #%d failed: %v", i, err)
+		}
+	}
+
+	for _, img := range indexImages(t, index) {
+		for _, ref := range imageRefs(t, img) {
+			if got := server.WriteAttempts(ref); got != 1 {
+				t.Errorf("UploadIndex() started %d writes of %s, want 1", got, ref)
+			}
+		}
+	}
+}
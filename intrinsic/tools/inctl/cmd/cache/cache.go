@@ -0,0 +1,28 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package cache contains commands to inspect and prune the local cache of
+// images uploaded to clusters.
+package cache
+
+import (
+	"github.com/spf13/cobra"
+	"intrinsic/storage/artifacts/client/client"
+	"intrinsic/tools/inctl/cmd/root"
+)
+
+// Can be overwritten/injected in tests.
+var openCache = client.OpenDefaultCache
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manages the local cache of uploaded images",
+	Long: `Manages the local cache of uploaded images.
+
+Installing skills and services remembers which images a cluster already holds. Installing an
+unchanged image again only uploads its manifest, without checking for missing layers or hashing
+them. Prune the cache if a cluster lost its content, e.g. after it was reset.`,
+}
+
+func init() {
+	root.RootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"intrinsic/storage/artifacts/client/client"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+var flagImages bool
+
+// targetList is printed by cache ls.
+type targetList struct {
+	Path    string        `json:"path"`
+	Targets []targetEntry `json:"targets"`
+}
+
+type targetEntry struct {
+	Target    string    `json:"target"`
+	Digests   int       `json:"digests"`
+	Confirmed time.Time `json:"confirmed"`
+}
+
+// Table returns a row for every target.
+func (l *targetList) Table() *printer.Table {
+	t := &printer.Table{Columns: []printer.Column{{Name: "Target"}, {Name: "Digests"}, {Name: "Last Confirmed"}}}
+	for _, target := range l.Targets {
+		t.Rows = append(t.Rows, []string{target.Target, strconv.Itoa(target.Digests), target.Confirmed.Format(time.RFC3339)})
+	}
+	return t
+}
+
+func (l *targetList) String() string {
+	if len(l.Targets) == 0 {
+		return fmt.Sprintf("No uploads cached in %s", l.Path)
+	}
+	return l.Table().String()
+}
+
+// imageList is printed by cache ls --images.
+type imageList struct {
+	Path   string       `json:"path"`
+	Images []imageEntry `json:"images"`
+}
+
+type imageEntry struct {
+	Config string    `json:"config"`
+	Digest string    `json:"digest"`
+	Blobs  int       `json:"blobs"`
+	Used   time.Time `json:"used"`
+}
+
+// Table returns a row for every image.
+func (l *imageList) Table() *printer.Table {
+	t := &printer.Table{Columns: []printer.Column{{Name: "Digest"}, {Name: "Config", Hidden: true}, {Name: "Blobs"}, {Name: "Last Used"}}}
+	for _, image := range l.Images {
+		t.Rows = append(t.Rows, []string{image.Digest, image.Config, strconv.Itoa(image.Blobs), image.Used.Format(time.RFC3339)})
+	}
+	return t
+}
+
+func (l *imageList) String() string {
+	if len(l.Images) == 0 {
+		return fmt.Sprintf("No images cached in %s", l.Path)
+	}
+	return l.Table().String()
+}
+
+func listTargets(cache *client.Cache) *targetList {
+	result := &targetList{Path: cache.Path()}
+	for _, target := range cache.Targets() {
+		result.Targets = append(result.Targets, targetEntry{
+			Target:    target.Name,
+			Digests:   target.Digests,
+			Confirmed: target.Confirmed,
+		})
+	}
+	return result
+}
+
+func listImages(cache *client.Cache) *imageList {
+	result := &imageList{Path: cache.Path()}
+	for _, image := range cache.Images() {
+		result.Images = append(result.Images, imageEntry{
+			Config: image.Config,
+			Digest: image.Digest,
+			Blobs:  len(image.Blobs),
+			Used:   image.Used,
+		})
+	}
+	return result
+}
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "Lists the targets and images known to the cache",
+	Long: `Lists the targets, e.g. clusters, the cache knows the content of.
+
+With --images, the cached images are listed instead, most recently used first.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		prtr, err := printer.NewPrinter(root.FlagOutput, printer.WithSortBy(root.FlagSortBy))
+		if err != nil {
+			return err
+		}
+		cache, err := openCache()
+		if err != nil {
+			return err
+		}
+		if flagImages {
+			prtr.Print(listImages(cache))
+		} else {
+			prtr.Print(listTargets(cache))
+		}
+		return nil
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(listCmd)
+	listCmd.Flags().BoolVar(&flagImages, "images", false, "Lists the cached images instead of the targets.")
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+var (
+	flagTarget    string
+	flagOlderThan time.Duration
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Removes entries from the cache",
+	Long: `Removes entries from the cache, by default all of them.
+
+With --target, only what the cache knows about the content of that target is removed. The next
+install to the target checks again which parts of the images are missing.`,
+	Example: `Forget everything about a cluster which was reset
+$ inctl cache prune --target dns:///www.endpoints.my_project.cloud.goog:443/my_cluster
+
+Remove entries which were not used for a day
+$ inctl cache prune --older_than 24h`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+		if flagOlderThan < 0 {
+			return fmt.Errorf("--older_than must not be negative, got %s", flagOlderThan)
+		}
+		cache, err := openCache()
+		if err != nil {
+			return err
+		}
+		pruned, err := cache.Prune(flagTarget, flagOlderThan)
+		if err != nil {
+			return fmt.Errorf("cannot prune cache: %w", err)
+		}
+		prtr.PrintSf("Removed %d entries from %s", pruned, cache.Path())
+		return nil
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().StringVar(&flagTarget, "target", "", "Only removes what the cache knows about this target, as listed by cache ls.")
+	pruneCmd.Flags().DurationVar(&flagOlderThan, "older_than", 0, "Only removes entries which were not used for this long. Removes all entries if 0.")
+}
@@ -3,17 +3,16 @@
 package cluster
 
 import (
-	"bytes"
 	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"slices"
-	"strings"
-	"text/tabwriter"
+	"strconv"
 
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 	clusterdiscoverygrpcpb "intrinsic/frontend/cloud/api/clusterdiscovery_api_go_grpc_proto"
 	clusterdiscoverypb "intrinsic/frontend/cloud/api/clusterdiscovery_api_go_grpc_proto"
 	"intrinsic/skills/tools/skill/cmd/dialerutil"
@@ -55,8 +54,8 @@ func (res *ListClusterDescriptionsResponse) MarshalJSON() ([]byte, error) {
 	}{Clusters: clusters})
 }
 
-// String converts a ListClusterDescriptionsResponse to a string
-func (res *ListClusterDescriptionsResponse) String() string {
+// Table converts a ListClusterDescriptionsResponse to a table.
+func (res *ListClusterDescriptionsResponse) Table() *printer.Table {
 	// Sort by display name to match IPC managers's default sort.
 	clusters := make([]*clusterdiscoverypb.ClusterDescription, len(res.m.Clusters))
 	copy(clusters, res.m.Clusters)
@@ -64,16 +63,39 @@ func (res *ListClusterDescriptionsResponse) String() string {
 		return cmp.Compare(a.GetDisplayName(), b.GetDisplayName())
 	})
 
-	b := new(bytes.Buffer)
-	w := tabwriter.NewWriter(b,
-		/*minwidth=*/ 1 /*tabwidth=*/, 1 /*padding=*/, 1 /*padchar=*/, ' ' /*flags=*/, 0)
-	fmt.Fprintf(w, "%s\t%s\t%s\n", "Display Name", "ID", "Region")
-	for _, c := range res.m.Clusters {
-		fmt.Fprintf(w, "%s\t%s\t%s\n", c.GetDisplayName(), c.GetClusterName(), c.GetRegion())
+	t := &printer.Table{
+		Columns: []printer.Column{
+			{Name: "Display Name"},
+			{Name: "ID"},
+			{Name: "Region"},
+			{Name: "K8s Context", Hidden: true},
+			{Name: "Can Do Sim", Hidden: true},
+			{Name: "Can Do Real", Hidden: true},
+			{Name: "Has GPU", Hidden: true},
+		},
 	}
-	w.Flush()
-	// Remove the trailing newline as the pretty-printer wrapper will add one.
-	return strings.TrimSuffix(b.String(), "\n")
+	for _, c := range clusters {
+		t.Rows = append(t.Rows, []string{
+			c.GetDisplayName(),
+			c.GetClusterName(),
+			c.GetRegion(),
+			c.GetK8SContext(),
+			strconv.FormatBool(c.GetCanDoSim()),
+			strconv.FormatBool(c.GetCanDoReal()),
+			strconv.FormatBool(c.GetHasGpu()),
+		})
+	}
+	return t
+}
+
+// Proto returns the underlying proto message.
+func (res *ListClusterDescriptionsResponse) Proto() proto.Message {
+	return res.m
+}
+
+// String converts a ListClusterDescriptionsResponse to a string
+func (res *ListClusterDescriptionsResponse) String() string {
+	return res.Table().String()
 }
 
 func fetchAndPrintClusters(ctx context.Context, conn *grpc.ClientConn, prtr printer.Printer) error {
@@ -95,7 +117,7 @@ var clusterListCmd = &cobra.Command{
 	Long:  "List compute cluster on the given project.",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		prtr, err := printer.NewPrinter(root.FlagOutput)
+		prtr, err := printer.NewPrinter(root.FlagOutput, printer.WithSortBy(root.FlagSortBy))
 		if err != nil {
 			return err
 		}
@@ -3,12 +3,11 @@
 package customer
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"slices"
 	"strings"
-	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
@@ -98,30 +97,54 @@ type users struct {
 	rs []*pb.RoleBinding
 }
 
-func (us *users) String() string {
-	b := new(bytes.Buffer)
-	w := tabwriter.NewWriter(b,
-		/*minwidth=*/ 1 /*tabwidth=*/, 1 /*padding=*/, 1 /*padchar=*/, ' ' /*flags=*/, 0)
-	fmt.Fprintf(w, "%s\t%s\t%s\n", "Email", "Roles", "Status")
+type user struct {
+	Email  string   `json:"email"`
+	Roles  []string `json:"roles"`
+	Status string   `json:"status"`
+}
+
+// list returns the members of the organization followed by the pending invitations, each sorted
+// by email.
+func (us *users) list() []user {
+	var out []user
 	// iterate memberships
 	slices.SortFunc(us.ms, func(a, b *pb.OrganizationMembership) int {
 		return strings.Compare(a.GetEmail(), b.GetEmail())
 	})
 	urs := userRoles(us.rs)
 	for _, m := range us.ms {
-		roles := urs[m.GetEmail()]
-		fmt.Fprintf(w, "%s\t%s\t%s\n", m.GetEmail(), formatRoles(roles), "active")
+		out = append(out, user{Email: m.GetEmail(), Roles: trimRoles(urs[m.GetEmail()]), Status: "active"})
 	}
 	// iterate invitations
 	slices.SortFunc(us.is, func(a, b *pb.OrganizationInvitation) int {
 		return strings.Compare(a.GetEmail(), b.GetEmail())
 	})
 	for _, o := range us.is {
-		fmt.Fprintf(w, "%s\t%s\t%s\n", o.GetEmail(), formatRoles(o.GetRoles()), "pending")
+		out = append(out, user{Email: o.GetEmail(), Roles: trimRoles(o.GetRoles()), Status: "pending"})
 	}
-	w.Flush()
-	// Remove the trailing newline as the pretty-printer wrapper will add one.
-	return strings.TrimSuffix(b.String(), "\n")
+	return out
+}
+
+// MarshalJSON converts the users to a byte slice.
+func (us *users) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Users []user `json:"users"`
+	}{Users: us.list()})
+}
+
+// Table converts the users to a table.
+func (us *users) Table() *printer.Table {
+	t := &printer.Table{
+		Columns: []printer.Column{{Name: "Email"}, {Name: "Roles"}, {Name: "Status"}},
+	}
+	for _, u := range us.list() {
+		t.Rows = append(t.Rows, []string{u.Email, strings.Join(u.Roles, ", "), u.Status})
+	}
+	return t
+}
+
+func (us *users) String() string {
+	return us.Table().String()
 }
 
 func userRoles(rs []*pb.RoleBinding) map[string][]string {
@@ -133,13 +156,13 @@ func userRoles(rs []*pb.RoleBinding) map[string][]string {
 	return roles
 }
 
-func formatRoles(rs []string) string {
+func trimRoles(rs []string) []string {
 	roles := []string{}
 	for _, r := range rs {
 		roles = append(roles, strings.TrimPrefix(r, "roles/"))
 	}
 	slices.Sort(roles)
-	return strings.Join(roles, ", ")
+	return roles
 }
 
 var listUsersHelp = `
@@ -173,7 +196,7 @@ var listUsers = &cobra.Command{
 			return err
 		}
 		// format and print the results
-		prtr, err := printer.NewPrinter(root.FlagOutput)
+		prtr, err := printer.NewPrinter(root.FlagOutput, printer.WithSortBy(root.FlagSortBy))
 		if err != nil {
 			return err
 		}
@@ -12,9 +12,9 @@ import (
 
 	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/spf13/viper"
-	"google.golang.org/grpc"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"intrinsic/assets/lro"
 	"intrinsic/config/environments"
 	"intrinsic/tools/inctl/cmd/root"
 	"intrinsic/tools/inctl/util/cobrautil"
@@ -69,50 +69,26 @@ func protoPrint(p proto.Message) {
 	fmt.Println(string(ms))
 }
 
-type getOperationFunc func(ctx context.Context, in *lropb.GetOperationRequest, opts ...grpc.CallOption) (*lropb.Operation, error)
-
 const (
 	pollInterval = time.Second * 5
 )
 
-func waitForOperation(ctx context.Context, getLongOp getOperationFunc, lro *lropb.Operation, timeout time.Duration) error {
-	if lro == nil {
+func waitForOperation(ctx context.Context, client lro.Client, op *lropb.Operation, timeout time.Duration) error {
+	if op == nil {
 		return fmt.Errorf("no operation to wait for")
 	}
-	if lro.Done {
-		fmt.Printf("Operation (%q) completed\n", lro.Name)
+	if op.GetDone() {
+		fmt.Printf("Operation (%q) completed\n", op.GetName())
 		return nil
 	}
 
-	fmt.Printf("Waiting for operation (%q) to complete (%.1f seconds timeout, %v poll interval).\n",
-		lro.Name, timeout.Seconds(), pollInterval)
+	fmt.Printf("Waiting for operation (%q) to complete (%.1f seconds timeout, %v max poll interval).\n",
+		op.GetName(), timeout.Seconds(), pollInterval)
 	ts := time.Now()
 	defer func() {
 		fmt.Printf("Waited %.1f seconds for operation.\n", time.Since(ts).Seconds())
 	}()
 
-	ctx, stop := context.WithTimeout(ctx, timeout)
-	defer stop()
-	ticker := time.NewTicker(pollInterval)
-	defer ticker.Stop()
-
-	req := lropb.GetOperationRequest{Name: lro.Name}
-	for {
-		select {
-		case <-ticker.C:
-			lro, err := getLongOp(ctx, &req)
-			if err != nil {
-				return err
-			}
-			if !lro.GetDone() {
-				continue
-			}
-			if lro.GetError() != nil {
-				return fmt.Errorf("operation %q failed: %v", lro.GetName(), lro.GetError())
-			}
-			return nil
-		case <-ctx.Done():
-			return fmt.Errorf("operation %q timed out", lro.GetName())
-		}
-	}
+	_, err := lro.Wait(ctx, client, op, lro.WithTimeout(timeout), lro.WithPollInterval(time.Second, pollInterval))
+	return err
 }
@@ -68,7 +68,7 @@ var createCmd = &cobra.Command{
 		if flagDebugRequests {
 			protoPrint(op)
 		}
-		if err := waitForOperation(ctx, cl.GetOperation, op, 10*time.Minute); err != nil {
+		if err := waitForOperation(ctx, cl, op, 10*time.Minute); err != nil {
 			return fmt.Errorf("failed to wait for operation: %w", err)
 		}
 		if flagSkipPaymentPlan {
@@ -89,7 +89,7 @@ var createCmd = &cobra.Command{
 		if flagDebugRequests {
 			protoPrint(op)
 		}
-		if err := waitForOperation(ctx, cl.GetOperation, op, 10*time.Minute); err != nil {
+		if err := waitForOperation(ctx, cl, op, 10*time.Minute); err != nil {
 			return fmt.Errorf("failed to wait for operation: %w", err)
 		}
 		return nil
@@ -80,7 +80,7 @@ var grantRoleBindingCmd = &cobra.Command{
 		if flagDebugRequests {
 			protoPrint(lrop)
 		}
-		if err := waitForOperation(ctx, cl.GetOperation, lrop, 10*time.Minute); err != nil {
+		if err := waitForOperation(ctx, cl, lrop, 10*time.Minute); err != nil {
 			return fmt.Errorf("failed to wait for operation: %w", err)
 		}
 		return nil
@@ -116,7 +116,7 @@ var revokeRoleBindingCmd = &cobra.Command{
 		if flagDebugRequests {
 			protoPrint(lrop)
 		}
-		if err := waitForOperation(ctx, cl.GetOperation, lrop, 10*time.Minute); err != nil {
+		if err := waitForOperation(ctx, cl, lrop, 10*time.Minute); err != nil {
 			return fmt.Errorf("failed to wait for operation: %w", err)
 		}
 		return nil
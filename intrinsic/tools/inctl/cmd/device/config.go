@@ -3,7 +3,6 @@
 package device
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -12,10 +11,10 @@ import (
 	"strings"
 	"time"
 
-	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"intrinsic/assets/lro"
 	clustermanagerpb "intrinsic/frontend/cloud/api/v1/clustermanager_api_go_grpc_proto"
 	"intrinsic/frontend/cloud/devicemanager/shared/shared"
 	"intrinsic/tools/inctl/cmd/root"
@@ -30,6 +29,8 @@ const (
 
 var (
 	errConfigGone = fmt.Errorf("config was rejected")
+
+	configSetTimeout time.Duration
 )
 
 func prettyPrintStatusInterfaces(interfaces map[string]shared.StatusInterface) string {
@@ -160,7 +161,7 @@ var configSetCmd = &cobra.Command{
 			Device:  deviceID,
 			Config:  translateToNetworkConfig(config),
 		}
-		lro, err := client.grpcClient.UpdateNetworkConfig(ctx, req)
+		op, err := client.grpcClient.UpdateNetworkConfig(ctx, req)
 		if err != nil {
 			switch status.Code(err) {
 			case codes.NotFound:
@@ -171,31 +172,11 @@ var configSetCmd = &cobra.Command{
 			return err
 		}
 
-		lroctx, stop := context.WithTimeout(ctx, time.Minute*3)
-		defer stop()
-
-		ticker := time.NewTicker(time.Second * 5)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				lro, err := client.grpcClient.GetOperation(ctx, &lropb.GetOperationRequest{Name: lro.GetName()})
-				if err != nil {
-					return err
-				}
-				if !lro.GetDone() {
-					continue
-				}
-				if lro.GetError() != nil {
-					return fmt.Errorf("operation %q failed: %v", lro.GetName(), lro.GetError())
-				}
-				fmt.Println("Successfully applied new network configuration to the device.")
-				return nil
-			case <-lroctx.Done():
-				return fmt.Errorf("operation %q timed out", lro.GetName())
-			}
+		if _, err := lro.Wait(ctx, client.grpcClient, op, lro.WithTimeout(configSetTimeout), lro.WithPollInterval(time.Second, 5*time.Second)); err != nil {
+			return err
 		}
+		fmt.Println("Successfully applied new network configuration to the device.")
+		return nil
 	},
 }
 
@@ -203,4 +184,5 @@ func init() {
 	deviceCmd.AddCommand(configCmd)
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configSetCmd)
+	configSetCmd.Flags().DurationVar(&configSetTimeout, "timeout", 3*time.Minute, "Maximum time to wait for the device to apply the new network configuration.")
 }
@@ -4,6 +4,7 @@
 package logs
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -31,6 +32,12 @@ const (
 	keyTypeResource  = "resource"
 	keyHiddenDebug   = "debug"
 	keyOnpremAddress = "onprem_address"
+	keySelector      = "selector"
+
+	// argTarget is the value of --skill and --service if they are given without a value. Their
+	// target is then taken from the positional arguments, like with the former boolean flags in
+	// "inctl logs my_skill --skill".
+	argTarget = "<ARG>"
 )
 
 const (
@@ -41,18 +48,66 @@ const (
 var (
 	flagContext      string
 	flagUseLocalhost bool
+	flagTargets      []flagTarget
+	flagSelector     string
 )
 
+// flagTarget is a value of --skill or --service.
+type flagTarget struct {
+	resourceType resourceType
+	target       string
+}
+
+// targetFlag collects the values of the repeatable --skill or --service flag. Both flags append to
+// one list to keep the order of the command line, which is needed to match flags without a value to
+// positional arguments.
+type targetFlag struct {
+	resourceType resourceType
+	targets      *[]flagTarget
+}
+
+func (f *targetFlag) String() string {
+	var values []string
+	for _, t := range *f.targets {
+		if t.resourceType == f.resourceType {
+			values = append(values, t.target)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+func (f *targetFlag) Set(value string) error {
+	*f.targets = append(*f.targets, flagTarget{resourceType: f.resourceType, target: value})
+	return nil
+}
+
+func (f *targetFlag) Type() string {
+	return "string"
+}
+
 var (
 	showLogs = &cobra.Command{
-		Use:        "logs",
-		Aliases:    []string{"slogs"},
-		Example:    "inctl logs --org ORGANIZATION --solution SOLUTION-ID --follow --service NAME",
-		Short:      "Prints logs from the solution",
-		Long:       "Prints resource logs (skill or service) from the instance running in given solution.",
-		Args:       cobra.ExactArgs(1),
-		ArgAliases: []string{"ID"},
-		RunE:       runLogsCmd,
+		Use:     "logs",
+		Aliases: []string{"slogs"},
+		Example: `Follow the logs of a service
+$ inctl logs --org ORGANIZATION --solution SOLUTION-ID --follow --service=NAME
+
+Follow the logs of a skill and a service at once
+$ inctl logs --org ORGANIZATION --solution SOLUTION-ID --follow --prefix_id --skill=ID --service=NAME
+
+Follow the logs of all skills and services of a vendor
+$ inctl logs --org ORGANIZATION --solution SOLUTION-ID --follow --prefix_type --prefix_id --selector "ai.intrinsic.*"`,
+		Short: "Prints logs from the solution",
+		Long: `Prints resource logs (skills or services) from the instances running in given solution.
+
+--skill and --service can be repeated and combined with --selector to read the logs of several
+skills and services at once. Their log lines are interleaved as they arrive and can be told apart
+with --prefix_type and --prefix_id.
+
+Values of --skill and --service are given with "=", e.g. --skill=ID. Passing them as separate
+arguments, e.g. "--skill ID" or "inctl logs ID --skill", is deprecated.`,
+		Args: cobra.ArbitraryArgs,
+		RunE: runLogsCmd,
 	}
 
 	localViper = viper.New()
@@ -60,11 +115,6 @@ var (
 )
 
 func runLogsCmd(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return cmd.Help()
-	}
-	target := args[0]
-
 	verboseDebug = cmdFlags.GetBool(keyHiddenDebug)
 	verboseOut = cmd.OutOrStderr()
 
@@ -77,18 +127,80 @@ func runLogsCmd(cmd *cobra.Command, args []string) error {
 		solution:      cmdFlags.GetString(cmdutils.KeySolution),
 		org:           cmdFlags.GetFlagOrganization(),
 		onpremAddress: cmdFlags.GetString(keyOnpremAddress),
+		sinceSeconds:  cmdFlags.GetString(keySinceSec),
+		prefixType:    cmdFlags.GetBool(keyPrefixType),
+		prefixID:      cmdFlags.GetBool(keyPrefixID),
 	}
 
-	var err error
-	if params.resourceType, err = getResourceType(); err != nil {
+	targets, err := getTargets(cmd.Context(), params, args, cmd.ErrOrStderr())
+	if err != nil {
 		return err
 	}
+	if len(targets) == 0 {
+		if flagSelector != "" {
+			return fmt.Errorf("no skills or services match --%s %q", keySelector, flagSelector)
+		}
+		return cmd.Help()
+	}
 
-	if params.resourceID, err = getResourceID(params.resourceType, target); err != nil {
-		return err
+	return readLogsFromSolution(cmd.Context(), params, targets, cmd.OutOrStdout())
+}
+
+// getTargets returns the skills and services given by --skill, --service and --selector. Each
+// target is only returned once.
+func getTargets(ctx context.Context, params *cmdParams, args []string, warnings io.Writer) ([]logTarget, error) {
+	flags, err := resolveFlagTargets(flagTargets, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(warnings, "Warning: passing skills and services as separate arguments is deprecated and will soon be an error. Please use --%s=ID or --%s=NAME.\n", keyTypeSkill, keyTypeService)
+	}
+
+	var targets []logTarget
+	for _, f := range flags {
+		id, err := getResourceID(f.resourceType, f.target)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, logTarget{resourceType: f.resourceType, resourceID: id})
+	}
+	if flagSelector != "" {
+		selected, err := selectTargets(ctx, params, flagSelector)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, selected...)
 	}
 
-	return readLogsFromSolution(cmd.Context(), params, cmd.OutOrStdout())
+	seen := make(map[logTarget]bool)
+	unique := targets[:0]
+	for _, target := range targets {
+		if !seen[target] {
+			seen[target] = true
+			unique = append(unique, target)
+		}
+	}
+	return unique, nil
+}
+
+// resolveFlagTargets replaces the values of --skill and --service flags which were given without a
+// value by the positional arguments in order.
+func resolveFlagTargets(flags []flagTarget, args []string) ([]flagTarget, error) {
+	resolved := make([]flagTarget, 0, len(flags))
+	for _, f := range flags {
+		if f.target == argTarget {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("--%s or --%s without a value needs an argument", keyTypeSkill, keyTypeService)
+			}
+			f.target, args = args[0], args[1:]
+		}
+		resolved = append(resolved, f)
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("unexpected arguments %q, use --%s or --%s", args, keyTypeSkill, keyTypeService)
+	}
+	return resolved, nil
 }
 
 func getResourceID(resType resourceType, target string) (string, error) {
@@ -130,22 +242,11 @@ func getResourceID(resType resourceType, target string) (string, error) {
 	return k8sNormalized, nil
 }
 
-func getResourceType() (resourceType, error) {
-	if cmdFlags.IsSet(keyTypeSkill) {
-		return rtSkill, nil
-	}
-	if cmdFlags.IsSet(keyTypeService) {
-		return rtService, nil
-	}
-	// todo: make sure resource is mentioned in error internally.
-	return -1, fmt.Errorf("resource type for target not set, needs --%s or --%s", keyTypeSkill, keyTypeService)
-}
-
 func init() {
 	root.RootCmd.AddCommand(showLogs)
 	cmdFlags.SetCommand(showLogs)
 
-	// inctl logs --(org|project) --solution [--address] --follow --(service|skill|resource) (manifest|id)
+	// inctl logs --(org|project) --solution [--address] --follow [--(service|skill)=(manifest|id)]... [--selector glob]
 
 	cmdFlags.AddFlagsProjectOrg()
 
@@ -161,8 +262,11 @@ func init() {
 	cmdFlags.OptionalInt(keyTailLines, 10, "The number of recent log lines to display. An input number less than 0 shows all log lines.")
 	cmdFlags.OptionalString(keySinceSec, "", "Show logs starting since value. Value is either relative (e.g 10m) or \ndate time in RFC3339 format (e.g: 2006-01-02T15:04:05Z07:00)")
 
-	cmdFlags.OptionalBool(keyTypeSkill, false, "Indicates logs source is the skill")
-	cmdFlags.OptionalBool(keyTypeService, false, "Indicates logs source is the service")
+	showLogs.Flags().Var(&targetFlag{resourceType: rtSkill, targets: &flagTargets}, keyTypeSkill, "The ID or manifest file of a skill to read logs from. Can be repeated.")
+	showLogs.Flags().Lookup(keyTypeSkill).NoOptDefVal = argTarget
+	showLogs.Flags().Var(&targetFlag{resourceType: rtService, targets: &flagTargets}, keyTypeService, "The instance name or manifest file of a service to read logs from. Can be repeated.")
+	showLogs.Flags().Lookup(keyTypeService).NoOptDefVal = argTarget
+	showLogs.Flags().StringVar(&flagSelector, keySelector, "", "Reads the logs of all skills and services whose ID or instance name matches this glob pattern, e.g. \"ai.intrinsic.*\" or \"*\".")
 
 	cmdFlags.OptionalBool(keyHiddenDebug, false, "Prints extensive debug messages")
 
@@ -170,6 +274,5 @@ func init() {
 	cmdFlags.OptionalString(keyOnpremAddress, "", "The onprem address (host:port) of the workcell. Used to circumvent the need of routing through the cloud, if the workcell is running in the same network as the inctl")
 
 	cmdFlags.MarkHidden(cmdutils.KeyContext, cmdutils.KeyProject, keyTypeResource)
-	showLogs.MarkFlagsMutuallyExclusive(keyTypeSkill, keyTypeService)
 
 }
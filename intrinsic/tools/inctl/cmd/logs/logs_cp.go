@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,12 +19,18 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	"intrinsic/assets/cmdutils"
 	bpb "intrinsic/logging/proto/blob_go_proto"
 	dgrpcpb "intrinsic/logging/proto/log_dispatcher_service_go_grpc_proto"
-	dpb "intrinsic/logging/proto/log_dispatcher_service_go_grpc_proto"
+	lipb "intrinsic/logging/proto/log_item_go_proto"
+	lgrpcpb "intrinsic/logging/proto/logger_service_go_grpc_proto"
+	lpb "intrinsic/logging/proto/logger_service_go_grpc_proto"
+	"intrinsic/skills/tools/skill/cmd/dialerutil"
 	"intrinsic/tools/inctl/auth/auth"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
 )
 
 const (
@@ -72,153 +79,223 @@ func newLogDispatcherClient(ctx context.Context) (dgrpcpb.LogDispatcherClient, e
 	return dgrpcpb.NewLogDispatcherClient(conn), nil
 }
 
-func writeBlob(blob *bpb.Blob, localDir string) error {
+// blobFile is printed for every blob written by logs cp.
+type blobFile struct {
+	BlobID string `json:"blobId"`
+	File   string `json:"file"`
+	Bytes  int    `json:"bytes"`
+}
+
+func (f *blobFile) String() string {
+	return "file://" + f.File
+}
+
+// responseFile is printed for every page of log items written by logs cp.
+type responseFile struct {
+	File     string `json:"file"`
+	NumItems int    `json:"numItems"`
+	NumBlobs int    `json:"numBlobs"`
+}
+
+func (f *responseFile) String() string {
+	return fmt.Sprintf("Wrote %d log items to file://%s", f.NumItems, f.File)
+}
+
+// copySummary is printed when logs cp finished.
+type copySummary struct {
+	EventSource string `json:"eventSource"`
+	Destination string `json:"destination"`
+	NumItems    int    `json:"numItems"`
+	NumBlobs    int    `json:"numBlobs"`
+	NumFiles    int    `json:"numFiles"`
+}
+
+func (s *copySummary) String() string {
+	return fmt.Sprintf("Copied %d log items and %d blobs of %s to %s", s.NumItems, s.NumBlobs, s.EventSource, s.Destination)
+}
+
+func writeBlob(blob *bpb.Blob, localDir string) (*blobFile, error) {
 	dir := path.Join(localDir, path.Dir(blob.BlobId))
 	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-		return errors.Wrapf(err, "os.MkdirAll %s", dir)
+		return nil, errors.Wrapf(err, "os.MkdirAll %s", dir)
 	}
 	p := path.Join(localDir, blob.BlobId)
 	if err := os.WriteFile(p, blob.GetData(), 0644); err != nil {
-		return errors.Wrapf(err, "os.WriteFile of blob to %s", p)
+		return nil, errors.Wrapf(err, "os.WriteFile of blob to %s", p)
 	}
+	f := &blobFile{BlobID: blob.GetBlobId(), File: p, Bytes: len(blob.GetData())}
 	// Clear the blob data so we can write the rest of the response as a textproto.
 	blob.Data = []byte{}
-	fmt.Printf("file://%s\n", p)
-	return nil
+	return f, nil
 }
 
-func getLogsOnprem(ctx context.Context, eventSource string, dir string) error {
-	return errors.New("not implemented")
+// newDataLoggerClient connects to the DataLogger of the cluster given by --context, either
+// directly through localhost or through the cloud relay of the project.
+func newDataLoggerClient(ctx context.Context) (context.Context, lgrpcpb.DataLoggerClient, *grpc.ClientConn, error) {
+	params := dialerutil.DialInfoParams{
+		Cluster:  flagContext,
+		CredName: cmdFlags.GetString(cmdutils.KeyProject),
+		CredOrg:  cmdFlags.GetString(cmdutils.KeyOrganization),
+	}
+	if flagUseLocalhost {
+		params = dialerutil.DialInfoParams{Address: localhostURL}
+	}
+	ctx, conn, err := dialerutil.DialConnectionCtx(ctx, params)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "could not connect to cluster %q", flagContext)
+	}
+	return ctx, lgrpcpb.NewDataLoggerClient(conn), conn, nil
 }
 
-func getLogsFromCloud(ctx context.Context, eventSource string, dir string) error {
-	client, err := newLogDispatcherClient(ctx)
-	if err != nil {
-		return errors.Wrap(err, "newLogDispatcherClient")
+// writeResponse writes a page of log items to dir. Blobs are written to separate files and the
+// remaining log items to a response_<nanos>.pbtxt file, the same layout the cloud path uses.
+func writeResponse(prtr printer.Printer, resp proto.Message, items []*lipb.LogItem, dir string) (*responseFile, error) {
+	page := &responseFile{NumItems: len(items)}
+	for _, item := range items {
+		if blob := item.GetBlobPayload(); blob != nil {
+			f, err := writeBlob(blob, dir)
+			if err != nil {
+				return nil, err
+			}
+			prtr.Print(f)
+			page.NumBlobs++
+		}
+		item.BlobPayload = nil
 	}
-	orgID := cmdFlags.GetString(cmdutils.KeyOrganization)
-	if orgID == "" {
-		return errors.Wrap(err, "org should be specificied")
+	page.File = path.Join(dir, fmt.Sprintf("response_%d.pbtxt", time.Now().UnixNano()))
+	if err := os.WriteFile(page.File, []byte(prototext.Format(resp)), 0644); err != nil {
+		return nil, errors.Wrapf(err, "os.WriteFile of response to %s", page.File)
 	}
-	if flagHistoricStartTimestamp == "" || flagHistoricEndTimestamp == "" {
-		return errors.Wrap(err, "historic start timestamp and historic end timestamp should be specified")
+	prtr.Print(page)
+	return page, nil
+}
+
+func getLogsOnprem(ctx context.Context, prtr printer.Printer, eventSources []string, dir string) error {
+	if !flagUseLocalhost && cmdFlags.GetString(cmdutils.KeyProject) == "" {
+		return fmt.Errorf("--%s is required unless --use_localhost is set", cmdutils.KeyProject)
 	}
-	fmt.Println("Creating cloud cache and loading logs...This may take a while.")
-	startTime, err := time.Parse(time.RFC3339, flagHistoricStartTimestamp)
+	ctx, client, conn, err := newDataLoggerClient(ctx)
 	if err != nil {
-		return errors.Wrapf(err, "invalid start timestamp: %s", flagHistoricStartTimestamp)
+		return err
 	}
-	endTime, err := time.Parse(time.RFC3339, flagHistoricEndTimestamp)
+	defer conn.Close()
+	return getLogsFromDataLogger(ctx, client, prtr, eventSources, dir)
+}
+
+// getLogsFromDataLogger copies the recent log items of the given event sources from client to dir.
+func getLogsFromDataLogger(ctx context.Context, client lgrpcpb.DataLoggerClient, prtr printer.Printer, eventSources []string, dir string) error {
+	sources, err := client.ListLogSources(ctx, &emptypb.Empty{})
 	if err != nil {
-		return errors.Wrapf(err, "invalid end timestamp: %s", flagHistoricEndTimestamp)
+		return errors.Wrap(err, "client.ListLogSources")
 	}
-	loadRequest := &dpb.LoadCloudLogItemsRequest{
-		LoadQuery: &dpb.LoadCloudLogItemsRequest_Query{
-			LogSource: &dpb.LogSource{
-				EventSource:  eventSource,
-				WorkcellName: flagContext,
-			},
-		},
-		StartTime:      timestamppb.New(startTime),
-		EndTime:        timestamppb.New(endTime),
-		OrganizationId: orgID,
+	for _, eventSource := range eventSources {
+		if !slices.Contains(sources.GetEventSources(), eventSource) {
+			available := slices.Clone(sources.GetEventSources())
+			slices.Sort(available)
+			return fmt.Errorf("event source %q not found on cluster %q, available event sources:\n  %s", eventSource, flagContext, strings.Join(available, "\n  "))
+		}
 	}
-	loadResp, err := client.LoadCloudLogItems(ctx, loadRequest)
-	if err != nil {
-		return errors.Wrap(err, "client.LoadCloudLogItems")
-	}
-	if loadResp.Metadata.NumItems == 0 {
-		fmt.Println("No logs found matched the query")
-		return errors.Wrapf(err, "no logs found matched the query")
-	}
-	fmt.Println("Finished creating cloud cache.")
-	fmt.Println("Getting logs from cloud cache and writing to disk...This may take a while.")
-	// Initial sleep to allow the logs to be loaded into the cloud cache.
-	time.Sleep(30 * time.Second)
-	getReq := &dpb.GetCloudLogItemsRequest{
-		Query: &dpb.GetCloudLogItemsRequest_GetQuery{
-			GetQuery: &dpb.GetCloudLogItemsRequest_Query{
-				LogSource: &dpb.LogSource{
-					EventSource:  eventSource,
-					WorkcellName: flagContext,
-				},
-				StartTime: timestamppb.New(startTime),
-				EndTime:   timestamppb.New(endTime),
-			},
+
+	endTime := time.Now()
+	for _, eventSource := range eventSources {
+		if err := copyOnpremLogs(ctx, client, prtr, eventSource, dir, endTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyOnpremLogs copies the log items of one event source logged within --lookback before endTime.
+func copyOnpremLogs(ctx context.Context, client lgrpcpb.DataLoggerClient, prtr printer.Printer, eventSource string, dir string, endTime time.Time) error {
+	req := &lpb.GetLogItemsRequest{
+		StartCondition: &lpb.GetLogItemsRequest_StartTime{
+			StartTime: timestamppb.New(endTime.Add(-flagLookBack)),
 		},
-		SessionToken:   loadResp.GetSessionToken(),
-		MaxNumItems:    proto.Uint32(defaultMaxNumItems),
-		OrganizationId: orgID,
+		EndTime:      timestamppb.New(endTime),
+		EventSources: []string{eventSource},
+		MaxNumItems:  defaultMaxNumItems,
 	}
-	waitTimeForLogs := 5 * time.Second
-	waitAttemptsForLogs := 10
+	summary := &copySummary{EventSource: eventSource, Destination: dir}
 	for {
-		getResp, err := client.GetCloudLogItems(ctx, getReq, grpc.MaxCallRecvMsgSize(defaultReceiveSize))
+		resp, err := client.GetLogItems(ctx, req, grpc.MaxCallRecvMsgSize(defaultReceiveSize))
 		if err != nil {
-			if waitAttemptsForLogs > 0 && strings.Contains(err.Error(), "No logs found") {
-				waitAttemptsForLogs--
-				fmt.Println("No logs found, waiting again for logs to be loaded...")
-				time.Sleep(waitTimeForLogs)
-				continue
-			}
-			return errors.Wrap(err, "client.GetCloudLogItems")
+			return errors.Wrap(err, "client.GetLogItems")
 		}
-		for _, item := range getResp.GetItems() {
-			blob := item.GetBlobPayload()
-			if blob != nil {
-				writeBlob(blob, dir)
+		if len(resp.GetLogItems()) > 0 {
+			page, err := writeResponse(prtr, resp, resp.GetLogItems(), dir)
+			if err != nil {
+				return err
 			}
-			item.BlobPayload = nil
-		}
-		responseFilename := fmt.Sprintf("response_%d.pbtxt", time.Now().UnixNano())
-		p := path.Join(dir, responseFilename)
-		if err = os.WriteFile(p, []byte(prototext.Format(getResp)), 0644); err != nil {
-			return errors.Wrapf(err, "os.WriteFile of response to %s", p)
+			summary.NumItems += page.NumItems
+			summary.NumBlobs += page.NumBlobs
+			summary.NumFiles++
 		}
-		if len(getResp.GetNextPageCursor()) == 0 {
+		if !resp.GetTruncated() || len(resp.GetCursor()) == 0 {
 			break
 		}
-		getReq = &dpb.GetCloudLogItemsRequest{
-			Query: &dpb.GetCloudLogItemsRequest_Cursor{
-				Cursor: getResp.GetNextPageCursor(),
+		req = &lpb.GetLogItemsRequest{
+			StartCondition: &lpb.GetLogItemsRequest_Cursor{
+				Cursor: resp.GetCursor(),
 			},
-			SessionToken:   loadResp.GetSessionToken(),
-			MaxNumItems:    proto.Uint32(defaultMaxNumItems),
-			OrganizationId: orgID,
+			EndTime:      timestamppb.New(endTime),
+			EventSources: []string{eventSource},
+			MaxNumItems:  defaultMaxNumItems,
 		}
 	}
+	if summary.NumItems == 0 {
+		prtr.PrintS(fmt.Sprintf("No logs of %s found in the last %v", eventSource, flagLookBack))
+	}
+	prtr.Print(summary)
 	return nil
 }
 
 var logsCpCmd = &cobra.Command{
-	Use:   "cp <event_source> <destination> [--lookback=600] | --historic [--historic_start_timestamp=2024-08-20T12:00:00Z --historic_end_timestamp=2024-08-20T12:00:00Z]",
+	Use:   "cp <event_source>... <destination> [--lookback=600] | --historic [--historic_start_timestamp=2024-08-20T12:00:00Z --historic_end_timestamp=2024-08-20T12:00:00Z]",
 	Short: "Copies recently logged blobs & logs to a local folder",
-	Long:  "Copies recently logged blobs & logs to a local folder",
-	Args:  cobra.ExactArgs(2),
+	Long: `Copies recently logged blobs & logs of one or more event sources to a local folder.
+
+By default, the log items of the last --lookback are read from the DataLogger of the cluster given
+by --context. Blobs are written to files named after their blob id and the remaining log items to
+response_*.pbtxt files in the destination folder.
+
+With --historic, log items which were synced to the cloud are copied instead. Up to --parallelism
+event sources are downloaded concurrently and the log items of each event source are written to a
+single <event_source>.binpb file of length-delimited LogItem protos, or a <event_source>.txtpb file
+with --item_format=textproto. The progress of the download is recorded in ` + manifestFilename + `
+in the destination folder, so running the same command again resumes an interrupted download.`,
+	Args: cobra.MinimumNArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
 		if flagContext == "minikube" && !flagUseLocalhost {
-			fmt.Printf("Context is set to \"minikube\". Setting --use_localhost.\n")
+			prtr.PrintS("Context is set to \"minikube\". Setting --use_localhost.")
 			flagUseLocalhost = true
 		}
 
 		ctx := cmd.Context()
-		if err := os.MkdirAll(args[1], os.ModePerm); err != nil {
-			return errors.Wrapf(err, "os.MkdirAll %s", args[1])
+		eventSources, dir := args[:len(args)-1], args[len(args)-1]
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return errors.Wrapf(err, "os.MkdirAll %s", dir)
 		}
 
 		if flagHistoric {
-			return getLogsFromCloud(ctx, args[0], args[1])
+			return getLogsFromCloud(ctx, prtr, eventSources, dir)
 		}
-		return getLogsOnprem(ctx, args[0], args[1])
+		return getLogsOnprem(ctx, prtr, eventSources, dir)
 	}}
 
 func init() {
 	showLogs.AddCommand(logsCpCmd)
 	logsCpCmd.Flags().DurationVar(&flagLookBack, "lookback", defaultLookback, "The time window to copy logs from")
 	logsCpCmd.Flags().StringVarP(&flagContext, "context", "c", "", "The Kubernetes cluster to use.")
-	logsCpCmd.Flags().BoolVar(&flagUseLocalhost, "use_localhost", false, "Connect to localhost instead of using SSH to connecting to the cluster.")
+	logsCpCmd.Flags().BoolVar(&flagUseLocalhost, "use_localhost", false, "Connect to the cluster through localhost instead of the cloud relay of --project.")
 	logsCpCmd.Flags().BoolVar(&flagHistoric, "historic", false, "Uses the cloud to fetch historical logs.")
 	logsCpCmd.Flags().StringVar(&flagHistoricStartTimestamp, "historic_start_timestamp", "", "Start timestamp in RFC3339 format for fetching historical logs. eg. 2024-08-20T12:00:00Z")
 	logsCpCmd.Flags().StringVar(&flagHistoricEndTimestamp, "historic_end_timestamp", "", "End timestamp in RFC3339 format for fetching historical logs. eg. 2024-08-20T12:00:00Z")
+	logsCpCmd.Flags().IntVar(&flagParallelism, "parallelism", defaultParallelism, "The number of event sources to download concurrently with --historic.")
+	logsCpCmd.Flags().StringVar(&flagItemFormat, "item_format", itemFormatBinary, fmt.Sprintf("The format of the log item files written with --historic, one of %q or %q.", itemFormatBinary, itemFormatTextProto))
+	logsCpCmd.Flags().DurationVar(&flagReadinessTimeout, "readiness_timeout", defaultReadinessTimeout, "The maximum time to wait for historic logs to be loaded into the cloud cache.")
 	logsCpCmd.MarkFlagRequired("context")
 }
@@ -0,0 +1,506 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	"intrinsic/assets/cmdutils"
+	dgrpcpb "intrinsic/logging/proto/log_dispatcher_service_go_grpc_proto"
+	dpb "intrinsic/logging/proto/log_dispatcher_service_go_grpc_proto"
+	lipb "intrinsic/logging/proto/log_item_go_proto"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+const (
+	// manifestFilename is the name of the file in the destination folder which records the progress
+	// of a historic download.
+	manifestFilename = "logs_cp_manifest.json"
+
+	itemFormatBinary    = "binary"
+	itemFormatTextProto = "textproto"
+
+	defaultParallelism      = 4
+	defaultReadinessTimeout = 5 * time.Minute
+	readinessInitialBackoff = time.Second
+	readinessMaxBackoff     = 15 * time.Second
+)
+
+var (
+	flagParallelism      int
+	flagItemFormat       string
+	flagReadinessTimeout time.Duration
+)
+
+var (
+	unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	errNotReady         = errors.New("log items are not loaded into the cloud cache yet")
+)
+
+// historicQuery identifies a historic download. A manifest is only resumed by the same query.
+type historicQuery struct {
+	Organization string    `json:"organization"`
+	Workcell     string    `json:"workcell"`
+	StartTime    time.Time `json:"startTime"`
+	EndTime      time.Time `json:"endTime"`
+	ItemFormat   string    `json:"itemFormat"`
+}
+
+// sourceProgress is the checkpoint of the download of one event source.
+type sourceProgress struct {
+	// File is the name of the log items file in the destination folder.
+	File string `json:"file"`
+	// SessionToken and SessionExpiry identify the cloud cache holding the loaded log items.
+	SessionToken  string    `json:"sessionToken,omitempty"`
+	SessionExpiry time.Time `json:"sessionExpiry"`
+	// Cursor points to the next page of log items. It is empty before the first page was written.
+	Cursor       []byte    `json:"cursor,omitempty"`
+	CursorExpiry time.Time `json:"cursorExpiry"`
+	// Offset is the size of File after the last written page. Anything after it is discarded when
+	// resuming.
+	Offset   int64 `json:"offset"`
+	NumItems int   `json:"numItems"`
+	NumBlobs int   `json:"numBlobs"`
+	Done     bool  `json:"done"`
+}
+
+// expired returns whether the session or cursor of a started download expired at the given time.
+func (p *sourceProgress) expired(now time.Time) bool {
+	if len(p.Cursor) == 0 {
+		return false
+	}
+	return now.After(p.SessionExpiry) || (!p.CursorExpiry.IsZero() && now.After(p.CursorExpiry))
+}
+
+// downloadManifest records the progress of a historic download. It is saved to the destination
+// folder after every page, so that an interrupted download can be resumed.
+type downloadManifest struct {
+	mu   sync.Mutex
+	path string
+
+	Query   historicQuery              `json:"query"`
+	Sources map[string]*sourceProgress `json:"sources"`
+}
+
+// loadManifest reads the manifest in dir or creates a new one if there is none. It fails if the
+// manifest in dir belongs to a different query.
+func loadManifest(dir string, query historicQuery) (*downloadManifest, error) {
+	m := &downloadManifest{
+		path:    path.Join(dir, manifestFilename),
+		Query:   query,
+		Sources: map[string]*sourceProgress{},
+	}
+	content, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "os.ReadFile of manifest %s", m.path)
+	}
+	var saved downloadManifest
+	if err := json.Unmarshal(content, &saved); err != nil {
+		return nil, errors.Wrapf(err, "invalid manifest %s", m.path)
+	}
+	if !saved.Query.equal(query) {
+		return nil, fmt.Errorf("%s contains the download of a different query, use another destination or delete it", m.path)
+	}
+	if saved.Sources != nil {
+		m.Sources = saved.Sources
+	}
+	return m, nil
+}
+
+func (q historicQuery) equal(other historicQuery) bool {
+	return q.Organization == other.Organization &&
+		q.Workcell == other.Workcell &&
+		q.StartTime.Equal(other.StartTime) &&
+		q.EndTime.Equal(other.EndTime) &&
+		q.ItemFormat == other.ItemFormat
+}
+
+// progress returns a copy of the checkpoint of an event source.
+func (m *downloadManifest) progress(eventSource string) sourceProgress {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.Sources[eventSource]; ok {
+		return *p
+	}
+	return sourceProgress{File: itemsFilename(eventSource, m.Query.ItemFormat)}
+}
+
+// checkpoint records the progress of an event source and saves the manifest.
+func (m *downloadManifest) checkpoint(eventSource string, p sourceProgress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Sources[eventSource] = &p
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "json.MarshalIndent of manifest")
+	}
+	// Write to a temporary file first so that an interruption never leaves a partial manifest.
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return errors.Wrapf(err, "os.WriteFile of manifest to %s", tmp)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return errors.Wrapf(err, "os.Rename of manifest to %s", m.path)
+	}
+	return nil
+}
+
+// itemsFilename returns the name of the file the log items of an event source are written to.
+func itemsFilename(eventSource string, itemFormat string) string {
+	name := unsafeFilenameChars.ReplaceAllString(eventSource, "_")
+	if itemFormat == itemFormatTextProto {
+		return name + ".txtpb"
+	}
+	return name + ".binpb"
+}
+
+// openItemsFile opens the log items file of an event source for appending after the last
+// checkpoint. Anything written after the checkpoint is discarded.
+func openItemsFile(p string, offset int64) (*os.File, error) {
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "os.OpenFile %s", p)
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "truncating %s", p)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "seeking in %s", p)
+	}
+	return f, nil
+}
+
+// writeItems appends log items to w. Binary items are written as length-delimited protos, text
+// items as log_items fields, so that a text file parses as a GetLogItemsResponse.
+func writeItems(w io.Writer, items []*lipb.LogItem, itemFormat string) error {
+	for _, item := range items {
+		switch itemFormat {
+		case itemFormatTextProto:
+			text := prototext.MarshalOptions{Multiline: true, Indent: "  "}.Format(item)
+			text = strings.ReplaceAll(strings.TrimSuffix(text, "\n"), "\n", "\n  ")
+			if _, err := fmt.Fprintf(w, "log_items {\n  %s\n}\n", text); err != nil {
+				return err
+			}
+		default:
+			if _, err := protodelim.MarshalTo(w, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncPrinter serializes the output of concurrent downloads.
+type syncPrinter struct {
+	mu sync.Mutex
+	p  printer.Printer
+}
+
+func (s *syncPrinter) Write(c []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.p.Write(c)
+}
+
+func (s *syncPrinter) Print(val any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.p.Print(val)
+}
+
+func (s *syncPrinter) PrintS(str string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.p.PrintS(str)
+}
+
+func (s *syncPrinter) PrintSf(format string, a ...any) {
+	s.PrintS(fmt.Sprintf(format, a...))
+}
+
+// historicDownload downloads the log items of several event sources from the cloud.
+type historicDownload struct {
+	client   dgrpcpb.LogDispatcherClient
+	prtr     printer.Printer
+	dir      string
+	query    historicQuery
+	manifest *downloadManifest
+}
+
+func getLogsFromCloud(ctx context.Context, prtr printer.Printer, eventSources []string, dir string) error {
+	orgID := cmdFlags.GetString(cmdutils.KeyOrganization)
+	if orgID == "" {
+		return errors.New("org should be specificied")
+	}
+	if flagHistoricStartTimestamp == "" || flagHistoricEndTimestamp == "" {
+		return errors.New("historic start timestamp and historic end timestamp should be specified")
+	}
+	if flagItemFormat != itemFormatBinary && flagItemFormat != itemFormatTextProto {
+		return fmt.Errorf("invalid --item_format %q, must be %q or %q", flagItemFormat, itemFormatBinary, itemFormatTextProto)
+	}
+	if flagParallelism < 1 {
+		return fmt.Errorf("--parallelism must be at least 1, got %d", flagParallelism)
+	}
+	startTime, err := time.Parse(time.RFC3339, flagHistoricStartTimestamp)
+	if err != nil {
+		return errors.Wrapf(err, "invalid start timestamp: %s", flagHistoricStartTimestamp)
+	}
+	endTime, err := time.Parse(time.RFC3339, flagHistoricEndTimestamp)
+	if err != nil {
+		return errors.Wrapf(err, "invalid end timestamp: %s", flagHistoricEndTimestamp)
+	}
+	client, err := newLogDispatcherClient(ctx)
+	if err != nil {
+		return errors.Wrap(err, "newLogDispatcherClient")
+	}
+
+	query := historicQuery{
+		Organization: orgID,
+		Workcell:     flagContext,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		ItemFormat:   flagItemFormat,
+	}
+	manifest, err := loadManifest(dir, query)
+	if err != nil {
+		return err
+	}
+	d := &historicDownload{
+		client:   client,
+		prtr:     &syncPrinter{p: prtr},
+		dir:      dir,
+		query:    query,
+		manifest: manifest,
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(flagParallelism)
+	for _, eventSource := range eventSources {
+		g.Go(func() error {
+			if err := d.downloadSource(ctx, eventSource); err != nil {
+				return errors.Wrapf(err, "downloading %s", eventSource)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return fmt.Errorf("%w\nrun the same command again to resume the download", err)
+	}
+	return nil
+}
+
+// downloadSource downloads the log items of one event source, starting from its last checkpoint.
+func (d *historicDownload) downloadSource(ctx context.Context, eventSource string) error {
+	p := d.manifest.progress(eventSource)
+	if p.Done {
+		d.prtr.PrintSf("Already downloaded %s, skipping it", eventSource)
+		d.printSummary(eventSource, p)
+		return nil
+	}
+	if p.expired(time.Now()) {
+		d.prtr.PrintSf("The cloud cache of %s expired, restarting its download", eventSource)
+		p = sourceProgress{File: p.File}
+	}
+
+	f, err := openItemsFile(path.Join(d.dir, p.File), p.Offset)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var resp *dpb.GetCloudLogItemsResponse
+	if len(p.Cursor) == 0 {
+		d.prtr.PrintSf("Loading logs of %s into the cloud cache...This may take a while.", eventSource)
+		load, err := d.load(ctx, eventSource)
+		if err != nil {
+			return err
+		}
+		if load.GetMetadata().GetNumItems() == 0 {
+			d.prtr.PrintSf("No logs of %s matched the query", eventSource)
+			p.Done = true
+			if err := d.manifest.checkpoint(eventSource, p); err != nil {
+				return err
+			}
+			d.printSummary(eventSource, p)
+			return nil
+		}
+		if load.GetTruncated() {
+			d.prtr.PrintSf("Loading logs of %s was truncated: %s", eventSource, load.GetTruncationCause())
+		}
+		p.SessionToken = load.GetSessionToken()
+		p.SessionExpiry = load.GetExpiry().AsTime()
+		if resp, err = d.waitForItems(ctx, d.firstPageRequest(eventSource, load)); err != nil {
+			return err
+		}
+	} else {
+		d.prtr.PrintSf("Resuming download of %s after %d log items", eventSource, p.NumItems)
+		if resp, err = d.getItems(ctx, d.cursorRequest(p.SessionToken, p.Cursor)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		page := &responseFile{File: f.Name(), NumItems: len(resp.GetItems())}
+		for _, item := range resp.GetItems() {
+			if blob := item.GetBlobPayload(); blob != nil {
+				bf, err := writeBlob(blob, d.dir)
+				if err != nil {
+					return err
+				}
+				d.prtr.Print(bf)
+				page.NumBlobs++
+			}
+			item.BlobPayload = nil
+		}
+		if err := writeItems(f, resp.GetItems(), d.query.ItemFormat); err != nil {
+			return errors.Wrapf(err, "writing log items to %s", f.Name())
+		}
+		if p.Offset, err = f.Seek(0, io.SeekCurrent); err != nil {
+			return errors.Wrapf(err, "seeking in %s", f.Name())
+		}
+		d.prtr.Print(page)
+
+		p.NumItems += page.NumItems
+		p.NumBlobs += page.NumBlobs
+		p.Cursor = resp.GetNextPageCursor()
+		p.CursorExpiry = time.Time{}
+		if resp.GetNextPageCursorExpiry() != nil {
+			p.CursorExpiry = resp.GetNextPageCursorExpiry().AsTime()
+		}
+		p.Done = len(p.Cursor) == 0
+		if err := d.manifest.checkpoint(eventSource, p); err != nil {
+			return err
+		}
+		if p.Done {
+			break
+		}
+		if resp, err = d.getItems(ctx, d.cursorRequest(p.SessionToken, p.Cursor)); err != nil {
+			return err
+		}
+	}
+	d.printSummary(eventSource, p)
+	return nil
+}
+
+func (d *historicDownload) printSummary(eventSource string, p sourceProgress) {
+	d.prtr.Print(&copySummary{
+		EventSource: eventSource,
+		Destination: path.Join(d.dir, p.File),
+		NumItems:    p.NumItems,
+		NumBlobs:    p.NumBlobs,
+		NumFiles:    1,
+	})
+}
+
+func (d *historicDownload) logSource(eventSource string) *dpb.LogSource {
+	return &dpb.LogSource{
+		EventSource:  eventSource,
+		WorkcellName: d.query.Workcell,
+	}
+}
+
+// load loads the log items of an event source into the cloud cache.
+func (d *historicDownload) load(ctx context.Context, eventSource string) (*dpb.LoadCloudLogItemsResponse, error) {
+	resp, err := d.client.LoadCloudLogItems(ctx, &dpb.LoadCloudLogItemsRequest{
+		LoadQuery: &dpb.LoadCloudLogItemsRequest_Query{
+			LogSource: d.logSource(eventSource),
+		},
+		StartTime:      timestamppb.New(d.query.StartTime),
+		EndTime:        timestamppb.New(d.query.EndTime),
+		OrganizationId: d.query.Organization,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.LoadCloudLogItems")
+	}
+	return resp, nil
+}
+
+// firstPageRequest returns the request for the first page of loaded log items. It uses the cursor
+// spanning the loaded range if the load returned one.
+func (d *historicDownload) firstPageRequest(eventSource string, load *dpb.LoadCloudLogItemsResponse) *dpb.GetCloudLogItemsRequest {
+	if len(load.GetCursor()) > 0 {
+		return d.cursorRequest(load.GetSessionToken(), load.GetCursor())
+	}
+	return &dpb.GetCloudLogItemsRequest{
+		Query: &dpb.GetCloudLogItemsRequest_GetQuery{
+			GetQuery: &dpb.GetCloudLogItemsRequest_Query{
+				LogSource: d.logSource(eventSource),
+				StartTime: timestamppb.New(d.query.StartTime),
+				EndTime:   timestamppb.New(d.query.EndTime),
+			},
+		},
+		SessionToken:   load.GetSessionToken(),
+		MaxNumItems:    proto.Uint32(defaultMaxNumItems),
+		OrganizationId: d.query.Organization,
+	}
+}
+
+func (d *historicDownload) cursorRequest(sessionToken string, cursor []byte) *dpb.GetCloudLogItemsRequest {
+	return &dpb.GetCloudLogItemsRequest{
+		Query: &dpb.GetCloudLogItemsRequest_Cursor{
+			Cursor: cursor,
+		},
+		SessionToken:   sessionToken,
+		MaxNumItems:    proto.Uint32(defaultMaxNumItems),
+		OrganizationId: d.query.Organization,
+	}
+}
+
+func (d *historicDownload) getItems(ctx context.Context, req *dpb.GetCloudLogItemsRequest) (*dpb.GetCloudLogItemsResponse, error) {
+	resp, err := d.client.GetCloudLogItems(ctx, req, grpc.MaxCallRecvMsgSize(defaultReceiveSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "client.GetCloudLogItems")
+	}
+	return resp, nil
+}
+
+// waitForItems gets the first page of log items after they were loaded into the cloud cache. The
+// load reported items to exist, so the cache is not ready as long as it returns NotFound or an
+// empty last page. Waiting backs off exponentially up to --readiness_timeout.
+func (d *historicDownload) waitForItems(ctx context.Context, req *dpb.GetCloudLogItemsRequest) (*dpb.GetCloudLogItemsResponse, error) {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = readinessInitialBackoff
+	b.MaxInterval = readinessMaxBackoff
+	b.MaxElapsedTime = flagReadinessTimeout
+	resp, err := backoff.RetryWithData(func() (*dpb.GetCloudLogItemsResponse, error) {
+		resp, err := d.client.GetCloudLogItems(ctx, req, grpc.MaxCallRecvMsgSize(defaultReceiveSize))
+		if status.Code(err) == codes.NotFound {
+			return nil, errNotReady
+		}
+		if err != nil {
+			return nil, backoff.Permanent(errors.Wrap(err, "client.GetCloudLogItems"))
+		}
+		if len(resp.GetItems()) == 0 && len(resp.GetNextPageCursor()) == 0 {
+			return nil, errNotReady
+		}
+		return resp, nil
+	}, backoff.WithContext(b, ctx))
+	if errors.Is(err, errNotReady) {
+		return nil, fmt.Errorf("%w after %v, increase --readiness_timeout", err, flagReadinessTimeout)
+	}
+	return resp, err
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testQuery() historicQuery {
+	return historicQuery{
+		Organization: "my_org",
+		Workcell:     "my_workcell",
+		StartTime:    time.Date(2024, 8, 20, 12, 0, 0, 0, time.UTC),
+		EndTime:      time.Date(2024, 8, 20, 13, 0, 0, 0, time.UTC),
+		ItemFormat:   itemFormatBinary,
+	}
+}
+
+func TestManifestResume(t *testing.T) {
+	dir := t.TempDir()
+	m, err := loadManifest(dir, testQuery())
+	if err != nil {
+		t.Fatalf("loadManifest() failed: %v", err)
+	}
+	p := m.progress("/robot/status")
+	if p.File != "_robot_status.binpb" {
+		t.Errorf("progress() returned file %q, want %q", p.File, "_robot_status.binpb")
+	}
+	p.SessionToken = "token"
+	p.SessionExpiry = time.Date(2024, 8, 21, 0, 0, 0, 0, time.UTC)
+	p.Cursor = []byte("cursor")
+	p.Offset = 42
+	p.NumItems = 5
+	if err := m.checkpoint("/robot/status", p); err != nil {
+		t.Fatalf("checkpoint() failed: %v", err)
+	}
+
+	resumed, err := loadManifest(dir, testQuery())
+	if err != nil {
+		t.Fatalf("loadManifest() of saved manifest failed: %v", err)
+	}
+	if diff := cmp.Diff(p, resumed.progress("/robot/status")); diff != "" {
+		t.Errorf("loadManifest() returned unexpected progress (-want +got):\n%s", diff)
+	}
+
+	other := testQuery()
+	other.EndTime = other.EndTime.Add(time.Hour)
+	if _, err := loadManifest(dir, other); err == nil || !strings.Contains(err.Error(), "different query") {
+		t.Errorf("loadManifest() for a different query returned error %v, want mismatch error", err)
+	}
+}
+
+func TestSourceProgressExpired(t *testing.T) {
+	now := time.Date(2024, 8, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		p    sourceProgress
+		want bool
+	}{
+		{name: "not started", p: sourceProgress{}, want: false},
+		{name: "valid", p: sourceProgress{Cursor: []byte("c"), SessionExpiry: now.Add(time.Minute)}, want: false},
+		{name: "session expired", p: sourceProgress{Cursor: []byte("c"), SessionExpiry: now.Add(-time.Minute)}, want: true},
+		{
+			name: "cursor expired",
+			p:    sourceProgress{Cursor: []byte("c"), SessionExpiry: now.Add(time.Hour), CursorExpiry: now.Add(-time.Minute)},
+			want: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.p.expired(now); got != tc.want {
+				t.Errorf("expired() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOpenItemsFileDiscardsUncheckpointedData(t *testing.T) {
+	p := path.Join(t.TempDir(), "items.binpb")
+	if err := os.WriteFile(p, []byte("checkpointed+partial page"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() failed: %v", err)
+	}
+	f, err := openItemsFile(p, int64(len("checkpointed")))
+	if err != nil {
+		t.Fatalf("openItemsFile() failed: %v", err)
+	}
+	if _, err := f.WriteString("+next page"); err != nil {
+		t.Fatalf("WriteString() failed: %v", err)
+	}
+	f.Close()
+
+	got, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatalf("os.ReadFile() failed: %v", err)
+	}
+	if want := "checkpointed+next page"; string(got) != want {
+		t.Errorf("items file contains %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	bpb "intrinsic/logging/proto/blob_go_proto"
+	lipb "intrinsic/logging/proto/log_item_go_proto"
+	lgrpcpb "intrinsic/logging/proto/logger_service_go_grpc_proto"
+	lpb "intrinsic/logging/proto/logger_service_go_grpc_proto"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+// fakeDataLoggerClient returns one page of log items per GetLogItems call. The cursor of a
+// response is the index of the next page.
+type fakeDataLoggerClient struct {
+	lgrpcpb.DataLoggerClient
+	eventSources []string
+	pages        [][]*lipb.LogItem
+	requests     []*lpb.GetLogItemsRequest
+}
+
+func (c *fakeDataLoggerClient) ListLogSources(ctx context.Context, req *emptypb.Empty, opts ...grpc.CallOption) (*lpb.ListLogSourcesResponse, error) {
+	return &lpb.ListLogSourcesResponse{EventSources: c.eventSources}, nil
+}
+
+func (c *fakeDataLoggerClient) GetLogItems(ctx context.Context, req *lpb.GetLogItemsRequest, opts ...grpc.CallOption) (*lpb.GetLogItemsResponse, error) {
+	c.requests = append(c.requests, req)
+	page := 0
+	if cursor := req.GetCursor(); cursor != nil {
+		var err error
+		if page, err = strconv.Atoi(string(cursor)); err != nil {
+			return nil, err
+		}
+	}
+	resp := &lpb.GetLogItemsResponse{}
+	if page < len(c.pages) {
+		for _, item := range c.pages[page] {
+			resp.LogItems = append(resp.LogItems, proto.Clone(item).(*lipb.LogItem))
+		}
+	}
+	if page+1 < len(c.pages) {
+		resp.Truncated = true
+		resp.Cursor = []byte(strconv.Itoa(page + 1))
+	}
+	return resp, nil
+}
+
+func logItem(eventSource string, uid uint64, blob *bpb.Blob) *lipb.LogItem {
+	return &lipb.LogItem{
+		Metadata:    &lipb.LogItem_Metadata{EventSource: eventSource, Uid: uid},
+		BlobPayload: blob,
+	}
+}
+
+func TestGetLogsFromDataLogger(t *testing.T) {
+	client := &fakeDataLoggerClient{
+		eventSources: []string{"/camera", "/robot/status"},
+		pages: [][]*lipb.LogItem{
+			{
+				logItem("/camera", 1, &bpb.Blob{BlobId: "images/1.jpg", Data: []byte("first")}),
+				logItem("/camera", 2, nil),
+			},
+			{logItem("/camera", 3, &bpb.Blob{BlobId: "images/3.jpg", Data: []byte("second")})},
+			{logItem("/camera", 4, nil)},
+		},
+	}
+	prtr, err := printer.NewPrinterWithWriter(printer.JSONOutputFormat, new(bytes.Buffer))
+	if err != nil {
+		t.Fatalf("NewPrinterWithWriter() failed: %v", err)
+	}
+	dir := t.TempDir()
+
+	if err := getLogsFromDataLogger(context.Background(), client, prtr, []string{"/camera"}, dir); err != nil {
+		t.Fatalf("getLogsFromDataLogger() failed: %v", err)
+	}
+
+	var cursors []string
+	for _, req := range client.requests {
+		if req.GetStartTime() == nil && req.GetCursor() == nil {
+			t.Errorf("GetLogItems() called without start time or cursor: %v", req)
+		}
+		if diff := cmp.Diff([]string{"/camera"}, req.GetEventSources()); diff != "" {
+			t.Errorf("GetLogItems() called with unexpected event sources (-want +got):\n%s", diff)
+		}
+		cursors = append(cursors, string(req.GetCursor()))
+	}
+	if diff := cmp.Diff([]string{"", "1", "2"}, cursors); diff != "" {
+		t.Errorf("GetLogItems() called with unexpected cursors (-want +got):\n%s", diff)
+	}
+
+	for blobID, want := range map[string]string{"images/1.jpg": "first", "images/3.jpg": "second"} {
+		got, err := os.ReadFile(path.Join(dir, blobID))
+		if err != nil {
+			t.Errorf("blob %q was not written: %v", blobID, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("blob %q has content %q, want %q", blobID, got, want)
+		}
+	}
+
+	responses, err := filepath.Glob(path.Join(dir, "response_*.pbtxt"))
+	if err != nil {
+		t.Fatalf("filepath.Glob() failed: %v", err)
+	}
+	var gotItems []*lipb.LogItem
+	for _, f := range responses {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("os.ReadFile(%q) failed: %v", f, err)
+		}
+		resp := &lpb.GetLogItemsResponse{}
+		if err := prototext.Unmarshal(b, resp); err != nil {
+			t.Fatalf("prototext.Unmarshal() of %q failed: %v", f, err)
+		}
+		gotItems = append(gotItems, resp.GetLogItems()...)
+	}
+	if len(responses) != len(client.pages) {
+		t.Errorf("getLogsFromDataLogger() wrote %d response files, want %d", len(responses), len(client.pages))
+	}
+	// Blobs are written to separate files and removed from the responses.
+	wantItems := []*lipb.LogItem{
+		logItem("/camera", 1, nil),
+		logItem("/camera", 2, nil),
+		logItem("/camera", 3, nil),
+		logItem("/camera", 4, nil),
+	}
+	if diff := cmp.Diff(wantItems, gotItems, protocmp.Transform(), protocmp.SortRepeated(func(a, b *lipb.LogItem) bool {
+		return a.GetMetadata().GetUid() < b.GetMetadata().GetUid()
+	})); diff != "" {
+		t.Errorf("getLogsFromDataLogger() wrote unexpected log items (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetLogsFromDataLoggerUnknownEventSource(t *testing.T) {
+	client := &fakeDataLoggerClient{eventSources: []string{"/robot/status", "/camera"}}
+	prtr, err := printer.NewPrinterWithWriter(printer.JSONOutputFormat, new(bytes.Buffer))
+	if err != nil {
+		t.Fatalf("NewPrinterWithWriter() failed: %v", err)
+	}
+
+	err = getLogsFromDataLogger(context.Background(), client, prtr, []string{"/camera", "/lidar"}, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "/camera\n  /robot/status") {
+		t.Errorf("getLogsFromDataLogger() returned error %v, want error listing the available event sources", err)
+	}
+	if len(client.requests) != 0 {
+		t.Errorf("getLogsFromDataLogger() called GetLogItems() %d times, want none", len(client.requests))
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	"intrinsic/assets/cmdutils"
+	dpb "intrinsic/logging/proto/log_dispatcher_service_go_grpc_proto"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+var flagMatch string
+
+// eventSources is printed by logs sources.
+type eventSources struct {
+	Workcell string   `json:"workcell"`
+	Sources  []string `json:"eventSources"`
+}
+
+// Table returns the event sources as a table with a single column.
+func (s *eventSources) Table() *printer.Table {
+	t := &printer.Table{Columns: []printer.Column{{Name: "Event Source"}}}
+	for _, source := range s.Sources {
+		t.Rows = append(t.Rows, []string{source})
+	}
+	return t
+}
+
+func (s *eventSources) String() string {
+	return strings.Join(s.Sources, "\n")
+}
+
+// listOnpremSources lists the event sources known to the DataLogger of the cluster.
+func listOnpremSources(ctx context.Context) ([]string, error) {
+	if !flagUseLocalhost && cmdFlags.GetString(cmdutils.KeyProject) == "" {
+		return nil, fmt.Errorf("--%s is required unless --use_localhost is set", cmdutils.KeyProject)
+	}
+	ctx, client, conn, err := newDataLoggerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp, err := client.ListLogSources(ctx, &emptypb.Empty{})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.ListLogSources")
+	}
+	var sources []string
+	for _, source := range resp.GetEventSources() {
+		if ok, _ := path.Match(flagMatch, source); flagMatch == "" || ok {
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+// listCloudSources lists the event sources of the workcell which hold log items in the cloud.
+func listCloudSources(ctx context.Context) ([]string, error) {
+	orgID := cmdFlags.GetString(cmdutils.KeyOrganization)
+	if orgID == "" {
+		return nil, errors.New("org should be specificied")
+	}
+	client, err := newLogDispatcherClient(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "newLogDispatcherClient")
+	}
+	query := &dpb.ListLogEventSourcesRequest_Query{WorkcellName: flagContext}
+	if flagMatch != "" {
+		query.MatchGlob = proto.String(flagMatch)
+	}
+	req := &dpb.ListLogEventSourcesRequest{
+		Query:          &dpb.ListLogEventSourcesRequest_ListQuery{ListQuery: query},
+		OrganizationId: orgID,
+	}
+	var sources []string
+	for {
+		resp, err := client.ListLogEventSources(ctx, req)
+		if err != nil {
+			return nil, errors.Wrap(err, "client.ListLogEventSources")
+		}
+		sources = append(sources, resp.GetEventSources()...)
+		if len(resp.GetNextPageCursor()) == 0 {
+			break
+		}
+		req = &dpb.ListLogEventSourcesRequest{
+			Query:          &dpb.ListLogEventSourcesRequest_Cursor{Cursor: resp.GetNextPageCursor()},
+			OrganizationId: orgID,
+		}
+	}
+	return sources, nil
+}
+
+var logsSourcesCmd = &cobra.Command{
+	Use:   "sources",
+	Short: "Lists the event sources which can be copied with logs cp",
+	Long: `Lists the event sources which can be copied with logs cp.
+
+By default, the event sources known to the DataLogger of the cluster given by --context are listed.
+With --historic, the event sources of the workcell which hold log items in the cloud are listed.`,
+	Example: `List the event sources of a cluster
+$ inctl logs sources --project my_project --context my_cluster
+
+List the event sources of robot logs synced to the cloud
+$ inctl logs sources --org my_org --context my_cluster --historic --match "/icon/*"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		prtr, err := printer.NewPrinter(root.FlagOutput, printer.WithSortBy(root.FlagSortBy))
+		if err != nil {
+			return err
+		}
+		if _, err := path.Match(flagMatch, ""); err != nil {
+			return fmt.Errorf("invalid --match %q: %w", flagMatch, err)
+		}
+		if flagContext == "minikube" && !flagUseLocalhost {
+			flagUseLocalhost = true
+		}
+
+		var sources []string
+		if flagHistoric {
+			sources, err = listCloudSources(cmd.Context())
+		} else {
+			sources, err = listOnpremSources(cmd.Context())
+		}
+		if err != nil {
+			return err
+		}
+		slices.Sort(sources)
+		prtr.Print(&eventSources{Workcell: flagContext, Sources: sources})
+		return nil
+	},
+}
+
+func init() {
+	showLogs.AddCommand(logsSourcesCmd)
+	logsSourcesCmd.Flags().StringVarP(&flagContext, "context", "c", "", "The Kubernetes cluster to use.")
+	logsSourcesCmd.Flags().BoolVar(&flagUseLocalhost, "use_localhost", false, "Connect to the cluster through localhost instead of the cloud relay of --project.")
+	logsSourcesCmd.Flags().BoolVar(&flagHistoric, "historic", false, "Lists the event sources which hold log items in the cloud.")
+	logsSourcesCmd.Flags().StringVar(&flagMatch, "match", "", "Only lists event sources matching this glob pattern.")
+	logsSourcesCmd.MarkFlagRequired("context")
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+)
+
+func TestResolveFlagTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []flagTarget
+		wantErr bool
+	}{
+		{
+			name: "values",
+			args: []string{"--skill=my_skill", "--service=my_service", "--skill=other_skill"},
+			want: []flagTarget{
+				{resourceType: rtSkill, target: "my_skill"},
+				{resourceType: rtService, target: "my_service"},
+				{resourceType: rtSkill, target: "other_skill"},
+			},
+		},
+		{
+			name: "deprecated argument",
+			args: []string{"my_skill", "--skill"},
+			want: []flagTarget{{resourceType: rtSkill, target: "my_skill"}},
+		},
+		{
+			name: "separate values",
+			args: []string{"--service", "my_service", "--skill=my_skill", "--skill", "other_skill"},
+			want: []flagTarget{
+				{resourceType: rtService, target: "my_service"},
+				{resourceType: rtSkill, target: "my_skill"},
+				{resourceType: rtSkill, target: "other_skill"},
+			},
+		},
+		{name: "missing argument", args: []string{"--skill"}, wantErr: true},
+		{name: "argument without flag", args: []string{"my_skill"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var targets []flagTarget
+			flags := pflag.NewFlagSet("logs", pflag.ContinueOnError)
+			flags.Var(&targetFlag{resourceType: rtSkill, targets: &targets}, keyTypeSkill, "")
+			flags.Lookup(keyTypeSkill).NoOptDefVal = argTarget
+			flags.Var(&targetFlag{resourceType: rtService, targets: &targets}, keyTypeService, "")
+			flags.Lookup(keyTypeService).NoOptDefVal = argTarget
+			if err := flags.Parse(tc.args); err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tc.args, err)
+			}
+
+			got, err := resolveFlagTargets(targets, flags.Args())
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("resolveFlagTargets() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveFlagTargets() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(flagTarget{})); diff != "" {
+				t.Errorf("resolveFlagTargets() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@
 package logs
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,8 +13,15 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strings"
+	"sync"
 	"time"
 
+	"google.golang.org/grpc"
+	rrgrpcpb "intrinsic/resources/proto/resource_registry_go_grpc_proto"
+	rrpb "intrinsic/resources/proto/resource_registry_go_grpc_proto"
+	skillregistrygrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	srpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
 	"intrinsic/skills/tools/skill/cmd/dialerutil"
 	"intrinsic/skills/tools/skill/cmd/solutionutil"
 	"intrinsic/tools/inctl/auth/auth"
@@ -152,8 +161,6 @@ const (
 )
 
 type cmdParams struct {
-	resourceType  resourceType
-	resourceID    string
 	follow        bool
 	timestamps    bool
 	tailLines     int
@@ -163,17 +170,133 @@ type cmdParams struct {
 	context       string
 	solution      string
 	org           string
+	prefixType    bool
+	prefixID      bool
 }
 
-func readLogsFromSolution(ctx context.Context, params *cmdParams, w io.Writer) error {
+// logTarget is a skill or service whose logs are read.
+type logTarget struct {
+	resourceType resourceType
+	resourceID   string
+}
+
+func (t logTarget) String() string {
+	return fmt.Sprintf("%s %s", t.typeTag(), t.resourceID)
+}
+
+func (t logTarget) typeTag() string {
+	switch t.resourceType {
+	case rtSkill:
+		return "skl"
+	case rtService:
+		return "srv"
+	default:
+		return "res"
+	}
+}
+
+// prefix returns the prefix of the log lines of the target, e.g. "[skl] [ai.int.my_skill] ".
+func (t logTarget) prefix(prefixType bool, prefixID bool) string {
+	var prefix string
+	if prefixType {
+		prefix += "[" + t.typeTag() + "] "
+	}
+	if prefixID {
+		prefix += "[" + shortID(t.resourceID) + "] "
+	}
+	return prefix
+}
+
+// shortID shortens all but the last part of a dotted ID to at most three characters, e.g.
+// ai.intrinsic.my_thing becomes ai.int.my_thing.
+func shortID(id string) string {
+	parts := strings.Split(id, ".")
+	for i, part := range parts[:len(parts)-1] {
+		if len(part) > 3 {
+			parts[i] = part[:3]
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// lineWriter writes complete lines to a writer shared by several targets, so that their logs
+// interleave line by line. Each line is prefixed with the origin of the logs.
+type lineWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := l.writeLine(l.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a remaining incomplete line.
+func (l *lineWriter) Flush() error {
+	if len(l.buf) == 0 {
+		return nil
+	}
+	err := l.writeLine(append(l.buf, '\n'))
+	l.buf = nil
+	return err
+}
+
+func (l *lineWriter) writeLine(line []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := io.WriteString(l.w, l.prefix); err != nil {
+		return err
+	}
+	_, err := l.w.Write(line)
+	return err
+}
+
+// readLogsFromSolution reads the logs of all targets concurrently and writes them to w. It only
+// returns when all logs were read, even if reading the logs of some targets failed.
+func readLogsFromSolution(ctx context.Context, params *cmdParams, targets []logTarget, w io.Writer) error {
 	endpoint, err := createEndpoint(ctx, params)
 	if err != nil {
 		return err
 	}
 
-	consoleLogsURL := endpoint.url
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make([]error, len(targets))
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lw := &lineWriter{mu: &mu, w: w, prefix: target.prefix(params.prefixType, params.prefixID)}
+			err := readLogs(ctx, endpoint, params, target, lw)
+			if flushErr := lw.Flush(); err == nil {
+				err = flushErr
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("cannot read logs of %s: %w", target, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// readLogs reads the logs of one target and writes them to w.
+func readLogs(ctx context.Context, endpoint *endpoint, params *cmdParams, target logTarget, w io.Writer) error {
+	consoleLogsURL := *endpoint.url
 	consoleLogsURL.Path = path.Join(consoleLogsURL.EscapedPath(), "consoleLogs")
-	consoleLogsQuery := setResourceID(params.resourceType, params.resourceID)
+	consoleLogsQuery := setResourceID(target.resourceType, target.resourceID)
 	if params.follow {
 		consoleLogsQuery.Set(paramFollow, fmt.Sprintf("%t", params.follow))
 	} else {
@@ -196,7 +319,7 @@ func readLogsFromSolution(ctx context.Context, params *cmdParams, w io.Writer) e
 
 	xsrfHeader := http.Header{"X-XSRF-TOKEN": []string{endpoint.xsrfToken}}
 
-	_, err = callEndpoint(ctx, http.MethodGet, consoleLogsURL, endpoint.authToken, xsrfHeader, nil,
+	_, err := callEndpoint(ctx, http.MethodGet, &consoleLogsURL, endpoint.authToken, xsrfHeader, nil,
 		func(_ context.Context, body io.Reader) (string, error) {
 			if _, err := io.Copy(w, body); err != nil {
 				return "", fmt.Errorf("error reading/writing logs: %w", err)
@@ -207,6 +330,89 @@ func readLogsFromSolution(ctx context.Context, params *cmdParams, w io.Writer) e
 	return err
 }
 
+// dialCluster creates a gRPC connection to the cluster the logs are read from, using the same
+// route as createEndpoint. It resolves the cluster of the solution only once by storing it in
+// params.context.
+func dialCluster(ctx context.Context, params *cmdParams) (context.Context, *grpc.ClientConn, error) {
+	dialParams := dialerutil.DialInfoParams{
+		CredName: params.projectName,
+		CredOrg:  params.org,
+	}
+	switch {
+	case params.context == "minikube":
+		dialParams.Address = localhostURL
+	case params.onpremAddress != "":
+		dialParams.Address = params.onpremAddress
+	default:
+		if params.context == "" {
+			cluster, err := getClusterName(ctx, params)
+			if err != nil {
+				return nil, nil, err
+			}
+			params.context = cluster
+		}
+		dialParams.Cluster = params.context
+	}
+	ctx, conn, err := dialerutil.DialConnectionCtx(ctx, dialParams)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create connection to cluster: %w", err)
+	}
+	return ctx, conn, nil
+}
+
+// selectTargets returns all skills and services of the solution whose ID or instance name matches
+// the glob pattern selector.
+func selectTargets(ctx context.Context, params *cmdParams, selector string) ([]logTarget, error) {
+	if _, err := path.Match(selector, ""); err != nil {
+		return nil, fmt.Errorf("invalid --%s %q: %w", keySelector, selector, err)
+	}
+	ctx, conn, err := dialCluster(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var targets []logTarget
+	srClient := skillregistrygrpcpb.NewSkillRegistryClient(conn)
+	var pageToken string
+	for {
+		resp, err := srClient.ListSkills(ctx, &srpb.ListSkillsRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, fmt.Errorf("could not list skills: %w", err)
+		}
+		for _, skill := range resp.GetSkills() {
+			if ok, _ := path.Match(selector, skill.GetId()); ok {
+				targets = append(targets, logTarget{resourceType: rtSkill, resourceID: skill.GetId()})
+			}
+		}
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			break
+		}
+	}
+
+	rrClient := rrgrpcpb.NewResourceRegistryClient(conn)
+	pageToken = ""
+	for {
+		resp, err := rrClient.ListResourceInstances(ctx, &rrpb.ListResourceInstanceRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, fmt.Errorf("could not list services: %w", err)
+		}
+		for _, inst := range resp.GetInstances() {
+			if ok, _ := path.Match(selector, inst.GetName()); ok {
+				id, err := getResourceID(rtService, inst.GetName())
+				if err != nil {
+					return nil, err
+				}
+				targets = append(targets, logTarget{resourceType: rtService, resourceID: id})
+			}
+		}
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			break
+		}
+	}
+	return targets, nil
+}
+
 func setResourceID(resType resourceType, id string) url.Values {
 	result := make(url.Values)
 	switch resType {
@@ -0,0 +1,53 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestLogTargetPrefix(t *testing.T) {
+	skill := logTarget{resourceType: rtSkill, resourceID: "ai.intrinsic.my_skill"}
+	service := logTarget{resourceType: rtService, resourceID: "my-service"}
+	tests := []struct {
+		name       string
+		target     logTarget
+		prefixType bool
+		prefixID   bool
+		want       string
+	}{
+		{name: "no prefix", target: skill, want: ""},
+		{name: "type", target: skill, prefixType: true, want: "[skl] "},
+		{name: "id", target: skill, prefixID: true, want: "[ai.int.my_skill] "},
+		{name: "service type and id", target: service, prefixType: true, prefixID: true, want: "[srv] [my-service] "},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.target.prefix(tc.prefixType, tc.prefixID); got != tc.want {
+				t.Errorf("prefix(%t, %t) = %q, want %q", tc.prefixType, tc.prefixID, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLineWriterInterleavesCompleteLines(t *testing.T) {
+	var mu sync.Mutex
+	out := new(bytes.Buffer)
+	a := &lineWriter{mu: &mu, w: out, prefix: "[a] "}
+	b := &lineWriter{mu: &mu, w: out, prefix: "[b] "}
+
+	a.Write([]byte("first "))
+	b.Write([]byte("second\nthi"))
+	a.Write([]byte("line\n"))
+	b.Write([]byte("rd"))
+	if err := b.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	want := "[b] second\n[a] first line\n[b] third\n"
+	if got := out.String(); got != want {
+		t.Errorf("lineWriter wrote %q, want %q", got, want)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"intrinsic/tools/inctl/cmd/root"
@@ -18,11 +19,9 @@ import (
 	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
-	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protodesc"
-	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
-	apb "intrinsic/executive/proto/annotations_go_proto"
+	"intrinsic/executive/go/behaviortree"
 	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
 	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
 	exsvcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
@@ -32,6 +31,7 @@ import (
 	skillspb "intrinsic/skills/proto/skills_go_proto"
 	"intrinsic/skills/tools/skill/cmd/dialerutil"
 	"intrinsic/skills/tools/skill/cmd/solutionutil"
+	"intrinsic/util/proto/registryutil"
 )
 
 const (
@@ -61,65 +61,13 @@ var (
 	flagClearTreeID   bool
 	flagClearNodeIDs  bool
 	flagProcessFormat string
+	flagOperation     string
 )
 
 var (
 	viperLocal = viper.New()
 )
 
-var (
-	protoNameBehaviorTree     = proto.MessageName(new(btpb.BehaviorTree))
-	protoNameBehaviorTreeNode = proto.MessageName(new(btpb.BehaviorTree_Node))
-)
-
-func clearField(fieldName string, refl protoreflect.Message) {
-	field := refl.Descriptor().Fields().ByTextName(fieldName)
-	if refl.Has(field) {
-		refl.Clear(field)
-	}
-}
-
-func clearTree(m proto.Message, clearTreeID bool, clearNodeIDs bool) error {
-	refl := m.ProtoReflect()
-
-	n := proto.MessageName(m)
-	if clearTreeID && n == protoNameBehaviorTree {
-		clearField("tree_id", refl)
-	}
-	if clearNodeIDs && n == protoNameBehaviorTreeNode {
-		clearField("id", refl)
-	}
-
-	for i := 0; i < refl.Descriptor().Fields().Len(); i++ {
-		field := refl.Descriptor().Fields().Get(i)
-		if !refl.Has(field) {
-			continue
-		}
-		options := field.Options().(*descriptorpb.FieldOptions)
-		outputOnly := proto.GetExtension(options, apb.E_OutputOnly).(bool)
-
-		if outputOnly {
-			refl.Clear(field)
-		}
-
-		if field.Kind() == protoreflect.MessageKind {
-			if field.IsList() {
-				list := refl.Get(field).List()
-				for j := 0; j < list.Len(); j++ {
-					if err := clearTree(list.Get(j).Message().Interface(), clearTreeID, clearNodeIDs); err != nil {
-						return err
-					}
-				}
-			} else if !field.IsMap() {
-				if err := clearTree(refl.Get(field).Message().Interface(), clearTreeID, clearNodeIDs); err != nil {
-					return err
-				}
-			}
-		}
-	}
-	return nil
-}
-
 func connectToCluster(ctx context.Context, projectName string, orgName string, address string, solutionName string, clusterName string) (context.Context, *grpc.ClientConn, error) {
 	if solutionName != "" {
 		// Look up solution name via cloud portal.
@@ -151,56 +99,134 @@ func connectToCluster(ctx context.Context, projectName string, orgName string, a
 	return ctx, conn, nil
 }
 
-func getActiveBT(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient) (*btpb.BehaviorTree, error) {
-	listOpResp, err := exC.ListOperations(ctx, &lrpb.ListOperationsRequest{})
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to list executive operations")
+// getOperationBT returns the behavior tree of the given executive operation.
+func getOperationBT(operation *lrpb.Operation) (*btpb.BehaviorTree, error) {
+	metadata := new(rmdpb.RunMetadata)
+	if err := operation.GetMetadata().UnmarshalTo(metadata); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal RunMetadata proto")
 	}
 
-	if len(listOpResp.Operations) == 0 {
-		return nil, fmt.Errorf("no operations found. Did you load a behavior tree into the executive?")
-	}
+	return metadata.GetBehaviorTree(), nil
+}
 
-	if len(listOpResp.Operations) > 1 {
-		fmt.Fprintf(os.Stderr, "Found %d concurrent operations, getting first one", len(listOpResp.Operations))
+// listOperations returns all operations of the executive.
+func listOperations(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient) ([]*lrpb.Operation, error) {
+	var (
+		operations    []*lrpb.Operation
+		nextPageToken string
+	)
+	for {
+		resp, err := exC.ListOperations(ctx, &lrpb.ListOperationsRequest{
+			PageToken: nextPageToken,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to list executive operations")
+		}
+		operations = append(operations, resp.GetOperations()...)
+		nextPageToken = resp.GetNextPageToken()
+		if nextPageToken == "" {
+			break
+		}
 	}
-	operation := listOpResp.Operations[0]
+	return operations, nil
+}
 
-	metadata := new(rmdpb.RunMetadata)
-	if err := operation.GetMetadata().UnmarshalTo(metadata); err != nil {
-		return nil, errors.Wrap(err, "unable to unmarshal RunMetadata proto")
+func operationNames(operations []*lrpb.Operation) string {
+	names := make([]string, len(operations))
+	for i, op := range operations {
+		names[i] = op.GetName()
 	}
+	return strings.Join(names, ", ")
+}
 
-	return metadata.GetBehaviorTree(), nil
+// getOperation returns the executive operation with the given name or, if the name is empty, the
+// only operation of the executive. It fails if there are several operations, so that commands which
+// change an operation never act on the wrong one.
+func getOperation(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) (*lrpb.Operation, error) {
+	return findOperation(ctx, exC, operationName, false)
+}
+
+// getOperationOrFirst is like getOperation but falls back to the first of several operations with a
+// warning. It is meant for commands which only read an operation.
+func getOperationOrFirst(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) (*lrpb.Operation, error) {
+	return findOperation(ctx, exC, operationName, true)
 }
 
-func setBT(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, bt *btpb.BehaviorTree) error {
-	listOpResp, err := exC.ListOperations(ctx, &lrpb.ListOperationsRequest{})
+func findOperation(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string, useFirst bool) (*lrpb.Operation, error) {
+	if operationName != "" {
+		operation, err := exC.GetOperation(ctx, &lrpb.GetOperationRequest{Name: operationName})
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get executive operation %q", operationName)
+		}
+		return operation, nil
+	}
+
+	operations, err := listOperations(ctx, exC)
 	if err != nil {
-		return errors.Wrap(err, "unable to list executive operations")
+		return nil, err
 	}
 
-	if len(listOpResp.Operations) > 1 {
-		return errors.Errorf("More than one concurrently loaded BT/executive operation, please delete all but one")
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("no operations found. Did you load a behavior tree into the executive?")
 	}
 
-	if len(listOpResp.Operations) == 1 {
-		operationToDelete := listOpResp.Operations[0]
-		if _, err = exC.DeleteOperation(ctx, &lrpb.DeleteOperationRequest{
-			Name: operationToDelete.Name,
-		}); err != nil {
-			return errors.Wrap(err, "unable to delete operation")
+	if len(operations) > 1 {
+		if !useFirst {
+			return nil, fmt.Errorf("found %d concurrent operations (%s), please select one with --operation", len(operations), operationNames(operations))
+		}
+		fmt.Fprintf(os.Stderr, "Found %d concurrent operations (%s), getting first one. Select one with --operation.\n", len(operations), operationNames(operations))
+	}
+	return operations[0], nil
+}
+
+func getActiveBT(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) (*btpb.BehaviorTree, error) {
+	operation, err := getOperationOrFirst(ctx, exC, operationName)
+	if err != nil {
+		return nil, err
+	}
+
+	return getOperationBT(operation)
+}
+
+// setBT creates a new executive operation for the given behavior tree and returns its name. If
+// keepOperations is false, the operation with the given name, or the only existing operation if the
+// name is empty, is deleted first.
+func setBT(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, bt *btpb.BehaviorTree, operationName string, keepOperations bool) (string, error) {
+	if !keepOperations {
+		operationToDelete := operationName
+		if operationName == "" {
+			operations, err := listOperations(ctx, exC)
+			if err != nil {
+				return "", err
+			}
+
+			if len(operations) > 1 {
+				return "", errors.Errorf("More than one concurrently loaded BT/executive operation (%s), please select the one to replace with --operation or use --add_operation", operationNames(operations))
+			}
+
+			if len(operations) == 1 {
+				operationToDelete = operations[0].GetName()
+			}
+		}
+
+		if operationToDelete != "" {
+			if _, err := exC.DeleteOperation(ctx, &lrpb.DeleteOperationRequest{
+				Name: operationToDelete,
+			}); err != nil {
+				return "", errors.Wrap(err, "unable to delete operation")
+			}
 		}
 	}
 
 	req := &exsvcpb.CreateOperationRequest{}
 	req.RunnableType = &exsvcpb.CreateOperationRequest_BehaviorTree{BehaviorTree: bt}
 
-	if _, err = exC.CreateOperation(ctx, req); err != nil {
-		return errors.Wrap(err, "unable to create executive operation")
+	operation, err := exC.CreateOperation(ctx, req)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create executive operation")
 	}
 
-	return nil
+	return operation.GetName(), nil
 }
 
 func getSkills(ctx context.Context, srC skillregistrygrpcpb.SkillRegistryClient) ([]*skillspb.Skill, error) {
@@ -268,6 +294,41 @@ func addFileDescriptorSetToFiles(fileDescriptorSet *descriptorpb.FileDescriptorS
 	return nil
 }
 
+// typeResolver resolves the message types of Any protos.
+type typeResolver interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+}
+
+// anyTypes returns the message types needed to decode the parameters and return values of the
+// given skills and the other Any protos in the given trees.
+func anyTypes(skills []*skillspb.Skill, trees ...*btpb.BehaviorTree) (*protoregistry.Types, error) {
+	files := new(protoregistry.Files)
+	for _, skill := range skills {
+		if err := addFileDescriptorSetToFiles(skill.GetParameterDescription().GetParameterDescriptorFileset(), files); err != nil {
+			return nil, errors.Wrap(err, "failed adding file descriptor set to files")
+		}
+		if err := addFileDescriptorSetToFiles(skill.GetReturnValueDescription().GetDescriptorFileset(), files); err != nil {
+			return nil, errors.Wrap(err, "failed adding file descriptor set to files")
+		}
+	}
+	for _, tree := range trees {
+		collector := fileDescriptorSetCollector{}
+		behaviortree.Walk(tree, &collector)
+		for _, fileDescriptorSet := range collector.fileDescriptorSets {
+			if err := addFileDescriptorSetToFiles(fileDescriptorSet, files); err != nil {
+				return nil, errors.Wrap(err, "failed adding file descriptor set to files")
+			}
+		}
+	}
+
+	types := new(protoregistry.Types)
+	if err := registryutil.PopulateTypesFromFiles(types, files); err != nil {
+		return nil, errors.Wrapf(err, "failed to populate types from files")
+	}
+	return types, nil
+}
+
 var processCmd = orgutil.WrapCmd(&cobra.Command{
 	Use:     root.ProcessCmdName,
 	Aliases: []string{root.ProcessCmdName},
@@ -276,12 +337,30 @@ var processCmd = orgutil.WrapCmd(&cobra.Command{
 
 	Examples:
 
+	To list the operations of the executive:
+	inctl process list --solution my-solution-id --cluster my-cluster
+
 	To download the current BT from the executive to a file:
 	inctl process get --solution my-solution-id --cluster my-cluster --output_file /tmp/process.textproto
 
 	To upload a BT from file to the executive:
 	inctl process set --solution my-solution --cluster my-cluster --input_file /tmp/my-process.textproto
 
+	To check a BT from file against the skills installed in the solution:
+	inctl process lint --solution my-solution --cluster my-cluster --input_file /tmp/my-process.textproto
+
+	To compare a BT from file with the BT in the executive:
+	inctl process diff /tmp/my-process.textproto --solution my-solution --cluster my-cluster
+
+	To run the BT in the executive and wait until it has finished:
+	inctl process run --solution my-solution --cluster my-cluster
+
+	To watch the execution of the BT which is already running:
+	inctl process watch --solution my-solution --cluster my-cluster
+
+	To list the values on the blackboard of the executive:
+	inctl process blackboard list --solution my-solution --cluster my-cluster
+
 `,
 	DisableFlagParsing: true,
 }, viperLocal)
@@ -289,6 +368,7 @@ var processCmd = orgutil.WrapCmd(&cobra.Command{
 func init() {
 	processCmd.PersistentFlags().BoolVar(&flagClearTreeID, "clear_tree_id", true, "Clear the tree_id field from the BT proto.")
 	processCmd.PersistentFlags().BoolVar(&flagClearNodeIDs, "clear_node_ids", true, "Clear the nodes' id fields from the BT proto.")
+	processCmd.PersistentFlags().StringVar(&flagOperation, "operation", "", "Name of the executive operation to use. Required if the executive has more than one operation. Use `inctl process list` to see all operations.")
 	processCmd.PersistentFlags().StringVar(&flagServerAddress, "server", "", "Server address of the cluster. Format is {ADDRESS}:{PORT}, for example 'localhost:17080'")
 	root.RootCmd.AddCommand(processCmd)
 }
@@ -0,0 +1,375 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+	bbgrpcpb "intrinsic/executive/proto/blackboard_service_go_grpc_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	skillregistrygrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+var (
+	flagBlackboardScope     string
+	flagBlackboardType      string
+	flagBlackboardValue     string
+	flagBlackboardValueFile string
+)
+
+// chainResolver resolves message and extension types with the first resolver that knows them.
+type chainResolver []typeResolver
+
+func (r chainResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	for _, resolver := range r {
+		if mt, err := resolver.FindMessageByName(name); err == nil {
+			return mt, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (r chainResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	for _, resolver := range r {
+		if mt, err := resolver.FindMessageByURL(url); err == nil {
+			return mt, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (r chainResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	for _, resolver := range r {
+		if xt, err := resolver.FindExtensionByName(field); err == nil {
+			return xt, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (r chainResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	for _, resolver := range r {
+		if xt, err := resolver.FindExtensionByNumber(message, field); err == nil {
+			return xt, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+// blackboardResolver returns a resolver for the types of blackboard values. These are usually the
+// return values of the installed skills or messages defined by the operation's behavior tree.
+func blackboardResolver(ctx context.Context, conn *grpc.ClientConn, operationName string) (typeResolver, error) {
+	skills, err := getSkills(ctx, skillregistrygrpcpb.NewSkillRegistryClient(conn))
+	if err != nil {
+		return nil, err
+	}
+	bt, err := getActiveBT(ctx, execgrpcpb.NewExecutiveServiceClient(conn), operationName)
+	if err != nil {
+		return nil, err
+	}
+	types, err := anyTypes(skills, bt)
+	if err != nil {
+		return nil, err
+	}
+	return chainResolver{types, protoregistry.GlobalTypes}, nil
+}
+
+// blackboardEntry is a single blackboard value. It can be printed as text or JSON.
+type blackboardEntry struct {
+	Key       string `json:"key"`
+	Scope     string `json:"scope"`
+	Operation string `json:"operation"`
+	Type      string `json:"type"`
+	// Value is the JSON representation of the value. It is empty if the type of the value is
+	// unknown.
+	Value json.RawMessage `json:"value,omitempty"`
+	// text and compactText are the textproto representations of the value.
+	text        string
+	compactText string
+}
+
+// String returns the value of the entry as textproto.
+func (e *blackboardEntry) String() string {
+	if e.Value == nil {
+		return fmt.Sprintf("# Value of unknown type %q", e.Type)
+	}
+	return fmt.Sprintf("# proto-message: %s\n%s", e.Type, strings.TrimSuffix(e.text, "\n"))
+}
+
+// listBlackboardResponse is the result of listing blackboard values.
+type listBlackboardResponse struct {
+	Values []*blackboardEntry `json:"values"`
+}
+
+// String converts a listBlackboardResponse to a string.
+func (r *listBlackboardResponse) String() string {
+	if len(r.Values) == 0 {
+		return "No blackboard values found."
+	}
+
+	b := new(bytes.Buffer)
+	w := tabwriter.NewWriter(b,
+		/*minwidth=*/ 1 /*tabwidth=*/, 1 /*padding=*/, 1 /*padchar=*/, ' ' /*flags=*/, 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "Key", "Scope", "Type", "Value")
+	for _, e := range r.Values {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Key, e.Scope, e.Type, e.compactText)
+	}
+	w.Flush()
+	// Remove the trailing newline as the pretty-printer wrapper will add one.
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// describeBlackboardValue decodes the given blackboard value with the given resolver. Values of
+// unknown type are described by their type only.
+func describeBlackboardValue(v *bbgrpcpb.BlackboardValue, resolver typeResolver) (*blackboardEntry, error) {
+	typeURL := v.GetValue().GetTypeUrl()
+	e := &blackboardEntry{
+		Key:       v.GetKey(),
+		Scope:     v.GetScope(),
+		Operation: v.GetOperationName(),
+		Type:      typeURL[strings.LastIndex(typeURL, "/")+1:],
+	}
+
+	msg, err := anypb.UnmarshalNew(v.GetValue(), proto.UnmarshalOptions{Resolver: resolver})
+	if errors.Is(err, protoregistry.NotFound) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not decode value of blackboard key %q", v.GetKey())
+	}
+
+	if e.Value, err = (protojson.MarshalOptions{Resolver: resolver}).Marshal(msg); err != nil {
+		return nil, errors.Wrapf(err, "could not convert value of blackboard key %q to JSON", v.GetKey())
+	}
+	text, err := (prototext.MarshalOptions{Multiline: true, Resolver: resolver}).Marshal(msg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not convert value of blackboard key %q to textproto", v.GetKey())
+	}
+	e.text = string(text)
+	compactText, err := (prototext.MarshalOptions{Resolver: resolver}).Marshal(msg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not convert value of blackboard key %q to textproto", v.GetKey())
+	}
+	e.compactText = string(compactText)
+	return e, nil
+}
+
+// parseBlackboardValue parses the given textproto as a message of the given type.
+func parseBlackboardValue(typeName string, text []byte, resolver typeResolver) (*anypb.Any, error) {
+	mt, err := resolver.FindMessageByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unknown message type %q", typeName)
+	}
+	msg := mt.New().Interface()
+	if err := (prototext.UnmarshalOptions{Resolver: resolver}).Unmarshal(text, msg); err != nil {
+		return nil, errors.Wrapf(err, "could not parse value as %q", typeName)
+	}
+	return anypb.New(msg)
+}
+
+// scopeFlag returns the value of the --scope flag or nil if it was not set.
+func scopeFlag(cmd *cobra.Command) *string {
+	if !cmd.Flags().Changed("scope") {
+		return nil
+	}
+	return &flagBlackboardScope
+}
+
+var processBlackboardCmd = &cobra.Command{
+	Use:   "blackboard",
+	Short: "Inspects and edits the blackboard of an operation.",
+	Long: `Inspects and edits the blackboard of an operation.
+
+Values are decoded with the return value types of the installed skills and the types defined by
+the operation's behavior tree.`,
+}
+
+var processBlackboardListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the values on the blackboard.",
+	Long: `List the values on the blackboard of an operation, optionally restricted to a scope.
+
+inctl process blackboard list --solution my-solution --cluster my-cluster [--scope scope] [--output json]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+		return withReadOperationConn(cmd, func(ctx context.Context, conn *grpc.ClientConn, operationName string) error {
+			resolver, err := blackboardResolver(ctx, conn, operationName)
+			if err != nil {
+				return err
+			}
+			resp, err := bbgrpcpb.NewExecutiveBlackboardClient(conn).ListBlackboardValues(ctx, &bbgrpcpb.ListBlackboardValuesRequest{
+				Scope:         scopeFlag(cmd),
+				OperationName: operationName,
+				View:          bbgrpcpb.ListBlackboardValuesRequest_FULL,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "unable to list blackboard values of operation %q", operationName)
+			}
+
+			list := &listBlackboardResponse{Values: []*blackboardEntry{}}
+			for _, v := range resp.GetValues() {
+				e, err := describeBlackboardValue(v, resolver)
+				if err != nil {
+					return err
+				}
+				list.Values = append(list.Values, e)
+			}
+			prtr.Print(list)
+			return nil
+		})
+	},
+}
+
+var processBlackboardGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a value on the blackboard.",
+	Long: `Print a value on the blackboard of an operation as textproto or JSON.
+
+inctl process blackboard get my_key --solution my-solution --cluster my-cluster [--scope scope] [--output json]`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+		return withReadOperationConn(cmd, func(ctx context.Context, conn *grpc.ClientConn, operationName string) error {
+			resolver, err := blackboardResolver(ctx, conn, operationName)
+			if err != nil {
+				return err
+			}
+			v, err := bbgrpcpb.NewExecutiveBlackboardClient(conn).GetBlackboardValue(ctx, &bbgrpcpb.GetBlackboardValueRequest{
+				Key:           args[0],
+				Scope:         scopeFlag(cmd),
+				OperationName: operationName,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "unable to get blackboard key %q", args[0])
+			}
+			e, err := describeBlackboardValue(v, resolver)
+			if err != nil {
+				return err
+			}
+			prtr.Print(e)
+			return nil
+		})
+	},
+}
+
+var processBlackboardSetCmd = &cobra.Command{
+	Use:   "set <key>",
+	Short: "Set a value on the blackboard.",
+	Long: `Set a value on the blackboard of an operation. The value is given as textproto with
+--value or --value_file. The message type is taken from --type or, if not set, from the current
+value of the key.
+
+inctl process blackboard set my_key --value 'value: 3.5' --type google.protobuf.DoubleValue --solution my-solution --cluster my-cluster [--scope scope]`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+		text := []byte(flagBlackboardValue)
+		switch {
+		case flagBlackboardValue != "" && flagBlackboardValueFile != "":
+			return fmt.Errorf("only one of --value and --value_file can be set")
+		case flagBlackboardValueFile != "":
+			var err error
+			if text, err = os.ReadFile(flagBlackboardValueFile); err != nil {
+				return errors.Wrapf(err, "could not read value file %q", flagBlackboardValueFile)
+			}
+		}
+
+		return withOperationConn(cmd, func(ctx context.Context, conn *grpc.ClientConn, operationName string) error {
+			resolver, err := blackboardResolver(ctx, conn, operationName)
+			if err != nil {
+				return err
+			}
+			bbC := bbgrpcpb.NewExecutiveBlackboardClient(conn)
+
+			typeName := flagBlackboardType
+			if typeName == "" {
+				current, err := bbC.GetBlackboardValue(ctx, &bbgrpcpb.GetBlackboardValueRequest{
+					Key:           key,
+					Scope:         scopeFlag(cmd),
+					OperationName: operationName,
+				})
+				if err != nil {
+					return errors.Wrapf(err, "unable to get blackboard key %q to determine its type, use --type for new keys", key)
+				}
+				typeURL := current.GetValue().GetTypeUrl()
+				typeName = typeURL[strings.LastIndex(typeURL, "/")+1:]
+			}
+
+			value, err := parseBlackboardValue(typeName, text, resolver)
+			if err != nil {
+				return err
+			}
+			if _, err := bbC.UpdateBlackboardValue(ctx, &bbgrpcpb.UpdateBlackboardValueRequest{
+				Value: &bbgrpcpb.BlackboardValue{
+					Key:           key,
+					Scope:         flagBlackboardScope,
+					OperationName: operationName,
+					Value:         value,
+				},
+			}); err != nil {
+				return errors.Wrapf(err, "unable to update blackboard key %q", key)
+			}
+			fmt.Printf("Updated blackboard key %q of operation %q.\n", key, operationName)
+			return nil
+		})
+	},
+}
+
+var processBlackboardDeleteCmd = &cobra.Command{
+	Use:   "delete <key>",
+	Short: "Delete a value from the blackboard.",
+	Long: `Delete a value from the blackboard of an operation.
+
+inctl process blackboard delete my_key --solution my-solution --cluster my-cluster [--scope scope]`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withOperationConn(cmd, func(ctx context.Context, conn *grpc.ClientConn, operationName string) error {
+			if _, err := bbgrpcpb.NewExecutiveBlackboardClient(conn).DeleteBlackboardValue(ctx, &bbgrpcpb.DeleteBlackboardValueRequest{
+				Key:           args[0],
+				Scope:         flagBlackboardScope,
+				OperationName: operationName,
+			}); err != nil {
+				return errors.Wrapf(err, "unable to delete blackboard key %q", args[0])
+			}
+			fmt.Printf("Deleted blackboard key %q of operation %q.\n", args[0], operationName)
+			return nil
+		})
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{processBlackboardListCmd, processBlackboardGetCmd, processBlackboardSetCmd, processBlackboardDeleteCmd} {
+		addTargetFlags(cmd)
+		cmd.Flags().StringVar(&flagBlackboardScope, "scope", "", "Scope of the blackboard values. Uses the executive's default scope if not set.")
+		processBlackboardCmd.AddCommand(cmd)
+	}
+	processBlackboardSetCmd.Flags().StringVar(&flagBlackboardType, "type", "", "Full name of the message type of the value, e.g., google.protobuf.DoubleValue. Defaults to the type of the current value.")
+	processBlackboardSetCmd.Flags().StringVar(&flagBlackboardValue, "value", "", "Value as textproto.")
+	processBlackboardSetCmd.Flags().StringVar(&flagBlackboardValueFile, "value_file", "", "File containing the value as textproto.")
+
+	processCmd.AddCommand(processBlackboardCmd)
+}
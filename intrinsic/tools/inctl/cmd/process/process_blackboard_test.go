@@ -0,0 +1,87 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	bbgrpcpb "intrinsic/executive/proto/blackboard_service_go_grpc_proto"
+)
+
+func TestDescribeBlackboardValue(t *testing.T) {
+	value, err := anypb.New(wrapperspb.Double(3.5))
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	resolver := chainResolver{new(protoregistry.Types), protoregistry.GlobalTypes}
+
+	got, err := describeBlackboardValue(&bbgrpcpb.BlackboardValue{
+		Key:           "result",
+		Scope:         "scope",
+		OperationName: "op1",
+		Value:         value,
+	}, resolver)
+	if err != nil {
+		t.Fatalf("describeBlackboardValue() failed: %v", err)
+	}
+	if got.Type != "google.protobuf.DoubleValue" {
+		t.Errorf("describeBlackboardValue() returned type %q, want %q", got.Type, "google.protobuf.DoubleValue")
+	}
+	if string(got.Value) != "3.5" {
+		t.Errorf("describeBlackboardValue() returned JSON value %s, want 3.5", got.Value)
+	}
+	// The text format output is deliberately unstable with respect to whitespace.
+	wantText := []string{"#", "proto-message:", "google.protobuf.DoubleValue", "value:", "3.5"}
+	if diff := cmp.Diff(wantText, strings.Fields(got.String())); diff != "" {
+		t.Errorf("describeBlackboardValue().String() returned unexpected text (-want +got):\n%s", diff)
+	}
+
+	unknown, err := describeBlackboardValue(&bbgrpcpb.BlackboardValue{
+		Key:   "unknown",
+		Value: &anypb.Any{TypeUrl: "type.googleapis.com/com.example.Unknown", Value: []byte{8, 1}},
+	}, resolver)
+	if err != nil {
+		t.Fatalf("describeBlackboardValue() failed for unknown type: %v", err)
+	}
+	if unknown.Type != "com.example.Unknown" || unknown.Value != nil {
+		t.Errorf("describeBlackboardValue() = %+v, want type com.example.Unknown without value", unknown)
+	}
+	b, err := json.Marshal(unknown)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	if want := `{"key":"unknown","scope":"","operation":"","type":"com.example.Unknown"}`; string(b) != want {
+		t.Errorf("json.Marshal() = %s, want %s", b, want)
+	}
+}
+
+func TestParseBlackboardValue(t *testing.T) {
+	resolver := chainResolver{new(protoregistry.Types), protoregistry.GlobalTypes}
+
+	got, err := parseBlackboardValue("google.protobuf.StringValue", []byte(`value: "hello"`), resolver)
+	if err != nil {
+		t.Fatalf("parseBlackboardValue() failed: %v", err)
+	}
+	msg, err := got.UnmarshalNew()
+	if err != nil {
+		t.Fatalf("UnmarshalNew() failed: %v", err)
+	}
+	if diff := cmp.Diff(proto.Message(wrapperspb.String("hello")), msg, protocmp.Transform()); diff != "" {
+		t.Errorf("parseBlackboardValue() returned unexpected value (-want +got):\n%s", diff)
+	}
+
+	if _, err := parseBlackboardValue("com.example.Unknown", nil, resolver); err == nil {
+		t.Errorf("parseBlackboardValue() with unknown type succeeded, want error")
+	}
+	if _, err := parseBlackboardValue("google.protobuf.StringValue", []byte("unknown_field: 1"), resolver); err == nil {
+		t.Errorf("parseBlackboardValue() with invalid textproto succeeded, want error")
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"intrinsic/executive/go/behaviortree"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	exsvcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+const nodeNamePathSeparator = "/"
+
+var (
+	flagTreeID         string
+	flagNodeID         uint32
+	flagBreakpointType string
+	flagAllBreakpoints bool
+)
+
+var breakpointTypes = map[string]btpb.BehaviorTree_Breakpoint_Type{
+	"before": btpb.BehaviorTree_Breakpoint_BEFORE,
+	"after":  btpb.BehaviorTree_Breakpoint_AFTER,
+}
+
+// nodeRef identifies a node in the behavior tree of an operation.
+type nodeRef struct {
+	treeID string
+	nodeID uint32
+}
+
+// indexedNode is a node of a behavior tree together with its name path, i.e., the names of all
+// nodes from the root of the tree to the node separated by "/".
+type indexedNode struct {
+	ref      nodeRef
+	hasID    bool
+	name     string
+	namePath string
+}
+
+// indexNodes returns all nodes of the tree, including the nodes of subtrees, in depth-first order.
+// Nodes in conditions are skipped.
+func indexNodes(bt *btpb.BehaviorTree) ([]*indexedNode, error) {
+	var nodes []*indexedNode
+	namePaths := map[*btpb.BehaviorTree_Node]string{}
+	w := &behaviortree.Walker{
+		PreNode: func(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) error {
+			namePath := node.GetName()
+			if c.Parent != nil {
+				namePath = namePaths[c.Parent] + nodeNamePathSeparator + namePath
+			}
+			namePaths[node] = namePath
+			nodes = append(nodes, &indexedNode{
+				ref:      nodeRef{treeID: c.Tree.GetTreeId(), nodeID: node.GetId()},
+				hasID:    c.Tree.GetTreeId() != "" && node.Id != nil,
+				name:     node.GetName(),
+				namePath: namePath,
+			})
+			return nil
+		},
+		PreCondition: func(*btpb.BehaviorTree_Condition, *behaviortree.Cursor) error {
+			return behaviortree.SkipChildren
+		},
+	}
+	if err := w.Walk(bt); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// findNode returns the node with the given name path. A plain node name (without "/") matches any
+// node of that name. It is an error if no node or more than one node matches.
+func findNode(bt *btpb.BehaviorTree, namePath string) (*indexedNode, error) {
+	nodes, err := indexNodes(bt)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*indexedNode
+	for _, n := range nodes {
+		if n.namePath == namePath || (!strings.Contains(namePath, nodeNamePathSeparator) && n.name == namePath) {
+			matches = append(matches, n)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no node with name path %q found", namePath)
+	case 1:
+		if !matches[0].hasID {
+			return nil, fmt.Errorf("node %q has no tree and node id, the operation's behavior tree might not have been loaded yet", namePath)
+		}
+		return matches[0], nil
+	}
+	var paths []string
+	for _, n := range matches {
+		paths = append(paths, n.namePath)
+	}
+	return nil, fmt.Errorf("%d nodes match %q (%s), please use the full name path or --tree_id and --node_id", len(matches), namePath, strings.Join(paths, ", "))
+}
+
+// resolveNodeRef returns the node selected by the command's argument, a node name path, or by the
+// --tree_id and --node_id flags.
+func resolveNodeRef(ctx context.Context, cmd *cobra.Command, exC execgrpcpb.ExecutiveServiceClient, operationName string, args []string) (nodeRef, error) {
+	hasIDFlags := cmd.Flags().Changed("tree_id") || cmd.Flags().Changed("node_id")
+	if len(args) == 0 {
+		if !cmd.Flags().Changed("tree_id") || !cmd.Flags().Changed("node_id") {
+			return nodeRef{}, fmt.Errorf("either a node name path or both --tree_id and --node_id must be given")
+		}
+		return nodeRef{treeID: flagTreeID, nodeID: flagNodeID}, nil
+	}
+	if hasIDFlags {
+		return nodeRef{}, fmt.Errorf("a node name path cannot be combined with --tree_id or --node_id")
+	}
+
+	bt, err := getActiveBT(ctx, exC, operationName)
+	if err != nil {
+		return nodeRef{}, err
+	}
+	node, err := findNode(bt, args[0])
+	if err != nil {
+		return nodeRef{}, err
+	}
+	return node.ref, nil
+}
+
+// breakpointDescription describes a breakpoint of an operation.
+type breakpointDescription struct {
+	TreeID   string `json:"treeId"`
+	NodeID   uint32 `json:"nodeId"`
+	Type     string `json:"type"`
+	NamePath string `json:"namePath,omitempty"`
+}
+
+// listBreakpointsResponse is the result of listing the breakpoints of an operation. It can be
+// printed as text or JSON.
+type listBreakpointsResponse struct {
+	Breakpoints []*breakpointDescription `json:"breakpoints"`
+}
+
+// String converts a listBreakpointsResponse to a string.
+func (r *listBreakpointsResponse) String() string {
+	if len(r.Breakpoints) == 0 {
+		return "No breakpoints found."
+	}
+
+	b := new(bytes.Buffer)
+	w := tabwriter.NewWriter(b,
+		/*minwidth=*/ 1 /*tabwidth=*/, 1 /*padding=*/, 1 /*padchar=*/, ' ' /*flags=*/, 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "Tree ID", "Node ID", "Type", "Node")
+	for _, bp := range r.Breakpoints {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", bp.TreeID, bp.NodeID, bp.Type, bp.NamePath)
+	}
+	w.Flush()
+	// Remove the trailing newline as the pretty-printer wrapper will add one.
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// describeBreakpoints annotates the given breakpoints with the name paths of their nodes in bt.
+func describeBreakpoints(bt *btpb.BehaviorTree, breakpoints []*btpb.BehaviorTree_Breakpoint) (*listBreakpointsResponse, error) {
+	nodes, err := indexNodes(bt)
+	if err != nil {
+		return nil, err
+	}
+	namePaths := map[nodeRef]string{}
+	for _, n := range nodes {
+		if n.hasID {
+			namePaths[n.ref] = n.namePath
+		}
+	}
+
+	resp := &listBreakpointsResponse{Breakpoints: []*breakpointDescription{}}
+	for _, bp := range breakpoints {
+		resp.Breakpoints = append(resp.Breakpoints, &breakpointDescription{
+			TreeID:   bp.GetTreeId(),
+			NodeID:   bp.GetNodeId(),
+			Type:     bp.GetType().String(),
+			NamePath: namePaths[nodeRef{treeID: bp.GetTreeId(), nodeID: bp.GetNodeId()}],
+		})
+	}
+	return resp, nil
+}
+
+func newBreakpoint(ctx context.Context, cmd *cobra.Command, exC execgrpcpb.ExecutiveServiceClient, operationName string, args []string) (*btpb.BehaviorTree_Breakpoint, error) {
+	bpType, err := parseChoice("type", flagBreakpointType, breakpointTypes)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := resolveNodeRef(ctx, cmd, exC, operationName, args)
+	if err != nil {
+		return nil, err
+	}
+	return &btpb.BehaviorTree_Breakpoint{TreeId: ref.treeID, NodeId: ref.nodeID, Type: bpType}, nil
+}
+
+var processBreakpointCmd = &cobra.Command{
+	Use:   "breakpoint",
+	Short: "Manages the breakpoints of an operation.",
+	Long: `Manages the breakpoints of an operation. The executive suspends the operation before
+or after executing a node with a breakpoint.
+
+Nodes are addressed either by their name path, i.e., the names of all nodes from the root to the
+node separated by "/" (for example "root/pick/grasp"), by their name alone if it is unique, or by
+--tree_id and --node_id.`,
+}
+
+var processBreakpointAddCmd = &cobra.Command{
+	Use:   "add [<node_name_path>]",
+	Short: "Add a breakpoint to a node.",
+	Long: `Add a breakpoint to a node of the operation's behavior tree.
+
+inctl process breakpoint add root/pick --solution my-solution --cluster my-cluster [--type before|after]
+inctl process breakpoint add --tree_id my-tree --node_id 3 --solution my-solution --cluster my-cluster`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			bp, err := newBreakpoint(ctx, cmd, exC, operationName, args)
+			if err != nil {
+				return err
+			}
+			if _, err := exC.CreateBreakpoint(ctx, &exsvcpb.CreateBreakpointRequest{Name: operationName, Breakpoint: bp}); err != nil {
+				return errors.Wrapf(err, "unable to create breakpoint in operation %q", operationName)
+			}
+			fmt.Printf("Added breakpoint %s node %d of tree %q.\n", strings.ToLower(bp.GetType().String()), bp.GetNodeId(), bp.GetTreeId())
+			return nil
+		})
+	},
+}
+
+var processBreakpointRmCmd = &cobra.Command{
+	Use:   "rm [<node_name_path>]",
+	Short: "Remove a breakpoint from a node.",
+	Long: `Remove a breakpoint from a node of the operation's behavior tree, or all
+breakpoints with --all.
+
+inctl process breakpoint rm root/pick --solution my-solution --cluster my-cluster [--type before|after]
+inctl process breakpoint rm --all --solution my-solution --cluster my-cluster`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			if flagAllBreakpoints {
+				if len(args) > 0 || cmd.Flags().Changed("tree_id") || cmd.Flags().Changed("node_id") {
+					return fmt.Errorf("--all cannot be combined with a node")
+				}
+				if _, err := exC.DeleteAllBreakpoints(ctx, &exsvcpb.DeleteAllBreakpointsRequest{Name: operationName}); err != nil {
+					return errors.Wrapf(err, "unable to delete breakpoints of operation %q", operationName)
+				}
+				fmt.Printf("Removed all breakpoints of operation %q.\n", operationName)
+				return nil
+			}
+
+			bp, err := newBreakpoint(ctx, cmd, exC, operationName, args)
+			if err != nil {
+				return err
+			}
+			if _, err := exC.DeleteBreakpoint(ctx, &exsvcpb.DeleteBreakpointRequest{Name: operationName, Breakpoint: bp}); err != nil {
+				return errors.Wrapf(err, "unable to delete breakpoint in operation %q", operationName)
+			}
+			fmt.Printf("Removed breakpoint %s node %d of tree %q.\n", strings.ToLower(bp.GetType().String()), bp.GetNodeId(), bp.GetTreeId())
+			return nil
+		})
+	},
+}
+
+var processBreakpointLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the breakpoints of an operation.",
+	Long: `List the breakpoints of an operation together with the name paths of their nodes.
+
+inctl process breakpoint ls --solution my-solution --cluster my-cluster [--output json]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+		return withReadOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			resp, err := exC.ListBreakpoints(ctx, &exsvcpb.ListBreakpointsRequest{Name: operationName})
+			if err != nil {
+				return errors.Wrapf(err, "unable to list breakpoints of operation %q", operationName)
+			}
+			bt, err := getActiveBT(ctx, exC, operationName)
+			if err != nil {
+				return err
+			}
+			desc, err := describeBreakpoints(bt, resp.GetBreakpoints())
+			if err != nil {
+				return err
+			}
+			prtr.Print(desc)
+			return nil
+		})
+	},
+}
+
+// addNodeRefFlags registers the flags which select a node by its tree and node id.
+func addNodeRefFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagTreeID, "tree_id", "", "Id of the tree containing the node. Use together with --node_id instead of a node name path.")
+	cmd.Flags().Uint32Var(&flagNodeID, "node_id", 0, "Id of the node. Use together with --tree_id instead of a node name path.")
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{processBreakpointAddCmd, processBreakpointRmCmd} {
+		addTargetFlags(cmd)
+		addNodeRefFlags(cmd)
+		cmd.Flags().StringVar(&flagBreakpointType, "type", "before", "Whether to suspend before or after the node is executed, one of: before, after.")
+		processBreakpointCmd.AddCommand(cmd)
+	}
+	processBreakpointRmCmd.Flags().BoolVar(&flagAllBreakpoints, "all", false, "Remove all breakpoints of the operation.")
+
+	addTargetFlags(processBreakpointLsCmd)
+	processBreakpointCmd.AddCommand(processBreakpointLsCmd)
+
+	processCmd.AddCommand(processBreakpointCmd)
+}
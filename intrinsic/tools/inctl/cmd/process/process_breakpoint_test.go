@@ -0,0 +1,88 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+)
+
+func breakpointTestTree(t *testing.T) *btpb.BehaviorTree {
+	t.Helper()
+	withID := func(n *btpb.BehaviorTree_Node, id uint32) *btpb.BehaviorTree_Node {
+		n.Id = proto.Uint32(id)
+		return n
+	}
+
+	sub := lintSequence(withID(lintTask(t, "pick", "ai.intrinsic.say", nil), 1))
+	sub.TreeId = proto.String("sub")
+	sub.GetRoot().Name = proto.String("subroot")
+	sub.GetRoot().Id = proto.Uint32(2)
+
+	bt := lintSequence(
+		withID(lintTask(t, "pick", "ai.intrinsic.say", nil), 1),
+		withID(lintTask(t, "place", "ai.intrinsic.say", nil), 2),
+		withID(&btpb.BehaviorTree_Node{
+			Name:     proto.String("sub"),
+			NodeType: &btpb.BehaviorTree_Node_SubTree{SubTree: &btpb.BehaviorTree_SubtreeNode{Tree: sub}},
+		}, 3),
+		lintTask(t, "no_id", "ai.intrinsic.say", nil),
+	)
+	bt.TreeId = proto.String("main")
+	bt.GetRoot().Id = proto.Uint32(4)
+	return bt
+}
+
+func TestFindNode(t *testing.T) {
+	bt := breakpointTestTree(t)
+
+	tests := []struct {
+		namePath string
+		want     nodeRef
+		wantErr  bool
+	}{
+		{namePath: "root/pick", want: nodeRef{treeID: "main", nodeID: 1}},
+		{namePath: "place", want: nodeRef{treeID: "main", nodeID: 2}},
+		{namePath: "root/sub/subroot/pick", want: nodeRef{treeID: "sub", nodeID: 1}},
+		{namePath: "root", want: nodeRef{treeID: "main", nodeID: 4}},
+		// Ambiguous.
+		{namePath: "pick", wantErr: true},
+		{namePath: "root/unknown", wantErr: true},
+		{namePath: "root/no_id", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := findNode(bt, tc.namePath)
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("findNode(%q) returned error %v, want error: %t", tc.namePath, err, tc.wantErr)
+			continue
+		}
+		if err == nil && got.ref != tc.want {
+			t.Errorf("findNode(%q) = %+v, want %+v", tc.namePath, got.ref, tc.want)
+		}
+	}
+}
+
+func TestDescribeBreakpoints(t *testing.T) {
+	got, err := describeBreakpoints(breakpointTestTree(t), []*btpb.BehaviorTree_Breakpoint{
+		{TreeId: "sub", NodeId: 1, Type: btpb.BehaviorTree_Breakpoint_BEFORE},
+		{TreeId: "main", NodeId: 2, Type: btpb.BehaviorTree_Breakpoint_AFTER},
+		{TreeId: "other", NodeId: 1, Type: btpb.BehaviorTree_Breakpoint_BEFORE},
+	})
+	if err != nil {
+		t.Fatalf("describeBreakpoints() failed: %v", err)
+	}
+
+	want := &listBreakpointsResponse{
+		Breakpoints: []*breakpointDescription{
+			{TreeID: "sub", NodeID: 1, Type: "BEFORE", NamePath: "root/sub/subroot/pick"},
+			{TreeID: "main", NodeID: 2, Type: "AFTER", NamePath: "root/place"},
+			{TreeID: "other", NodeID: 1, Type: "BEFORE"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("describeBreakpoints() returned unexpected result (-want +got):\n%s", diff)
+	}
+}
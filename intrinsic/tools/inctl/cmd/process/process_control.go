@@ -0,0 +1,261 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	eepb "intrinsic/executive/proto/executive_execution_mode_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	exsvcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	"intrinsic/tools/inctl/util/orgutil"
+)
+
+var (
+	flagExecutionMode  string
+	flagSimulationMode string
+	flagResumeMode     string
+	flagKeepBlackboard bool
+)
+
+var executionModes = map[string]eepb.ExecutionMode{
+	"normal":    eepb.ExecutionMode_EXECUTION_MODE_NORMAL,
+	"step_wise": eepb.ExecutionMode_EXECUTION_MODE_STEP_WISE,
+}
+
+var simulationModes = map[string]eepb.SimulationMode{
+	"reality": eepb.SimulationMode_SIMULATION_MODE_REALITY,
+	"draft":   eepb.SimulationMode_SIMULATION_MODE_DRAFT,
+}
+
+var resumeModes = map[string]exsvcpb.ResumeOperationRequest_ResumeMode{
+	"continue": exsvcpb.ResumeOperationRequest_CONTINUE,
+	"step":     exsvcpb.ResumeOperationRequest_STEP,
+	"next":     exsvcpb.ResumeOperationRequest_NEXT,
+}
+
+// parseChoice looks up the value of a flag which only accepts the keys of choices.
+func parseChoice[T any](flag string, value string, choices map[string]T) (T, error) {
+	if v, ok := choices[value]; ok {
+		return v, nil
+	}
+	var keys []string
+	for k := range choices {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var zero T
+	return zero, fmt.Errorf("invalid value %q for --%s, must be one of: %s", value, flag, strings.Join(keys, ", "))
+}
+
+// withOperationConn connects to the selected cluster and invokes fn with the connection and the
+// name of the operation selected with --operation (or the only operation of the executive).
+func withOperationConn(cmd *cobra.Command, fn func(ctx context.Context, conn *grpc.ClientConn, operationName string) error) error {
+	return withSelectedOperation(cmd, getOperation, fn)
+}
+
+// withReadOperationConn is like withOperationConn but uses the first of several operations if
+// --operation is not set. It is meant for commands which only read an operation.
+func withReadOperationConn(cmd *cobra.Command, fn func(ctx context.Context, conn *grpc.ClientConn, operationName string) error) error {
+	return withSelectedOperation(cmd, getOperationOrFirst, fn)
+}
+
+func withSelectedOperation(cmd *cobra.Command, selectOperation func(context.Context, execgrpcpb.ExecutiveServiceClient, string) (*lrpb.Operation, error), fn func(ctx context.Context, conn *grpc.ClientConn, operationName string) error) error {
+	projectName := viperLocal.GetString(orgutil.KeyProject)
+	orgName := viperLocal.GetString(orgutil.KeyOrganization)
+	ctx, conn, err := connectToCluster(cmd.Context(), projectName,
+		orgName, flagServerAddress,
+		flagSolutionName, flagClusterName)
+	if err != nil {
+		return errors.Wrapf(err, "could not dial connection")
+	}
+	defer conn.Close()
+
+	operation, err := selectOperation(ctx, execgrpcpb.NewExecutiveServiceClient(conn), flagOperation)
+	if err != nil {
+		return err
+	}
+	return fn(ctx, conn, operation.GetName())
+}
+
+// withOperation is like withOperationConn but passes an executive client to fn.
+func withOperation(cmd *cobra.Command, fn func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error) error {
+	return withOperationConn(cmd, func(ctx context.Context, conn *grpc.ClientConn, operationName string) error {
+		return fn(ctx, execgrpcpb.NewExecutiveServiceClient(conn), operationName)
+	})
+}
+
+// withReadOperation is like withReadOperationConn but passes an executive client to fn.
+func withReadOperation(cmd *cobra.Command, fn func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error) error {
+	return withReadOperationConn(cmd, func(ctx context.Context, conn *grpc.ClientConn, operationName string) error {
+		return fn(ctx, execgrpcpb.NewExecutiveServiceClient(conn), operationName)
+	})
+}
+
+func startOperation(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+	executionMode, err := parseChoice("execution_mode", flagExecutionMode, executionModes)
+	if err != nil {
+		return err
+	}
+	simulationMode, err := parseChoice("simulation_mode", flagSimulationMode, simulationModes)
+	if err != nil {
+		return err
+	}
+
+	if _, err := exC.StartOperation(ctx, &exsvcpb.StartOperationRequest{
+		Name:           operationName,
+		ExecutionMode:  executionMode,
+		SimulationMode: simulationMode,
+	}); err != nil {
+		return errors.Wrapf(err, "unable to start operation %q", operationName)
+	}
+	return nil
+}
+
+var processStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start executing an operation.",
+	Long: `Start executing the behavior tree of an executive operation. The operation must be
+in the ACCEPTED state, e.g., after "inctl process set" or "inctl process reset".
+
+The command returns immediately. Use "inctl process run" to start an operation and wait for it
+to finish.
+
+inctl process start --solution my-solution --cluster my-cluster [--operation op] [--execution_mode step_wise] [--simulation_mode draft]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return withOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			if err := startOperation(ctx, exC, operationName); err != nil {
+				return err
+			}
+			fmt.Printf("Started operation %q.\n", operationName)
+			return nil
+		})
+	},
+}
+
+var processSuspendCmd = &cobra.Command{
+	Use:   "suspend",
+	Short: "Suspend a running operation.",
+	Long: `Suspend a running operation. Active skills are allowed to finish before the rest of
+the behavior tree is paused. The command returns without waiting for the operation to be
+suspended.
+
+inctl process suspend --solution my-solution --cluster my-cluster [--operation op]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return withOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			if _, err := exC.SuspendOperation(ctx, &exsvcpb.SuspendOperationRequest{Name: operationName}); err != nil {
+				return errors.Wrapf(err, "unable to suspend operation %q", operationName)
+			}
+			fmt.Printf("Suspending operation %q.\n", operationName)
+			return nil
+		})
+	},
+}
+
+var processResumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume a suspended operation.",
+	Long: `Resume a suspended operation. In step-wise execution mode, --mode step executes
+the next node and --mode next continues until the next node outside of the current one.
+
+inctl process resume --solution my-solution --cluster my-cluster [--operation op] [--mode continue|step|next]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		mode, err := parseChoice("mode", flagResumeMode, resumeModes)
+		if err != nil {
+			return err
+		}
+		return withOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			if _, err := exC.ResumeOperation(ctx, &exsvcpb.ResumeOperationRequest{
+				Name: operationName,
+				Mode: mode.Enum(),
+			}); err != nil {
+				return errors.Wrapf(err, "unable to resume operation %q", operationName)
+			}
+			fmt.Printf("Resumed operation %q.\n", operationName)
+			return nil
+		})
+	},
+}
+
+var processCancelCmd = &cobra.Command{
+	Use:   "cancel",
+	Short: "Cancel a running or suspended operation.",
+	Long: `Cancel a running or suspended operation. The command returns without waiting for
+the cancellation to finish.
+
+inctl process cancel --solution my-solution --cluster my-cluster [--operation op]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return withOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			if _, err := exC.CancelOperation(ctx, &lrpb.CancelOperationRequest{Name: operationName}); err != nil {
+				return errors.Wrapf(err, "unable to cancel operation %q", operationName)
+			}
+			fmt.Printf("Canceling operation %q.\n", operationName)
+			return nil
+		})
+	},
+}
+
+var processResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Reset a finished operation so that it can be started again.",
+	Long: `Reset an operation into the state it had after it was created. The operation must
+not be running. The blackboard is cleared unless --keep_blackboard is set.
+
+inctl process reset --solution my-solution --cluster my-cluster [--operation op] [--keep_blackboard]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return withOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			if _, err := exC.ResetOperation(ctx, &exsvcpb.ResetOperationRequest{
+				Name:           operationName,
+				KeepBlackboard: flagKeepBlackboard,
+			}); err != nil {
+				return errors.Wrapf(err, "unable to reset operation %q", operationName)
+			}
+			fmt.Printf("Reset operation %q.\n", operationName)
+			return nil
+		})
+	},
+}
+
+// addTargetFlags registers the flags which select the solution or cluster of a command.
+func addTargetFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagSolutionName, "solution", "", "Solution to use. For example, use `inctl solutions list --org orgname@projectname --output json [--filter running_in_sim]` to see the list of solutions.")
+	cmd.Flags().StringVar(&flagClusterName, "cluster", "", "Cluster to use.")
+}
+
+// addStartFlags registers the flags which configure how an operation is started.
+func addStartFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagExecutionMode, "execution_mode", "normal", "Execution mode, one of: normal, step_wise.")
+	cmd.Flags().StringVar(&flagSimulationMode, "simulation_mode", "reality", "Simulation mode, one of: reality, draft.")
+}
+
+func init() {
+	addTargetFlags(processStartCmd)
+	addStartFlags(processStartCmd)
+	processCmd.AddCommand(processStartCmd)
+
+	addTargetFlags(processSuspendCmd)
+	processCmd.AddCommand(processSuspendCmd)
+
+	addTargetFlags(processResumeCmd)
+	processResumeCmd.Flags().StringVar(&flagResumeMode, "mode", "continue", "Resume mode, one of: continue, step, next.")
+	processCmd.AddCommand(processResumeCmd)
+
+	addTargetFlags(processCancelCmd)
+	processCmd.AddCommand(processCancelCmd)
+
+	addTargetFlags(processResetCmd)
+	processResetCmd.Flags().BoolVar(&flagKeepBlackboard, "keep_blackboard", false, "Keep the blackboard of the operation instead of clearing it.")
+	processCmd.AddCommand(processResetCmd)
+}
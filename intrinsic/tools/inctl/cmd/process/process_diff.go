@@ -0,0 +1,526 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"intrinsic/executive/go/behaviortree"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	sgrpcpb "intrinsic/frontend/solution_service/proto/solution_service_go_grpc_proto"
+	skillregistrygrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/orgutil"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+const (
+	diffKindAdded   = "added"
+	diffKindRemoved = "removed"
+	diffKindMoved   = "moved"
+	diffKindChanged = "changed"
+
+	diffUnset = "<unset>"
+)
+
+var (
+	flagDiffProcess  string
+	flagDiffExitCode bool
+)
+
+// fieldChange is a changed field of a node, e.g., a skill parameter.
+type fieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// nodeChange describes how a node differs between two behavior trees.
+type nodeChange struct {
+	Kind    string         `json:"kind"`
+	Node    string         `json:"node"`
+	OldPath string         `json:"oldPath,omitempty"`
+	NewPath string         `json:"newPath,omitempty"`
+	Fields  []*fieldChange `json:"fields,omitempty"`
+}
+
+// processDiff is the semantic difference between two behavior trees. It can be printed as text or
+// JSON.
+type processDiff struct {
+	Changes []*nodeChange `json:"changes"`
+}
+
+// String converts a processDiff to a string.
+func (d *processDiff) String() string {
+	if len(d.Changes) == 0 {
+		return "No differences."
+	}
+
+	b := new(bytes.Buffer)
+	for i, c := range d.Changes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch c.Kind {
+		case diffKindAdded:
+			fmt.Fprintf(b, "+ added %q at %s", c.Node, c.NewPath)
+		case diffKindRemoved:
+			fmt.Fprintf(b, "- removed %q at %s", c.Node, c.OldPath)
+		case diffKindMoved:
+			fmt.Fprintf(b, "~ moved %q from %s to %s", c.Node, c.OldPath, c.NewPath)
+		default:
+			fmt.Fprintf(b, "* changed %q at %s", c.Node, c.NewPath)
+		}
+		for _, f := range c.Fields {
+			fmt.Fprintf(b, "\n    %s: %s -> %s", f.Field, f.Old, f.New)
+		}
+	}
+	return b.String()
+}
+
+// diffNode is a node of a behavior tree together with the information needed to align it with the
+// nodes of another tree.
+type diffNode struct {
+	key    string
+	node   *btpb.BehaviorTree_Node
+	path   string
+	parent string
+}
+
+// collectDiffNodes returns the nodes of the given tree in depth-first order. Nodes are keyed by
+// their id if set and by their name otherwise. Keys of nodes in subtrees are prefixed with the key
+// of the subtree node. Trees nested in conditions are treated as part of the node owning the
+// condition.
+func collectDiffNodes(bt *btpb.BehaviorTree) ([]*diffNode, error) {
+	var nodes []*diffNode
+	keys := make(map[*btpb.BehaviorTree_Node]string)
+	scopes := make(map[*btpb.BehaviorTree]string)
+	used := make(map[string]int)
+
+	w := &behaviortree.Walker{
+		PreTree: func(tree *btpb.BehaviorTree, c *behaviortree.Cursor) error {
+			if c.Parent != nil {
+				scopes[tree] = keys[c.Parent] + "/"
+			}
+			return nil
+		},
+		PreCondition: func(cond *btpb.BehaviorTree_Condition, c *behaviortree.Cursor) error {
+			return behaviortree.SkipChildren
+		},
+		PreNode: func(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) error {
+			var local string
+			switch {
+			case node.Id != nil:
+				local = fmt.Sprintf("id=%d", node.GetId())
+			case node.GetName() != "":
+				local = "name=" + node.GetName()
+			default:
+				local = "path=" + c.Path()
+			}
+			key := scopes[c.Tree] + local
+			used[key]++
+			if n := used[key]; n > 1 {
+				key = fmt.Sprintf("%s#%d", key, n)
+			}
+			keys[node] = key
+
+			parent := ""
+			if c.Parent != nil {
+				parent = keys[c.Parent]
+			}
+			nodes = append(nodes, &diffNode{key: key, node: node, path: c.Path(), parent: parent})
+			return nil
+		},
+	}
+	if err := w.Walk(bt); err != nil {
+		return nil, errors.Wrap(err, "could not walk behavior tree")
+	}
+	return nodes, nil
+}
+
+// shallowNode returns a copy of the given node without its child nodes.
+func shallowNode(node *btpb.BehaviorTree_Node) *btpb.BehaviorTree_Node {
+	n := proto.Clone(node).(*btpb.BehaviorTree_Node)
+	switch n.NodeType.(type) {
+	case *btpb.BehaviorTree_Node_Sequence:
+		n.GetSequence().Children = nil
+	case *btpb.BehaviorTree_Node_Parallel:
+		n.GetParallel().Children = nil
+	case *btpb.BehaviorTree_Node_Selector:
+		n.GetSelector().Children = nil
+	case *btpb.BehaviorTree_Node_Fallback:
+		n.GetFallback().Children = nil
+	case *btpb.BehaviorTree_Node_Branch:
+		n.GetBranch().Then = nil
+		n.GetBranch().Else = nil
+	case *btpb.BehaviorTree_Node_Loop:
+		n.GetLoop().Do = nil
+	case *btpb.BehaviorTree_Node_Retry:
+		n.GetRetry().Child = nil
+		n.GetRetry().Recovery = nil
+	case *btpb.BehaviorTree_Node_SubTree:
+		if tree := n.GetSubTree().GetTree(); tree != nil {
+			tree.Root = nil
+		}
+	}
+	return n
+}
+
+// hasNodeIDs tells if any node of the given tree has an id.
+func hasNodeIDs(bt *btpb.BehaviorTree) (bool, error) {
+	found := false
+	w := &behaviortree.Walker{
+		PreNode: func(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) error {
+			found = found || node.Id != nil
+			return nil
+		},
+	}
+	if err := w.Walk(bt); err != nil {
+		return false, errors.Wrap(err, "could not walk behavior tree")
+	}
+	return found, nil
+}
+
+// diffBTs computes the semantic difference between the old and the new behavior tree. Output-only
+// fields are ignored. Node ids are ignored as well if one of the trees has none, e.g., because it
+// was stored with "inctl process get", which clears them by default. Any protos are decoded with
+// the given resolver where possible so that changes are reported per field.
+func diffBTs(oldBT, newBT *btpb.BehaviorTree, resolver typeResolver) (*processDiff, error) {
+	oldBT = proto.Clone(oldBT).(*btpb.BehaviorTree)
+	newBT = proto.Clone(newBT).(*btpb.BehaviorTree)
+	clearNodeIDs := false
+	for _, bt := range []*btpb.BehaviorTree{oldBT, newBT} {
+		hasIDs, err := hasNodeIDs(bt)
+		if err != nil {
+			return nil, err
+		}
+		clearNodeIDs = clearNodeIDs || !hasIDs
+	}
+	for _, bt := range []*btpb.BehaviorTree{oldBT, newBT} {
+		if err := behaviortree.ClearOutputOnlyFields(bt, true, clearNodeIDs); err != nil {
+			return nil, errors.Wrap(err, "could not clear output-only fields")
+		}
+	}
+
+	oldNodes, err := collectDiffNodes(oldBT)
+	if err != nil {
+		return nil, err
+	}
+	newNodes, err := collectDiffNodes(newBT)
+	if err != nil {
+		return nil, err
+	}
+	oldByKey := make(map[string]*diffNode)
+	for _, n := range oldNodes {
+		oldByKey[n.key] = n
+	}
+	newByKey := make(map[string]*diffNode)
+	for _, n := range newNodes {
+		newByKey[n.key] = n
+	}
+
+	d := &processDiff{Changes: []*nodeChange{}}
+	for _, o := range oldNodes {
+		if _, ok := newByKey[o.key]; !ok {
+			d.Changes = append(d.Changes, &nodeChange{Kind: diffKindRemoved, Node: o.node.GetName(), OldPath: o.path})
+		}
+	}
+
+	moved := movedNodes(oldNodes, newNodes, oldByKey, newByKey)
+	for _, n := range newNodes {
+		o, ok := oldByKey[n.key]
+		if !ok {
+			d.Changes = append(d.Changes, &nodeChange{Kind: diffKindAdded, Node: n.node.GetName(), NewPath: n.path})
+			continue
+		}
+		if moved[n.key] {
+			d.Changes = append(d.Changes, &nodeChange{Kind: diffKindMoved, Node: n.node.GetName(), OldPath: o.path, NewPath: n.path})
+		}
+		fields := diffMessages("", shallowNode(o.node).ProtoReflect(), shallowNode(n.node).ProtoReflect(), resolver)
+		if len(fields) > 0 {
+			d.Changes = append(d.Changes, &nodeChange{Kind: diffKindChanged, Node: n.node.GetName(), OldPath: o.path, NewPath: n.path, Fields: fields})
+		}
+	}
+	return d, nil
+}
+
+// movedNodes returns the keys of the nodes which have a different parent in the new tree or which
+// changed their order relative to their siblings. To report as few moves as possible, siblings
+// which are in the longest common subsequence of the old and new order are not considered moved.
+func movedNodes(oldNodes, newNodes []*diffNode, oldByKey, newByKey map[string]*diffNode) map[string]bool {
+	moved := make(map[string]bool)
+	for _, n := range newNodes {
+		if o, ok := oldByKey[n.key]; ok && o.parent != n.parent {
+			moved[n.key] = true
+		}
+	}
+
+	oldSiblings := commonSiblings(oldNodes, newByKey)
+	newSiblings := commonSiblings(newNodes, oldByKey)
+	for parent, oldKeys := range oldSiblings {
+		inOrder := longestCommonSubsequence(oldKeys, newSiblings[parent])
+		for _, key := range oldKeys {
+			if !inOrder[key] {
+				moved[key] = true
+			}
+		}
+	}
+	return moved
+}
+
+// commonSiblings returns the keys of the children of each parent which have the same parent in the
+// other tree.
+func commonSiblings(nodes []*diffNode, other map[string]*diffNode) map[string][]string {
+	siblings := make(map[string][]string)
+	for _, n := range nodes {
+		if o, ok := other[n.key]; ok && o.parent == n.parent {
+			siblings[n.parent] = append(siblings[n.parent], n.key)
+		}
+	}
+	return siblings
+}
+
+func longestCommonSubsequence(a, b []string) map[string]bool {
+	// lengths[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	result := make(map[string]bool)
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			result[a[i]] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return result
+}
+
+// diffMessages returns the fields which differ between the given messages of the same type.
+func diffMessages(prefix string, a, b protoreflect.Message, resolver typeResolver) []*fieldChange {
+	if a.Descriptor().FullName() == "google.protobuf.Any" {
+		if changes, ok := diffAnys(prefix, a, b, resolver); ok {
+			return changes
+		}
+	}
+
+	var changes []*fieldChange
+	fields := a.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := string(fd.Name())
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		hasA, hasB := a.Has(fd), b.Has(fd)
+		switch {
+		case !hasA && !hasB:
+			continue
+		case hasA && hasB && fd.Message() != nil && !fd.IsList() && !fd.IsMap():
+			changes = append(changes, diffMessages(name, a.Get(fd).Message(), b.Get(fd).Message(), resolver)...)
+		case hasA != hasB || !a.Get(fd).Equal(b.Get(fd)):
+			changes = append(changes, &fieldChange{
+				Field: name,
+				Old:   formatField(a, fd, resolver),
+				New:   formatField(b, fd, resolver),
+			})
+		}
+	}
+	return changes
+}
+
+// diffAnys compares two Any protos by their unpacked contents if both have the same, known type.
+func diffAnys(prefix string, a, b protoreflect.Message, resolver typeResolver) ([]*fieldChange, bool) {
+	typeURLA, valueA := anyContents(a)
+	typeURLB, valueB := anyContents(b)
+	if typeURLA != typeURLB || resolver == nil {
+		return nil, false
+	}
+	mt, err := resolver.FindMessageByURL(typeURLA)
+	if err != nil {
+		return nil, false
+	}
+	ma, mb := mt.New(), mt.New()
+	unmarshaller := proto.UnmarshalOptions{Resolver: resolver}
+	if unmarshaller.Unmarshal(valueA, ma.Interface()) != nil || unmarshaller.Unmarshal(valueB, mb.Interface()) != nil {
+		return nil, false
+	}
+	return diffMessages(prefix, ma, mb, resolver), true
+}
+
+func anyContents(m protoreflect.Message) (string, []byte) {
+	fields := m.Descriptor().Fields()
+	return m.Get(fields.ByName("type_url")).String(), m.Get(fields.ByName("value")).Bytes()
+}
+
+func formatField(m protoreflect.Message, fd protoreflect.FieldDescriptor, resolver typeResolver) string {
+	if !m.Has(fd) {
+		return diffUnset
+	}
+	v := m.Get(fd)
+	switch {
+	case fd.IsList():
+		l := v.List()
+		items := make([]string, l.Len())
+		for i := 0; i < l.Len(); i++ {
+			items[i] = formatValue(fd, l.Get(i), resolver)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case fd.IsMap():
+		var items []string
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			items = append(items, fmt.Sprintf("%v: %s", k.Interface(), formatValue(fd.MapValue(), mv, resolver)))
+			return true
+		})
+		sort.Strings(items)
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return formatValue(fd, v, resolver)
+}
+
+func formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, resolver typeResolver) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		marshaller := prototext.MarshalOptions{Resolver: resolver}
+		return "{" + strings.TrimSpace(marshaller.Format(v.Message().Interface())) + "}"
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return fmt.Sprintf("%d", v.Enum())
+	case protoreflect.StringKind:
+		return fmt.Sprintf("%q", v.String())
+	case protoreflect.BytesKind:
+		return fmt.Sprintf("%q", v.Bytes())
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// readDiffInput reads a behavior tree from the given file.
+func readDiffInput(ctx context.Context, srC skillregistrygrpcpb.SkillRegistryClient, path string) (*btpb.BehaviorTree, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read file %s", path)
+	}
+	bt, err := deserializeBT(ctx, srC, flagProcessFormat, content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not deserialize BT from %s", path)
+	}
+	return bt, nil
+}
+
+var processDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare two processes (behavior trees). ",
+	Long: `Compare two processes (behavior trees) semantically.
+
+Nodes are aligned by their id, or by their name if they have no id. Ids are
+ignored if one of the processes has none, like files written by "inctl process
+get" without --clear_node_ids=false. The command prints added, removed and moved
+nodes as well as changed node settings and skill parameters. Skill parameters
+are decoded using the parameter descriptors of the skills installed in the
+solution. Output-only fields are ignored.
+
+To compare two process files:
+
+inctl process diff /tmp/old.textproto /tmp/new.textproto --solution my-solution --cluster my-cluster
+
+To compare a process file against the active process of the executive, or
+against a process stored in the solution with --process:
+
+inctl process diff /tmp/process.textproto [--process my_process] --solution my-solution --cluster my-cluster [--output json]`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 2 && flagDiffProcess != "" {
+			return fmt.Errorf("--process cannot be used when comparing two files")
+		}
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+
+		projectName := viperLocal.GetString(orgutil.KeyProject)
+		orgName := viperLocal.GetString(orgutil.KeyOrganization)
+		ctx, conn, err := connectToCluster(cmd.Context(), projectName,
+			orgName, flagServerAddress,
+			flagSolutionName, flagClusterName)
+		if err != nil {
+			return errors.Wrapf(err, "could not dial connection")
+		}
+		defer conn.Close()
+
+		srC := skillregistrygrpcpb.NewSkillRegistryClient(conn)
+		oldBT, err := readDiffInput(ctx, srC, args[0])
+		if err != nil {
+			return err
+		}
+		var newBT *btpb.BehaviorTree
+		if len(args) == 2 {
+			if newBT, err = readDiffInput(ctx, srC, args[1]); err != nil {
+				return err
+			}
+		} else {
+			newBT, err = fetchBT(ctx, execgrpcpb.NewExecutiveServiceClient(conn), sgrpcpb.NewSolutionServiceClient(conn), flagDiffProcess, flagOperation)
+			if err != nil {
+				return err
+			}
+		}
+
+		skills, err := getSkills(ctx, srC)
+		if err != nil {
+			return errors.Wrapf(err, "could not list skills")
+		}
+		types, err := anyTypes(skills, oldBT, newBT)
+		if err != nil {
+			return err
+		}
+		d, err := diffBTs(oldBT, newBT, types)
+		if err != nil {
+			return err
+		}
+		prtr.Print(d)
+
+		if flagDiffExitCode && len(d.Changes) > 0 {
+			return fmt.Errorf("processes differ in %d node(s)", len(d.Changes))
+		}
+		return nil
+	},
+}
+
+func init() {
+	processDiffCmd.Flags().StringVar(
+		&flagProcessFormat, "process_format", TextProtoFormat,
+		fmt.Sprintf("(optional) format of the input files. One of: (%s)", strings.Join(allowedSetFormats, ", ")))
+	processDiffCmd.Flags().StringVar(&flagSolutionName, "solution", "", "Solution to get skills and the live process from. For example, use `inctl solutions list --org orgname@projectname --output json [--filter running_in_sim]` to see the list of solutions.")
+	processDiffCmd.Flags().StringVar(&flagClusterName, "cluster", "", "Cluster to get skills and the live process from.")
+	processDiffCmd.Flags().StringVar(&flagDiffProcess, "process", "", "If set and only one file is given, compares against the process with this name in the solution instead of the active process.")
+	processDiffCmd.Flags().BoolVar(&flagDiffExitCode, "exit_code", false, "If set, the command fails if the processes differ.")
+	processCmd.AddCommand(processDiffCmd)
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+)
+
+func TestDiffBTs(t *testing.T) {
+	withID := func(n *btpb.BehaviorTree_Node, id uint32) *btpb.BehaviorTree_Node {
+		n.Id = proto.Uint32(id)
+		return n
+	}
+	withState := func(n *btpb.BehaviorTree_Node) *btpb.BehaviorTree_Node {
+		n.State = btpb.BehaviorTree_Node_SUCCEEDED.Enum()
+		return n
+	}
+
+	oldBT := lintSequence(
+		withID(lintTask(t, "a", "ai.intrinsic.say", wrapperspb.String("hello")), 1),
+		withID(lintTask(t, "b", "ai.intrinsic.say", nil), 2),
+		withID(lintTask(t, "c", "ai.intrinsic.say", nil), 3),
+		lintTask(t, "removed", "ai.intrinsic.say", nil),
+	)
+	newBT := lintSequence(
+		// Output-only fields are ignored.
+		withState(withID(lintTask(t, "a", "ai.intrinsic.say", wrapperspb.String("world")), 1)),
+		lintTask(t, "added", "ai.intrinsic.say", nil),
+		withID(lintTask(t, "c", "ai.intrinsic.say", nil), 3),
+		withID(lintTask(t, "b renamed", "ai.intrinsic.say", nil), 2),
+	)
+
+	got, err := diffBTs(oldBT, newBT, protoregistry.GlobalTypes)
+	if err != nil {
+		t.Fatalf("diffBTs() failed: %v", err)
+	}
+
+	want := &processDiff{
+		Changes: []*nodeChange{
+			{Kind: diffKindRemoved, Node: "removed", OldPath: "root.sequence.children[3]"},
+			{
+				Kind:    diffKindChanged,
+				Node:    "a",
+				OldPath: "root.sequence.children[0]",
+				NewPath: "root.sequence.children[0]",
+				Fields: []*fieldChange{
+					{Field: "task.call_behavior.parameters.value", Old: `"hello"`, New: `"world"`},
+				},
+			},
+			{Kind: diffKindAdded, Node: "added", NewPath: "root.sequence.children[1]"},
+			{Kind: diffKindMoved, Node: "b renamed", OldPath: "root.sequence.children[1]", NewPath: "root.sequence.children[3]"},
+			{
+				Kind:    diffKindChanged,
+				Node:    "b renamed",
+				OldPath: "root.sequence.children[1]",
+				NewPath: "root.sequence.children[3]",
+				Fields: []*fieldChange{
+					{Field: "name", Old: `"b"`, New: `"b renamed"`},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diffBTs() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiffBTsWithoutNodeIDs(t *testing.T) {
+	withID := func(n *btpb.BehaviorTree_Node, id uint32) *btpb.BehaviorTree_Node {
+		n.Id = proto.Uint32(id)
+		return n
+	}
+
+	// "inctl process get" clears node ids by default, the live process has them.
+	fileBT := lintSequence(
+		lintTask(t, "a", "ai.intrinsic.say", wrapperspb.String("hello")),
+		lintTask(t, "b", "ai.intrinsic.say", nil),
+	)
+	liveBT := lintSequence(
+		withID(lintTask(t, "a", "ai.intrinsic.say", wrapperspb.String("world")), 2),
+		withID(lintTask(t, "b", "ai.intrinsic.say", nil), 3),
+	)
+	liveBT.GetRoot().Id = proto.Uint32(1)
+
+	got, err := diffBTs(fileBT, liveBT, protoregistry.GlobalTypes)
+	if err != nil {
+		t.Fatalf("diffBTs() failed: %v", err)
+	}
+
+	want := &processDiff{
+		Changes: []*nodeChange{
+			{
+				Kind:    diffKindChanged,
+				Node:    "a",
+				OldPath: "root.sequence.children[0]",
+				NewPath: "root.sequence.children[0]",
+				Fields: []*fieldChange{
+					{Field: "task.call_behavior.parameters.value", Old: `"hello"`, New: `"world"`},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diffBTs() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiffBTsIdentical(t *testing.T) {
+	bt := lintSequence(lintTask(t, "a", "ai.intrinsic.say", wrapperspb.String("hello")))
+
+	got, err := diffBTs(bt, proto.Clone(bt).(*btpb.BehaviorTree), protoregistry.GlobalTypes)
+	if err != nil {
+		t.Fatalf("diffBTs() failed: %v", err)
+	}
+	if len(got.Changes) != 0 {
+		t.Errorf("diffBTs() = %v, want no changes", got)
+	}
+}
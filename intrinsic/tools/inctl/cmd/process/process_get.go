@@ -27,9 +27,13 @@ import (
 var allowedGetFormats = []string{TextProtoFormat, BinaryProtoFormat, PythonScriptFormat, PythonMinimalFormat, PythonNotebookFormat}
 
 const (
-	pythonScriptTemplate = `from intrinsic.solutions import deployments
+	pythonScriptTemplate = `import datetime
+
+from intrinsic.solutions import deployments
 from intrinsic.solutions import behavior_tree as bt
 from intrinsic.math.python import data_types
+from intrinsic.executive.proto import any_with_assignments_pb2
+from intrinsic.executive.proto import world_query_pb2
 
 solution = deployments.connect_to_selected_solution()
 
@@ -49,8 +53,12 @@ executive.run(tree)
 	"metadata": {},
 	"outputs": [],
 	"source": [
+		"import datetime\n",
+		"\n",
 		"from intrinsic.solutions import behavior_tree as bt\n",
 		"from intrinsic.solutions import deployments\n",
+		"from intrinsic.executive.proto import any_with_assignments_pb2\n",
+		"from intrinsic.executive.proto import world_query_pb2\n",
 		"\n",
 		"solution = deployments.connect_to_selected_solution()\n",
 		"\n",
@@ -207,6 +215,7 @@ func serializeBT(ctx context.Context, srC skillregistrygrpcpb.SkillRegistryClien
 	if format == PythonNotebookFormat {
 		lines := strings.SplitN(string(data), "\n", -1)
 		for i, line := range lines {
+			line = strings.Replace(line, "\\", "\\\\", -1)
 			line = strings.Replace(line, "\"", "\\\"", -1)
 			lines[i] = fmt.Sprintf("\t\t\"%s\"", line)
 		}
@@ -222,30 +231,39 @@ type getProcessParams struct {
 	soC          sgrpcpb.SolutionServiceClient
 	srC          skillregistrygrpcpb.SkillRegistryClient
 	name         string
+	operation    string
 	format       string
 	clearTreeID  bool
 	clearNodeIDs bool
 }
 
-func getProcess(ctx context.Context, params *getProcessParams) ([]byte, error) {
-	var bt *btpb.BehaviorTree
-	if params.name == "" {
-		activeBT, err := getActiveBT(ctx, params.exC)
+// fetchBT returns the behavior tree of the given executive operation if name is empty and the
+// behavior tree with the given name from the solution otherwise.
+func fetchBT(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, soC sgrpcpb.SolutionServiceClient, name string, operationName string) (*btpb.BehaviorTree, error) {
+	if name == "" {
+		activeBT, err := getActiveBT(ctx, exC, operationName)
 		if err != nil {
 			return nil, errors.Wrap(err, "could not get active behavior tree")
 		}
-		bt = activeBT
-	} else {
-		namedBT, err := params.soC.GetBehaviorTree(ctx, &spb.GetBehaviorTreeRequest{
-			Name: params.name,
-		})
-		if err != nil {
-			return nil, errors.Wrap(err, "could not get named behavior tree")
-		}
-		bt = namedBT
+		return activeBT, nil
+	}
+
+	namedBT, err := soC.GetBehaviorTree(ctx, &spb.GetBehaviorTreeRequest{
+		Name: name,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get named behavior tree")
+	}
+	return namedBT, nil
+}
+
+func getProcess(ctx context.Context, params *getProcessParams) ([]byte, error) {
+	bt, err := fetchBT(ctx, params.exC, params.soC, params.name, params.operation)
+	if err != nil {
+		return nil, err
 	}
 
-	clearTree(bt, params.clearTreeID, params.clearNodeIDs)
+	behaviortree.ClearOutputOnlyFields(bt, params.clearTreeID, params.clearNodeIDs)
 
 	return serializeBT(ctx, params.srC, bt, params.format)
 }
@@ -257,9 +275,10 @@ var processGetCmd = &cobra.Command{
 
 There are two main operation modes. The first one is to get the "active" process
 in the executive. This is the default behavior if no name is provided as the
-first argument.
+first argument. If the executive has more than one operation, select the
+operation with --operation (see "inctl process list").
 
-inctl process get --solution my-solution-id --cluster my-cluster [--output_file /tmp/process.textproto] [--process_format textproto|binaryproto]
+inctl process get --solution my-solution-id --cluster my-cluster [--operation my-operation] [--output_file /tmp/process.textproto] [--process_format textproto|binaryproto]
 
 ---
 
@@ -289,6 +308,7 @@ inctl process get my_process --solution my-solution-id --cluster my-cluster [--o
 			soC:          sgrpcpb.NewSolutionServiceClient(conn),
 			srC:          skillregistrygrpcpb.NewSkillRegistryClient(conn),
 			name:         name,
+			operation:    flagOperation,
 			format:       flagProcessFormat,
 			clearTreeID:  flagClearTreeID,
 			clearNodeIDs: flagClearNodeIDs,
@@ -0,0 +1,549 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"intrinsic/assets/idutils"
+	"intrinsic/executive/go/behaviortree"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	sgrpcpb "intrinsic/frontend/solution_service/proto/solution_service_go_grpc_proto"
+	skillregistrygrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	skillspb "intrinsic/skills/proto/skills_go_proto"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/orgutil"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+const (
+	lintSeverityError   = "error"
+	lintSeverityWarning = "warning"
+)
+
+const (
+	lintCheckSkill       = "skill"
+	lintCheckParameters  = "parameters"
+	lintCheckResources   = "resources"
+	lintCheckBlackboard  = "blackboard"
+	lintCheckReturnValue = "return-value"
+	lintCheckNodeID      = "node-id"
+	lintCheckReachable   = "reachability"
+)
+
+// lintFinding is a single problem found in a behavior tree.
+type lintFinding struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Path     string `json:"path"`
+	Node     string `json:"node,omitempty"`
+	Message  string `json:"message"`
+}
+
+// lintReport is the result of linting a behavior tree. It can be printed as text or JSON.
+type lintReport struct {
+	Findings []*lintFinding `json:"findings"`
+}
+
+func (r *lintReport) count(severity string) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// String converts a lintReport to a string.
+func (r *lintReport) String() string {
+	if len(r.Findings) == 0 {
+		return "No problems found."
+	}
+
+	b := new(bytes.Buffer)
+	w := tabwriter.NewWriter(b,
+		/*minwidth=*/ 1 /*tabwidth=*/, 1 /*padding=*/, 1 /*padchar=*/, ' ' /*flags=*/, 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", "Severity", "Check", "Node", "Path", "Message")
+	for _, f := range r.Findings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Severity, f.Check, f.Node, f.Path, f.Message)
+	}
+	w.Flush()
+	fmt.Fprintf(b, "\n%d error(s), %d warning(s)", r.count(lintSeverityError), r.count(lintSeverityWarning))
+	return b.String()
+}
+
+// blackboardAccess is a read or write of a blackboard key. The index orders accesses by the
+// position of their node in a depth-first traversal of the tree, which approximates the order of
+// execution.
+type blackboardAccess struct {
+	index int
+	path  string
+	node  string
+}
+
+type linter struct {
+	skillsByID        map[string]*skillspb.Skill
+	skillsByIDVersion map[string]*skillspb.Skill
+	parameterFiles    map[string]protoDescriptorFinder
+
+	report      *lintReport
+	index       int
+	nodeIDs     map[*btpb.BehaviorTree]map[uint32]string
+	reads       map[string][]blackboardAccess
+	writes      map[string][]blackboardAccess
+	returnNames map[string]blackboardAccess
+}
+
+type protoDescriptorFinder interface {
+	FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+}
+
+func newLinter(skills []*skillspb.Skill) *linter {
+	l := &linter{
+		skillsByID:        make(map[string]*skillspb.Skill),
+		skillsByIDVersion: make(map[string]*skillspb.Skill),
+		parameterFiles:    make(map[string]protoDescriptorFinder),
+		report:            &lintReport{Findings: []*lintFinding{}},
+		nodeIDs:           make(map[*btpb.BehaviorTree]map[uint32]string),
+		reads:             make(map[string][]blackboardAccess),
+		writes:            make(map[string][]blackboardAccess),
+		returnNames:       make(map[string]blackboardAccess),
+	}
+	for _, skill := range skills {
+		l.skillsByID[skill.GetId()] = skill
+		l.skillsByIDVersion[skill.GetIdVersion()] = skill
+	}
+	return l
+}
+
+// lintBT checks the given behavior tree against the given installed skills.
+func lintBT(bt *btpb.BehaviorTree, skills []*skillspb.Skill) (*lintReport, error) {
+	l := newLinter(skills)
+	w := &behaviortree.Walker{
+		PreNode:      l.preNode,
+		PostNode:     l.postNode,
+		PreCondition: l.preCondition,
+	}
+	if err := w.Walk(bt); err != nil {
+		return nil, errors.Wrap(err, "could not walk behavior tree")
+	}
+	l.checkBlackboard()
+	return l.report, nil
+}
+
+func (l *linter) add(severity, check string, node *btpb.BehaviorTree_Node, c *behaviortree.Cursor, format string, a ...any) {
+	l.addAt(severity, check, node.GetName(), c.Path(), format, a...)
+}
+
+func (l *linter) addAt(severity, check, node, path, format string, a ...any) {
+	l.report.Findings = append(l.report.Findings, &lintFinding{
+		Severity: severity,
+		Check:    check,
+		Path:     path,
+		Node:     node,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (l *linter) read(key string, node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) {
+	if key == "" {
+		return
+	}
+	l.reads[key] = append(l.reads[key], blackboardAccess{index: l.index, path: c.Path(), node: node.GetName()})
+}
+
+func (l *linter) readCEL(expr string, node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) {
+	for _, key := range celIdentifiers(expr) {
+		l.read(key, node, c)
+	}
+}
+
+func (l *linter) write(key string, node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) {
+	if key == "" {
+		return
+	}
+	l.writes[key] = append(l.writes[key], blackboardAccess{index: l.index, path: c.Path(), node: node.GetName()})
+}
+
+func (l *linter) preNode(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) error {
+	l.index++
+	l.checkNodeID(node, c)
+	l.checkReachability(node, c)
+
+	switch node.NodeType.(type) {
+	case *btpb.BehaviorTree_Node_Task:
+		l.checkTask(node, c)
+	case *btpb.BehaviorTree_Node_Data:
+		createOrUpdate := node.GetData().GetCreateOrUpdate()
+		l.readCEL(createOrUpdate.GetCelExpression(), node, c)
+		for _, a := range createOrUpdate.GetFromWorld().GetAssign() {
+			l.readCEL(a.GetCelExpression(), node, c)
+		}
+		// Reads of the data node happen before the write.
+		l.index++
+		l.write(createOrUpdate.GetBlackboardKey(), node, c)
+	case *btpb.BehaviorTree_Node_Loop:
+		loop := node.GetLoop()
+		l.write(loop.GetLoopCounterBlackboardKey(), node, c)
+		l.readCEL(loop.GetForEach().GetGeneratorCelExpression(), node, c)
+		l.write(loop.GetForEach().GetValueBlackboardKey(), node, c)
+	case *btpb.BehaviorTree_Node_Retry:
+		l.write(node.GetRetry().GetRetryCounterBlackboardKey(), node, c)
+	}
+	return nil
+}
+
+func (l *linter) postNode(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) error {
+	// The extended status of a failure is written when the node has finished.
+	l.index++
+	l.write(node.GetDecorators().GetOnFailure().GetEmitExtendedStatus().GetToBlackboardKey(), node, c)
+	return nil
+}
+
+func (l *linter) preCondition(cond *btpb.BehaviorTree_Condition, c *behaviortree.Cursor) error {
+	l.index++
+	l.readCEL(cond.GetBlackboard().GetCelExpression(), c.Parent, c)
+	l.read(cond.GetStatusMatch().GetBlackboardKey(), c.Parent, c)
+	return nil
+}
+
+func (l *linter) checkNodeID(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) {
+	if node.Id == nil {
+		return
+	}
+	ids, ok := l.nodeIDs[c.Tree]
+	if !ok {
+		ids = make(map[uint32]string)
+		l.nodeIDs[c.Tree] = ids
+	}
+	if other, ok := ids[node.GetId()]; ok {
+		l.add(lintSeverityError, lintCheckNodeID, node, c, "node id %d is already used by the node at %s", node.GetId(), other)
+		return
+	}
+	ids[node.GetId()] = c.Path()
+}
+
+// alwaysFails reports whether the given node unconditionally fails when it is executed.
+func alwaysFails(node *btpb.BehaviorTree_Node) bool {
+	decorators := node.GetDecorators()
+	return node.GetFail() != nil && decorators.GetCondition() == nil &&
+		decorators.GetExecutionSettings().GetMode() != btpb.BehaviorTree_Node_ExecutionSettings_DISABLED
+}
+
+func (l *linter) checkReachability(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) {
+	switch node.NodeType.(type) {
+	case *btpb.BehaviorTree_Node_Sequence:
+		children := node.GetSequence().GetChildren()
+		for i, child := range children {
+			if !alwaysFails(child) {
+				continue
+			}
+			for j := i + 1; j < len(children); j++ {
+				l.addAt(lintSeverityWarning, lintCheckReachable, children[j].GetName(), fmt.Sprintf("%s.sequence.children[%d]", c.Path(), j),
+					"node is unreachable because the preceding node %q always fails", child.GetName())
+			}
+			break
+		}
+	case *btpb.BehaviorTree_Node_Branch:
+		branch := node.GetBranch()
+		if branch.GetIf() == nil {
+			l.add(lintSeverityError, lintCheckReachable, node, c, "branch has no condition")
+		}
+		if branch.GetThen() == nil && branch.GetElse() == nil {
+			l.add(lintSeverityWarning, lintCheckReachable, node, c, "branch has neither a then nor an else node")
+		}
+	}
+}
+
+func (l *linter) checkTask(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) {
+	call := node.GetTask().GetCallBehavior()
+	if call == nil {
+		return
+	}
+	for _, a := range call.GetAssignments() {
+		l.readCEL(a.GetCelExpression(), node, c)
+	}
+	if name := call.GetReturnValueName(); name != "" {
+		if other, ok := l.returnNames[name]; ok {
+			l.add(lintSeverityWarning, lintCheckReturnValue, node, c,
+				"return value key %q is also used by node %q at %s", name, other.node, other.path)
+		} else {
+			l.returnNames[name] = blackboardAccess{index: l.index, path: c.Path(), node: node.GetName()}
+		}
+		// The return value is written after the skill's parameters have been evaluated.
+		l.index++
+		l.write(name, node, c)
+	}
+
+	skill := l.checkSkill(node, call.GetSkillId(), c)
+	if skill == nil {
+		return
+	}
+	if call.GetReturnValueName() != "" && skill.GetReturnValueDescription().GetReturnValueMessageFullName() == "" {
+		l.add(lintSeverityWarning, lintCheckReturnValue, node, c,
+			"skill %q has no return value but its result is stored in %q", skill.GetId(), call.GetReturnValueName())
+	}
+	l.checkParameters(node, skill, c)
+	for slot := range call.GetResources() {
+		if _, ok := skill.GetResourceSelectors()[slot]; !ok {
+			l.add(lintSeverityError, lintCheckResources, node, c, "skill %q has no resource slot %q", skill.GetId(), slot)
+		}
+	}
+}
+
+// checkSkill returns the installed skill for the given skill id or id version, or nil if there is
+// no matching skill.
+func (l *linter) checkSkill(node *btpb.BehaviorTree_Node, skillID string, c *behaviortree.Cursor) *skillspb.Skill {
+	if skillID == "" {
+		l.add(lintSeverityError, lintCheckSkill, node, c, "task has no skill id")
+		return nil
+	}
+	if skill, ok := l.skillsByID[skillID]; ok {
+		return skill
+	}
+	if skill, ok := l.skillsByIDVersion[skillID]; ok {
+		return skill
+	}
+	if idutils.IsIDVersion(skillID) {
+		if id, err := idutils.RemoveVersionFrom(skillID); err == nil {
+			if skill, ok := l.skillsByID[id]; ok {
+				l.add(lintSeverityError, lintCheckSkill, node, c,
+					"skill %q is not installed, the installed version is %q", skillID, skill.GetIdVersion())
+				return nil
+			}
+		}
+	}
+	l.add(lintSeverityError, lintCheckSkill, node, c, "skill %q is not installed", skillID)
+	return nil
+}
+
+func (l *linter) parameterFilesFor(skill *skillspb.Skill) (protoDescriptorFinder, error) {
+	if files, ok := l.parameterFiles[skill.GetIdVersion()]; ok {
+		return files, nil
+	}
+	files, err := protodesc.NewFiles(skill.GetParameterDescription().GetParameterDescriptorFileset())
+	if err != nil {
+		return nil, err
+	}
+	l.parameterFiles[skill.GetIdVersion()] = files
+	return files, nil
+}
+
+func (l *linter) checkParameters(node *btpb.BehaviorTree_Node, skill *skillspb.Skill, c *behaviortree.Cursor) {
+	params := node.GetTask().GetCallBehavior().GetParameters()
+	want := protoreflect.FullName(skill.GetParameterDescription().GetParameterMessageFullName())
+	if params == nil {
+		return
+	}
+	if want == "" {
+		l.add(lintSeverityError, lintCheckParameters, node, c, "skill %q takes no parameters but got %q", skill.GetId(), params.MessageName())
+		return
+	}
+	if got := params.MessageName(); got != want {
+		l.add(lintSeverityError, lintCheckParameters, node, c, "skill %q expects parameters of type %q but got %q", skill.GetId(), want, got)
+		return
+	}
+
+	files, err := l.parameterFilesFor(skill)
+	if err != nil {
+		l.add(lintSeverityError, lintCheckParameters, node, c, "invalid parameter descriptors of skill %q: %v", skill.GetId(), err)
+		return
+	}
+	desc, err := files.FindDescriptorByName(want)
+	if err != nil {
+		l.add(lintSeverityError, lintCheckParameters, node, c, "parameter descriptors of skill %q do not contain %q", skill.GetId(), want)
+		return
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		l.add(lintSeverityError, lintCheckParameters, node, c, "%q in the parameter descriptors of skill %q is not a message", want, skill.GetId())
+		return
+	}
+	if err := proto.Unmarshal(params.GetValue(), dynamicpb.NewMessage(md)); err != nil {
+		l.add(lintSeverityError, lintCheckParameters, node, c, "parameters do not match the parameter descriptor of skill %q: %v", skill.GetId(), err)
+	}
+}
+
+// checkBlackboard reports blackboard keys which are read before any node writes them. Keys which
+// are never written by the tree are assumed to be provided externally and are not reported.
+func (l *linter) checkBlackboard() {
+	keys := make([]string, 0, len(l.reads))
+	for key := range l.reads {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		writes, ok := l.writes[key]
+		if !ok {
+			continue
+		}
+		firstWrite := writes[0]
+		for _, w := range writes[1:] {
+			if w.index < firstWrite.index {
+				firstWrite = w
+			}
+		}
+		for _, r := range l.reads[key] {
+			if r.index >= firstWrite.index {
+				continue
+			}
+			l.addAt(lintSeverityWarning, lintCheckBlackboard, r.node, r.path,
+				"blackboard key %q is read before it is written by node %q at %s", key, firstWrite.node, firstWrite.path)
+		}
+	}
+}
+
+// celIdentifiers returns the top-level identifiers referenced in the given CEL expression, i.e.,
+// identifiers which are neither field selections, function calls nor inside string literals.
+func celIdentifiers(expr string) []string {
+	var identifiers []string
+	runes := []rune(expr)
+	prev := ' '
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '"' || r == '\'':
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			i++
+			prev = r
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			ident := string(runes[start:i])
+			next := i
+			for next < len(runes) && unicode.IsSpace(runes[next]) {
+				next++
+			}
+			isCall := next < len(runes) && runes[next] == '('
+			if prev != '.' && !isCall && !celKeywords[ident] {
+				identifiers = append(identifiers, ident)
+			}
+			prev = 'a'
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			prev = '0'
+		case unicode.IsSpace(r):
+			i++
+		default:
+			prev = r
+			i++
+		}
+	}
+	return identifiers
+}
+
+var celKeywords = map[string]bool{
+	"true": true, "false": true, "null": true, "in": true,
+}
+
+var processLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check a process (behavior tree) for problems. ",
+	Long: `Check a process (behavior tree) against the skills installed in a solution.
+
+The linter reports tasks which call skills that are not installed or not
+installed in the requested version, parameters which do not match the skill's
+parameter descriptor, unknown resource slots, blackboard keys which are read
+before they are written, return value keys used by multiple tasks, duplicate
+node ids and unreachable nodes.
+
+The process is read from a file if --input_file is given:
+
+inctl process lint --solution my-solution --cluster my-cluster --input_file /tmp/my-process.textproto [--process_format textproto|binaryproto]
+
+Otherwise the active process of the executive is checked, or the process with
+the given name from the solution:
+
+inctl process lint [my_process] --solution my-solution --cluster my-cluster [--output json]
+
+The command fails if any errors are found. Warnings do not cause a failure.`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		if name != "" && flagInputFile != "" {
+			return fmt.Errorf("a process name and --input_file cannot be specified together")
+		}
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+
+		projectName := viperLocal.GetString(orgutil.KeyProject)
+		orgName := viperLocal.GetString(orgutil.KeyOrganization)
+		ctx, conn, err := connectToCluster(cmd.Context(), projectName,
+			orgName, flagServerAddress,
+			flagSolutionName, flagClusterName)
+		if err != nil {
+			return errors.Wrapf(err, "could not dial connection")
+		}
+		defer conn.Close()
+
+		srC := skillregistrygrpcpb.NewSkillRegistryClient(conn)
+		var bt *btpb.BehaviorTree
+		if flagInputFile != "" {
+			content, err := os.ReadFile(flagInputFile)
+			if err != nil {
+				return errors.Wrapf(err, "could not read input file")
+			}
+			if bt, err = deserializeBT(ctx, srC, flagProcessFormat, content); err != nil {
+				return errors.Wrapf(err, "could not deserialize BT")
+			}
+		} else {
+			bt, err = fetchBT(ctx, execgrpcpb.NewExecutiveServiceClient(conn), sgrpcpb.NewSolutionServiceClient(conn), name, flagOperation)
+			if err != nil {
+				return err
+			}
+		}
+
+		skills, err := getSkills(ctx, srC)
+		if err != nil {
+			return errors.Wrapf(err, "could not list skills")
+		}
+		report, err := lintBT(bt, skills)
+		if err != nil {
+			return err
+		}
+		prtr.Print(report)
+
+		if n := report.count(lintSeverityError); n > 0 {
+			return fmt.Errorf("found %d error(s) in the process", n)
+		}
+		return nil
+	},
+}
+
+func init() {
+	processLintCmd.Flags().StringVar(
+		&flagProcessFormat, "process_format", TextProtoFormat,
+		fmt.Sprintf("(optional) input format. One of: (%s)", strings.Join(allowedSetFormats, ", ")))
+	processLintCmd.Flags().StringVar(&flagSolutionName, "solution", "", "Solution to check the process against. For example, use `inctl solutions list --org orgname@projectname --output json [--filter running_in_sim]` to see the list of solutions.")
+	processLintCmd.Flags().StringVar(&flagClusterName, "cluster", "", "Cluster to check the process against.")
+	processLintCmd.Flags().StringVar(&flagInputFile, "input_file", "", "If set, reads the process from the given file instead of the solution.")
+	processCmd.AddCommand(processLintCmd)
+}
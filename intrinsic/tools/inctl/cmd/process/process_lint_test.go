@@ -0,0 +1,177 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"testing"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	bcpb "intrinsic/executive/proto/behavior_call_go_proto"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	skillspb "intrinsic/skills/proto/skills_go_proto"
+)
+
+func lintSkills() []*skillspb.Skill {
+	return []*skillspb.Skill{
+		{
+			Id:        "ai.intrinsic.say",
+			IdVersion: "ai.intrinsic.say.0.0.1",
+			ParameterDescription: &skillspb.ParameterDescription{
+				ParameterMessageFullName: "google.protobuf.StringValue",
+				ParameterDescriptorFileset: &descriptorpb.FileDescriptorSet{
+					File: []*descriptorpb.FileDescriptorProto{
+						protodesc.ToFileDescriptorProto(wrapperspb.File_google_protobuf_wrappers_proto),
+					},
+				},
+			},
+			ReturnValueDescription: &skillspb.ReturnValueDescription{
+				ReturnValueMessageFullName: "google.protobuf.StringValue",
+			},
+		},
+	}
+}
+
+func lintTask(t *testing.T, name string, skillID string, params proto.Message) *btpb.BehaviorTree_Node {
+	t.Helper()
+	call := &bcpb.BehaviorCall{SkillId: skillID}
+	if params != nil {
+		a, err := anypb.New(params)
+		if err != nil {
+			t.Fatalf("anypb.New(%v) failed: %v", params, err)
+		}
+		call.Parameters = a
+	}
+	return &btpb.BehaviorTree_Node{
+		Name: proto.String(name),
+		NodeType: &btpb.BehaviorTree_Node_Task{
+			Task: &btpb.BehaviorTree_TaskNode{
+				TaskType: &btpb.BehaviorTree_TaskNode_CallBehavior{CallBehavior: call},
+			},
+		},
+	}
+}
+
+func lintSequence(children ...*btpb.BehaviorTree_Node) *btpb.BehaviorTree {
+	return &btpb.BehaviorTree{
+		Root: &btpb.BehaviorTree_Node{
+			Name: proto.String("root"),
+			NodeType: &btpb.BehaviorTree_Node_Sequence{
+				Sequence: &btpb.BehaviorTree_SequenceNode{Children: children},
+			},
+		},
+	}
+}
+
+func TestLintBT(t *testing.T) {
+	withID := func(n *btpb.BehaviorTree_Node, id uint32) *btpb.BehaviorTree_Node {
+		n.Id = proto.Uint32(id)
+		return n
+	}
+	withReturnValue := func(n *btpb.BehaviorTree_Node, key string) *btpb.BehaviorTree_Node {
+		n.GetTask().GetCallBehavior().ReturnValueName = key
+		return n
+	}
+	withAssignment := func(n *btpb.BehaviorTree_Node, expr string) *btpb.BehaviorTree_Node {
+		call := n.GetTask().GetCallBehavior()
+		call.Assignments = append(call.Assignments, &bcpb.BehaviorCall_ParameterAssignment{
+			Target: &bcpb.BehaviorCall_ParameterAssignment_ParameterPath{ParameterPath: "value"},
+			Source: &bcpb.BehaviorCall_ParameterAssignment_CelExpression{CelExpression: expr},
+		})
+		return n
+	}
+
+	tests := []struct {
+		desc string
+		bt   *btpb.BehaviorTree
+		want []*lintFinding
+	}{
+		{
+			desc: "valid tree",
+			bt: lintSequence(
+				withReturnValue(lintTask(t, "a", "ai.intrinsic.say", wrapperspb.String("hello")), "result"),
+				withAssignment(lintTask(t, "b", "ai.intrinsic.say.0.0.1", nil), "result.value + 'x'"),
+			),
+			want: []*lintFinding{},
+		},
+		{
+			desc: "unknown skill and version",
+			bt: lintSequence(
+				lintTask(t, "a", "ai.intrinsic.unknown", nil),
+				lintTask(t, "b", "ai.intrinsic.say.0.0.2", nil),
+			),
+			want: []*lintFinding{
+				{Severity: lintSeverityError, Check: lintCheckSkill, Path: "root.sequence.children[0]", Node: "a", Message: `skill "ai.intrinsic.unknown" is not installed`},
+				{Severity: lintSeverityError, Check: lintCheckSkill, Path: "root.sequence.children[1]", Node: "b", Message: `skill "ai.intrinsic.say.0.0.2" is not installed, the installed version is "ai.intrinsic.say.0.0.1"`},
+			},
+		},
+		{
+			desc: "parameter type mismatch",
+			bt:   lintSequence(lintTask(t, "a", "ai.intrinsic.say", wrapperspb.Int32(1))),
+			want: []*lintFinding{
+				{Severity: lintSeverityError, Check: lintCheckParameters, Path: "root.sequence.children[0]", Node: "a", Message: `skill "ai.intrinsic.say" expects parameters of type "google.protobuf.StringValue" but got "google.protobuf.Int32Value"`},
+			},
+		},
+		{
+			desc: "blackboard key read before write",
+			bt: lintSequence(
+				withAssignment(lintTask(t, "a", "ai.intrinsic.say", nil), "size(result.value) > 0 && other"),
+				withReturnValue(lintTask(t, "b", "ai.intrinsic.say", nil), "result"),
+				withReturnValue(lintTask(t, "c", "ai.intrinsic.say", nil), "result"),
+			),
+			want: []*lintFinding{
+				{Severity: lintSeverityWarning, Check: lintCheckReturnValue, Path: "root.sequence.children[2]", Node: "c", Message: `return value key "result" is also used by node "b" at root.sequence.children[1]`},
+				{Severity: lintSeverityWarning, Check: lintCheckBlackboard, Path: "root.sequence.children[0]", Node: "a", Message: `blackboard key "result" is read before it is written by node "b" at root.sequence.children[1]`},
+			},
+		},
+		{
+			desc: "duplicate node ids and unreachable nodes",
+			bt: lintSequence(
+				withID(lintTask(t, "a", "ai.intrinsic.say", nil), 1),
+				withID(&btpb.BehaviorTree_Node{
+					Name:     proto.String("fail"),
+					NodeType: &btpb.BehaviorTree_Node_Fail{Fail: &btpb.BehaviorTree_FailNode{}},
+				}, 1),
+				lintTask(t, "b", "ai.intrinsic.say", nil),
+			),
+			want: []*lintFinding{
+				{Severity: lintSeverityWarning, Check: lintCheckReachable, Path: "root.sequence.children[2]", Node: "b", Message: `node is unreachable because the preceding node "fail" always fails`},
+				{Severity: lintSeverityError, Check: lintCheckNodeID, Path: "root.sequence.children[1]", Node: "fail", Message: "node id 1 is already used by the node at root.sequence.children[0]"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := lintBT(tc.bt, lintSkills())
+			if err != nil {
+				t.Fatalf("lintBT() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got.Findings); diff != "" {
+				t.Errorf("lintBT() returned unexpected findings (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCELIdentifiers(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{expr: "a.b.c > 1", want: []string{"a"}},
+		{expr: "size(x) == 2 && y", want: []string{"x", "y"}},
+		{expr: `"foo" + bar + 'baz'`, want: []string{"bar"}},
+		{expr: "true || null == z . field", want: []string{"z"}},
+		{expr: "1.5e3 > m[\"k\"]", want: []string{"m"}},
+	}
+	for _, tc := range tests {
+		if diff := cmp.Diff(tc.want, celIdentifiers(tc.expr)); diff != "" {
+			t.Errorf("celIdentifiers(%q) returned unexpected identifiers (-want +got):\n%s", tc.expr, diff)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	rmdpb "intrinsic/executive/proto/run_metadata_go_proto"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/orgutil"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+// operationDescription summarizes an executive operation.
+type operationDescription struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	TreeName string `json:"treeName,omitempty"`
+	TreeID   string `json:"treeId,omitempty"`
+	Done     bool   `json:"done"`
+}
+
+// listOperationsResponse is the result of listing the executive operations. It can be printed as
+// text or JSON.
+type listOperationsResponse struct {
+	Operations []*operationDescription `json:"operations"`
+}
+
+// String converts a listOperationsResponse to a string.
+func (r *listOperationsResponse) String() string {
+	if len(r.Operations) == 0 {
+		return "No operations found."
+	}
+
+	b := new(bytes.Buffer)
+	w := tabwriter.NewWriter(b,
+		/*minwidth=*/ 1 /*tabwidth=*/, 1 /*padding=*/, 1 /*padchar=*/, ' ' /*flags=*/, 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "Name", "State", "Tree Name", "Tree ID")
+	for _, op := range r.Operations {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", op.Name, op.State, op.TreeName, op.TreeID)
+	}
+	w.Flush()
+	// Remove the trailing newline as the pretty-printer wrapper will add one.
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func describeOperation(operation *lrpb.Operation) (*operationDescription, error) {
+	metadata := new(rmdpb.RunMetadata)
+	if err := operation.GetMetadata().UnmarshalTo(metadata); err != nil {
+		return nil, errors.Wrapf(err, "unable to unmarshal RunMetadata proto of operation %q", operation.GetName())
+	}
+
+	return &operationDescription{
+		Name:     operation.GetName(),
+		State:    metadata.GetBehaviorTreeState().String(),
+		TreeName: metadata.GetBehaviorTree().GetName(),
+		TreeID:   metadata.GetBehaviorTree().GetTreeId(),
+		Done:     operation.GetDone(),
+	}, nil
+}
+
+func listProcesses(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient) (*listOperationsResponse, error) {
+	operations, err := listOperations(ctx, exC)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &listOperationsResponse{Operations: []*operationDescription{}}
+	for _, operation := range operations {
+		desc, err := describeOperation(operation)
+		if err != nil {
+			return nil, err
+		}
+		resp.Operations = append(resp.Operations, desc)
+	}
+	return resp, nil
+}
+
+var processListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the operations of the executive. ",
+	Long: `List all operations of the executive together with their state and the name
+of their process (behavior tree).
+
+The operation names can be passed to other process commands with --operation.
+
+inctl process list --solution my-solution --cluster my-cluster [--output json]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+
+		projectName := viperLocal.GetString(orgutil.KeyProject)
+		orgName := viperLocal.GetString(orgutil.KeyOrganization)
+		ctx, conn, err := connectToCluster(cmd.Context(), projectName,
+			orgName, flagServerAddress,
+			flagSolutionName, flagClusterName)
+		if err != nil {
+			return errors.Wrapf(err, "could not dial connection")
+		}
+		defer conn.Close()
+
+		resp, err := listProcesses(ctx, execgrpcpb.NewExecutiveServiceClient(conn))
+		if err != nil {
+			return errors.Wrapf(err, "could not list operations")
+		}
+		prtr.Print(resp)
+		return nil
+	},
+}
+
+func init() {
+	processListCmd.Flags().StringVar(&flagSolutionName, "solution", "", "Solution to list the operations of. For example, use `inctl solutions list --org orgname@projectname --output json [--filter running_in_sim]` to see the list of solutions.")
+	processListCmd.Flags().StringVar(&flagClusterName, "cluster", "", "Cluster to list the operations of.")
+	processCmd.AddCommand(processListCmd)
+}
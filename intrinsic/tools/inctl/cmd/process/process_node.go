@@ -0,0 +1,110 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	exsvcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+)
+
+var (
+	flagDisabledResult string
+)
+
+var disabledResultStates = map[string]btpb.BehaviorTree_Node_ExecutionSettings_DisabledResultState{
+	"succeeded": btpb.BehaviorTree_Node_ExecutionSettings_SUCCEEDED,
+	"failed":    btpb.BehaviorTree_Node_ExecutionSettings_FAILED,
+}
+
+// setNodeExecutionSettings applies the given execution settings to the node selected by args or
+// by the --tree_id and --node_id flags.
+func setNodeExecutionSettings(ctx context.Context, cmd *cobra.Command, exC execgrpcpb.ExecutiveServiceClient, operationName string, args []string, settings *btpb.BehaviorTree_Node_ExecutionSettings) (nodeRef, error) {
+	ref, err := resolveNodeRef(ctx, cmd, exC, operationName, args)
+	if err != nil {
+		return nodeRef{}, err
+	}
+	if _, err := exC.SetNodeExecutionSettings(ctx, &exsvcpb.SetNodeExecutionSettingsRequest{
+		Name:              operationName,
+		TreeId:            ref.treeID,
+		NodeId:            ref.nodeID,
+		ExecutionSettings: settings,
+	}); err != nil {
+		return nodeRef{}, errors.Wrapf(err, "unable to set execution settings of node %d of tree %q", ref.nodeID, ref.treeID)
+	}
+	return ref, nil
+}
+
+var processNodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Manages the execution settings of the nodes of an operation.",
+	Long: `Manages the execution settings of the nodes of an operation.
+
+Nodes are addressed either by their name path, i.e., the names of all nodes from the root to the
+node separated by "/" (for example "root/pick/grasp"), by their name alone if it is unique, or by
+--tree_id and --node_id.`,
+}
+
+var processNodeDisableCmd = &cobra.Command{
+	Use:   "disable [<node_name_path>]",
+	Short: "Disable the execution of a node.",
+	Long: `Disable the execution of a node. A disabled node is not executed and immediately
+finishes with the state given by --result.
+
+inctl process node disable root/pick --solution my-solution --cluster my-cluster [--result succeeded|failed]`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := parseChoice("result", flagDisabledResult, disabledResultStates)
+		if err != nil {
+			return err
+		}
+		return withOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			ref, err := setNodeExecutionSettings(ctx, cmd, exC, operationName, args, &btpb.BehaviorTree_Node_ExecutionSettings{
+				Mode:                btpb.BehaviorTree_Node_ExecutionSettings_DISABLED,
+				DisabledResultState: result.Enum(),
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Disabled node %d of tree %q.\n", ref.nodeID, ref.treeID)
+			return nil
+		})
+	},
+}
+
+var processNodeEnableCmd = &cobra.Command{
+	Use:   "enable [<node_name_path>]",
+	Short: "Enable the execution of a disabled node.",
+	Long: `Enable the execution of a node which has been disabled.
+
+inctl process node enable root/pick --solution my-solution --cluster my-cluster`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			ref, err := setNodeExecutionSettings(ctx, cmd, exC, operationName, args, &btpb.BehaviorTree_Node_ExecutionSettings{
+				Mode: btpb.BehaviorTree_Node_ExecutionSettings_NORMAL,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Enabled node %d of tree %q.\n", ref.nodeID, ref.treeID)
+			return nil
+		})
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{processNodeDisableCmd, processNodeEnableCmd} {
+		addTargetFlags(cmd)
+		addNodeRefFlags(cmd)
+		processNodeCmd.AddCommand(cmd)
+	}
+	processNodeDisableCmd.Flags().StringVar(&flagDisabledResult, "result", "succeeded", "State the disabled node finishes with, one of: succeeded, failed.")
+
+	processCmd.AddCommand(processNodeCmd)
+}
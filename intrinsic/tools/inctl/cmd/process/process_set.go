@@ -27,6 +27,8 @@ import (
 
 var allowedSetFormats = []string{TextProtoFormat, BinaryProtoFormat}
 
+var flagAddOperation bool
+
 // resolverToEmpty is a dummy implementation of prototext.UnmarshalOptions.Resolver that always
 // returns the Empty message type for any type name or type URL.
 type resolverToEmpty struct {
@@ -149,6 +151,8 @@ type setProcessParams struct {
 	srC          skillregistrygrpcpb.SkillRegistryClient
 	soC          sgrpcpb.SolutionServiceClient
 	name         string
+	operation    string
+	addOperation bool
 	format       string
 	content      []byte
 	clearTreeID  bool
@@ -173,28 +177,31 @@ func deserializeBT(ctx context.Context, srC skillregistrygrpcpb.SkillRegistryCli
 	return bt, nil
 }
 
-func setProcess(ctx context.Context, params *setProcessParams) error {
+// setProcess sets the process and returns the name of the created executive operation, if any.
+func setProcess(ctx context.Context, params *setProcessParams) (string, error) {
 	bt, err := deserializeBT(ctx, params.srC, params.format, params.content)
 	if err != nil {
-		return errors.Wrapf(err, "could not deserialize BT")
+		return "", errors.Wrapf(err, "could not deserialize BT")
 	}
 
-	clearTree(bt, params.clearTreeID, params.clearNodeIDs)
+	behaviortree.ClearOutputOnlyFields(bt, params.clearTreeID, params.clearNodeIDs)
 
 	if params.name == "" {
-		if err := setBT(ctx, params.exC, bt); err != nil {
-			return errors.Wrapf(err, "could not set active behavior tree")
-		}
-	} else {
-		if _, err := params.soC.CreateBehaviorTree(ctx, &spb.CreateBehaviorTreeRequest{
-			BehaviorTreeId: params.name,
-			BehaviorTree:   bt,
-		}); err != nil {
-			return errors.Wrapf(err, "could not create behavior tree in the solution")
+		operationName, err := setBT(ctx, params.exC, bt, params.operation, params.addOperation)
+		if err != nil {
+			return "", errors.Wrapf(err, "could not set active behavior tree")
 		}
+		return operationName, nil
+	}
+
+	if _, err := params.soC.CreateBehaviorTree(ctx, &spb.CreateBehaviorTreeRequest{
+		BehaviorTreeId: params.name,
+		BehaviorTree:   bt,
+	}); err != nil {
+		return "", errors.Wrapf(err, "could not create behavior tree in the solution")
 	}
 
-	return nil
+	return "", nil
 }
 
 var processSetCmd = &cobra.Command{
@@ -206,7 +213,11 @@ There are two main operation modes. The first one is to set the "active" process
 in the executive. This prepares the process for execution. This is the default
 behavior if no name is provided as the first argument.
 
-inctl process set --solution my-solution --cluster my-cluster --input_file /tmp/my-process.textproto [--process_format textproto|binaryproto]
+The executive operation is replaced by a new one. If the executive has more than
+one operation, select the operation to replace with --operation. Use
+--add_operation to create a new operation and keep all existing ones.
+
+inctl process set --solution my-solution --cluster my-cluster --input_file /tmp/my-process.textproto [--operation my-operation|--add_operation] [--process_format textproto|binaryproto]
 
 ---
 
@@ -226,6 +237,9 @@ inctl process set name_to_store_with --solution my-solution --cluster my-cluster
 		if flagInputFile == "" {
 			return fmt.Errorf("--input_file must be specified")
 		}
+		if flagAddOperation && flagOperation != "" {
+			return fmt.Errorf("--operation and --add_operation cannot be specified together")
+		}
 
 		name := ""
 		if len(args) == 1 {
@@ -246,21 +260,24 @@ inctl process set name_to_store_with --solution my-solution --cluster my-cluster
 			return errors.Wrapf(err, "could not read input file")
 		}
 
-		if err = setProcess(ctx, &setProcessParams{
+		operationName, err := setProcess(ctx, &setProcessParams{
 			exC:          execgrpcpb.NewExecutiveServiceClient(conn),
 			srC:          skillregistrygrpcpb.NewSkillRegistryClient(conn),
 			soC:          sgrpcpb.NewSolutionServiceClient(conn),
 			content:      content,
 			name:         name,
+			operation:    flagOperation,
+			addOperation: flagAddOperation,
 			format:       flagProcessFormat,
 			clearTreeID:  flagClearTreeID,
 			clearNodeIDs: flagClearNodeIDs,
-		}); err != nil {
+		})
+		if err != nil {
 			return errors.Wrapf(err, "could not set BT")
 		}
 
 		if name == "" {
-			fmt.Println("BT loaded successfully to the executive. To edit behavior tree in the frontend: Process -> Load -> From executive")
+			fmt.Printf("BT loaded successfully to the executive as operation %q. To edit behavior tree in the frontend: Process -> Load -> From executive\n", operationName)
 		} else {
 			fmt.Println("BT added to the solution. To edit and execute the process in the frontend: Process -> Load -> <process name>")
 		}
@@ -276,6 +293,7 @@ func init() {
 	processSetCmd.Flags().StringVar(&flagSolutionName, "solution", "", "Solution to set the process on. For example, use `inctl solutions list --org orgname@projectname --output json [--filter running_in_sim]` to see the list of solutions.")
 	processSetCmd.Flags().StringVar(&flagClusterName, "cluster", "", "Cluster to set the process on.")
 	processSetCmd.Flags().StringVar(&flagInputFile, "input_file", "", "File from which to read the process.")
+	processSetCmd.Flags().BoolVar(&flagAddOperation, "add_operation", false, "If set, creates a new executive operation without deleting any existing operation.")
 	processCmd.AddCommand(processSetCmd)
 
 }
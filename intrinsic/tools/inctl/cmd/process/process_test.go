@@ -0,0 +1,183 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	exsvcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	rmdpb "intrinsic/executive/proto/run_metadata_go_proto"
+)
+
+// fakeExecutiveClient keeps operations in memory. Only the methods used by the process commands
+// are implemented.
+type fakeExecutiveClient struct {
+	execgrpcpb.ExecutiveServiceClient
+	operations []*lrpb.Operation
+	nextID     int
+}
+
+func newFakeExecutiveClient(t *testing.T, trees ...*btpb.BehaviorTree) *fakeExecutiveClient {
+	t.Helper()
+	c := &fakeExecutiveClient{}
+	for _, tree := range trees {
+		if _, err := c.CreateOperation(context.Background(), &exsvcpb.CreateOperationRequest{
+			RunnableType: &exsvcpb.CreateOperationRequest_BehaviorTree{BehaviorTree: tree},
+		}); err != nil {
+			t.Fatalf("CreateOperation() failed: %v", err)
+		}
+	}
+	return c
+}
+
+func (c *fakeExecutiveClient) names() []string {
+	var names []string
+	for _, op := range c.operations {
+		names = append(names, op.GetName())
+	}
+	return names
+}
+
+func (c *fakeExecutiveClient) CreateOperation(ctx context.Context, req *exsvcpb.CreateOperationRequest, opts ...grpc.CallOption) (*lrpb.Operation, error) {
+	metadata, err := anypb.New(&rmdpb.RunMetadata{
+		RunnableType: &rmdpb.RunMetadata_BehaviorTree{BehaviorTree: req.GetBehaviorTree()},
+		StateType:    &rmdpb.RunMetadata_BehaviorTreeState{BehaviorTreeState: btpb.BehaviorTree_ACCEPTED},
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.nextID++
+	op := &lrpb.Operation{Name: fmt.Sprintf("op%d", c.nextID), Metadata: metadata}
+	c.operations = append(c.operations, op)
+	return op, nil
+}
+
+func (c *fakeExecutiveClient) ListOperations(ctx context.Context, req *lrpb.ListOperationsRequest, opts ...grpc.CallOption) (*lrpb.ListOperationsResponse, error) {
+	// Return one operation per page to exercise pagination.
+	start := 0
+	if req.GetPageToken() != "" {
+		fmt.Sscanf(req.GetPageToken(), "%d", &start)
+	}
+	resp := &lrpb.ListOperationsResponse{}
+	if start < len(c.operations) {
+		resp.Operations = c.operations[start : start+1]
+	}
+	if start+1 < len(c.operations) {
+		resp.NextPageToken = fmt.Sprintf("%d", start+1)
+	}
+	return resp, nil
+}
+
+func (c *fakeExecutiveClient) GetOperation(ctx context.Context, req *lrpb.GetOperationRequest, opts ...grpc.CallOption) (*lrpb.Operation, error) {
+	for _, op := range c.operations {
+		if op.GetName() == req.GetName() {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("operation %q not found", req.GetName())
+}
+
+func (c *fakeExecutiveClient) DeleteOperation(ctx context.Context, req *lrpb.DeleteOperationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	for i, op := range c.operations {
+		if op.GetName() == req.GetName() {
+			c.operations = append(c.operations[:i], c.operations[i+1:]...)
+			return &emptypb.Empty{}, nil
+		}
+	}
+	return nil, fmt.Errorf("operation %q not found", req.GetName())
+}
+
+func TestGetActiveBT(t *testing.T) {
+	ctx := context.Background()
+	exC := newFakeExecutiveClient(t, &btpb.BehaviorTree{Name: "a"}, &btpb.BehaviorTree{Name: "b"})
+
+	// like "process get" always did, the first of several operations is used.
+	bt, err := getActiveBT(ctx, exC, "")
+	if err != nil {
+		t.Fatalf("getActiveBT() with two operations failed: %v", err)
+	}
+	if bt.GetName() != "a" {
+		t.Errorf("getActiveBT() returned tree %q, want %q", bt.GetName(), "a")
+	}
+	// commands which change operations need to select one.
+	if _, err := getOperation(ctx, exC, ""); err == nil {
+		t.Errorf("getOperation() with two operations and no operation name succeeded, want error")
+	}
+
+	bt, err = getActiveBT(ctx, exC, "op2")
+	if err != nil {
+		t.Fatalf("getActiveBT(op2) failed: %v", err)
+	}
+	if bt.GetName() != "b" {
+		t.Errorf("getActiveBT(op2) returned tree %q, want %q", bt.GetName(), "b")
+	}
+
+	if _, err := getActiveBT(ctx, newFakeExecutiveClient(t), ""); err == nil {
+		t.Errorf("getActiveBT() without operations succeeded, want error")
+	}
+}
+
+func TestSetBT(t *testing.T) {
+	ctx := context.Background()
+	tree := &btpb.BehaviorTree{Name: "new"}
+
+	tests := []struct {
+		desc           string
+		existing       int
+		operationName  string
+		keepOperations bool
+		want           []string
+		wantErr        bool
+	}{
+		{desc: "no operation", existing: 0, want: []string{"op1"}},
+		{desc: "replace single operation", existing: 1, want: []string{"op2"}},
+		{desc: "ambiguous", existing: 2, wantErr: true, want: []string{"op1", "op2"}},
+		{desc: "replace selected operation", existing: 2, operationName: "op1", want: []string{"op2", "op3"}},
+		{desc: "add operation", existing: 2, keepOperations: true, want: []string{"op1", "op2", "op3"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var existing []*btpb.BehaviorTree
+			for i := 0; i < tc.existing; i++ {
+				existing = append(existing, &btpb.BehaviorTree{Name: fmt.Sprintf("old%d", i)})
+			}
+			exC := newFakeExecutiveClient(t, existing...)
+
+			_, err := setBT(ctx, exC, tree, tc.operationName, tc.keepOperations)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("setBT() returned error %v, want error: %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, exC.names()); diff != "" {
+				t.Errorf("setBT() resulted in unexpected operations (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListProcesses(t *testing.T) {
+	exC := newFakeExecutiveClient(t, &btpb.BehaviorTree{Name: "a"}, &btpb.BehaviorTree{Name: "b", TreeId: proto.String("b_id")})
+
+	got, err := listProcesses(context.Background(), exC)
+	if err != nil {
+		t.Fatalf("listProcesses() failed: %v", err)
+	}
+	want := &listOperationsResponse{
+		Operations: []*operationDescription{
+			{Name: "op1", State: "ACCEPTED", TreeName: "a"},
+			{Name: "op2", State: "ACCEPTED", TreeName: "b", TreeID: "b_id"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("listProcesses() returned unexpected result (-want +got):\n%s", diff)
+	}
+}
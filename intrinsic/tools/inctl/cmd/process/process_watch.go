@@ -0,0 +1,315 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"intrinsic/executive/go/behaviortree"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	rmdpb "intrinsic/executive/proto/run_metadata_go_proto"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/color"
+	"intrinsic/tools/inctl/util/printer"
+	"intrinsic/util/status/extstatus"
+)
+
+var (
+	flagWatchInterval time.Duration
+)
+
+// nodeState is the execution state of a single node of a behavior tree.
+type nodeState struct {
+	Path   string `json:"path"`
+	Name   string `json:"name,omitempty"`
+	TreeID string `json:"treeId,omitempty"`
+	NodeID uint32 `json:"nodeId,omitempty"`
+	State  string `json:"state"`
+	depth  int
+}
+
+func (n *nodeState) hasState() bool {
+	return n.State != btpb.BehaviorTree_Node_UNSPECIFIED.String()
+}
+
+// collectNodeStates returns the states of all nodes of the given tree in depth-first order. Nodes
+// in conditions are skipped.
+func collectNodeStates(bt *btpb.BehaviorTree) ([]*nodeState, error) {
+	var states []*nodeState
+	w := &behaviortree.Walker{
+		PreNode: func(node *btpb.BehaviorTree_Node, c *behaviortree.Cursor) error {
+			states = append(states, &nodeState{
+				Path:   c.Path(),
+				Name:   node.GetName(),
+				TreeID: c.Tree.GetTreeId(),
+				NodeID: node.GetId(),
+				State:  node.GetState().String(),
+				depth:  c.Depth(),
+			})
+			return nil
+		},
+		PreCondition: func(*btpb.BehaviorTree_Condition, *behaviortree.Cursor) error {
+			return behaviortree.SkipChildren
+		},
+	}
+	if err := w.Walk(bt); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// watchEvent is a change of the state of an operation's behavior tree or of one of its nodes.
+type watchEvent struct {
+	Time      time.Time  `json:"time"`
+	Operation string     `json:"operation"`
+	TreeState string     `json:"treeState,omitempty"`
+	Node      *nodeState `json:"node,omitempty"`
+	OldState  string     `json:"oldState,omitempty"`
+}
+
+// String converts a watchEvent to a single line of text.
+func (e *watchEvent) String() string {
+	prefix := fmt.Sprintf("%s %s", e.Time.Format("15:04:05.000"), e.Operation)
+	if e.Node == nil {
+		return fmt.Sprintf("%s: tree %s", prefix, e.TreeState)
+	}
+	name := e.Node.Path
+	if e.Node.Name != "" {
+		name = fmt.Sprintf("%q (%s)", e.Node.Name, e.Node.Path)
+	}
+	if e.OldState == "" {
+		return fmt.Sprintf("%s: node %s %s", prefix, name, e.Node.State)
+	}
+	return fmt.Sprintf("%s: node %s %s -> %s", prefix, name, e.OldState, e.Node.State)
+}
+
+// operationSnapshot is the state of an operation at one point in time.
+type operationSnapshot struct {
+	operation *lrpb.Operation
+	metadata  *rmdpb.RunMetadata
+	nodes     []*nodeState
+}
+
+func newOperationSnapshot(operation *lrpb.Operation) (*operationSnapshot, error) {
+	metadata := new(rmdpb.RunMetadata)
+	if err := operation.GetMetadata().UnmarshalTo(metadata); err != nil {
+		return nil, errors.Wrapf(err, "unable to unmarshal RunMetadata proto of operation %q", operation.GetName())
+	}
+	nodes, err := collectNodeStates(metadata.GetBehaviorTree())
+	if err != nil {
+		return nil, err
+	}
+	return &operationSnapshot{operation: operation, metadata: metadata, nodes: nodes}, nil
+}
+
+// finished returns true if the operation will not change its state anymore.
+func (s *operationSnapshot) finished() bool {
+	switch s.metadata.GetBehaviorTreeState() {
+	case btpb.BehaviorTree_SUCCEEDED, btpb.BehaviorTree_FAILED, btpb.BehaviorTree_CANCELED:
+		return true
+	}
+	return s.operation.GetDone()
+}
+
+// diffSnapshots returns the events which lead from prev to next. All states of next are reported
+// if prev is nil.
+func diffSnapshots(prev, next *operationSnapshot, now time.Time) []*watchEvent {
+	var events []*watchEvent
+	treeState := next.metadata.GetBehaviorTreeState()
+	if prev == nil || prev.metadata.GetBehaviorTreeState() != treeState {
+		events = append(events, &watchEvent{Time: now, Operation: next.operation.GetName(), TreeState: treeState.String()})
+	}
+
+	oldStates := map[string]string{}
+	if prev != nil {
+		for _, n := range prev.nodes {
+			oldStates[n.Path] = n.State
+		}
+	}
+	for _, n := range next.nodes {
+		oldState, ok := oldStates[n.Path]
+		if ok && oldState == n.State {
+			continue
+		}
+		if prev == nil && !n.hasState() {
+			continue
+		}
+		events = append(events, &watchEvent{Time: now, Operation: next.operation.GetName(), Node: n, OldState: oldState})
+	}
+	return events
+}
+
+// stateColor returns the color used to render a (tree or node) state.
+func stateColor(state string) func(w io.Writer, format string, a ...any) {
+	c := color.C.Default()
+	switch state {
+	case "RUNNING", "EVALUATING_CONDITION", "SELECTED", "READY":
+		c = color.C.Yellow()
+	case "SUCCEEDED":
+		c = color.C.Green()
+	case "FAILED":
+		c = color.C.Red()
+	case "SUSPENDING", "SUSPENDED", "CANCELING", "CANCELED":
+		c = color.C.Magenta()
+	case "UNSPECIFIED", "ACCEPTED":
+		c = color.C.LightGray()
+	}
+	return func(w io.Writer, format string, a ...any) {
+		c.Fprintf(w, format, a...)
+	}
+}
+
+// renderSnapshot renders the state of the operation as an indented tree.
+func renderSnapshot(s *operationSnapshot, w io.Writer) {
+	treeState := s.metadata.GetBehaviorTreeState().String()
+	fmt.Fprintf(w, "Operation %s (%s): ", s.operation.GetName(), s.metadata.GetBehaviorTree().GetName())
+	stateColor(treeState)(w, "%s", treeState)
+	fmt.Fprintln(w)
+	for _, n := range s.nodes {
+		name := n.Name
+		if name == "" {
+			name = n.Path
+		}
+		fmt.Fprintf(w, "%s%s ", strings.Repeat("  ", n.depth+1), name)
+		stateColor(n.State)(w, "[%s]", n.State)
+		fmt.Fprintln(w)
+	}
+}
+
+// operationResult returns an error if the finished operation did not succeed. The error carries
+// the operation's diagnostics as extended status, which inctl renders when printing the error.
+func operationResult(s *operationSnapshot) error {
+	name := s.operation.GetName()
+	state := s.metadata.GetBehaviorTreeState()
+	if state == btpb.BehaviorTree_SUCCEEDED && s.operation.GetError() == nil {
+		return nil
+	}
+
+	es := s.metadata.GetDiagnostics()
+	if es == nil && s.operation.GetError() != nil {
+		if fromError, ok := extstatus.FromGRPCStatusProto(s.operation.GetError()); ok {
+			es = fromError.Proto()
+		}
+	}
+	switch {
+	case es != nil:
+		return errors.Wrapf(extstatus.FromProto(es).Err(), "operation %q finished in state %s", name, state)
+	case s.operation.GetError() != nil:
+		return fmt.Errorf("operation %q finished in state %s: %s", name, state, s.operation.GetError().GetMessage())
+	}
+	return fmt.Errorf("operation %q finished in state %s", name, state)
+}
+
+// watchOperation polls the given operation until it is finished and passes every new snapshot and
+// the events since the previous one to onUpdate. It returns the last snapshot.
+func watchOperation(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string, interval time.Duration, onUpdate func(*operationSnapshot, []*watchEvent)) (*operationSnapshot, error) {
+	var prev *operationSnapshot
+	for {
+		operation, err := exC.GetOperation(ctx, &lrpb.GetOperationRequest{Name: operationName})
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get executive operation %q", operationName)
+		}
+		next, err := newOperationSnapshot(operation)
+		if err != nil {
+			return nil, err
+		}
+		if events := diffSnapshots(prev, next, time.Now()); len(events) > 0 {
+			onUpdate(next, events)
+		}
+		if next.finished() {
+			return next, nil
+		}
+		prev = next
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// watch watches the given operation until it is finished and prints its progress. On a terminal
+// and with text output, the whole tree is redrawn on every change; otherwise one line (or JSON
+// object) is printed per state change.
+func watch(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+	prtr, err := printer.NewPrinter(root.FlagOutput)
+	if err != nil {
+		return err
+	}
+
+	live := root.FlagOutput == printer.TextOutputFormat && isTerminal(os.Stdout)
+	last, err := watchOperation(ctx, exC, operationName, flagWatchInterval, func(s *operationSnapshot, events []*watchEvent) {
+		if live {
+			// Move the cursor home and clear the screen before redrawing the tree.
+			fmt.Fprint(os.Stdout, "\x1b[H\x1b[2J")
+			renderSnapshot(s, os.Stdout)
+			return
+		}
+		for _, e := range events {
+			prtr.Print(e)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return operationResult(last)
+}
+
+var processWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch the execution of an operation.",
+	Long: `Watch the execution of an operation until it has finished. On a terminal, the
+behavior tree is shown with the live state of every node. Otherwise, or with --output json, every
+state change is printed on a separate line.
+
+The command fails if the operation does not succeed and prints its extended status.
+
+inctl process watch --solution my-solution --cluster my-cluster [--operation op] [--output json]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return withReadOperation(cmd, watch)
+	},
+}
+
+var processRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Start an operation and watch it until it has finished.",
+	Long: `Start an operation and watch it until it has finished, see "inctl process start"
+and "inctl process watch". The command fails if the operation does not succeed, so it can be used
+to run processes headlessly, e.g., in continuous integration.
+
+inctl process run --solution my-solution --cluster my-cluster [--operation op] [--simulation_mode draft] [--output json]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return withOperation(cmd, func(ctx context.Context, exC execgrpcpb.ExecutiveServiceClient, operationName string) error {
+			if err := startOperation(ctx, exC, operationName); err != nil {
+				return err
+			}
+			return watch(ctx, exC, operationName)
+		})
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{processWatchCmd, processRunCmd} {
+		addTargetFlags(cmd)
+		cmd.Flags().DurationVar(&flagWatchInterval, "interval", 500*time.Millisecond, "Interval at which the operation is polled.")
+		processCmd.AddCommand(cmd)
+	}
+	addStartFlags(processRunCmd)
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	rmdpb "intrinsic/executive/proto/run_metadata_go_proto"
+	"intrinsic/tools/inctl/util/statusrender"
+	espb "intrinsic/util/status/extended_status_go_proto"
+)
+
+// scriptedExecutiveClient returns the given operations, one per call to GetOperation, and repeats
+// the last one afterwards.
+type scriptedExecutiveClient struct {
+	execgrpcpb.ExecutiveServiceClient
+	operations []*lrpb.Operation
+	calls      int
+}
+
+func (c *scriptedExecutiveClient) GetOperation(ctx context.Context, req *lrpb.GetOperationRequest, opts ...grpc.CallOption) (*lrpb.Operation, error) {
+	op := c.operations[min(c.calls, len(c.operations)-1)]
+	c.calls++
+	if op.GetName() != req.GetName() {
+		return nil, fmt.Errorf("operation %q not found", req.GetName())
+	}
+	return op, nil
+}
+
+func watchTestOperation(t *testing.T, treeState btpb.BehaviorTree_State, nodeStates ...btpb.BehaviorTree_Node_State) *lrpb.Operation {
+	t.Helper()
+	bt := lintSequence(lintTask(t, "a", "ai.intrinsic.say", nil), lintTask(t, "b", "ai.intrinsic.say", nil))
+	bt.Name = "tree"
+	for i, state := range nodeStates {
+		bt.GetRoot().GetSequence().GetChildren()[i].State = state.Enum()
+	}
+	metadata, err := anypb.New(&rmdpb.RunMetadata{
+		RunnableType: &rmdpb.RunMetadata_BehaviorTree{BehaviorTree: bt},
+		StateType:    &rmdpb.RunMetadata_BehaviorTreeState{BehaviorTreeState: treeState},
+	})
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	return &lrpb.Operation{Name: "op1", Metadata: metadata}
+}
+
+func TestWatchOperation(t *testing.T) {
+	exC := &scriptedExecutiveClient{
+		operations: []*lrpb.Operation{
+			watchTestOperation(t, btpb.BehaviorTree_ACCEPTED),
+			watchTestOperation(t, btpb.BehaviorTree_RUNNING, btpb.BehaviorTree_Node_RUNNING),
+			watchTestOperation(t, btpb.BehaviorTree_RUNNING, btpb.BehaviorTree_Node_RUNNING),
+			watchTestOperation(t, btpb.BehaviorTree_SUCCEEDED, btpb.BehaviorTree_Node_SUCCEEDED, btpb.BehaviorTree_Node_SUCCEEDED),
+		},
+	}
+
+	var got []string
+	last, err := watchOperation(context.Background(), exC, "op1", time.Millisecond, func(s *operationSnapshot, events []*watchEvent) {
+		for _, e := range events {
+			// Strip the time stamp.
+			got = append(got, strings.SplitN(e.String(), " ", 2)[1])
+		}
+	})
+	if err != nil {
+		t.Fatalf("watchOperation() failed: %v", err)
+	}
+
+	want := []string{
+		"op1: tree ACCEPTED",
+		"op1: tree RUNNING",
+		`op1: node "a" (root.sequence.children[0]) UNSPECIFIED -> RUNNING`,
+		"op1: tree SUCCEEDED",
+		`op1: node "a" (root.sequence.children[0]) RUNNING -> SUCCEEDED`,
+		`op1: node "b" (root.sequence.children[1]) UNSPECIFIED -> SUCCEEDED`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("watchOperation() reported unexpected events (-want +got):\n%s", diff)
+	}
+	if exC.calls != 4 {
+		t.Errorf("watchOperation() called GetOperation %d times, want 4", exC.calls)
+	}
+	if err := operationResult(last); err != nil {
+		t.Errorf("operationResult() = %v, want nil", err)
+	}
+}
+
+func TestOperationResult(t *testing.T) {
+	failed := watchTestOperation(t, btpb.BehaviorTree_FAILED)
+	metadata := &rmdpb.RunMetadata{}
+	if err := failed.GetMetadata().UnmarshalTo(metadata); err != nil {
+		t.Fatalf("UnmarshalTo() failed: %v", err)
+	}
+	metadata.Diagnostics = &espb.ExtendedStatus{
+		StatusCode: &espb.StatusCode{Component: "ai.intrinsic.executive", Code: 3},
+		Title:      "Process failed",
+		UserReport: &espb.ExtendedStatus_UserReport{Message: "Skill failed", Instructions: "Check the skill"},
+		Context: []*espb.ExtendedStatus{
+			{
+				StatusCode: &espb.StatusCode{Component: "ai.intrinsic.say", Code: 7},
+				Title:      "Nothing to say",
+			},
+		},
+	}
+	packed, err := anypb.New(metadata)
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	failed.Metadata = packed
+
+	s, err := newOperationSnapshot(failed)
+	if err != nil {
+		t.Fatalf("newOperationSnapshot() failed: %v", err)
+	}
+	got := operationResult(s)
+	if got == nil {
+		t.Fatalf("operationResult() = nil, want error")
+	}
+	want := `operation "op1" finished in state FAILED: ai.intrinsic.executive:3: Process failed`
+	if diff := cmp.Diff(want, got.Error()); diff != "" {
+		t.Errorf("operationResult() returned unexpected error (-want +got):\n%s", diff)
+	}
+	es, ok := statusrender.FromError(got)
+	if !ok {
+		t.Fatalf("statusrender.FromError(%v) found no extended status", got)
+	}
+	if diff := cmp.Diff(metadata.GetDiagnostics(), es, protocmp.Transform()); diff != "" {
+		t.Errorf("operationResult() returned unexpected extended status (-want +got):\n%s", diff)
+	}
+}
@@ -3,10 +3,12 @@
 package recordings
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
 	pb "intrinsic/logging/proto/bag_packager_service_go_grpc_proto"
 	"intrinsic/tools/inctl/util/orgutil"
 )
@@ -30,11 +32,35 @@ var generateRecordingE = func(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	fmt.Println(fmt.Sprintf("Generated ROS bag for ID %s", resp.GetBag().GetBagMetadata().GetBagId()))
+	prtr, err := commandPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	prtr.Print(&generateBagResponse{m: resp})
 
 	return nil
 }
 
+// generateBagResponse embeds pb.GenerateBagResponse.
+type generateBagResponse struct {
+	m *pb.GenerateBagResponse
+}
+
+// MarshalJSON converts a generateBagResponse to a byte slice.
+func (res *generateBagResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bagFromRecord(res.m.GetBag()))
+}
+
+// Proto returns the underlying proto message.
+func (res *generateBagResponse) Proto() proto.Message {
+	return res.m
+}
+
+// String converts a generateBagResponse to a string.
+func (res *generateBagResponse) String() string {
+	return fmt.Sprintf("Generated ROS bag for ID %s", res.m.GetBag().GetBagMetadata().GetBagId())
+}
+
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generates a ROS bag for a given recording id",
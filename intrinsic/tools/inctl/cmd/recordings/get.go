@@ -3,11 +3,13 @@
 package recordings
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
 	pb "intrinsic/logging/proto/bag_packager_service_go_grpc_proto"
 )
 
@@ -32,10 +34,37 @@ var getRecordingE = func(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	fmt.Print(prototext.Format(resp))
+	prtr, err := commandPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	prtr.Print(&getBagResponse{m: resp})
 	return nil
 }
 
+// getBagResponse embeds pb.GetBagResponse.
+type getBagResponse struct {
+	m *pb.GetBagResponse
+}
+
+// MarshalJSON converts a getBagResponse to a byte slice.
+func (res *getBagResponse) MarshalJSON() ([]byte, error) {
+	b := bagFromRecord(res.m.GetBag())
+	b.URL = res.m.GetUrl()
+	b.URLExpiryTime = formatTimestamp(res.m.GetUrlExpiryTime())
+	return json.Marshal(b)
+}
+
+// Proto returns the underlying proto message.
+func (res *getBagResponse) Proto() proto.Message {
+	return res.m
+}
+
+// String converts a getBagResponse to a string.
+func (res *getBagResponse) String() string {
+	return strings.TrimSuffix(prototext.Format(res.m), "\n")
+}
+
 var getCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Gets a ROS bag for a given recording id",
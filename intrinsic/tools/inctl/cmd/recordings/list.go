@@ -3,16 +3,17 @@
 package recordings
 
 import (
-	"fmt"
-	"sort"
-	"strings"
+	"encoding/json"
+	"slices"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	pb "intrinsic/logging/proto/bag_packager_service_go_grpc_proto"
 	"intrinsic/tools/inctl/util/orgutil"
+	"intrinsic/tools/inctl/util/printer"
 )
 
 var (
@@ -53,34 +54,74 @@ var listRecordingsE = func(cmd *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
-	if len(resp.GetBags()) == 0 {
-		return nil
-	}
-	const formatString = "%-40s %-30s %-45s %-45s %-45s"
-	lines := []string{
-		fmt.Sprintf(formatString, "Description", "Status", "ID", "Start Time", "End Time"),
+	prtr, err := commandPrinter(cmd)
+	if err != nil {
+		return err
 	}
+	prtr.Print(&listBagsResponse{m: resp})
 
-	// Sort the response by start time.
-	sort.Slice(resp.GetBags(), func(i, j int) bool {
-		return resp.GetBags()[i].GetBagMetadata().GetStartTime().AsTime().Before(resp.GetBags()[j].GetBagMetadata().GetStartTime().AsTime())
+	return nil
+}
+
+// listBagsResponse embeds pb.ListBagsResponse.
+type listBagsResponse struct {
+	m *pb.ListBagsResponse
+}
+
+// bags returns the recordings sorted by start time.
+func (res *listBagsResponse) bags() []bag {
+	records := slices.Clone(res.m.GetBags())
+	slices.SortStableFunc(records, func(a, b *pb.BagRecord) int {
+		return a.GetBagMetadata().GetStartTime().AsTime().Compare(b.GetBagMetadata().GetStartTime().AsTime())
 	})
-	for _, bag := range resp.GetBags() {
-		description := bag.GetBagMetadata().GetDescription()
+	bags := make([]bag, len(records))
+	for i, r := range records {
+		bags[i] = bagFromRecord(r)
+	}
+	return bags
+}
+
+// MarshalJSON converts a listBagsResponse to a byte slice.
+func (res *listBagsResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		// bags intentionally not omitted when empty
+		Bags []bag `json:"bags"`
+	}{Bags: res.bags()})
+}
+
+// Proto returns the underlying proto message.
+func (res *listBagsResponse) Proto() proto.Message {
+	return res.m
+}
+
+// Table converts a listBagsResponse to a table sorted by start time.
+func (res *listBagsResponse) Table() *printer.Table {
+	t := &printer.Table{
+		Columns: []printer.Column{
+			{Name: "Description"},
+			{Name: "Status"},
+			{Name: "ID"},
+			{Name: "Start Time"},
+			{Name: "End Time"},
+			{Name: "Solution", Hidden: true},
+			{Name: "Workcell", Hidden: true},
+		},
+	}
+	for _, b := range res.bags() {
+		description := b.Description
 		if description == "" {
 			description = "<NO-DESCRIPTION>"
 		}
-		lines = append(lines,
-			fmt.Sprintf(formatString,
-				description,
-				bag.GetBagMetadata().GetStatus().GetStatus().String(),
-				bag.GetBagMetadata().GetBagId(),
-				bag.GetBagMetadata().GetStartTime().AsTime(),
-				bag.GetBagMetadata().GetEndTime().AsTime()))
+		t.Rows = append(t.Rows, []string{
+			description, b.Status, b.BagID, b.StartTime, b.EndTime, b.SolutionName, b.WorkcellName,
+		})
 	}
-	fmt.Println(strings.Join(lines, "\n"))
+	return t
+}
 
-	return nil
+// String converts a listBagsResponse to a string.
+func (res *listBagsResponse) String() string {
+	return res.Table().String()
 }
 
 var listCmd = &cobra.Command{
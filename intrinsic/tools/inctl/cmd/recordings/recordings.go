@@ -4,9 +4,14 @@
 package recordings
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	"intrinsic/assets/cmdutils"
+	pb "intrinsic/logging/proto/bag_packager_service_go_grpc_proto"
 	"intrinsic/tools/inctl/cmd/root"
 	"intrinsic/tools/inctl/util/printer"
 )
@@ -20,11 +25,63 @@ const (
 	keyProjectShort = "p"
 )
 
-func setPrinterFromOutputFlag(command *cobra.Command, args []string) (err error) {
-	if out, err := printer.NewPrinter(root.FlagOutput); err == nil {
-		command.SetOut(out)
+func setPrinterFromOutputFlag(command *cobra.Command, args []string) error {
+	out, err := printer.NewPrinter(root.FlagOutput, printer.WithSortBy(root.FlagSortBy))
+	if err != nil {
+		return err
+	}
+	command.SetOut(out)
+	return nil
+}
+
+// commandPrinter returns the printer set up by setPrinterFromOutputFlag.
+func commandPrinter(cmd *cobra.Command) (printer.Printer, error) {
+	prtr, ok := printer.AsPrinter(cmd.OutOrStdout(), printer.TextOutputFormat)
+	if !ok {
+		return nil, fmt.Errorf("invalid output configuration")
+	}
+	return prtr, nil
+}
+
+// bag is the machine-readable record of a recording printed by the recordings commands.
+type bag struct {
+	BagID         string `json:"bagId"`
+	Status        string `json:"status"`
+	StartTime     string `json:"startTime,omitempty"`
+	EndTime       string `json:"endTime,omitempty"`
+	Description   string `json:"description,omitempty"`
+	SolutionName  string `json:"solutionName,omitempty"`
+	WorkcellName  string `json:"workcellName,omitempty"`
+	TotalLogItems uint64 `json:"totalLogItems,omitempty"`
+	TotalBytes    uint64 `json:"totalBytes,omitempty"`
+	FilePath      string `json:"filePath,omitempty"`
+	FileByteSize  uint64 `json:"fileByteSize,omitempty"`
+	URL           string `json:"url,omitempty"`
+	URLExpiryTime string `json:"urlExpiryTime,omitempty"`
+}
+
+func formatTimestamp(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	return ts.AsTime().Format(time.RFC3339)
+}
+
+func bagFromRecord(r *pb.BagRecord) bag {
+	md := r.GetBagMetadata()
+	return bag{
+		BagID:         md.GetBagId(),
+		Status:        md.GetStatus().GetStatus().String(),
+		StartTime:     formatTimestamp(md.GetStartTime()),
+		EndTime:       formatTimestamp(md.GetEndTime()),
+		Description:   md.GetDescription(),
+		SolutionName:  md.GetSolutionName(),
+		WorkcellName:  md.GetWorkcellName(),
+		TotalLogItems: md.GetTotalLogItems(),
+		TotalBytes:    md.GetTotalBytes(),
+		FilePath:      r.GetBagFile().GetFilePath(),
+		FileByteSize:  r.GetBagFile().GetFileByteSize(),
 	}
-	return
 }
 
 var (
@@ -5,6 +5,7 @@ package root
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -19,6 +20,7 @@ import (
 	"intrinsic/skills/tools/skill/cmd/dialerutil"
 	"intrinsic/tools/inctl/util/orgutil"
 	"intrinsic/tools/inctl/util/printer"
+	"intrinsic/tools/inctl/util/statusrender"
 
 	grpccodes "google.golang.org/grpc/codes"
 	grpcstatus "google.golang.org/grpc/status"
@@ -51,6 +53,12 @@ var (
 	// FlagOutput holds the value of the --output flag.
 	FlagOutput = printer.TextOutputFormat
 
+	// FlagSortBy holds the value of the --sort_by flag.
+	FlagSortBy = ""
+
+	// FlagDebugStatus holds the value of the --debug_status flag.
+	FlagDebugStatus = false
+
 	// FlagPrintTrace prints the trace identifier to stderr on exit.
 )
 
@@ -133,6 +141,29 @@ func (e *executionContext) RewriteError(err error, cmdNames []string) string {
 	return err.Error()
 }
 
+// formatError formats an error for printing to stderr. If the error carries an extended status,
+// it is rendered as a tree in text output and as its full proto with --output=json.
+func (e *executionContext) formatError(err error, cmdNames []string) string {
+	msg := e.RewriteError(err, cmdNames)
+	es, ok := statusrender.FromError(err)
+	if !ok {
+		return "Error: " + msg
+	}
+	if FlagOutput == printer.JSONOutputFormat {
+		esJSON, jsonErr := statusrender.JSON(es)
+		if jsonErr == nil {
+			out, jsonErr := json.Marshal(struct {
+				Error          string          `json:"error"`
+				ExtendedStatus json.RawMessage `json:"extended_status"`
+			}{Error: msg, ExtendedStatus: esJSON})
+			if jsonErr == nil {
+				return string(out)
+			}
+		}
+	}
+	return fmt.Sprintf("Error: %s\n%s", msg, statusrender.Text(es, statusrender.Options{Debug: FlagDebugStatus}))
+}
+
 // getCommandNames returns a vector of subcommand names - e.g. ["app", "status"]
 // for "inctl app status" or [] for "inctl". Returns an error if there is no
 // matching command, e.g. because the user misspelled the command name(s).
@@ -166,7 +197,7 @@ func Execute(ec executionContext) bool {
 	success := true
 	if err := RootCmd.ExecuteContext(ctx); err != nil {
 		cmdNames, _ := getCommandNames() // ignore error, cmdNames will simply be nil
-		fmt.Fprintln(os.Stderr, "Error:", ec.RewriteError(err, cmdNames))
+		fmt.Fprintln(os.Stderr, ec.formatError(err, cmdNames))
 		success = false
 	}
 
@@ -189,4 +220,10 @@ func init() {
 	RootCmd.PersistentFlags().StringVarP(
 		&FlagOutput, printer.KeyOutput, "o", printer.TextOutputFormat,
 		fmt.Sprintf("(optional) Output format. One of: (%s)", strings.Join(printer.AllowedFormats, ", ")))
+	RootCmd.PersistentFlags().StringVar(
+		&FlagSortBy, printer.KeySortBy, "",
+		"(optional) Column to sort table output of list commands by.")
+	RootCmd.PersistentFlags().BoolVar(
+		&FlagDebugStatus, "debug_status", false,
+		"(optional) Include debug reports, stack traces and log contexts of extended statuses in errors.")
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 	clusterdiscoverypb "intrinsic/frontend/cloud/api/clusterdiscovery_api_go_grpc_proto"
 	solutiondiscoverygrpcpb "intrinsic/frontend/cloud/api/solutiondiscovery_api_go_grpc_proto"
 	solutiondiscoverypb "intrinsic/frontend/cloud/api/solutiondiscovery_api_go_grpc_proto"
@@ -57,11 +58,15 @@ func (res *ListSolutionDescriptionsResponse) MarshalJSON() ([]byte, error) {
 	}{Solutions: solutions})
 }
 
-// String converts a ListSolutionDescriptionsResponse to a string
-func (res *ListSolutionDescriptionsResponse) String() string {
-	const formatString = "%-50s %-15s %-50s"
-	lines := []string{
-		fmt.Sprintf(formatString, "Name", "State", "ID"),
+// Table converts a ListSolutionDescriptionsResponse to a table.
+func (res *ListSolutionDescriptionsResponse) Table() *printer.Table {
+	t := &printer.Table{
+		Columns: []printer.Column{
+			{Name: "Name"},
+			{Name: "State"},
+			{Name: "ID"},
+			{Name: "Cluster", Hidden: true},
+		},
 	}
 	for _, c := range res.m.GetSolutions() {
 		name := c.GetDisplayName()
@@ -74,11 +79,19 @@ func (res *ListSolutionDescriptionsResponse) String() string {
 			statusStr = fmt.Sprintf("%s on %s", statusStr, c.GetClusterName())
 		}
 
-		lines = append(
-			lines,
-			fmt.Sprintf(formatString, name, statusStr, c.GetName()))
+		t.Rows = append(t.Rows, []string{name, statusStr, c.GetName(), c.GetClusterName()})
 	}
-	return strings.Join(lines, "\n")
+	return t
+}
+
+// Proto returns the underlying proto message.
+func (res *ListSolutionDescriptionsResponse) Proto() proto.Message {
+	return res.m
+}
+
+// String converts a ListSolutionDescriptionsResponse to a string
+func (res *ListSolutionDescriptionsResponse) String() string {
+	return res.Table().String()
 }
 
 func validateAndGetFilters(filterNames []string) ([]clusterdiscoverypb.SolutionState, error) {
@@ -126,7 +139,7 @@ var solutionListCmd = &cobra.Command{
 	Long:  "List solutions on the given project.",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		prtr, err := printer.NewPrinter(root.FlagOutput)
+		prtr, err := printer.NewPrinter(root.FlagOutput, printer.WithSortBy(root.FlagSortBy))
 		if err != nil {
 			return err
 		}
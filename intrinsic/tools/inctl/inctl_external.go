@@ -7,6 +7,7 @@ import (
 	_ "intrinsic/assets/services/inctl/service"
 	_ "intrinsic/tools/inctl/cmd/auth/auth"
 	_ "intrinsic/tools/inctl/cmd/bazel/bazel"
+	_ "intrinsic/tools/inctl/cmd/cache/cache"
 	_ "intrinsic/tools/inctl/cmd/cluster/cluster"
 	_ "intrinsic/tools/inctl/cmd/customer/customer"
 	_ "intrinsic/tools/inctl/cmd/device/device"
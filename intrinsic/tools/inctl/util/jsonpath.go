@@ -0,0 +1,222 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a parsed JSONPath template as accepted by --output=jsonpath=EXPRESSION. It supports
+// the subset of JSONPath which is useful for scripting: literal text, quoted string literals like
+// {"\n"} and paths like {.items[*].name}, {.items[0]['display name']} or {$.items[-1]}.
+type jsonPath struct {
+	segments []jsonPathSegment
+}
+
+// jsonPathSegment is either literal text or a path to evaluate.
+type jsonPathSegment struct {
+	text  string
+	steps []jsonPathStep
+}
+
+// jsonPathStep selects a field of an object or an element of an array. A step with wildcard set
+// selects all fields or elements.
+type jsonPathStep struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func parseJSONPath(expr string) (*jsonPath, error) {
+	p := &jsonPath{}
+	for expr != "" {
+		start := strings.Index(expr, "{")
+		if start < 0 {
+			p.segments = append(p.segments, jsonPathSegment{text: expr})
+			break
+		}
+		if start > 0 {
+			p.segments = append(p.segments, jsonPathSegment{text: expr[:start]})
+		}
+		end := strings.Index(expr[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed '{' in %q", expr)
+		}
+		inner := strings.TrimSpace(expr[start+1 : start+end])
+		expr = expr[start+end+1:]
+
+		if strings.HasPrefix(inner, `"`) {
+			text, err := strconv.Unquote(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string literal %s: %v", inner, err)
+			}
+			p.segments = append(p.segments, jsonPathSegment{text: text})
+			continue
+		}
+		steps, err := parseJSONPathSteps(inner)
+		if err != nil {
+			return nil, err
+		}
+		p.segments = append(p.segments, jsonPathSegment{steps: steps})
+	}
+	return p, nil
+}
+
+func parseJSONPathSteps(path string) ([]jsonPathStep, error) {
+	rest := strings.TrimPrefix(path, "$")
+	if rest == path && !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+		return nil, fmt.Errorf("path %q must start with '.', '[' or '$'", path)
+	}
+	steps := []jsonPathStep{}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			n := strings.IndexAny(rest, ".[")
+			if n < 0 {
+				n = len(rest)
+			}
+			name := rest[:n]
+			rest = rest[n:]
+			switch name {
+			case "":
+				// A lone "." refers to the current value.
+			case "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			default:
+				steps = append(steps, jsonPathStep{field: name})
+			}
+		case '[':
+			n := strings.Index(rest, "]")
+			if n < 0 {
+				return nil, fmt.Errorf("unclosed '[' in path %q", path)
+			}
+			inner := strings.TrimSpace(rest[1:n])
+			rest = rest[n+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case strings.HasPrefix(inner, "'") && strings.HasSuffix(inner, "'") && len(inner) >= 2:
+				steps = append(steps, jsonPathStep{field: inner[1 : len(inner)-1]})
+			case strings.HasPrefix(inner, `"`):
+				field, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid field name %s in path %q", inner, path)
+				}
+				steps = append(steps, jsonPathStep{field: field})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q in path %q", inner, path)
+				}
+				steps = append(steps, jsonPathStep{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in path %q", rest, path)
+		}
+	}
+	return steps, nil
+}
+
+// Execute evaluates the template on a generic JSON value and writes the result to w. Multiple
+// values selected by a path are separated by spaces.
+func (p *jsonPath) Execute(w io.Writer, v any) error {
+	var b strings.Builder
+	for _, s := range p.segments {
+		if s.steps == nil {
+			b.WriteString(s.text)
+			continue
+		}
+		values, err := evalJSONPathSteps(s.steps, []any{v})
+		if err != nil {
+			return err
+		}
+		for i, value := range values {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			text, err := formatJSONValue(value)
+			if err != nil {
+				return err
+			}
+			b.WriteString(text)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func evalJSONPathSteps(steps []jsonPathStep, values []any) ([]any, error) {
+	for _, step := range steps {
+		var next []any
+		for _, v := range values {
+			switch {
+			case step.wildcard:
+				switch t := v.(type) {
+				case []any:
+					next = append(next, t...)
+				case map[string]any:
+					for _, k := range sortedKeys(t) {
+						next = append(next, t[k])
+					}
+				default:
+					return nil, fmt.Errorf("cannot apply [*] to %T", v)
+				}
+			case step.isIndex:
+				a, ok := v.([]any)
+				if !ok {
+					return nil, fmt.Errorf("cannot index %T with [%d]", v, step.index)
+				}
+				i := step.index
+				if i < 0 {
+					i += len(a)
+				}
+				if i < 0 || i >= len(a) {
+					return nil, fmt.Errorf("index [%d] out of range for array of length %d", step.index, len(a))
+				}
+				next = append(next, a[i])
+			default:
+				m, ok := v.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("cannot get field %q of %T", step.field, v)
+				}
+				// Fields which are omitted in the JSON representation are empty.
+				if f, ok := m[step.field]; ok {
+					next = append(next, f)
+				}
+			}
+		}
+		values = next
+	}
+	return values, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// formatJSONValue formats strings and numbers as plain text and other values as JSON.
+func formatJSONValue(v any) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
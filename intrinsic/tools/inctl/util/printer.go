@@ -4,23 +4,56 @@
 package printer
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	// KeyOutput is a string used to refer the output flag.
 	KeyOutput = "output"
+	// KeySortBy is a string used to refer the flag which sorts table output.
+	KeySortBy = "sort_by"
 	// JSONOutputFormat is a string indicating JSON output format.
 	JSONOutputFormat = "json"
+	// YAMLOutputFormat is a string indicating YAML output format.
+	YAMLOutputFormat = "yaml"
+	// TextProtoOutputFormat is a string indicating proto text format output of proto-backed
+	// results.
+	TextProtoOutputFormat = "textproto"
+	// TableOutputFormat is a string indicating table output format. It can be followed by
+	// "=COLUMN,..." to select the columns to print.
+	TableOutputFormat = "table"
+	// TemplateOutputFormat is a string indicating Go template output format. It must be followed by
+	// "=TEMPLATE". The template is executed with the JSON representation of every result.
+	TemplateOutputFormat = "template"
+	// JSONPathOutputFormat is a string indicating JSONPath output format. It must be followed by
+	// "=EXPRESSION". The expression is evaluated on the JSON representation of every result.
+	JSONPathOutputFormat = "jsonpath"
 	// TextOutputFormat is a string indicating human-readable text output format.
 	TextOutputFormat = ""
 )
 
 // AllowedFormats is a list of possible output formats.
-var AllowedFormats = []string{JSONOutputFormat}
+var AllowedFormats = []string{
+	JSONOutputFormat,
+	YAMLOutputFormat,
+	TextProtoOutputFormat,
+	TableOutputFormat + "[=COLUMN,...]",
+	TemplateOutputFormat + "=TEMPLATE",
+	JSONPathOutputFormat + "=EXPRESSION",
+}
 
 type any = interface{}
 
@@ -92,12 +125,393 @@ func (p *TextPrinter) PrintSf(format string, a ...any) {
 	p.PrintS(fmt.Sprintf(format, a...))
 }
 
+// ProtoBacked is implemented by results which are backed by a proto message. The proto message is
+// printed with --output=textproto.
+type ProtoBacked interface {
+	Proto() proto.Message
+}
+
+// Column describes a column of a Table.
+type Column struct {
+	// Name is the header of the column. Columns are selected by their name, ignoring case, spaces,
+	// dashes and underscores.
+	Name string
+	// Hidden columns are only printed if they are selected explicitly.
+	Hidden bool
+}
+
+// Table is a result in tabular form.
+type Table struct {
+	Columns []Column
+	// Rows holds the cells of every row, in the order of Columns.
+	Rows [][]string
+}
+
+// Tabular is implemented by results which can be printed as a table.
+type Tabular interface {
+	Table() *Table
+}
+
+// String renders the visible columns of the table in the order of its rows.
+func (t *Table) String() string {
+	s, _ := t.render(nil, "")
+	return s
+}
+
+func normalizeColumnName(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+func (t *Table) columnIndex(name string) (int, error) {
+	want := normalizeColumnName(name)
+	for i, c := range t.Columns {
+		if normalizeColumnName(c.Name) == want {
+			return i, nil
+		}
+	}
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	return 0, fmt.Errorf("unknown column %q, available columns: %s", name, strings.Join(names, ", "))
+}
+
+// render renders the selected columns, or all visible columns if none are selected, with the rows
+// sorted by the sortBy column, if set.
+func (t *Table) render(columns []string, sortBy string) (string, error) {
+	var indices []int
+	if len(columns) == 0 {
+		for i, c := range t.Columns {
+			if !c.Hidden {
+				indices = append(indices, i)
+			}
+		}
+	}
+	for _, name := range columns {
+		i, err := t.columnIndex(name)
+		if err != nil {
+			return "", err
+		}
+		indices = append(indices, i)
+	}
+
+	rows := slices.Clone(t.Rows)
+	if sortBy != "" {
+		i, err := t.columnIndex(sortBy)
+		if err != nil {
+			return "", err
+		}
+		slices.SortStableFunc(rows, func(a, b []string) int {
+			return strings.Compare(cell(a, i), cell(b, i))
+		})
+	}
+
+	b := new(bytes.Buffer)
+	w := tabwriter.NewWriter(b,
+		/*minwidth=*/ 1 /*tabwidth=*/, 1 /*padding=*/, 1 /*padchar=*/, ' ' /*flags=*/, 0)
+	header := make([]string, len(indices))
+	for j, i := range indices {
+		header[j] = t.Columns[i].Name
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(indices))
+		for j, i := range indices {
+			cells[j] = cell(row, i)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+	// Remove the trailing newline as the printers add one.
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func cell(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// toJSONValue converts val into the generic value (maps, slices, strings, numbers and booleans)
+// of its JSON representation. Proto messages are converted with protojson.
+func toJSONValue(val any) (any, error) {
+	var b []byte
+	var err error
+	if m, ok := val.(proto.Message); ok {
+		b, err = protojson.Marshal(m)
+	} else {
+		b, err = json.Marshal(val)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// printError reports an error which occurred while printing a result. Printers cannot return
+// errors, so it goes to stderr to not corrupt the output.
+func printError(err error) {
+	fmt.Fprintf(os.Stderr, "Error: could not print result: %v\n", err)
+}
+
+// YAMLPrinter implements Printer.
+type YAMLPrinter struct {
+	w       io.Writer
+	printed bool
+}
+
+func (p *YAMLPrinter) Write(c []byte) (n int, err error) {
+	p.PrintS(string(c))
+	return len(c), nil
+}
+
+// Print prints the JSON representation of val in YAML format. Multiple results are separated
+// as YAML documents.
+func (p *YAMLPrinter) Print(val any) {
+	v, err := toJSONValue(val)
+	if err != nil {
+		printError(err)
+		return
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		printError(err)
+		return
+	}
+	if p.printed {
+		fmt.Fprintln(p.w, "---")
+	}
+	p.printed = true
+	p.w.Write(b)
+}
+
+// PrintS prints a string as a YAML object with a single "msg" field.
+func (p *YAMLPrinter) PrintS(str string) {
+	p.Print(&Message{Msg: str})
+}
+
+// PrintSf prints the formatted string as a YAML object with a single "msg" field.
+func (p *YAMLPrinter) PrintSf(format string, a ...any) {
+	p.PrintS(fmt.Sprintf(format, a...))
+}
+
+// TextProtoPrinter implements Printer.
+type TextProtoPrinter struct {
+	w io.Writer
+}
+
+func (p *TextProtoPrinter) Write(c []byte) (n int, err error) {
+	p.PrintS(string(c))
+	return len(c), nil
+}
+
+// Print prints val in proto text format if it is a proto message or backed by one. Other values
+// are printed as comments so that the output remains valid text format.
+func (p *TextProtoPrinter) Print(val any) {
+	var m proto.Message
+	switch v := val.(type) {
+	case proto.Message:
+		m = v
+	case ProtoBacked:
+		m = v.Proto()
+	default:
+		p.PrintS(fmt.Sprint(val))
+		return
+	}
+	b, err := prototext.MarshalOptions{Multiline: true}.Marshal(m)
+	if err != nil {
+		printError(err)
+		return
+	}
+	p.w.Write(b)
+}
+
+// PrintS prints a string as a text format comment.
+func (p *TextProtoPrinter) PrintS(str string) {
+	for _, line := range strings.Split(strings.TrimSuffix(str, "\n"), "\n") {
+		fmt.Fprintf(p.w, "# %s\n", line)
+	}
+}
+
+// PrintSf prints the formatted string as a text format comment.
+func (p *TextProtoPrinter) PrintSf(format string, a ...any) {
+	p.PrintS(fmt.Sprintf(format, a...))
+}
+
+// TablePrinter implements Printer.
+type TablePrinter struct {
+	w       io.Writer
+	columns []string
+	sortBy  string
+}
+
+func (p *TablePrinter) Write(c []byte) (n int, err error) {
+	return p.w.Write(c)
+}
+
+// Print prints val as a table with the selected columns if it is Tabular, and in human-readable
+// text format otherwise.
+func (p *TablePrinter) Print(val any) {
+	t, ok := val.(Tabular)
+	if !ok {
+		(&TextPrinter{w: p.w}).Print(val)
+		return
+	}
+	s, err := t.Table().render(p.columns, p.sortBy)
+	if err != nil {
+		printError(err)
+		return
+	}
+	fmt.Fprintln(p.w, s)
+}
+
+// PrintS prints a string in human-readable text format.
+func (p *TablePrinter) PrintS(str string) {
+	fmt.Fprintln(p.w, str)
+}
+
+// PrintSf prints the formatted string in human-readable text format.
+func (p *TablePrinter) PrintSf(format string, a ...any) {
+	p.PrintS(fmt.Sprintf(format, a...))
+}
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// TemplatePrinter implements Printer.
+type TemplatePrinter struct {
+	w io.Writer
+	t *template.Template
+}
+
+func (p *TemplatePrinter) Write(c []byte) (n int, err error) {
+	p.PrintS(string(c))
+	return len(c), nil
+}
+
+// Print executes the template with the JSON representation of val.
+func (p *TemplatePrinter) Print(val any) {
+	v, err := toJSONValue(val)
+	if err != nil {
+		printError(err)
+		return
+	}
+	if err := p.t.Execute(p.w, v); err != nil {
+		printError(err)
+	}
+}
+
+// PrintS prints a string to stderr, so that it does not interfere with the templated output.
+func (p *TemplatePrinter) PrintS(str string) {
+	fmt.Fprintln(os.Stderr, strings.TrimSuffix(str, "\n"))
+}
+
+// PrintSf prints the formatted string to stderr.
+func (p *TemplatePrinter) PrintSf(format string, a ...any) {
+	p.PrintS(fmt.Sprintf(format, a...))
+}
+
+// JSONPathPrinter implements Printer.
+type JSONPathPrinter struct {
+	w    io.Writer
+	expr *jsonPath
+}
+
+func (p *JSONPathPrinter) Write(c []byte) (n int, err error) {
+	p.PrintS(string(c))
+	return len(c), nil
+}
+
+// Print evaluates the JSONPath expression on the JSON representation of val.
+func (p *JSONPathPrinter) Print(val any) {
+	v, err := toJSONValue(val)
+	if err != nil {
+		printError(err)
+		return
+	}
+	if err := p.expr.Execute(p.w, v); err != nil {
+		printError(err)
+	}
+}
+
+// PrintS prints a string to stderr, so that it does not interfere with the JSONPath output.
+func (p *JSONPathPrinter) PrintS(str string) {
+	fmt.Fprintln(os.Stderr, strings.TrimSuffix(str, "\n"))
+}
+
+// PrintSf prints the formatted string to stderr.
+func (p *JSONPathPrinter) PrintSf(format string, a ...any) {
+	p.PrintS(fmt.Sprintf(format, a...))
+}
+
+// Option configures a Printer.
+type Option func(*printerOptions)
+
+type printerOptions struct {
+	sortBy string
+}
+
+// WithSortBy sorts the rows of table output by the given column.
+func WithSortBy(column string) Option {
+	return func(o *printerOptions) {
+		o.sortBy = column
+	}
+}
+
 // NewPrinterWithWriter returns a new Printer which writes to the given writer
 // using the given output format.
-func NewPrinterWithWriter(outputFormat string, w io.Writer) (Printer, error) {
-	if outputFormat == JSONOutputFormat {
+func NewPrinterWithWriter(outputFormat string, w io.Writer, opts ...Option) (Printer, error) {
+	o := &printerOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	format, arg, hasArg := strings.Cut(outputFormat, "=")
+	if hasArg && format != TableOutputFormat && format != TemplateOutputFormat && format != JSONPathOutputFormat {
+		return nil, fmt.Errorf("unknown output format %q", outputFormat)
+	}
+	switch format {
+	case JSONOutputFormat:
 		return &JSONPrinter{enc: json.NewEncoder(w)}, nil
-	} else if outputFormat == TextOutputFormat {
+	case YAMLOutputFormat:
+		return &YAMLPrinter{w: w}, nil
+	case TextProtoOutputFormat:
+		return &TextProtoPrinter{w: w}, nil
+	case TableOutputFormat:
+		var columns []string
+		if arg != "" {
+			columns = strings.Split(arg, ",")
+		}
+		return &TablePrinter{w: w, columns: columns, sortBy: o.sortBy}, nil
+	case TemplateOutputFormat:
+		if arg == "" {
+			return nil, fmt.Errorf("output format %q requires a template, e.g., %s='{{.name}}'", format, format)
+		}
+		t, err := template.New("output").Option("missingkey=error").Funcs(templateFuncs).Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output template: %w", err)
+		}
+		return &TemplatePrinter{w: w, t: t}, nil
+	case JSONPathOutputFormat:
+		if arg == "" {
+			return nil, fmt.Errorf("output format %q requires an expression, e.g., %s='{.name}'", format, format)
+		}
+		expr, err := parseJSONPath(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output JSONPath: %w", err)
+		}
+		return &JSONPathPrinter{w: w, expr: expr}, nil
+	case TextOutputFormat:
 		return &TextPrinter{w: w}, nil
 	}
 	return nil, fmt.Errorf("unknown output format %q", outputFormat)
@@ -105,8 +519,8 @@ func NewPrinterWithWriter(outputFormat string, w io.Writer) (Printer, error) {
 
 // NewPrinter returns a new Printer which writes to os.Stdout using the given
 // output format.
-func NewPrinter(outputFormat string) (Printer, error) {
-	return NewPrinterWithWriter(outputFormat, os.Stdout)
+func NewPrinter(outputFormat string, opts ...Option) (Printer, error) {
+	return NewPrinterWithWriter(outputFormat, os.Stdout, opts...)
 }
 
 // AsPrinter tries to convert supplied io.Writer to Printer.
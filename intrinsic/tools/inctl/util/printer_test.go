@@ -0,0 +1,209 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type testItem struct {
+	Name   string `json:"name"`
+	Region string `json:"region"`
+	Size   int    `json:"size"`
+}
+
+type testResult struct {
+	Items []testItem `json:"items"`
+}
+
+func (r *testResult) Table() *Table {
+	t := &Table{Columns: []Column{{Name: "Name"}, {Name: "Region"}, {Name: "Size", Hidden: true}}}
+	for _, i := range r.Items {
+		t.Rows = append(t.Rows, []string{i.Name, i.Region, strconv.Itoa(i.Size)})
+	}
+	return t
+}
+
+func (r *testResult) Proto() proto.Message {
+	return wrapperspb.String(r.Items[0].Name)
+}
+
+func (r *testResult) String() string {
+	return r.Table().String()
+}
+
+var result = &testResult{Items: []testItem{
+	{Name: "b", Region: "europe", Size: 1},
+	{Name: "a", Region: "us", Size: 2},
+}}
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   []Option
+		want   string
+	}{
+		{
+			format: TextOutputFormat,
+			want:   "Name Region\nb    europe\na    us\n",
+		},
+		{
+			format: JSONOutputFormat,
+			want:   `{"items":[{"name":"b","region":"europe","size":1},{"name":"a","region":"us","size":2}]}` + "\n",
+		},
+		{
+			format: YAMLOutputFormat,
+			want: `items:
+    - name: b
+      region: europe
+      size: 1
+    - name: a
+      region: us
+      size: 2
+`,
+		},
+		{
+			format: TextProtoOutputFormat,
+			want:   "value: \"b\"\n",
+		},
+		{
+			format: TableOutputFormat,
+			want:   "Name Region\nb    europe\na    us\n",
+		},
+		{
+			format: "table=size,NAME",
+			opts:   []Option{WithSortBy("name")},
+			want:   "Size Name\n2    a\n1    b\n",
+		},
+		{
+			format: `template={{range .items}}{{.name}}:{{.size}}{{"\n"}}{{end}}`,
+			want:   "b:1\na:2\n",
+		},
+		{
+			format: `jsonpath={.items[*].name}{"\n"}{.items[-1]['region']}`,
+			want:   "b a\nus",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			p, err := NewPrinterWithWriter(tc.format, b, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewPrinterWithWriter(%q) failed: %v", tc.format, err)
+			}
+			p.Print(result)
+			got := b.String()
+			if tc.format == TextProtoOutputFormat {
+				// prototext randomly adds spaces to keep its output from being relied upon.
+				got = strings.Join(strings.Fields(got), " ") + "\n"
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Print() returned unexpected output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintS(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: TextOutputFormat, want: "hello\n"},
+		{format: JSONOutputFormat, want: `{"msg":"hello"}` + "\n"},
+		{format: YAMLOutputFormat, want: "msg: hello\n"},
+		{format: TextProtoOutputFormat, want: "# hello\n"},
+		{format: TableOutputFormat, want: "hello\n"},
+		{format: "template={{.msg}}", want: ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			p, err := NewPrinterWithWriter(tc.format, b)
+			if err != nil {
+				t.Fatalf("NewPrinterWithWriter(%q) failed: %v", tc.format, err)
+			}
+			p.PrintS("hello")
+			if diff := cmp.Diff(tc.want, b.String()); diff != "" {
+				t.Errorf("PrintS() returned unexpected output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewPrinterInvalidFormat(t *testing.T) {
+	for _, format := range []string{
+		"xml",
+		"json=foo",
+		"template",
+		"template={{.name",
+		"jsonpath",
+		"jsonpath={.name",
+		"jsonpath={name}",
+	} {
+		if _, err := NewPrinterWithWriter(format, new(bytes.Buffer)); err == nil {
+			t.Errorf("NewPrinterWithWriter(%q) succeeded, want error", format)
+		}
+	}
+}
+
+func TestTableUnknownColumn(t *testing.T) {
+	if _, err := result.Table().render([]string{"zone"}, ""); err == nil {
+		t.Errorf("render() succeeded with unknown column, want error")
+	}
+	if _, err := result.Table().render(nil, "zone"); err == nil {
+		t.Errorf("render() succeeded with unknown sort column, want error")
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	var v any
+	if err := json.Unmarshal([]byte(`{"a": {"b": [1, 2.5, "x"], "c d": true}, "e": null}`), &v); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: "{.a.b[0]}", want: "1"},
+		{expr: "{$.a.b[*]}", want: "1 2.5 x"},
+		{expr: "{.a['c d']}", want: "true"},
+		{expr: "{.a.b}", want: `[1,2.5,"x"]`},
+		{expr: "{.e}", want: "null"},
+		{expr: "{.missing}", want: ""},
+		{expr: "b: {.a.b[1]}, x: {.a.b[2]}", want: "b: 2.5, x: x"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			p, err := parseJSONPath(tc.expr)
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q) failed: %v", tc.expr, err)
+			}
+			b := new(bytes.Buffer)
+			if err := p.Execute(b, v); err != nil {
+				t.Fatalf("Execute(%q) failed: %v", tc.expr, err)
+			}
+			if got := b.String(); got != tc.want {
+				t.Errorf("Execute(%q) = %q, want %q", tc.expr, got, tc.want)
+			}
+		})
+	}
+
+	for _, expr := range []string{"{.a.b[5]}", "{.a.b.c}", "{.a.b[0][0]}"} {
+		p, err := parseJSONPath(expr)
+		if err != nil {
+			t.Fatalf("parseJSONPath(%q) failed: %v", expr, err)
+		}
+		if err := p.Execute(new(bytes.Buffer), v); err == nil {
+			t.Errorf("Execute(%q) succeeded, want error", expr)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package statusrender renders extended statuses carried by errors in inctl output.
+package statusrender
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	espb "intrinsic/util/status/extended_status_go_proto"
+	"intrinsic/util/status/extstatus"
+)
+
+// Options configures the text rendering of extended statuses.
+type Options struct {
+	// Debug includes debug reports, stack traces and log contexts.
+	Debug bool
+}
+
+// FromError returns the extended status carried by err, if any. This finds extended statuses
+// created with extstatus as well as those attached to gRPC statuses, also if err wraps them.
+func FromError(err error) (*espb.ExtendedStatus, bool) {
+	if err == nil {
+		return nil, false
+	}
+	// Extended statuses created with extstatus.FromProto have no gRPC code, so their gRPC status
+	// carries no details.
+	var e *extstatus.Error
+	if errors.As(err, &e) {
+		if es, ok := extstatus.FromError(e); ok {
+			return es.Proto(), true
+		}
+	}
+	es, ok := extstatus.FromGRPCError(err)
+	if !ok {
+		return nil, false
+	}
+	return es.Proto(), true
+}
+
+// Text renders an extended status and its context as an indented tree.
+func Text(es *espb.ExtendedStatus, opts Options) string {
+	b := new(bytes.Buffer)
+	writeText(b, es, "", opts)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeText(w io.Writer, es *espb.ExtendedStatus, indent string, opts Options) {
+	fmt.Fprintf(w, "%s%s:%d: %s\n", indent, es.GetStatusCode().GetComponent(), es.GetStatusCode().GetCode(), es.GetTitle())
+	if msg := es.GetUserReport().GetMessage(); msg != "" {
+		writeIndented(w, indent+"  ", "", msg)
+	}
+	if instructions := es.GetUserReport().GetInstructions(); instructions != "" {
+		writeIndented(w, indent+"  ", "Instructions: ", instructions)
+	}
+	if opts.Debug {
+		if msg := es.GetDebugReport().GetMessage(); msg != "" {
+			writeIndented(w, indent+"  ", "Debug: ", msg)
+		}
+		if trace := es.GetDebugReport().GetStackTrace(); trace != "" {
+			writeIndented(w, indent+"  ", "Stack trace: ", trace)
+		}
+		if lc := es.GetRelatedTo().GetLogContext(); lc != nil {
+			text, err := prototext.MarshalOptions{}.Marshal(lc)
+			if err == nil {
+				writeIndented(w, indent+"  ", "Log context: ", strings.TrimSpace(string(text)))
+			}
+		}
+	}
+	for _, c := range es.GetContext() {
+		writeText(w, c, indent+"  ", opts)
+	}
+}
+
+// writeIndented writes a possibly multi-line text with the given label, aligning continuation
+// lines with the first one.
+func writeIndented(w io.Writer, indent, label, text string) {
+	continuation := indent + strings.Repeat(" ", len(label))
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	fmt.Fprintf(w, "%s%s%s\n", indent, label, lines[0])
+	for _, line := range lines[1:] {
+		fmt.Fprintf(w, "%s%s\n", continuation, line)
+	}
+}
+
+// JSON renders an extended status including all of its fields as JSON.
+func JSON(es *espb.ExtendedStatus) ([]byte, error) {
+	return protojson.MarshalOptions{UseProtoNames: true}.Marshal(es)
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package statusrender
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/testing/protocmp"
+	espb "intrinsic/util/status/extended_status_go_proto"
+	"intrinsic/util/status/extstatus"
+)
+
+func testStatus() *extstatus.ExtendedStatus {
+	return extstatus.New("ai.intrinsic.test", 42,
+		extstatus.WithTitle("Test failed"),
+		extstatus.WithUserMessage("Something went wrong"),
+		extstatus.WithUserInstructions("Try again\nor ask for help"),
+		extstatus.WithDebugMessage("Internal details"),
+		extstatus.WithContextProto(&espb.ExtendedStatus{
+			StatusCode: &espb.StatusCode{Component: "ai.intrinsic.dependency", Code: 7},
+			Title:      "Dependency failed",
+			DebugReport: &espb.ExtendedStatus_DebugReport{
+				Message: "Connection refused",
+			},
+		}))
+}
+
+func TestFromError(t *testing.T) {
+	es := testStatus()
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "extended status error", err: es.Err(), want: true},
+		{name: "grpc status", err: es.GRPCStatus().Err(), want: true},
+		{name: "wrapped with pkg/errors", err: errors.Wrap(es.Err(), "context"), want: true},
+		{name: "wrapped with fmt", err: fmt.Errorf("context: %w", es.GRPCStatus().Err()), want: true},
+		{name: "from proto", err: errors.Wrap(extstatus.FromProto(es.Proto()).Err(), "context"), want: true},
+		{name: "plain error", err: fmt.Errorf("plain"), want: false},
+		{name: "nil", err: nil, want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := FromError(tc.err)
+			if ok != tc.want {
+				t.Fatalf("FromError(%v) returned ok = %t, want %t", tc.err, ok, tc.want)
+			}
+			if ok {
+				if diff := cmp.Diff(es.Proto(), got, protocmp.Transform()); diff != "" {
+					t.Errorf("FromError(%v) returned unexpected status (-want +got):\n%s", tc.err, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "default",
+			want: `ai.intrinsic.test:42: Test failed
+  Something went wrong
+  Instructions: Try again
+                or ask for help
+  ai.intrinsic.dependency:7: Dependency failed`,
+		},
+		{
+			name: "debug",
+			opts: Options{Debug: true},
+			want: `ai.intrinsic.test:42: Test failed
+  Something went wrong
+  Instructions: Try again
+                or ask for help
+  Debug: Internal details
+  ai.intrinsic.dependency:7: Dependency failed
+    Debug: Connection refused`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, Text(testStatus().Proto(), tc.opts)); diff != "" {
+				t.Errorf("Text() returned unexpected text (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	want := testStatus().Proto()
+	b, err := JSON(want)
+	if err != nil {
+		t.Fatalf("JSON() failed: %v", err)
+	}
+	got := &espb.ExtendedStatus{}
+	if err := protojson.Unmarshal(b, got); err != nil {
+		t.Fatalf("protojson.Unmarshal(%s) failed: %v", b, err)
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("JSON() did not round trip (-want +got):\n%s", diff)
+	}
+}
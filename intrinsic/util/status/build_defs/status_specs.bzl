@@ -0,0 +1,84 @@
+# Copyright 2023 Intrinsic Innovation LLC
+
+"""
+Bazel rules for status specs.
+"""
+
+load("//bazel:go_macros.bzl", "go_library")
+
+def _go_status_specs_gen_impl(ctx):
+    output = ctx.actions.declare_file(ctx.label.name + ".go")
+    args = ctx.actions.args()
+    args.add("--specs", ctx.file.src)
+    args.add("--component", ctx.attr.component)
+    args.add("--package", ctx.attr.package)
+    args.add("--min_code", ctx.attr.min_code)
+    args.add("--max_code", ctx.attr.max_code)
+    args.add("--output", output)
+
+    ctx.actions.run(
+        inputs = [ctx.file.src],
+        outputs = [output],
+        executable = ctx.executable._statusspecgen,
+        arguments = [args],
+        mnemonic = "StatusSpecsGen",
+        progress_message = "Generating status specs code for %{label}",
+    )
+    return [DefaultInfo(files = depset([output]))]
+
+_go_status_specs_gen = rule(
+    implementation = _go_status_specs_gen_impl,
+    attrs = {
+        "src": attr.label(
+            allow_single_file = [".pbtxt", ".textproto"],
+            mandatory = True,
+            doc = "A text proto file with an intrinsic_proto.assets.StatusSpecs message.",
+        ),
+        "component": attr.string(mandatory = True),
+        "package": attr.string(mandatory = True),
+        "min_code": attr.int(default = 0),
+        "max_code": attr.int(default = 0),
+        "_statusspecgen": attr.label(
+            default = Label("//intrinsic/util/status/build_defs:statusspecgen_main"),
+            cfg = "exec",
+            executable = True,
+        ),
+    },
+)
+
+def go_status_specs_library(name, src, component, min_code = 0, max_code = 0, **kwargs):
+    """Validates status specs and generates a Go library with constants and constructors for them.
+
+    The build fails if the specs are invalid, e.g., if codes are declared more than once, titles or
+    recovery instructions are missing, or codes are outside of [min_code, max_code].
+
+    Args:
+      name: Name of the go_library. This is also the name of the generated Go package.
+      src: Text proto file with an intrinsic_proto.assets.StatusSpecs message.
+      component: The component the status codes belong to, e.g., "ai.intrinsic.my_skill".
+      min_code: Smallest code the component may declare, or 0 for the smallest code available to
+        components.
+      max_code: Largest code the component may declare, or 0 for no restriction.
+      **kwargs: Extra arguments passed to the go_library.
+    """
+    gen_name = name + "_gen"
+    _go_status_specs_gen(
+        name = gen_name,
+        src = src,
+        component = component,
+        package = name,
+        min_code = min_code,
+        max_code = max_code,
+        visibility = ["//visibility:private"],
+    )
+
+    go_library(
+        name = name,
+        srcs = [":" + gen_name],
+        deps = [
+            Label("//intrinsic/assets/proto:status_spec_go_proto"),
+            Label("//intrinsic/util/status:extstatus"),
+            Label("//intrinsic/util/status:statusspecs"),
+        ],
+        **kwargs
+    )
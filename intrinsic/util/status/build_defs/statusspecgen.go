@@ -0,0 +1,140 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package statusspecgen generates Go code for the status specs of a component.
+//
+// The generated package declares a constant and a typed constructor function for every status
+// code, so that undeclared codes are caught by the compiler instead of when an error is reported.
+package statusspecgen
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"go/token"
+	"math"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	specpb "intrinsic/assets/proto/status_spec_go_proto"
+	"intrinsic/util/status/statusspecs"
+)
+
+//go:embed statusspecgen.go.tmpl
+var embeddedTemplate embed.FS
+
+// Options configures the generated code.
+type Options struct {
+	// Component is the component the status codes belong to, e.g., "ai.intrinsic.my_skill".
+	Component string
+	// Package is the name of the generated Go package.
+	Package string
+	// MinCode and MaxCode restrict the codes the component may declare. Zero values default to
+	// statusspecs.MinComponentCode and no upper bound, respectively.
+	MinCode uint32
+	MaxCode uint32
+}
+
+type templateSpec struct {
+	Name         string
+	Code         uint32
+	Title        string
+	Instructions string
+}
+
+type templateParameters struct {
+	Component string
+	Package   string
+	Specs     []templateSpec
+}
+
+// Validate checks the status specs against the given options, see statusspecs.Validate. It also
+// checks that the titles of the specs result in unique Go identifiers.
+func Validate(specs *specpb.StatusSpecs, opts Options) error {
+	if _, err := templateSpecs(specs, opts); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Generate returns the formatted source of a Go package for the given status specs.
+func Generate(specs *specpb.StatusSpecs, opts Options) ([]byte, error) {
+	if !token.IsIdentifier(opts.Package) {
+		return nil, fmt.Errorf("invalid package name %q", opts.Package)
+	}
+	tSpecs, err := templateSpecs(specs, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := template.New("statusspecgen.go.tmpl").ParseFS(embeddedTemplate, "statusspecgen.go.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse template: %v", err)
+	}
+	b := new(bytes.Buffer)
+	if err := t.Execute(b, templateParameters{
+		Component: opts.Component,
+		Package:   opts.Package,
+		Specs:     tSpecs,
+	}); err != nil {
+		return nil, fmt.Errorf("cannot populate: %v", err)
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid Go code: %v", err)
+	}
+	return src, nil
+}
+
+func templateSpecs(specs *specpb.StatusSpecs, opts Options) ([]templateSpec, error) {
+	minCode := opts.MinCode
+	if minCode == 0 {
+		minCode = statusspecs.MinComponentCode
+	}
+	maxCode := opts.MaxCode
+	if maxCode == 0 {
+		maxCode = math.MaxUint32
+	}
+	if err := statusspecs.Validate(opts.Component, specs.GetStatusInfo(), statusspecs.WithCodeRange(minCode, maxCode)); err != nil {
+		return nil, err
+	}
+
+	var tSpecs []templateSpec
+	names := map[string]uint32{}
+	for _, spec := range specs.GetStatusInfo() {
+		name := Identifier(spec.GetTitle())
+		if name == "" {
+			return nil, fmt.Errorf("title %q of code %d does not contain any letters or digits", spec.GetTitle(), spec.GetCode())
+		}
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("titles of codes %d and %d both result in the name %q, make them distinct", other, spec.GetCode(), name)
+		}
+		names[name] = spec.GetCode()
+		tSpecs = append(tSpecs, templateSpec{
+			Name:         name,
+			Code:         spec.GetCode(),
+			Title:        spec.GetTitle(),
+			Instructions: spec.GetRecoveryInstructions(),
+		})
+	}
+	sort.Slice(tSpecs, func(i, j int) bool { return tSpecs[i].Code < tSpecs[j].Code })
+	return tSpecs, nil
+}
+
+// Identifier converts the title of a status spec into an exported Go identifier, e.g., "Robot
+// not reachable" into "RobotNotReachable". It returns an empty string if the title contains no
+// letters or digits.
+func Identifier(title string) string {
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])))
+		b.WriteString(string(r[1:]))
+	}
+	return b.String()
+}
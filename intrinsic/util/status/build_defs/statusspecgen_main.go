@@ -0,0 +1,60 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// package main validates a status specs text proto and generates a Go package for it.
+package main
+
+import (
+	"os"
+
+	"flag"
+	log "github.com/golang/glog"
+	specpb "intrinsic/assets/proto/status_spec_go_proto"
+	intrinsic "intrinsic/production/intrinsic"
+	"intrinsic/util/proto/protoio"
+	"intrinsic/util/status/build_defs/statusspecgen"
+)
+
+var (
+	flagSpecs        = flag.String("specs", "", "Path to a StatusSpecs textproto file.")
+	flagComponent    = flag.String("component", "", "The component the status codes belong to.")
+	flagPackage      = flag.String("package", "", "The name of the generated Go package.")
+	flagMinCode      = flag.Uint("min_code", 0, "Optional smallest code the component may declare, defaults to the smallest code available to components.")
+	flagMaxCode      = flag.Uint("max_code", 0, "Optional largest code the component may declare.")
+	flagOutput       = flag.String("output", "", "Optional output path for the generated Go source.")
+	flagBinaryOutput = flag.String("binary_output", "", "Optional output path for the status specs as binary proto, e.g., for statusspecs.InitFromFile.")
+)
+
+func main() {
+	intrinsic.Init()
+
+	specs := new(specpb.StatusSpecs)
+	if err := protoio.ReadTextProto(*flagSpecs, specs); err != nil {
+		log.Exitf("Failed to read status specs: %v", err)
+	}
+
+	opts := statusspecgen.Options{
+		Component: *flagComponent,
+		Package:   *flagPackage,
+		MinCode:   uint32(*flagMinCode),
+		MaxCode:   uint32(*flagMaxCode),
+	}
+	if *flagOutput == "" {
+		if err := statusspecgen.Validate(specs, opts); err != nil {
+			log.Exitf("Invalid status specs in %s: %v", *flagSpecs, err)
+		}
+	} else {
+		src, err := statusspecgen.Generate(specs, opts)
+		if err != nil {
+			log.Exitf("Failed to generate code for %s: %v", *flagSpecs, err)
+		}
+		if err := os.WriteFile(*flagOutput, src, 0644); err != nil {
+			log.Exitf("Failed to write %s: %v", *flagOutput, err)
+		}
+	}
+
+	if *flagBinaryOutput != "" {
+		if err := protoio.WriteBinaryProto(*flagBinaryOutput, specs, protoio.WithDeterministic(true)); err != nil {
+			log.Exitf("Failed to write %s: %v", *flagBinaryOutput, err)
+		}
+	}
+}
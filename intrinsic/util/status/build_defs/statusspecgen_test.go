@@ -0,0 +1,120 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package statusspecgen
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	specpb "intrinsic/assets/proto/status_spec_go_proto"
+)
+
+func TestIdentifier(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "Robot not reachable", want: "RobotNotReachable"},
+		{title: "gRPC call failed", want: "GRPCCallFailed"},
+		{title: "Invalid pose (out of reach)", want: "InvalidPoseOutOfReach"},
+		{title: "Timeout after 5s", want: "TimeoutAfter5s"},
+		{title: "...", want: ""},
+	}
+	for _, tc := range tests {
+		if got := Identifier(tc.title); got != tc.want {
+			t.Errorf("Identifier(%q) = %q, want %q", tc.title, got, tc.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	specs := &specpb.StatusSpecs{
+		StatusInfo: []*specpb.StatusSpec{
+			{Code: 10002, Title: "Gripper jammed", RecoveryInstructions: "Open the gripper \"manually\"."},
+			{Code: 10001, Title: "Robot not reachable", RecoveryInstructions: "Check the network connection."},
+		},
+	}
+
+	src, err := Generate(specs, Options{Component: "ai.intrinsic.test", Package: "teststatus"})
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "teststatus.go", src, 0); err != nil {
+		t.Fatalf("Generate() returned invalid Go code: %v\n%s", err, src)
+	}
+	for _, want := range []string{
+		"package teststatus",
+		`const Component = "ai.intrinsic.test"`,
+		"CodeRobotNotReachable uint32 = 10001",
+		"CodeGripperJammed uint32 = 10002",
+		`RecoveryInstructions: "Open the gripper \"manually\".",`,
+		"func NewRobotNotReachable(userMessage string, options ...statusspecs.CreateOption) *extstatus.ExtendedStatus {",
+		"return Registry.Create(CodeGripperJammed, userMessage, options...)",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("Generate() returned code without %q:\n%s", want, src)
+		}
+	}
+	if strings.Index(string(src), "CodeRobotNotReachable uint32") > strings.Index(string(src), "CodeGripperJammed ") {
+		t.Errorf("Generate() did not sort codes:\n%s", src)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	valid := &specpb.StatusSpec{Code: 10001, Title: "Robot not reachable", RecoveryInstructions: "Check the network connection."}
+
+	tests := []struct {
+		name      string
+		specs     []*specpb.StatusSpec
+		opts      Options
+		wantError string
+	}{
+		{
+			name:      "invalid package",
+			specs:     []*specpb.StatusSpec{valid},
+			opts:      Options{Component: "ai.intrinsic.test", Package: "test-status"},
+			wantError: "invalid package name",
+		},
+		{
+			name: "invalid spec",
+			specs: []*specpb.StatusSpec{
+				{Code: 10001, Title: "Robot not reachable"},
+			},
+			opts:      Options{Component: "ai.intrinsic.test", Package: "teststatus"},
+			wantError: "no recovery instructions",
+		},
+		{
+			name:      "outside of code range",
+			specs:     []*specpb.StatusSpec{valid},
+			opts:      Options{Component: "ai.intrinsic.test", Package: "teststatus", MinCode: 20000, MaxCode: 20999},
+			wantError: "outside of the range",
+		},
+		{
+			name: "conflicting names",
+			specs: []*specpb.StatusSpec{
+				valid,
+				{Code: 10002, Title: "Robot not reachable!", RecoveryInstructions: "Check the robot."},
+			},
+			opts:      Options{Component: "ai.intrinsic.test", Package: "teststatus"},
+			wantError: "both result in the name",
+		},
+		{
+			name: "title without letters",
+			specs: []*specpb.StatusSpec{
+				{Code: 10001, Title: "???", RecoveryInstructions: "Check the robot."},
+			},
+			opts:      Options{Component: "ai.intrinsic.test", Package: "teststatus"},
+			wantError: "does not contain any letters or digits",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Generate(&specpb.StatusSpecs{StatusInfo: tc.specs}, tc.opts)
+			if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+				t.Errorf("Generate() returned error %v, want error containing %q", err, tc.wantError)
+			}
+		})
+	}
+}
@@ -19,11 +19,19 @@
 // process, it is not to be used in API libraries. Call this in a process's
 // main() function, not in a library. This must finish before calling any other
 // function, in particular before any call to Create!
+//
+// Alternatively, a Registry holds the specs of one component without touching
+// the package state, so that several components can coexist in one binary. The
+// statusspecgen tool generates a package with a Registry, constants and
+// constructor functions for every declared code from a status specs text proto
+// and validates the file at build time.
 package statusspecs
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"strings"
 	"time"
 
 	log "github.com/golang/glog"
@@ -35,53 +43,77 @@ import (
 	espb "intrinsic/util/status/extended_status_go_proto"
 )
 
-type initData struct {
+// MinComponentCode is the smallest code components may declare in their status specs. Codes up to
+// 10000 are reserved.
+const MinComponentCode = 10001
+
+// Registry holds the status specs of one component and creates extended statuses from them.
+// Processes which report errors for several components, e.g., because they link in generated
+// status spec packages of multiple components, use one Registry per component.
+type Registry struct {
 	component string
 	specs     map[uint32]*specpb.StatusSpec
 }
 
-var (
-	pkgData initData
-)
+// NewRegistry creates a registry for the given component from a list of status specs. If a code
+// is declared more than once, the last spec wins. Use Validate to check the specs, e.g., for
+// duplicate codes.
+func NewRegistry(component string, statusSpecs []*specpb.StatusSpec) *Registry {
+	specs := map[uint32]*specpb.StatusSpec{}
+	for _, spec := range statusSpecs {
+		specs[spec.GetCode()] = spec
+	}
+	return &Registry{
+		component: component,
+		specs:     specs,
+	}
+}
 
-// InitFromFile initializes status specs from a file.
-func InitFromFile(component string, filename string) error {
+// NewRegistryFromFile creates a registry for the given component from a binary proto file
+// containing an intrinsic_proto.assets.StatusSpecs message.
+func NewRegistryFromFile(component string, filename string) (*Registry, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	specFile := &specpb.StatusSpecs{}
-
 	if err = proto.Unmarshal(data, specFile); err != nil {
-		return err
+		return nil, err
 	}
 
-	specs := map[uint32]*specpb.StatusSpec{}
-	for _, spec := range specFile.StatusInfo {
-		specs[spec.GetCode()] = spec
-	}
+	return NewRegistry(component, specFile.GetStatusInfo()), nil
+}
 
-	pkgData = initData{
-		component: component,
-		specs:     specs,
-	}
+// Component returns the component the registry creates extended statuses for.
+func (r *Registry) Component() string {
+	return r.component
+}
 
-	return nil
+// Spec returns the status spec declared for the given code, if any.
+func (r *Registry) Spec(code uint32) (*specpb.StatusSpec, bool) {
+	spec, ok := r.specs[code]
+	return spec, ok
 }
 
-// InitFromList initializes status specs from a given list.
-func InitFromList(component string, statusSpecs []*specpb.StatusSpec) error {
-	specs := map[uint32]*specpb.StatusSpec{}
-	for _, spec := range statusSpecs {
-		specs[spec.GetCode()] = spec
-	}
+var (
+	defaultRegistry = &Registry{}
+)
 
-	pkgData = initData{
-		component: component,
-		specs:     specs,
+// InitFromFile initializes the status specs used by Create from a file.
+func InitFromFile(component string, filename string) error {
+	r, err := NewRegistryFromFile(component, filename)
+	if err != nil {
+		return err
 	}
 
+	defaultRegistry = r
+	return nil
+}
+
+// InitFromList initializes the status specs used by Create from a given list.
+func InitFromList(component string, statusSpecs []*specpb.StatusSpec) error {
+	defaultRegistry = NewRegistry(component, statusSpecs)
 	return nil
 }
 
@@ -131,26 +163,32 @@ func WithContext(context *extstatus.ExtendedStatus) CreateOption {
 	}
 }
 
-// Create creates an ExtendedStatus based on information initialized from specs.
+// Create creates an ExtendedStatus based on information initialized from specs with InitFromFile
+// or InitFromList.
 func Create(code uint32, userMessage string, options ...CreateOption) *extstatus.ExtendedStatus {
+	return defaultRegistry.Create(code, userMessage, options...)
+}
+
+// Create creates an ExtendedStatus based on the status spec declared for code in the registry.
+func (r *Registry) Create(code uint32, userMessage string, options ...CreateOption) *extstatus.ExtendedStatus {
 	opts := createOptions{}
 	for _, optFunc := range options {
 		optFunc(&opts)
 	}
 
-	if pkgData.component == "" {
+	if r.component == "" {
 		log.Warningf("No component specified when populating error %d, call an Init* function.", code)
 	}
 
 	title := ""
 	userInstructions := ""
-	spec, ok := pkgData.specs[code]
+	spec, ok := r.specs[code]
 
 	if ok {
 		title = spec.GetTitle()
 		userInstructions = spec.GetRecoveryInstructions()
 	} else {
-		title = fmt.Sprintf("Undeclared error %s:%d", pkgData.component, code)
+		title = fmt.Sprintf("Undeclared error %s:%d", r.component, code)
 		opts.context = append(opts.context, &espb.ExtendedStatus{
 			StatusCode: &espb.StatusCode{
 				Component: "ai.intrinsic.errors",
@@ -159,20 +197,80 @@ func Create(code uint32, userMessage string, options ...CreateOption) *extstatus
 			Severity: espb.ExtendedStatus_WARNING,
 			Title:    "Error code not declared",
 			UserReport: &espb.ExtendedStatus_UserReport{
-				Message:      fmt.Sprintf("The code %s:%d has not been declared by the component.", pkgData.component, code),
-				Instructions: fmt.Sprintf("Inform the owner of %s to add error %d to the status specs file.", pkgData.component, code),
+				Message:      fmt.Sprintf("The code %s:%d has not been declared by the component.", r.component, code),
+				Instructions: fmt.Sprintf("Inform the owner of %s to add error %d to the status specs file.", r.component, code),
 			},
 		})
 
 	}
 
-	return extstatus.New(pkgData.component, code,
-		extstatus.WithTimestamp(*opts.timestamp),
+	esOpts := []extstatus.NewOption{
 		extstatus.WithTitle(title),
 		extstatus.WithUserMessage(userMessage),
 		extstatus.WithUserInstructions(userInstructions),
 		extstatus.WithDebugMessage(opts.debugMessage),
 		extstatus.WithContextProtos(opts.context),
 		extstatus.WithLogContext(opts.logContext),
-	)
+	}
+	if opts.timestamp != nil {
+		esOpts = append(esOpts, extstatus.WithTimestamp(*opts.timestamp))
+	}
+	return extstatus.New(r.component, code, esOpts...)
+}
+
+// validateOptions defines optional arguments to the Validate call.
+type validateOptions struct {
+	minCode uint32
+	maxCode uint32
+}
+
+// ValidateOption is a function type for modifying validateOptions.
+type ValidateOption func(*validateOptions)
+
+// WithCodeRange returns an option function to require all codes to be within [minCode, maxCode].
+// Components use this to reserve ranges of codes, e.g., for different subsystems.
+func WithCodeRange(minCode, maxCode uint32) ValidateOption {
+	return func(o *validateOptions) {
+		o.minCode = minCode
+		o.maxCode = maxCode
+	}
+}
+
+// Validate checks the status specs of a component. Every code must be declared once and within
+// the allowed range, and every spec must have a single-line title and recovery instructions. All
+// violations are reported in the returned error.
+func Validate(component string, statusSpecs []*specpb.StatusSpec, options ...ValidateOption) error {
+	opts := validateOptions{minCode: MinComponentCode, maxCode: math.MaxUint32}
+	for _, optFunc := range options {
+		optFunc(&opts)
+	}
+
+	var problems []string
+	if component == "" {
+		problems = append(problems, "no component specified")
+	}
+	seen := map[uint32]bool{}
+	for _, spec := range statusSpecs {
+		code := spec.GetCode()
+		if seen[code] {
+			problems = append(problems, fmt.Sprintf("code %d is declared more than once", code))
+		}
+		seen[code] = true
+		if code < opts.minCode || code > opts.maxCode {
+			problems = append(problems, fmt.Sprintf("code %d is outside of the range [%d, %d] of %s", code, opts.minCode, opts.maxCode, component))
+		}
+		if strings.TrimSpace(spec.GetTitle()) == "" {
+			problems = append(problems, fmt.Sprintf("code %d has no title", code))
+		} else if strings.Contains(spec.GetTitle(), "\n") {
+			problems = append(problems, fmt.Sprintf("title of code %d must be a single line", code))
+		}
+		if strings.TrimSpace(spec.GetRecoveryInstructions()) == "" {
+			problems = append(problems, fmt.Sprintf("code %d has no recovery instructions", code))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid status specs for %q:\n  %s", component, strings.Join(problems, "\n  "))
+	}
+	return nil
 }
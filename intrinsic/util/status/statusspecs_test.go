@@ -3,6 +3,7 @@
 package statusspecs
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -181,3 +182,146 @@ func TestCreateOptions(t *testing.T) {
 		t.Errorf("CreateWithOptions returned unexpected diff (-want +got):\n%s", diff)
 	}
 }
+
+func TestRegistriesCoexist(t *testing.T) {
+	r1 := NewRegistry("ai.intrinsic.test1", []*specpb.StatusSpec{
+		{Code: 10001, Title: "Error 1", RecoveryInstructions: "Test instructions 1"},
+	})
+	r2 := NewRegistry("ai.intrinsic.test2", []*specpb.StatusSpec{
+		{Code: 10001, Title: "Error 2", RecoveryInstructions: "Test instructions 2"},
+	})
+
+	timestamp, _ := time.Parse(time.RFC3339, "2024-03-26T11:51:13Z")
+	got1 := r1.Create(10001, "User 1", WithTimestamp(timestamp))
+	got2 := r2.Create(10001, "User 2", WithTimestamp(timestamp))
+
+	want1 := &espb.ExtendedStatus{
+		StatusCode: &espb.StatusCode{Component: "ai.intrinsic.test1", Code: 10001},
+		Title:      "Error 1",
+		Timestamp:  &timestamppb.Timestamp{Seconds: 1711453873},
+		UserReport: &espb.ExtendedStatus_UserReport{
+			Message:      "User 1",
+			Instructions: "Test instructions 1",
+		},
+	}
+	want2 := &espb.ExtendedStatus{
+		StatusCode: &espb.StatusCode{Component: "ai.intrinsic.test2", Code: 10001},
+		Title:      "Error 2",
+		Timestamp:  &timestamppb.Timestamp{Seconds: 1711453873},
+		UserReport: &espb.ExtendedStatus_UserReport{
+			Message:      "User 2",
+			Instructions: "Test instructions 2",
+		},
+	}
+	if diff := cmp.Diff(want1, got1.Proto(), protocmp.Transform()); diff != "" {
+		t.Errorf("r1.Create() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want2, got2.Proto(), protocmp.Transform()); diff != "" {
+		t.Errorf("r2.Create() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCreateWithoutTimestamp(t *testing.T) {
+	r := NewRegistry("ai.intrinsic.test", nil)
+
+	if got := r.Create(10001, "User 1"); got.Proto().GetTimestamp() == nil {
+		t.Errorf("Create() returned status without timestamp: %v", got.Proto())
+	}
+}
+
+func TestNewRegistryDuplicateCode(t *testing.T) {
+	// later declarations win, like InitFromList and InitFromFile always did.
+	r := NewRegistry("ai.intrinsic.test", []*specpb.StatusSpec{
+		{Code: 10001, Title: "Error 1", RecoveryInstructions: "Test instructions 1"},
+		{Code: 10001, Title: "Error 2", RecoveryInstructions: "Test instructions 2"},
+	})
+	if spec, ok := r.Spec(10001); !ok || spec.GetTitle() != "Error 2" {
+		t.Errorf("Spec(10001) = %v, %t, want the last declaration", spec, ok)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := &specpb.StatusSpec{Code: 10001, Title: "Error 1", RecoveryInstructions: "Test instructions 1"}
+
+	tests := []struct {
+		name      string
+		component string
+		specs     []*specpb.StatusSpec
+		options   []ValidateOption
+		wantError []string
+	}{
+		{
+			name:      "valid",
+			component: "ai.intrinsic.test",
+			specs:     []*specpb.StatusSpec{valid},
+		},
+		{
+			name:      "valid with code range",
+			component: "ai.intrinsic.test",
+			specs:     []*specpb.StatusSpec{valid},
+			options:   []ValidateOption{WithCodeRange(10001, 10999)},
+		},
+		{
+			name:      "no component",
+			specs:     []*specpb.StatusSpec{valid},
+			wantError: []string{"no component specified"},
+		},
+		{
+			name:      "duplicate code",
+			component: "ai.intrinsic.test",
+			specs: []*specpb.StatusSpec{
+				valid,
+				{Code: 10001, Title: "Error 2", RecoveryInstructions: "Test instructions 2"},
+			},
+			wantError: []string{"code 10001 is declared more than once"},
+		},
+		{
+			name:      "reserved code",
+			component: "ai.intrinsic.test",
+			specs: []*specpb.StatusSpec{
+				{Code: 604, Title: "Error 1", RecoveryInstructions: "Test instructions 1"},
+			},
+			wantError: []string{"code 604 is outside of the range"},
+		},
+		{
+			name:      "outside of code range",
+			component: "ai.intrinsic.test",
+			specs:     []*specpb.StatusSpec{valid},
+			options:   []ValidateOption{WithCodeRange(20000, 20999)},
+			wantError: []string{"code 10001 is outside of the range [20000, 20999]"},
+		},
+		{
+			name:      "missing title and instructions",
+			component: "ai.intrinsic.test",
+			specs: []*specpb.StatusSpec{
+				{Code: 10001},
+				{Code: 10002, Title: "Two\nlines", RecoveryInstructions: "Test instructions 2"},
+			},
+			wantError: []string{
+				"code 10001 has no title",
+				"code 10001 has no recovery instructions",
+				"title of code 10002 must be a single line",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.component, tc.specs, tc.options...)
+			if len(tc.wantError) == 0 {
+				if err != nil {
+					t.Errorf("Validate() failed: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() succeeded, want error containing %q", tc.wantError)
+			}
+			for _, want := range tc.wantError {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() returned error %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}